@@ -0,0 +1,401 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Config is the access information of Client, the empty fields are loaded from the config
+// file like the command line, ConfigFile is ~/.ossutilconfig if it's empty
+type Config struct {
+	ConfigFile      string
+	Endpoint        string
+	AccessKeyID     string
+	AccessKeySecret string
+	STSToken        string
+	RetryTimes      int64
+}
+
+// Client runs ossutil commands in go programs, it returns the results as values and prints
+// nothing to stdout
+type Client struct {
+	config Config
+}
+
+// NewClient creates a Client with the access information
+func NewClient(config Config) *Client {
+	return &Client{config: config}
+}
+
+// ListEntry is a bucket, object, directory or multipart upload listed by List
+type ListEntry struct {
+	Type         string // ListEntryBucket, ListEntryObject, ListEntryDirectory or ListEntryMultipart
+	Bucket       string
+	Key          string // object name, directory prefix, or object name of multipart upload
+	Size         int64
+	LastModified time.Time // creation time of bucket, initiated time of multipart upload
+	StorageClass string
+	ETag         string
+	ObjectType   string
+	Location     string // region of bucket
+	UploadID     string
+
+	// RestoreStatus is the restore status of Archive object if ListOptions.RestoreStatus is true,
+	// it's RestoreStatusUnknown if the status can't be queried
+	RestoreStatus string
+}
+
+// Progress is the statistic of a running Copy or Remove
+type Progress struct {
+	TotalNum  int64 // the number scanned, it's the total number if ScanEnd is true
+	TotalSize int64
+	ScanEnd   bool
+	DealNum   int64
+	DealSize  int64
+	OKNum     int64
+	ErrNum    int64
+	SkipNum   int64
+}
+
+// ItemError is the error of a single file or object, which does not stop a recursive command
+type ItemError struct {
+	Message string
+	Err     error
+}
+
+func (e ItemError) Error() string {
+	return fmt.Sprintf("%s error, info: %s", e.Message, e.Err.Error())
+}
+
+// ListOptions is the options of List
+type ListOptions struct {
+	Directory      bool
+	Multipart      bool
+	AllType        bool
+	LimitedNum     int64 // 0 means no limit
+	Marker         string
+	UploadIDMarker string
+	EncodingType   string
+	RestoreStatus  bool // query the restore status of Archive objects, it needs a HEAD request per object
+
+	// OnEntry receives the entries one by one instead of returning them, it's useful for large buckets
+	OnEntry func(ListEntry)
+}
+
+// CopyOptions is the options of Copy and Sync
+type CopyOptions struct {
+	Recursive        bool
+	Update           bool
+	Checksum         bool
+	SizeOnly         bool
+	Verify           bool
+	Preserve         bool
+	AutoRestore      bool
+	Symlinks         string
+	Range            string
+	EncodingType     string
+	Routines         int64
+	Parallel         int64
+	PartSize         int64
+	BigFileThreshold int64
+	CheckpointDir    string // the checkpoint dir is removed when Copy succeeds, concurrent Copies should use different dirs
+	OutputDir        string
+	SnapshotPath     string
+
+	OnProgress func(Progress)
+	OnError    func(ItemError)
+}
+
+// TransferSummary is the result of Copy and Sync
+type TransferSummary struct {
+	TotalNum     int64
+	TotalSize    int64
+	FileNum      int64
+	DirNum       int64
+	SkipNum      int64
+	ErrNum       int64
+	TransferSize int64
+	SkipSize     int64
+	ReportFile   string // the report file of errors, empty if there is no error
+}
+
+// RemoveOptions is the options of Remove
+type RemoveOptions struct {
+	Recursive    bool
+	Bucket       bool
+	Multipart    bool
+	AllType      bool
+	EncodingType string
+
+	OnProgress func(Progress)
+	OnError    func(ItemError)
+}
+
+// RemoveSummary is the result of Remove
+type RemoveSummary struct {
+	ObjectNum      int64
+	UploadIDNum    int64
+	ErrObjectNum   int64
+	ErrUploadIDNum int64
+	RemovedBucket  string
+}
+
+// apiHooks receives the progress and errors of Copy, the command prints nothing if it's set
+type apiHooks struct {
+	onProgress func(Progress)
+	onError    func(ItemError)
+}
+
+func (h *apiHooks) progress(progress Progress) {
+	if h != nil && h.onProgress != nil {
+		h.onProgress(progress)
+	}
+}
+
+func (h *apiHooks) itemError(msg string, err error) {
+	if h != nil && h.onError != nil {
+		h.onError(ItemError{msg, err})
+	}
+}
+
+// List lists buckets if url is empty or oss://, otherwise lists objects and multipart uploads
+// of oss://bucket[/prefix]
+func (c *Client) List(ctx context.Context, url string, opts ListOptions) ([]ListEntry, error) {
+	lsOption, err := opts.listOption()
+	if err != nil {
+		return nil, err
+	}
+	entries := []ListEntry{}
+	if lsOption.onEntry == nil {
+		lsOption.onEntry = func(entry ListEntry) { entries = append(entries, entry) }
+	}
+
+	args := []string{}
+	if url != "" {
+		args = append(args, url)
+	}
+	lc := ListCommand{command: listCommand.command}
+	if err := c.init(ctx, &lc, &lc.command, args); err != nil {
+		return nil, err
+	}
+	lc.lsOption = lsOption
+	if err := lc.list(ctx, url); err != nil {
+		return nil, err
+	}
+	if opts.OnEntry != nil {
+		return nil, nil
+	}
+	return entries, nil
+}
+
+// listOption checks the options and makes the options of ls
+func (opts ListOptions) listOption() (listOptionType, error) {
+	if opts.EncodingType != "" {
+		if err := checkAlternativeOptionValue(OptionEncodingType, opts.EncodingType); err != nil {
+			return listOptionType{}, err
+		}
+	}
+	lsOption := listOptionType{
+		directory:      opts.Directory,
+		typeSet:        getSubjectType(opts.Multipart, opts.AllType),
+		limitedNum:     opts.LimitedNum,
+		marker:         opts.Marker,
+		uploadIDMarker: opts.UploadIDMarker,
+		encodingType:   opts.EncodingType,
+		restoreStatus:  opts.RestoreStatus,
+		onEntry:        opts.OnEntry,
+	}
+	if lsOption.limitedNum <= 0 {
+		lsOption.limitedNum = DefaultLimitedNum
+	}
+	return lsOption, nil
+}
+
+// Stat returns the stat information of bucket or object, with the same names as the stat command
+func (c *Client) Stat(ctx context.Context, url string) (map[string]string, error) {
+	sc := StatCommand{command: statCommand.command}
+	if err := c.init(ctx, &sc, &sc.command, []string{url}); err != nil {
+		return nil, err
+	}
+	cloudURL, err := CloudURLFromString(url, "")
+	if err != nil {
+		return nil, err
+	}
+	items, err := sc.stat(ctx, cloudURL)
+	if err != nil {
+		return nil, err
+	}
+	stat := make(map[string]string, len(items))
+	for _, item := range items {
+		stat[item.name] = item.value
+	}
+	return stat, nil
+}
+
+// Copy uploads, downloads or copies files like the cp command, it never asks for confirmation.
+// A recursive Copy continues when an item fails, the failures are passed to OnError and recorded
+// in the report file.
+func (c *Client) Copy(ctx context.Context, srcURLs []string, destURL string, opts CopyOptions) (TransferSummary, error) {
+	cpOption, err := opts.copyOption()
+	if err != nil {
+		return TransferSummary{}, err
+	}
+
+	cc := CopyCommand{command: copyCommand.command}
+	if err := c.init(ctx, &cc, &cc.command, append(append([]string{}, srcURLs...), destURL)); err != nil {
+		return TransferSummary{}, err
+	}
+	cc.command.hooks = &apiHooks{onProgress: opts.OnProgress, onError: opts.OnError}
+	cc.cpOption = cpOption
+	err = cc.copy(srcURLs, destURL)
+
+	summary := cc.monitor.getSummary()
+	summary.TotalNum = cc.monitor.totalNum
+	summary.TotalSize = cc.monitor.totalSize
+	if cc.cpOption.reporter != nil && cc.cpOption.reporter.written {
+		summary.ReportFile = cc.cpOption.reporter.path
+	}
+	return summary, err
+}
+
+// copyOption checks the options and makes the options of cp, 0 and empty values are the defaults
+// of cp command
+func (opts CopyOptions) copyOption() (copyOptionType, error) {
+	for name, val := range map[string]int64{
+		OptionRoutines:         opts.Routines,
+		OptionParallel:         opts.Parallel,
+		OptionPartSize:         opts.PartSize,
+		OptionBigFileThreshold: opts.BigFileThreshold,
+	} {
+		if val == 0 {
+			continue
+		}
+		if err := checkIntOptionValue(name, val); err != nil {
+			return copyOptionType{}, err
+		}
+	}
+	for name, val := range map[string]string{
+		OptionSymlinks:     opts.Symlinks,
+		OptionEncodingType: opts.EncodingType,
+	} {
+		if val == "" {
+			continue
+		}
+		if err := checkAlternativeOptionValue(name, val); err != nil {
+			return copyOptionType{}, err
+		}
+	}
+
+	cpOption := copyOptionType{
+		batchOptionType: batchOptionType{ctnu: opts.Recursive},
+		recursive:       opts.Recursive,
+		force:           true,
+		update:          opts.Update,
+		threshold:       opts.BigFileThreshold,
+		cpDir:           opts.CheckpointDir,
+		outputDir:       opts.OutputDir,
+		routines:        opts.Routines,
+		partSize:        opts.PartSize,
+		parallel:        opts.Parallel,
+		snapshotPath:    opts.SnapshotPath,
+		vrange:          opts.Range,
+		encodingType:    opts.EncodingType,
+		checksum:        opts.Checksum,
+		sizeOnly:        opts.SizeOnly,
+		verify:          opts.Verify,
+		preserve:        opts.Preserve,
+		symlinks:        opts.Symlinks,
+		autoRestore:     opts.AutoRestore,
+	}
+	if cpOption.threshold == 0 {
+		cpOption.threshold = DefaultBigFileThreshold
+	}
+	if cpOption.cpDir == "" {
+		cpOption.cpDir = CheckpointDir
+	}
+	if cpOption.outputDir == "" {
+		cpOption.outputDir = DefaultOutputDir
+	}
+	if cpOption.routines == 0 {
+		cpOption.routines = int64(Routines)
+	}
+	return cpOption, nil
+}
+
+// Sync copies the files of srcURL which are new or changed to destURL recursively, it's Copy
+// with Recursive and Update
+func (c *Client) Sync(ctx context.Context, srcURL, destURL string, opts CopyOptions) (TransferSummary, error) {
+	opts.Recursive = true
+	opts.Update = true
+	return c.Copy(ctx, []string{srcURL}, destURL, opts)
+}
+
+// Remove removes objects, multipart uploads or bucket like the rm command, it never asks for
+// confirmation
+func (c *Client) Remove(ctx context.Context, url string, opts RemoveOptions) (RemoveSummary, error) {
+	if opts.EncodingType != "" {
+		if err := checkAlternativeOptionValue(OptionEncodingType, opts.EncodingType); err != nil {
+			return RemoveSummary{}, err
+		}
+	}
+
+	rc := RemoveCommand{command: removeCommand.command}
+	if err := c.init(ctx, &rc, &rc.command, []string{url}); err != nil {
+		return RemoveSummary{}, err
+	}
+	cloudURL, err := CloudURLFromString(url, opts.EncodingType)
+	if err != nil {
+		return RemoveSummary{}, err
+	}
+	if cloudURL.bucket == "" {
+		return RemoveSummary{}, fmt.Errorf("invalid cloud url: %s, miss bucket", url)
+	}
+
+	rc.rmOption = removeOptionType{
+		recursive:  opts.Recursive,
+		force:      true,
+		onProgress: opts.OnProgress,
+		onError:    opts.OnError,
+	}
+	if err := rc.assembleTypeSet(cloudURL, opts.Multipart, opts.AllType, opts.Bucket); err != nil {
+		return RemoveSummary{}, err
+	}
+	rc.monitor.init()
+	err = rc.remove(ctx, cloudURL)
+	return rc.monitor.getSummary(), err
+}
+
+// init initializes cmd with the access information of client, the options of command are set
+// by the caller instead of option map
+func (c *Client) init(ctx context.Context, cmder Commander, cmd *Command, args []string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	config := c.config
+	retryTimes := ""
+	if config.RetryTimes != 0 {
+		retryTimes = strconv.FormatInt(config.RetryTimes, 10)
+	}
+	options := OptionMapType{
+		OptionConfigFile:      &config.ConfigFile,
+		OptionEndpoint:        &config.Endpoint,
+		OptionAccessKeyID:     &config.AccessKeyID,
+		OptionAccessKeySecret: &config.AccessKeySecret,
+		OptionSTSToken:        &config.STSToken,
+		OptionRetryTimes:      &retryTimes,
+	}
+	if err := checkOption(options); err != nil {
+		return err
+	}
+	if err := cmder.Init(args, options); err != nil {
+		return err
+	}
+	cmd.ctx = ctx
+	return nil
+}
@@ -0,0 +1,228 @@
+package lib
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	. "gopkg.in/check.v1"
+)
+
+func (s *OssutilCommandSuite) TestAPIListStat(c *C) {
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+
+	prefix := "api/"
+	s.createFile(uploadFileName, content, c)
+	for _, name := range []string{"a", "b", "dir/c"} {
+		s.putObject(bucketName, prefix+name, uploadFileName, c)
+	}
+
+	client := NewClient(Config{ConfigFile: configFile})
+
+	// list objects
+	entries, err := client.List(context.Background(), CloudURLToString(bucketName, prefix), ListOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(len(entries), Equals, 3)
+	c.Assert(entries[0].Type, Equals, ListEntryObject)
+	c.Assert(entries[0].Bucket, Equals, bucketName)
+	c.Assert(entries[0].Key, Equals, prefix+"a")
+	c.Assert(entries[0].Size, Equals, int64(len(content)))
+
+	// list directories and limited num
+	entries, err = client.List(context.Background(), CloudURLToString(bucketName, prefix), ListOptions{Directory: true})
+	c.Assert(err, IsNil)
+	c.Assert(len(entries), Equals, 3)
+	c.Assert(entries[2], Equals, ListEntry{Type: ListEntryDirectory, Bucket: bucketName, Key: prefix + "dir/"})
+
+	entries, err = client.List(context.Background(), CloudURLToString(bucketName, prefix), ListOptions{LimitedNum: 1})
+	c.Assert(err, IsNil)
+	c.Assert(len(entries), Equals, 1)
+
+	// stream entries
+	keys := []string{}
+	entries, err = client.List(context.Background(), CloudURLToString(bucketName, prefix), ListOptions{OnEntry: func(entry ListEntry) {
+		keys = append(keys, entry.Key)
+	}})
+	c.Assert(err, IsNil)
+	c.Assert(entries, IsNil)
+	c.Assert(keys, DeepEquals, []string{prefix + "a", prefix + "b", prefix + "dir/c"})
+
+	// list buckets
+	entries, err = client.List(context.Background(), "", ListOptions{})
+	c.Assert(err, IsNil)
+	found := false
+	for _, entry := range entries {
+		c.Assert(entry.Type, Equals, ListEntryBucket)
+		found = found || entry.Bucket == bucketName
+	}
+	c.Assert(found, Equals, true)
+
+	// stat
+	stat, err := client.Stat(context.Background(), CloudURLToString(bucketName, prefix+"a"))
+	c.Assert(err, IsNil)
+	c.Assert(stat["Content-Length"], Equals, "3")
+	c.Assert(strings.Contains(stat["Etag"], "\""), Equals, false)
+
+	stat, err = client.Stat(context.Background(), CloudURLToString(bucketName, ""))
+	c.Assert(err, IsNil)
+	c.Assert(stat[StatName], Equals, bucketName)
+
+	_, err = client.Stat(context.Background(), CloudURLToString(bucketName, "notexist"))
+	c.Assert(err, NotNil)
+
+	s.removeBucket(bucketName, true, c)
+}
+
+func (s *OssutilCommandSuite) TestAPICopyRemove(c *C) {
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+
+	dir := "ossutil-test-api-" + randLowStr(5)
+	c.Assert(os.MkdirAll(dir+"/sub", 0755), IsNil)
+	s.createFile(dir+"/a", content, c)
+	s.createFile(dir+"/sub/b", content, c)
+
+	client := NewClient(Config{ConfigFile: configFile})
+
+	// upload
+	progressNum := 0
+	summary, err := client.Copy(context.Background(), []string{dir}, CloudURLToString(bucketName, "api/"), CopyOptions{
+		Recursive:  true,
+		OnProgress: func(Progress) { progressNum++ },
+	})
+	c.Assert(err, IsNil)
+	c.Assert(summary.FileNum, Equals, int64(2))
+	c.Assert(summary.DirNum, Equals, int64(1))
+	c.Assert(summary.ErrNum, Equals, int64(0))
+	c.Assert(summary.ReportFile, Equals, "")
+
+	// sync skips the same files
+	summary, err = client.Sync(context.Background(), dir, CloudURLToString(bucketName, "api/"), CopyOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(summary.FileNum, Equals, int64(0))
+	c.Assert(summary.SkipNum, Equals, int64(3))
+
+	// download
+	summary, err = client.Copy(context.Background(), []string{CloudURLToString(bucketName, "api/sub/b")}, downloadFileName, CopyOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(summary.FileNum, Equals, int64(1))
+	c.Assert(s.readFile(downloadFileName, c), Equals, content)
+
+	// canceled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.Copy(ctx, []string{dir}, CloudURLToString(bucketName, "api2/"), CopyOptions{Recursive: true})
+	c.Assert(err, Equals, context.Canceled)
+
+	// remove
+	rs, err := client.Remove(context.Background(), CloudURLToString(bucketName, "api/"), RemoveOptions{Recursive: true})
+	c.Assert(err, IsNil)
+	c.Assert(rs.ObjectNum, Equals, int64(3))
+
+	entries, err := client.List(context.Background(), CloudURLToString(bucketName, ""), ListOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(len(entries), Equals, 0)
+
+	// remove bucket
+	rs, err = client.Remove(context.Background(), CloudURLToString(bucketName, ""), RemoveOptions{Bucket: true})
+	c.Assert(err, IsNil)
+	c.Assert(rs.RemovedBucket, Equals, bucketName)
+
+	os.RemoveAll(dir)
+	os.Remove(downloadFileName)
+}
+
+func (s *OssutilCommandSuite) TestAPIOptions(c *C) {
+	client := NewClient(Config{Endpoint: "oss-cn-hangzhou.aliyuncs.com", AccessKeyID: "ak", AccessKeySecret: "sk", RetryTimes: 3})
+	lc := ListCommand{command: listCommand.command}
+	err := client.init(context.Background(), &lc, &lc.command, nil)
+	c.Assert(err, IsNil)
+	endpoint, _ := GetString(OptionEndpoint, lc.command.options)
+	c.Assert(endpoint, Equals, "oss-cn-hangzhou.aliyuncs.com")
+	retryTimes, _ := GetInt(OptionRetryTimes, lc.command.options)
+	c.Assert(retryTimes, Equals, int64(3))
+
+	// the default retry times
+	client = NewClient(Config{Endpoint: "oss-cn-hangzhou.aliyuncs.com", AccessKeyID: "ak", AccessKeySecret: "sk"})
+	err = client.init(context.Background(), &lc, &lc.command, nil)
+	c.Assert(err, IsNil)
+	retryTimes, _ = GetInt(OptionRetryTimes, lc.command.options)
+	c.Assert(retryTimes, Equals, int64(RetryTimes))
+
+	// invalid option value
+	_, err = client.Copy(context.Background(), []string{uploadFileName}, CloudURLToString(bucketNameNotExist, ""), CopyOptions{Symlinks: "invalid"})
+	c.Assert(err, NotNil)
+	_, err = client.List(context.Background(), CloudURLToString(bucketNameNotExist, ""), ListOptions{EncodingType: "invalid"})
+	c.Assert(err, NotNil)
+	_, err = client.Remove(context.Background(), CloudURLToString(bucketNameNotExist, ""), RemoveOptions{Multipart: true})
+	c.Assert(err, NotNil)
+
+	// canceled before run
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.List(ctx, CloudURLToString(bucketNameNotExist, ""), ListOptions{})
+	c.Assert(err, Equals, context.Canceled)
+}
+
+func (s *OssutilCommandSuite) TestAPIConcurrentCopy(c *C) {
+	server := newFakeOSSServer("apiID", "apiSecret")
+	defer server.close()
+	ossClient, err := oss.New(server.endpoint(), "apiID", "apiSecret")
+	c.Assert(err, IsNil)
+	bucketName := bucketNamePrefix + randLowStr(10)
+	c.Assert(ossClient.CreateBucket(bucketName), IsNil)
+
+	// every Copy uploads a big file in parts and small files
+	big := randStr(300 * 1024)
+	dirs := make([]string, 4)
+	for i := range dirs {
+		dirs[i] = randStr(10)
+		defer os.RemoveAll(dirs[i])
+		c.Assert(os.MkdirAll(dirs[i], 0755), IsNil)
+		s.createFile(dirs[i]+"/big", big, c)
+		for j := 0; j < 5; j++ {
+			s.createFile(dirs[i]+"/"+strconv.Itoa(j), content, c)
+		}
+	}
+
+	client := NewClient(Config{ConfigFile: configFile, Endpoint: server.endpoint(), AccessKeyID: "apiID", AccessKeySecret: "apiSecret"})
+	summaries := make([]TransferSummary, len(dirs))
+	errs := make([]error, len(dirs))
+	progressNums := make([]int, len(dirs))
+	var wg sync.WaitGroup
+	for i := range dirs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer os.RemoveAll(dirs[i] + "-cp")
+			summaries[i], errs[i] = client.Copy(context.Background(), []string{dirs[i]}, CloudURLToString(bucketName, dirs[i]+"/"), CopyOptions{
+				Recursive:        true,
+				BigFileThreshold: 100 * 1024,
+				PartSize:         100 * 1024,
+				CheckpointDir:    dirs[i] + "-cp",
+				OnProgress:       func(Progress) { progressNums[i]++ },
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range dirs {
+		c.Assert(errs[i], IsNil)
+		c.Assert(summaries[i].FileNum, Equals, int64(6))
+		c.Assert(summaries[i].ErrNum, Equals, int64(0))
+		c.Assert(progressNums[i] > 0, Equals, true)
+		bucket, err := ossClient.Bucket(bucketName)
+		c.Assert(err, IsNil)
+		body, err := bucket.GetObject(dirs[i] + "/big")
+		c.Assert(err, IsNil)
+		data, err := ioutil.ReadAll(body)
+		body.Close()
+		c.Assert(err, IsNil)
+		c.Assert(string(data), Equals, big)
+	}
+}
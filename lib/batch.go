@@ -0,0 +1,236 @@
+package lib
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// batchOptionType is the options shared by recursive commands
+type batchOptionType struct {
+	ctnu     bool
+	reporter *Reporter
+	journal  *jobJournal
+	hooks    *itemHooks
+	notifier *notifier
+}
+
+// batchItem is an item of a recursive command, key is the name recorded in the job journal,
+// the item is not recorded if key is empty
+type batchItem struct {
+	key   string
+	value interface{}
+}
+
+// batchSource produces the items of a job with send, it should stop producing when send returns
+// false, which means the job is stopped by error or interrupt. The error returned stops the job.
+type batchSource func(send func(batchItem) bool) error
+
+// batchAction deals an item, it returns the description of the item used in report, and the
+// error of the item
+type batchAction func(item batchItem) (string, error)
+
+// errBatchStopped is used by sources to break walking when the job is stopped
+var errBatchStopped = errors.New("batch job is stopped")
+
+// batchEngine runs a recursive command: the items from source are dealed by action concurrently,
+// the result of each item is recorded in journal, monitor and reporter. When an item fails, the
+// job continues if option.ctnu is true, which is turned off by filterError for the errors that
+// all the items will meet, otherwise the job stops dispatching and waits for the running items.
+type batchEngine struct {
+	command  *Command
+	option   *batchOptionType
+	routines int64
+	source   batchSource
+	action   batchAction
+
+	// monitor is updated with the result of each item if it's not nil
+	monitor *Monitor
+
+	// finish shows the result when the job ends, it's the finish bar of monitor by default
+	finish func(exitStat int)
+
+	// hookItem makes the detail of item for the hooks of option, the hooks are not run by engine
+	// if it's nil, e.g. cp runs them itself, since only it knows whether a file is skipped
+	hookItem func(item batchItem) hookItem
+
+	// queue is the number of items listed ahead, ChannelBuf by default
+	queue int
+
+	stopped int32
+	mu      sync.Mutex
+	err     error
+}
+
+func (cmd *Command) newBatchEngine(option *batchOptionType, routines int64, source batchSource, action batchAction) *batchEngine {
+	if routines <= 0 {
+		routines = 1
+	}
+	return &batchEngine{command: cmd, option: option, routines: routines, source: source, action: action, queue: ChannelBuf}
+}
+
+// run runs the job and waits until all the items are dealed. It returns the error of source,
+// or the error of item if the job is stopped by it, the errors of items are reported instead
+// of returned if the job continues.
+func (e *batchEngine) run() error {
+	chItems := make(chan batchItem, e.queue)
+	var wg sync.WaitGroup
+	for i := 0; int64(i) < e.routines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.consume(chItems)
+		}()
+	}
+
+	serr := e.source(e.send(chItems))
+	if serr == errBatchStopped {
+		serr = nil
+	}
+	if serr != nil {
+		e.stop()
+	}
+	close(chItems)
+	wg.Wait()
+
+	if serr != nil {
+		return serr
+	}
+	if e.err != nil && !e.option.ctnu {
+		e.showFinish(errExit)
+		return e.err
+	}
+	e.showFinish(normalExit)
+	return nil
+}
+
+func (e *batchEngine) send(chItems chan<- batchItem) func(batchItem) bool {
+	return func(item batchItem) bool {
+		if e.isStopped() {
+			return false
+		}
+		chItems <- item
+		return true
+	}
+}
+
+func (e *batchEngine) consume(chItems <-chan batchItem) {
+	// drain the items queued before the job is stopped
+	for item := range chItems {
+		if e.isStopped() {
+			continue
+		}
+
+		msg, err := e.action(item)
+		if item.key != "" {
+			e.option.journal.done(item.key, err)
+		}
+		if e.monitor != nil {
+			e.command.updateMonitor(err, e.monitor)
+		}
+		if e.hookItem != nil {
+			e.option.hooks.done(e.option, e.hookItem(item), err)
+		}
+		e.command.report(msg, err, e.option)
+		if err != nil {
+			e.setError(err)
+		}
+	}
+}
+
+func (e *batchEngine) setError(err error) {
+	e.mu.Lock()
+	if e.err == nil || !e.option.ctnu {
+		e.err = err
+	}
+	e.mu.Unlock()
+	if !e.option.ctnu {
+		e.stop()
+	}
+}
+
+func (e *batchEngine) stop() {
+	atomic.StoreInt32(&e.stopped, 1)
+}
+
+func (e *batchEngine) isStopped() bool {
+	return atomic.LoadInt32(&e.stopped) == 1 || e.command.canceled()
+}
+
+func (e *batchEngine) showFinish(exitStat int) {
+	if e.finish != nil {
+		e.finish(exitStat)
+	} else if e.monitor != nil {
+		e.command.printf(e.monitor.progressBar(true, exitStat))
+	}
+}
+
+// sliceSource produces the items in order
+func sliceSource(items ...batchItem) batchSource {
+	return func(send func(batchItem) bool) error {
+		for _, item := range items {
+			if !send(item) {
+				break
+			}
+		}
+		return nil
+	}
+}
+
+// objectSource lists the objects under cloudURL from the marker of journal, makeItem makes the
+// item of an object, the object is skipped if ok is false. The object name is used as the item
+// if makeItem is nil.
+func (cmd *Command) objectSource(bucket *oss.Bucket, cloudURL CloudURL, journal *jobJournal, makeItem func(object oss.ObjectProperties) (item batchItem, ok bool)) batchSource {
+	if makeItem == nil {
+		makeItem = func(object oss.ObjectProperties) (batchItem, bool) {
+			return batchItem{object.Key, object.Key}, true
+		}
+	}
+
+	return func(send func(batchItem) bool) error {
+		pre := oss.Prefix(cloudURL.object)
+		marker := oss.Marker(journal.marker())
+		for {
+			lor, err := cmd.ossListObjectsRetry(bucket, marker, pre)
+			if err != nil {
+				return err
+			}
+
+			items := []batchItem{}
+			keys := []string{}
+			for _, object := range lor.Objects {
+				if item, ok := makeItem(object); ok {
+					items = append(items, item)
+					keys = append(keys, item.key)
+				}
+			}
+			journal.addPage(lor.NextMarker, keys)
+			for _, item := range items {
+				if !send(item) {
+					return nil
+				}
+			}
+
+			pre = oss.Prefix(lor.Prefix)
+			marker = oss.Marker(lor.NextMarker)
+			if !lor.IsTruncated {
+				return nil
+			}
+		}
+	}
+}
+
+// scanSource records the scan end or error of source in monitor, the items should be counted by
+// source, so that the command needs not list twice
+func scanSource(monitor Monitorer, source batchSource) batchSource {
+	return func(send func(batchItem) bool) error {
+		if err := source(send); err != nil {
+			monitor.setScanError(err)
+			return err
+		}
+		monitor.setScanEnd()
+		return nil
+	}
+}
@@ -0,0 +1,103 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	. "gopkg.in/check.v1"
+)
+
+// objectItems makes the batch items of objects, the object name is the key of item
+func objectItems(objects ...string) []batchItem {
+	items := make([]batchItem, 0, len(objects))
+	for _, object := range objects {
+		items = append(items, batchItem{object, object})
+	}
+	return items
+}
+
+func (s *OssutilCommandSuite) TestBatchEngineContinue(c *C) {
+	var dealed int64
+	option := batchOptionType{ctnu: true}
+	monitor := Monitor{}
+	monitor.init("Dealed")
+	cmd := Command{}
+	engine := cmd.newBatchEngine(&option, 3, sliceSource(objectItems("a", "b", "c", "d")...), func(item batchItem) (string, error) {
+		atomic.AddInt64(&dealed, 1)
+		if item.key == "b" {
+			return item.key, fmt.Errorf("test error")
+		}
+		return item.key, nil
+	})
+	engine.monitor = &monitor
+
+	err := engine.run()
+	c.Assert(err, IsNil)
+	c.Assert(dealed, Equals, int64(4))
+	snap := monitor.getSnapshot()
+	c.Assert(snap.okNum, Equals, int64(3))
+	c.Assert(snap.errNum, Equals, int64(1))
+}
+
+func (s *OssutilCommandSuite) TestBatchEngineStop(c *C) {
+	// the error which all items will meet stops the job
+	var dealed int64
+	option := batchOptionType{ctnu: true}
+	cmd := Command{}
+	engine := cmd.newBatchEngine(&option, 1, sliceSource(objectItems("a", "b", "c")...), func(item batchItem) (string, error) {
+		atomic.AddInt64(&dealed, 1)
+		return item.key, ObjectError{oss.ServiceError{Code: "AccessDenied"}, "bucket", item.key}
+	})
+
+	err := engine.run()
+	c.Assert(err, NotNil)
+	c.Assert(option.ctnu, Equals, false)
+	c.Assert(dealed, Equals, int64(1))
+
+	// the job stops at the first error without continue
+	dealed = 0
+	option = batchOptionType{}
+	engine = cmd.newBatchEngine(&option, 1, sliceSource(objectItems("a", "b", "c")...), func(item batchItem) (string, error) {
+		atomic.AddInt64(&dealed, 1)
+		return item.key, fmt.Errorf("test error")
+	})
+	err = engine.run()
+	c.Assert(err, NotNil)
+	c.Assert(dealed, Equals, int64(1))
+
+	// error of source
+	engine = cmd.newBatchEngine(&batchOptionType{ctnu: true}, 2, func(send func(batchItem) bool) error {
+		send(batchItem{"a", "a"})
+		return fmt.Errorf("list error")
+	}, func(item batchItem) (string, error) {
+		return item.key, nil
+	})
+	err = engine.run()
+	c.Assert(err, NotNil)
+	c.Assert(err.Error(), Equals, "list error")
+}
+
+func (s *OssutilCommandSuite) TestBatchEngineCanceled(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := Command{ctx: ctx}
+	var dealed int64
+	engine := cmd.newBatchEngine(&batchOptionType{ctnu: true}, 1, func(send func(batchItem) bool) error {
+		for i := 0; ; i++ {
+			if !send(batchItem{value: i}) {
+				return nil
+			}
+		}
+	}, func(item batchItem) (string, error) {
+		if atomic.AddInt64(&dealed, 1) == 10 {
+			cancel()
+		}
+		return "", nil
+	})
+
+	err := engine.run()
+	c.Assert(err, IsNil)
+	c.Assert(cmd.cancelError(), Equals, context.Canceled)
+	c.Assert(dealed, Equals, int64(10))
+}
@@ -0,0 +1,82 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	leveldb "github.com/syndtr/goleveldb/leveldb"
+)
+
+// checksumCache caches crc64 of local files by path, size and modify time,
+// so that files unchanged since last run need not be read again
+type checksumCache struct {
+	db *leveldb.DB
+}
+
+// openChecksumCache opens the cache under home dir. The cache is only used to speed up,
+// if it can not be opened(e.g. used by another ossutil process), crc64 is computed every time.
+func openChecksumCache() *checksumCache {
+	path := ChecksumCacheDir
+	if usr, err := user.Current(); err == nil {
+		path = strings.Replace(path, "~", usr.HomeDir, 1)
+	}
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return &checksumCache{}
+	}
+	return &checksumCache{db: db}
+}
+
+func (c *checksumCache) close() {
+	if c != nil && c.db != nil {
+		c.db.Close()
+	}
+}
+
+// fileCRC64 returns crc64 of local file, read from cache if the file is not changed
+func (c *checksumCache) fileCRC64(filePath string) (uint64, error) {
+	return c.crc64(filePath, true)
+}
+
+// computeCRC64 reads the whole file to get crc64 regardless of the cache, and updates the cache.
+// Size and modify time do not show if the content of a downloaded file is right, since the modify
+// time is set to Last-Modified of object, so verify must not trust the cache.
+func (c *checksumCache) computeCRC64(filePath string) (uint64, error) {
+	return c.crc64(filePath, false)
+}
+
+func (c *checksumCache) crc64(filePath string, cached bool) (uint64, error) {
+	f, err := os.Stat(filePath)
+	if err != nil {
+		return 0, err
+	}
+	absPath, _ := filepath.Abs(filePath)
+	stamp := fmt.Sprintf("%d%s%d%s", f.Size(), SnapshotSep, f.ModTime().UnixNano(), SnapshotSep)
+
+	if cached && c != nil && c.db != nil {
+		if val, err := c.db.Get([]byte(absPath), nil); err == nil && strings.HasPrefix(string(val), stamp) {
+			if crc, err := strconv.ParseUint(string(val[len(stamp):]), 10, 64); err == nil {
+				return crc, nil
+			}
+		}
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	crc, err := calcCRC64(file)
+	if err != nil {
+		return 0, err
+	}
+
+	if c != nil && c.db != nil {
+		c.db.Put([]byte(absPath), []byte(stamp+strconv.FormatUint(crc, 10)), nil)
+	}
+	return crc, nil
+}
@@ -1,8 +1,10 @@
 package lib
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"reflect"
 	"strconv"
 	"strings"
 
@@ -47,6 +49,12 @@ type Command struct {
 	args             []string
 	options          OptionMapType
 	configOptions    OptionMapType
+	ctx              context.Context
+	hooks            *apiHooks
+	metrics          *metrics
+
+	// explicitEndpoint means endpoint is specified by option, instead of config file
+	explicitEndpoint bool
 }
 
 // Commander is the interface of all commands
@@ -70,6 +78,9 @@ func (cmd *Command) Init(args []string, options OptionMapType, cmder interface{}
 	cmd.args = args
 	cmd.options = options
 	cmd.configOptions = OptionMapType{}
+	endpoint, _ := GetString(OptionEndpoint, cmd.options)
+	cmd.explicitEndpoint = endpoint != ""
+	cmd.ctx = commandContext
 
 	if err := cmd.checkArgs(); err != nil {
 		return err
@@ -115,7 +126,7 @@ func (cmd *Command) loadConfig(configFile string, cmder interface{}) error {
 		return cmdder.rewriteLoadConfig(configFile)
 	}
 	var err error
-	if cmd.configOptions, err = LoadConfig(configFile); err != nil && cmd.needConfigFile() {
+	if cmd.configOptions, err = currentSession.loadConfig(configFile); err != nil && cmd.needConfigFile() {
 		return err
 	}
 	return nil
@@ -160,7 +171,14 @@ func (cmd *Command) assembleOptions(cmder interface{}) {
 
 	for name, option := range cmd.configOptions {
 		if _, ok := cmd.options[name]; ok {
-			if OptionMap[name].optionType != OptionTypeFlagTrue {
+			if OptionMap[name].optionType == OptionTypeFlagTrue {
+				// the flag can only be turned on in config file
+				if val, _ := GetBool(name, cmd.options); !val {
+					opval, _ := strconv.ParseBool(option.(string))
+					cmd.options[name] = &opval
+					delete(cmd.configOptions, name)
+				}
+			} else {
 				if val, _ := GetString(name, cmd.options); val == "" {
 					opval := option.(string)
 					cmd.options[name] = &opval
@@ -193,6 +211,26 @@ func (cmd *Command) assembleOptions(cmder interface{}) {
 	}
 }
 
+// printf prints to stdout, it prints nothing if the command is run by go api
+func (cmd *Command) printf(format string, a ...interface{}) {
+	if cmd.hooks == nil {
+		fmt.Printf(format, a...)
+	}
+}
+
+// canceled shows if the context of the command is done, the producers stop dispatching then
+func (cmd *Command) canceled() bool {
+	return cmd.ctx != nil && cmd.ctx.Err() != nil
+}
+
+// cancelError returns the error of the context if the command is canceled
+func (cmd *Command) cancelError() error {
+	if cmd.canceled() {
+		return cmd.ctx.Err()
+	}
+	return nil
+}
+
 // FormatHelper is the interface for all commands to format spec information
 type FormatHelper interface {
 	formatHelpForWhole() string
@@ -284,16 +322,46 @@ func (cmd *Command) ossClient(bucket string) (*oss.Client, error) {
 	if err := cmd.checkCredentials(endpoint, accessKeyID, accessKeySecret); err != nil {
 		return nil, err
 	}
-	options := []oss.ClientOption{oss.UseCname(isCname), oss.SecurityToken(stsToken), oss.UserAgent(getUserAgent()), oss.Timeout(120, 1200)}
+
+	endpoint = cmd.httpsEndpoint(endpoint)
+
+	// the commands run in shell share the client, except the ones serving --metrics-addr, whose
+	// requests are observed by the metrics of the command
+	session := currentSession
+	if cmd.metrics != nil {
+		session = nil
+	}
+	key := strings.Join([]string{endpoint, strconv.FormatBool(isCname), accessKeyID, accessKeySecret, stsToken, strconv.FormatBool(disableCRC64), cmd.transportKey()}, "\n")
+	if client := session.client(key); client != nil {
+		return client, nil
+	}
+
+	connectTimeout, readTimeout := cmd.timeouts()
+	options := []oss.ClientOption{oss.UseCname(isCname), oss.SecurityToken(stsToken), oss.UserAgent(getUserAgent()), oss.Timeout(connectTimeout, readTimeout)}
 	if disableCRC64 {
 		options = append(options, oss.EnableCRC(false))
 	} else {
 		options = append(options, oss.EnableCRC(true))
 	}
+	httpClient, err := cmd.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	// the requests are observed for --metrics-addr
+	if cmd.metrics != nil {
+		if httpClient == nil {
+			httpClient = &http.Client{Transport: cmd.newTransport(nil, nil)}
+		}
+		httpClient.Transport = cmd.metrics.transport(httpClient.Transport)
+	}
+	if httpClient != nil {
+		options = append(options, oss.HTTPClient(httpClient))
+	}
 	client, err := oss.New(endpoint, accessKeyID, accessKeySecret, options...)
 	if err != nil {
 		return nil, err
 	}
+	session.addClient(key, client)
 	return client, nil
 }
 
@@ -323,7 +391,11 @@ func (cmd *Command) getEndpoint(bucket string) (string, bool) {
 		}
 	}
 
+	// the endpoint in config file is replaced by the one of the region where bucket is
 	endpoint, _ := GetString(OptionEndpoint, cmd.options)
+	if !cmd.explicitEndpoint {
+		endpoint = cmd.discoverEndpoint(bucket, endpoint)
+	}
 	return endpoint, false
 }
 
@@ -351,6 +423,7 @@ func (cmd *Command) ossListObjectsRetry(bucket *oss.Bucket, options ...oss.Optio
 		if int64(i) >= retryTimes {
 			return lor, BucketError{err, bucket.BucketName}
 		}
+		cmd.metrics.retry()
 	}
 }
 
@@ -364,6 +437,7 @@ func (cmd *Command) ossListMultipartUploadsRetry(bucket *oss.Bucket, options ...
 		if int64(i) >= retryTimes {
 			return lmr, BucketError{err, bucket.BucketName}
 		}
+		cmd.metrics.retry()
 	}
 }
 
@@ -377,6 +451,7 @@ func (cmd *Command) ossGetObjectStatRetry(bucket *oss.Bucket, object string) (ht
 		if int64(i) >= retryTimes {
 			return props, ObjectError{err, bucket.BucketName, object}
 		}
+		cmd.metrics.retry()
 	}
 }
 
@@ -390,16 +465,103 @@ func (cmd *Command) ossGetObjectMetaRetry(bucket *oss.Bucket, object string) (ht
 		if int64(i) >= retryTimes {
 			return props, ObjectError{err, bucket.BucketName, object}
 		}
+		cmd.metrics.retry()
 	}
 }
 
-func (cmd *Command) objectStatistic(bucket *oss.Bucket, cloudURL CloudURL, monitor Monitorer) {
+func (cmd *Command) ossCreateSymlinkRetry(bucket *oss.Bucket, symlinkObject, targetObject string) error {
+	retryTimes, _ := GetInt(OptionRetryTimes, cmd.options)
+	for i := 1; ; i++ {
+		err := bucket.PutSymlink(symlinkObject, targetObject)
+		if err == nil {
+			return err
+		}
+		if int64(i) >= retryTimes {
+			return ObjectError{err, bucket.BucketName, symlinkObject}
+		}
+		cmd.metrics.retry()
+	}
+}
+
+func (cmd *Command) ossGetSymlinkRetry(bucket *oss.Bucket, symlinkObject string) (http.Header, error) {
+	retryTimes, _ := GetInt(OptionRetryTimes, cmd.options)
+	for i := 1; ; i++ {
+		props, err := bucket.GetSymlink(symlinkObject)
+		if err == nil {
+			return props, err
+		}
+		if int64(i) >= retryTimes {
+			return props, ObjectError{err, bucket.BucketName, symlinkObject}
+		}
+		cmd.metrics.retry()
+	}
+}
+
+func (cmd *Command) ossGetBucketACLRetry(client *oss.Client, bucket string) (oss.GetBucketACLResult, error) {
+	retryTimes, _ := GetInt(OptionRetryTimes, cmd.options)
+	for i := 1; ; i++ {
+		gbar, err := client.GetBucketACL(bucket)
+		if err == nil {
+			return gbar, err
+		}
+		if int64(i) >= retryTimes {
+			return gbar, BucketError{err, bucket}
+		}
+		cmd.metrics.retry()
+	}
+}
+
+func (cmd *Command) ossGetObjectACLRetry(bucket *oss.Bucket, object string) (oss.GetObjectACLResult, error) {
+	retryTimes, _ := GetInt(OptionRetryTimes, cmd.options)
+	for i := 1; ; i++ {
+		goar, err := bucket.GetObjectACL(object)
+		if err == nil {
+			return goar, err
+		}
+		if int64(i) >= retryTimes {
+			return goar, ObjectError{err, bucket.BucketName, object}
+		}
+		cmd.metrics.retry()
+	}
+}
+
+// ossCopyObjectRetry copies objectName of bucket to destObjectName of destBucketName
+func (cmd *Command) ossCopyObjectRetry(bucket *oss.Bucket, objectName, destBucketName, destObjectName string, options ...oss.Option) error {
+	retryTimes, _ := GetInt(OptionRetryTimes, cmd.options)
+	for i := 1; ; i++ {
+		_, err := bucket.CopyObjectTo(destBucketName, destObjectName, objectName, options...)
+		if err == nil {
+			return err
+		}
+		if int64(i) >= retryTimes {
+			return ObjectError{err, bucket.BucketName, objectName}
+		}
+		cmd.metrics.retry()
+	}
+}
+
+// ossResumeCopyRetry copies objectName of srcBucketName to destObjectName of destBucket by multipart
+func (cmd *Command) ossResumeCopyRetry(destBucket *oss.Bucket, srcBucketName, objectName, destObjectName string, partSize int64, options ...oss.Option) error {
+	retryTimes, _ := GetInt(OptionRetryTimes, cmd.options)
+	for i := 1; ; i++ {
+		err := destBucket.CopyFile(srcBucketName, objectName, destObjectName, partSize, options...)
+		if err == nil {
+			return err
+		}
+		if int64(i) >= retryTimes {
+			return ObjectError{err, destBucket.BucketName, objectName}
+		}
+		cmd.metrics.retry()
+	}
+}
+
+func (cmd *Command) objectStatistic(bucket *oss.Bucket, cloudURL CloudURL, monitor Monitorer, journal *jobJournal) {
 	if monitor == nil {
 		return
 	}
 
 	pre := oss.Prefix(cloudURL.object)
-	marker := oss.Marker("")
+	marker := oss.Marker(journal.marker())
 	for {
 		lor, err := cmd.ossListObjectsRetry(bucket, marker, pre)
 		if err != nil {
@@ -418,28 +580,17 @@ func (cmd *Command) objectStatistic(bucket *oss.Bucket, cloudURL CloudURL, monit
 	monitor.setScanEnd()
 }
 
-func (cmd *Command) objectProducer(bucket *oss.Bucket, cloudURL CloudURL, chObjects chan<- string, chError chan<- error) {
-	pre := oss.Prefix(cloudURL.object)
-	marker := oss.Marker("")
-	for {
-		lor, err := cmd.ossListObjectsRetry(bucket, marker, pre)
-		if err != nil {
-			chError <- err
-			break
-		}
-
-		for _, object := range lor.Objects {
-			chObjects <- object.Key
-		}
-
-		pre = oss.Prefix(lor.Prefix)
-		marker = oss.Marker(lor.NextMarker)
-		if !lor.IsTruncated {
-			break
-		}
+func (cmd *Command) getObjectKeys(lor oss.ListObjectsResult) []string {
+	keys := make([]string, 0, len(lor.Objects))
+	for _, object := range lor.Objects {
+		keys = append(keys, object.Key)
 	}
-	defer close(chObjects)
-	chError <- nil
+	return keys
+}
+
+func (cmd *Command) openJobJournal() (*jobJournal, error) {
+	cpDir, _ := GetString(OptionCheckpointDir, cmd.options)
+	return openJobJournal(cpDir, cmd.name, cmd.args)
 }
 
 func (cmd *Command) updateMonitor(err error, monitor *Monitor) {
@@ -451,22 +602,36 @@ func (cmd *Command) updateMonitor(err error, monitor *Monitor) {
 	} else {
 		monitor.updateErrNum(1)
 	}
-	fmt.Printf(monitor.progressBar(false, normalExit))
+	cmd.printf(monitor.progressBar(false, normalExit))
 }
 
 func (cmd *Command) report(msg string, err error, option *batchOptionType) {
+	if err != nil {
+		cmd.hooks.itemError(msg, err)
+	}
 	if cmd.filterError(err, option) {
 		option.reporter.ReportError(fmt.Sprintf("%s error, info: %s", msg, err.Error()))
-		option.reporter.Prompt(err)
+		if cmd.hooks == nil {
+			option.reporter.Prompt(err)
+		}
 	}
 }
 
+// filterError shows if the error should be reported, it turns off continue of the job for the
+// errors which all the items will meet
 func (cmd *Command) filterError(err error, option *batchOptionType) bool {
 	if err == nil {
 		return false
 	}
 
-	err = err.(ObjectError).err
+	switch err.(type) {
+	case FileError:
+		err = err.(FileError).err
+	case ObjectError:
+		err = err.(ObjectError).err
+	case BucketError:
+		err = err.(BucketError).err
+	}
 
 	switch err.(type) {
 	case oss.ServiceError:
@@ -475,10 +640,23 @@ func (cmd *Command) filterError(err error, option *batchOptionType) bool {
 			option.ctnu = false
 			return false
 		}
+	case CopyError:
+		option.ctnu = false
+		return false
 	}
 	return true
 }
 
+// commandValidOptionNames returns the valid option names of command in GetAllCommands
+func commandValidOptionNames(cmd interface{}) []string {
+	optionNames := reflect.ValueOf(cmd).Elem().FieldByName("command").FieldByName("validOptionNames")
+	names := make([]string, 0, optionNames.Len())
+	for i := 0; i < optionNames.Len(); i++ {
+		names = append(names, optionNames.Index(i).String())
+	}
+	return names
+}
+
 // GetAllCommands returns all commands list
 func GetAllCommands() []interface{} {
 	return []interface{}{
@@ -489,12 +667,18 @@ func GetAllCommands() []interface{} {
 		&removeCommand,
 		&statCommand,
 		&setACLCommand,
+		&getACLCommand,
 		&setMetaCommand,
+		&setStorageClassCommand,
 		&copyCommand,
+		&diffCommand,
 		&restoreCommand,
 		&createSymlinkCommand,
 		&readSymlinkCommand,
 		&hashCommand,
+		&completionCommand,
+		&shellCommand,
+		&snapshotCommand,
 		&updateCommand,
 	}
 }
@@ -1,16 +1,25 @@
 package lib
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"reflect"
 	"runtime"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
 var commandLine string
 
+// commandContext is the context of the commands run from command line, it's canceled by
+// the first interrupt signal, then the commands stop dealing new items, wait for the running
+// ones and keep the checkpoints, so that the job can continue when run again
+var commandContext = context.Background()
+
 // ParseAndRunCommand parse command line user input, get command and options, then run command
 func ParseAndRunCommand() error {
 	ts := time.Now().UnixNano()
@@ -24,7 +33,20 @@ func ParseAndRunCommand() error {
 		return err
 	}
 
+	// shell watches the interrupt for each command run in it
+	if len(args) == 0 || args[0] != shellCommand.command.name {
+		var stop func()
+		commandContext, stop = watchInterrupt()
+		defer func() {
+			stop()
+			commandContext = context.Background()
+		}()
+	}
+
 	showElapse, err := RunCommand(args, options)
+	if commandContext.Err() != nil {
+		return fmt.Errorf("the command is interrupted, run it again to continue the unfinished job")
+	}
 	if err != nil {
 		return err
 	}
@@ -36,6 +58,65 @@ func ParseAndRunCommand() error {
 	return nil
 }
 
+// watchInterrupt returns a context canceled by the first interrupt signal, the second
+// one aborts the process immediately. stop stops watching the signals.
+func watchInterrupt() (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	chSignal := make(chan os.Signal, 2)
+	signal.Notify(chSignal, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-chSignal:
+		case <-done:
+			return
+		}
+		// nothing is running while waiting for the answer of user, abort at once, except in shell
+		// which is not left by Ctrl-C, the answer is ignored there
+		if atomic.LoadInt32(&promptingNum) > 0 && currentSession == nil {
+			removeDownloadingFiles()
+			fmt.Printf("\naborted.\n")
+			os.Exit(130)
+		}
+		fmt.Printf("\ninterrupted, waiting for the running tasks to finish, press Ctrl-C again to abort.\n")
+		cancel()
+
+		select {
+		case <-chSignal:
+		case <-done:
+			return
+		}
+		removeDownloadingFiles()
+		fmt.Printf("\naborted.\n")
+		os.Exit(130)
+	}()
+
+	return ctx, func() {
+		signal.Stop(chSignal)
+		close(done)
+		cancel()
+	}
+}
+
+// promptingNum is the number of interactive prompts waiting for the input of user
+var promptingNum int32
+
+// scanln reads the answer of user at an interactive prompt as fmt.Scanln. The first interrupt
+// signal aborts the process during it, the answer is ignored once the command is interrupted.
+func scanln(a ...interface{}) (int, error) {
+	if err := commandContext.Err(); err != nil {
+		return 0, err
+	}
+	atomic.AddInt32(&promptingNum, 1)
+	n, err := fmt.Scanln(a...)
+	atomic.AddInt32(&promptingNum, -1)
+	if cerr := commandContext.Err(); cerr != nil {
+		return 0, cerr
+	}
+	return n, err
+}
+
 func getCommandLine() string {
 	return strings.Join(os.Args, " ")
 }
@@ -1,6 +1,7 @@
 package lib
 
 import (
+	"context"
 	"fmt"
 	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
 	"io/ioutil"
@@ -9,8 +10,10 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -32,6 +35,9 @@ var (
 	accessKeyID     = "<testAccessKeyID>"
 	accessKeySecret = "<testAccessKeySecret>"
 	stsToken        = "<testSTSToken>"
+
+	// fakeOSS is the oss emulator which the suites run against when OSS_TEST_ENDPOINT is not set
+	fakeOSS *fakeOSSServer
 )
 
 var (
@@ -88,6 +94,10 @@ func SetUpCredential() {
 	if accessKeySecret == "<testAccessKeySecret>" {
 		accessKeySecret = os.Getenv("OSS_TEST_ACCESS_KEY_SECRET")
 	}
+	// run against the in-process oss emulator if no endpoint is configured
+	if endpoint == "" {
+		setUpFakeOSS()
+	}
 	if ue := os.Getenv("OSS_TEST_UPDATE_ENDPOINT"); ue != "" {
 		vUpdateEndpoint = ue
 	}
@@ -102,6 +112,40 @@ func SetUpCredential() {
 	}
 }
 
+// skipOnFakeOSS skips the test which needs the real oss service, such as dns of bucket cname
+func skipOnFakeOSS(c *C, reason string) {
+	if fakeOSS != nil {
+		c.Skip(reason + ", which the oss emulator can not serve")
+	}
+}
+
+// skipUpdateOnFakeOSS skips the test which downloads the binary of update from the internet,
+// unless the update endpoint is configured
+func skipUpdateOnFakeOSS(c *C) {
+	if os.Getenv("OSS_TEST_UPDATE_ENDPOINT") == "" {
+		skipOnFakeOSS(c, "update downloads from the internet")
+	}
+}
+
+// setUpFakeOSS starts the oss emulator once, the access key configured is used if any
+func setUpFakeOSS() {
+	if fakeOSS == nil {
+		if accessKeyID == "" {
+			accessKeyID = "fakeAccessKeyID"
+		}
+		if accessKeySecret == "" {
+			accessKeySecret = "fakeAccessKeySecret"
+		}
+		fakeOSS = newFakeOSSServer(accessKeyID, accessKeySecret)
+	}
+	endpoint = fakeOSS.endpoint()
+	accessKeyID = fakeOSS.accessKeyID
+	accessKeySecret = fakeOSS.accessKeySecret
+
+	// the emulator is consistent at once
+	sleepTime = 0
+}
+
 func (s *OssutilCommandSuite) SetUpBucketEnv(c *C) {
 	s.removeBuckets(bucketNamePrefix, c)
 	s.putBucket(bucketNameExist, c)
@@ -1413,3 +1457,36 @@ func (s *OssutilCommandSuite) TestNeedConfig(c *C) {
 
 	c.Assert(copyCommand.command.needConfigFile(), Equals, false)
 }
+
+func (s *OssutilCommandSuite) TestWatchInterrupt(c *C) {
+	if runtime.GOOS == "windows" {
+		c.Skip("interrupt signal can not be sent to self on windows")
+	}
+
+	ctx, stop := watchInterrupt()
+	c.Assert(ctx.Err(), IsNil)
+
+	p, err := os.FindProcess(os.Getpid())
+	c.Assert(err, IsNil)
+	c.Assert(p.Signal(os.Interrupt), IsNil)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		c.Fatal("context is not canceled by interrupt")
+	}
+	stop()
+
+	cmd := Command{}
+	commandContext = ctx
+	cmd.Init([]string{}, OptionMapType{}, &cmd)
+
+	// no answer is read at the prompts after interrupted
+	var val string
+	_, err = scanln(&val)
+	commandContext = context.Background()
+	c.Assert(err, Equals, context.Canceled)
+	c.Assert(atomic.LoadInt32(&promptingNum), Equals, int32(0))
+	c.Assert(cmd.canceled(), Equals, true)
+	c.Assert(cmd.cancelError(), Equals, context.Canceled)
+}
@@ -0,0 +1,664 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+var specChineseCompletion = SpecText{
+
+	synopsisText: "生成shell的自动补全脚本",
+
+	paramText: "shell [options]",
+
+	syntaxText: `
+    ossutil completion bash|zsh|fish [-c file] [-L language]
+`,
+
+	detailHelpText: `
+    该命令输出指定shell（bash、zsh或fish）的自动补全脚本，脚本可以补全命令名、命令支持的
+    选项（包括短选项和长选项）以及可选值类型选项的取值（例如--storage-class的取值）。
+
+    补全形如oss://bucket/prefix的cloud_url时，脚本以隐藏的列举模式调用ossutil，列举匹配的
+    bucket，或者bucket中prefix下一级的目录和object（至多` + completionMaxKeysText + `个），命令行中的-c、
+    -e、-i、-k、-t选项会传给列举模式。列举结果缓存在` + CompletionCacheDir + `目录下，
+    缓存` + completionCacheTTLText + `秒，列举出错时不补全。
+
+    fish补全中命令和选项的描述使用-L选项指定的语言。
+
+    启用补全：
+
+    bash:
+        source <(ossutil completion bash)
+        或者将输出保存到/etc/bash_completion.d/ossutil
+
+    zsh（在compinit之后）:
+        source <(ossutil completion zsh)
+        或者将输出保存为fpath中某个目录下的_ossutil文件
+
+    fish:
+        ossutil completion fish > ~/.config/fish/completions/ossutil.fish
+
+    补全脚本对执行该命令的程序名（例如ossutil64）生效。
+
+用法：
+
+    ossutil completion bash|zsh|fish
+`,
+
+	sampleText: `
+    1) 在当前bash中启用补全
+        source <(ossutil completion bash)
+
+    2) 为fish安装补全脚本，描述使用英文
+        ossutil completion fish -L en > ~/.config/fish/completions/ossutil.fish
+`,
+}
+
+var specEnglishCompletion = SpecText{
+
+	synopsisText: "Generate the completion script of shell",
+
+	paramText: "shell [options]",
+
+	syntaxText: `
+    ossutil completion bash|zsh|fish [-c file] [-L language]
+`,
+
+	detailHelpText: `
+    The command outputs the completion script of the specified shell(bash, zsh or fish),
+    the script completes command names, the options of commands(both short and long
+    names), and the values of alternative options(e.g. the values of --storage-class).
+
+    When completing cloud_url like oss://bucket/prefix, the script calls ossutil in a
+    hidden listing mode, which lists the matching buckets, or the directories and objects
+    one level under the prefix in bucket(at most ` + completionMaxKeysText + `), the -c, -e, -i, -k, -t
+    options in command line are passed to the listing mode. The listings are cached in
+    ` + CompletionCacheDir + ` directory for ` + completionCacheTTLText + ` seconds, nothing is completed if listing fails.
+
+    The descriptions of commands and options in fish completion are in the language
+    specified by -L option.
+
+    Enable completion:
+
+    bash:
+        source <(ossutil completion bash)
+        or save the output to /etc/bash_completion.d/ossutil
+
+    zsh(after compinit):
+        source <(ossutil completion zsh)
+        or save the output as file _ossutil in a directory of fpath
+
+    fish:
+        ossutil completion fish > ~/.config/fish/completions/ossutil.fish
+
+    The script works for the name of program which runs the command(e.g. ossutil64).
+
+Usage:
+
+    ossutil completion bash|zsh|fish
+`,
+
+	sampleText: `
+    1) Enable completion in current bash
+        source <(ossutil completion bash)
+
+    2) Install completion script for fish, with descriptions in English
+        ossutil completion fish -L en > ~/.config/fish/completions/ossutil.fish
+`,
+}
+
+var (
+	completionMaxKeysText  = fmt.Sprintf("%d", CompletionMaxKeys)
+	completionCacheTTLText = fmt.Sprintf("%d", CompletionCacheTTL)
+)
+
+// completionCredentialOptions are the options passed to the listing mode by the scripts
+var completionCredentialOptions = []string{
+	OptionConfigFile,
+	OptionEndpoint,
+	OptionAccessKeyID,
+	OptionAccessKeySecret,
+	OptionSTSToken,
+}
+
+// CompletionCommand is the command to generate completion scripts
+type CompletionCommand struct {
+	command Command
+}
+
+var completionCommand = CompletionCommand{
+	command: Command{
+		name:        "completion",
+		nameAlias:   []string{},
+		minArgc:     1,
+		maxArgc:     2,
+		specChinese: specChineseCompletion,
+		specEnglish: specEnglishCompletion,
+		group:       GroupTypeAdditionalCommand,
+		validOptionNames: []string{
+			OptionConfigFile,
+			OptionEndpoint,
+			OptionAccessKeyID,
+			OptionAccessKeySecret,
+			OptionSTSToken,
+			OptionLanguage,
+		},
+	},
+}
+
+// function for RewriteLoadConfiger interface
+func (cc *CompletionCommand) rewriteLoadConfig(configFile string) error {
+	// the scripts need no config, and the listing mode shows nothing if config is wrong
+	var err error
+	if cc.command.configOptions, err = LoadConfig(configFile); err != nil {
+		cc.command.configOptions = OptionMapType{}
+	}
+	return nil
+}
+
+// function for FormatHelper interface
+func (cc *CompletionCommand) formatHelpForWhole() string {
+	return cc.command.formatHelpForWhole()
+}
+
+func (cc *CompletionCommand) formatIndependHelp() string {
+	return cc.command.formatIndependHelp()
+}
+
+// Init simulate inheritance, and polymorphism
+func (cc *CompletionCommand) Init(args []string, options OptionMapType) error {
+	return cc.command.Init(args, options, cc)
+}
+
+// RunCommand simulate inheritance, and polymorphism
+func (cc *CompletionCommand) RunCommand() error {
+	shell := cc.command.args[0]
+	if shell == CompletionListMode {
+		if len(cc.command.args) < 2 {
+			return nil
+		}
+		// the output is taken as candidates by the scripts, so errors are not shown
+		urls, err := cc.completeURL(cc.command.args[1])
+		if err == nil {
+			for _, url := range urls {
+				fmt.Println(url)
+			}
+		}
+		return nil
+	}
+
+	if len(cc.command.args) > 1 {
+		return CommandError{cc.command.name, "the command needs only 1 argument: shell"}
+	}
+	language, _ := GetString(OptionLanguage, cc.command.options)
+	data := newCompletionData(language)
+	switch strings.ToLower(shell) {
+	case CompletionBash:
+		fmt.Print(data.bashScript())
+	case CompletionZsh:
+		fmt.Print(data.zshScript())
+	case CompletionFish:
+		fmt.Print(data.fishScript())
+	default:
+		return CommandError{cc.command.name, fmt.Sprintf("unsupported shell: %s, please use %s/%s/%s", shell, CompletionBash, CompletionZsh, CompletionFish)}
+	}
+	return nil
+}
+
+// completeURL returns the cloud urls matching prefix, which are read from cache if it's fresh
+func (cc *CompletionCommand) completeURL(prefix string) ([]string, error) {
+	if !strings.HasPrefix(strings.ToLower(prefix), SchemePrefix) {
+		return nil, nil
+	}
+
+	cachePath := cc.cachePath(prefix)
+	if urls, ok := readCompletionCache(cachePath); ok {
+		return urls, nil
+	}
+	urls, err := cc.listURL(prefix)
+	if err != nil {
+		return nil, err
+	}
+	writeCompletionCache(cachePath, urls)
+	return urls, nil
+}
+
+// listURL lists the buckets if prefix does not contain bucket name, otherwise the directories
+// and objects one level under prefix
+func (cc *CompletionCommand) listURL(prefix string) ([]string, error) {
+	path := prefix[len(SchemePrefix):]
+	pos := strings.Index(path, "/")
+	if pos == -1 {
+		client, err := cc.command.ossClient("")
+		if err != nil {
+			return nil, err
+		}
+		lbr, err := client.ListBuckets(oss.Prefix(path), oss.MaxKeys(CompletionMaxKeys))
+		if err != nil {
+			return nil, err
+		}
+		urls := []string{}
+		for _, bucket := range lbr.Buckets {
+			urls = append(urls, SchemePrefix+bucket.Name+"/")
+		}
+		return urls, nil
+	}
+
+	bucketName := path[:pos]
+	bucket, err := cc.command.ossBucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+	lor, err := cc.command.ossListObjectsRetry(bucket, oss.Prefix(path[pos+1:]), oss.Delimiter("/"), oss.MaxKeys(CompletionMaxKeys))
+	if err != nil {
+		return nil, err
+	}
+	urls := []string{}
+	for _, dir := range lor.CommonPrefixes {
+		urls = append(urls, SchemePrefix+bucketName+"/"+dir)
+	}
+	for _, object := range lor.Objects {
+		urls = append(urls, SchemePrefix+bucketName+"/"+object.Key)
+	}
+	sort.Strings(urls)
+	return urls, nil
+}
+
+// cachePath returns the cache file of prefix, the config and credential are part of the key,
+// so that the listings of different accounts are not mixed
+func (cc *CompletionCommand) cachePath(prefix string) string {
+	h := md5.New()
+	for _, name := range []string{OptionConfigFile, OptionEndpoint, OptionAccessKeyID} {
+		val, _ := GetString(name, cc.command.options)
+		io.WriteString(h, val+"\n")
+	}
+	io.WriteString(h, prefix)
+	return filepath.Join(completionCacheDir(), hex.EncodeToString(h.Sum(nil)))
+}
+
+func completionCacheDir() string {
+	dir := CompletionCacheDir
+	if usr, err := user.Current(); err == nil {
+		dir = strings.Replace(dir, "~", usr.HomeDir, 1)
+	}
+	return dir
+}
+
+func readCompletionCache(path string) ([]string, bool) {
+	f, err := os.Stat(path)
+	if err != nil || time.Since(f.ModTime()) > time.Duration(CompletionCacheTTL)*time.Second {
+		return nil, false
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	urls := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			urls = append(urls, line)
+		}
+	}
+	return urls, true
+}
+
+// writeCompletionCache writes the cache, and removes the expired ones. The cache is only used to
+// speed up, so errors are ignored.
+func writeCompletionCache(path string, urls []string) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return
+	}
+	ioutil.WriteFile(path, []byte(strings.Join(urls, "\n")), 0600)
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		if time.Since(f.ModTime()) > time.Duration(CompletionCacheTTL)*time.Second {
+			os.Remove(filepath.Join(dir, f.Name()))
+		}
+	}
+}
+
+// completionData is what the scripts complete, collected from the commands and options
+type completionData struct {
+	program      string
+	function     string
+	commands     []string
+	synopses     map[string]string   // command name -> synopsis
+	options      map[string][]string // command name -> option names
+	valueOptions []string            // option names which need a value
+	alternatives []string            // option names whose values are alternative
+	language     string
+}
+
+var completionFunctionRegexp = regexp.MustCompile("[^A-Za-z0-9_]")
+
+func newCompletionData(language string) completionData {
+	program := strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	data := completionData{
+		program:  program,
+		function: "_" + completionFunctionRegexp.ReplaceAllString(program, "_"),
+		synopses: map[string]string{},
+		options:  map[string][]string{},
+		language: language,
+	}
+
+	for _, cmd := range GetAllCommands() {
+		command := reflect.ValueOf(cmd).Elem().FieldByName("command")
+		name := command.FieldByName("name").String()
+		data.commands = append(data.commands, name)
+
+		spec := command.FieldByName("specChinese")
+		if strings.ToLower(language) == LEnglishLanguage {
+			spec = command.FieldByName("specEnglish")
+		}
+		data.synopses[name] = spec.FieldByName("synopsisText").String()
+
+		data.options[name] = commandValidOptionNames(cmd)
+	}
+
+	for name, option := range OptionMap {
+		if option.optionType != OptionTypeFlagTrue {
+			data.valueOptions = append(data.valueOptions, name)
+		}
+		if option.optionType == OptionTypeAlternative {
+			data.alternatives = append(data.alternatives, name)
+		}
+	}
+	sort.Strings(data.valueOptions)
+	sort.Strings(data.alternatives)
+	return data
+}
+
+// names returns the short and long names of options
+func (d completionData) names(options []string) []string {
+	names := []string{}
+	for _, name := range options {
+		if option, ok := OptionMap[name]; ok {
+			if option.name != "" {
+				names = append(names, option.name)
+			}
+			if option.nameAlias != "" {
+				names = append(names, option.nameAlias)
+			}
+		}
+	}
+	return names
+}
+
+// otherValueOptions returns the options which need a value except the credential ones
+func (d completionData) otherValueOptions() []string {
+	options := []string{}
+	for _, name := range d.valueOptions {
+		if FindPos(name, completionCredentialOptions) == -1 {
+			options = append(options, name)
+		}
+	}
+	return options
+}
+
+func (d completionData) alternativeValues(name string) []string {
+	return strings.Split(OptionMap[name].minVal, "/")
+}
+
+// description returns the first clause of help, which is short enough for completion menu
+func (d completionData) description(help string) string {
+	if pos := strings.IndexAny(help, ".(，。（"); pos != -1 {
+		help = help[:pos]
+	}
+	return strings.TrimSpace(help)
+}
+
+func (d completionData) bashScript() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# bash completion for %s, generated by \"%s completion bash\"\n", d.program, d.program)
+	fmt.Fprintf(&b, "%s()\n{\n", d.function)
+	b.WriteString(`    local line="${COMP_LINE:0:COMP_POINT}"
+    local cur="${line##*[[:space:]]}"
+    local prev="${COMP_WORDS[COMP_CWORD-1]}"
+    local cmd="" skip="" word url i opts
+    local -a creds
+    for ((i=1; i<COMP_CWORD; i++)); do
+        word="${COMP_WORDS[i]}"
+        if [[ -n "$skip" ]]; then
+            [[ "$skip" != "-" ]] && creds+=("$skip" "$word")
+            skip=""
+            continue
+        fi
+        case "$word" in
+`)
+	fmt.Fprintf(&b, "        %s) skip=\"$word\" ;;\n", strings.Join(d.names(completionCredentialOptions), "|"))
+	fmt.Fprintf(&b, "        %s) skip=\"-\" ;;\n", strings.Join(d.names(d.otherValueOptions()), "|"))
+	b.WriteString(`        -*) ;;
+        *) [[ -z "$cmd" ]] && cmd="$word" ;;
+        esac
+    done
+
+    COMPREPLY=()
+    if [[ "$cur" == oss://* ]]; then
+        # the url is split by colon in COMP_WORDBREAKS, so the part before colon is removed
+        local colon=""
+        [[ "$COMP_WORDBREAKS" == *:* ]] && colon="${cur%"${cur##*:}"}"
+        while IFS= read -r url; do
+            COMPREPLY+=("${url#"$colon"}")
+            [[ "$url" == */ ]] && compopt -o nospace 2>/dev/null
+        done < <("${COMP_WORDS[0]}" completion ` + CompletionListMode + ` "$cur" "${creds[@]}" 2>/dev/null)
+        return 0
+    fi
+
+    case "$prev" in
+`)
+	for _, name := range d.alternatives {
+		fmt.Fprintf(&b, "    %s)\n        COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n        return 0 ;;\n",
+			strings.Join(d.names([]string{name}), "|"), strings.Join(d.alternativeValues(name), " "))
+	}
+	fmt.Fprintf(&b, "    %s)\n        COMPREPLY=($(compgen -f -- \"$cur\"))\n        return 0 ;;\n", strings.Join(d.names(d.valueOptions), "|"))
+	b.WriteString(`    esac
+
+    if [[ "$cur" == -* ]]; then
+        case "$cmd" in
+`)
+	for _, name := range d.commands {
+		fmt.Fprintf(&b, "        %s) opts=\"%s\" ;;\n", name, strings.Join(d.names(d.options[name]), " "))
+	}
+	fmt.Fprintf(&b, "        *) opts=\"%s\" ;;\n", strings.Join(d.names([]string{OptionVersion}), " "))
+	b.WriteString(`        esac
+        COMPREPLY=($(compgen -W "$opts" -- "$cur"))
+        return 0
+    fi
+
+    if [[ -z "$cmd" || ( "$cmd" == help && "$prev" == help ) ]]; then
+`)
+	fmt.Fprintf(&b, "        COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(d.commands, " "))
+	b.WriteString(`        return 0
+    fi
+    COMPREPLY=($(compgen -f -- "$cur"))
+}
+`)
+	fmt.Fprintf(&b, "complete -o filenames -F %s %s\n", d.function, d.program)
+	return b.String()
+}
+
+func (d completionData) zshScript() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "#compdef %s\n", d.program)
+	fmt.Fprintf(&b, "# zsh completion for %s, generated by \"%s completion zsh\"\n", d.program, d.program)
+	fmt.Fprintf(&b, "%s() {\n", d.function)
+	b.WriteString(`    local cur="${words[CURRENT]}" prev="${words[CURRENT-1]}" cmd="" skip="" word i
+    local -a creds opts urls
+    for ((i=2; i<CURRENT; i++)); do
+        word="${words[i]}"
+        if [[ -n "$skip" ]]; then
+            [[ "$skip" != "-" ]] && creds+=("$skip" "$word")
+            skip=""
+            continue
+        fi
+        case "$word" in
+`)
+	fmt.Fprintf(&b, "        (%s) skip=\"$word\" ;;\n", strings.Join(d.names(completionCredentialOptions), "|"))
+	fmt.Fprintf(&b, "        (%s) skip=\"-\" ;;\n", strings.Join(d.names(d.otherValueOptions()), "|"))
+	b.WriteString(`        (-*) ;;
+        (*) [[ -z "$cmd" ]] && cmd="$word" ;;
+        esac
+    done
+
+    if [[ "$cur" == oss://* ]]; then
+        urls=(${(f)"$("${words[1]}" completion ` + CompletionListMode + ` "$cur" "${creds[@]}" 2>/dev/null)"})
+        # no space after directories, so that the objects under them can be completed
+        compadd -S '' -- ${(M)urls:#*/}
+        compadd -- ${urls:#*/}
+        return
+    fi
+
+    case "$prev" in
+`)
+	for _, name := range d.alternatives {
+		fmt.Fprintf(&b, "    (%s)\n        compadd -- %s\n        return ;;\n",
+			strings.Join(d.names([]string{name}), "|"), strings.Join(d.alternativeValues(name), " "))
+	}
+	fmt.Fprintf(&b, "    (%s)\n        _files\n        return ;;\n", strings.Join(d.names(d.valueOptions), "|"))
+	b.WriteString(`    esac
+
+    if [[ "$cur" == -* ]]; then
+        case "$cmd" in
+`)
+	for _, name := range d.commands {
+		fmt.Fprintf(&b, "        (%s) opts=(%s) ;;\n", name, strings.Join(d.names(d.options[name]), " "))
+	}
+	fmt.Fprintf(&b, "        (*) opts=(%s) ;;\n", strings.Join(d.names([]string{OptionVersion}), " "))
+	b.WriteString(`        esac
+        compadd -- "${opts[@]}"
+        return
+    fi
+
+    if [[ -z "$cmd" || ( "$cmd" == help && "$prev" == help ) ]]; then
+`)
+	fmt.Fprintf(&b, "        compadd -- %s\n", strings.Join(d.commands, " "))
+	b.WriteString(`        return
+    fi
+    _files
+}
+`)
+	fmt.Fprintf(&b, "compdef %s %s\n", d.function, d.program)
+	return b.String()
+}
+
+func (d completionData) fishScript() string {
+	var b bytes.Buffer
+	fn := "_" + d.function
+	fmt.Fprintf(&b, "# fish completion for %s, generated by \"%s completion fish\"\n", d.program, d.program)
+
+	// the command is the first word which is neither an option nor the value of option
+	fmt.Fprintf(&b, "function %s_command\n", fn)
+	b.WriteString(`    set -l skip 0
+    for token in (commandline -opc)[2..-1]
+        if test $skip -eq 1
+            set skip 0
+            continue
+        end
+        switch $token
+`)
+	fmt.Fprintf(&b, "            case %s\n                set skip 1\n", strings.Join(fishQuoteAll(d.names(d.valueOptions)), " "))
+	b.WriteString(`            case '-*'
+            case '*'
+                echo $token
+                return 0
+        end
+    end
+    return 1
+end
+
+`)
+	fmt.Fprintf(&b, "function %s_using\n    test (%s_command) = $argv[1]\nend\n\n", fn, fn)
+
+	fmt.Fprintf(&b, "function %s_credentials\n", fn)
+	b.WriteString(`    set -l next 0
+    for token in (commandline -opc)[2..-1]
+        if test $next -eq 1
+            echo $token
+            set next 0
+            continue
+        end
+        switch $token
+`)
+	fmt.Fprintf(&b, "            case %s\n                echo $token\n                set next 1\n", strings.Join(fishQuoteAll(d.names(completionCredentialOptions)), " "))
+	b.WriteString(`        end
+    end
+end
+
+`)
+	fmt.Fprintf(&b, "function %s_urls\n", fn)
+	b.WriteString(`    set -l cur (commandline -ct)
+    string match -q -- 'oss://*' $cur; or return
+    set -l program (commandline -opc)[1]
+`)
+	fmt.Fprintf(&b, "    $program completion %s $cur (%s_credentials) 2>/dev/null\nend\n\n", CompletionListMode, fn)
+
+	for _, name := range d.commands {
+		fmt.Fprintf(&b, "complete -c %s -n 'not %s_command' -f -a %s -d %s\n", d.program, fn, name, fishQuote(d.description(d.synopses[name])))
+	}
+	fmt.Fprintf(&b, "complete -c %s -n '%s_using help' -f -a '%s'\n", d.program, fn, strings.Join(d.commands, " "))
+	fmt.Fprintf(&b, "complete -c %s -n 'not %s_command' %s\n", d.program, fn, d.fishOption(OptionVersion))
+	for _, cmd := range d.commands {
+		for _, name := range d.options[cmd] {
+			if _, ok := OptionMap[name]; !ok {
+				continue
+			}
+			fmt.Fprintf(&b, "complete -c %s -n '%s_using %s' %s\n", d.program, fn, cmd, d.fishOption(name))
+		}
+	}
+	fmt.Fprintf(&b, "complete -c %s -n '%s_command' -a '(%s_urls)'\n", d.program, fn, fn)
+	return b.String()
+}
+
+// fishOption returns the arguments of fish complete for option
+func (d completionData) fishOption(name string) string {
+	option := OptionMap[name]
+	args := []string{}
+	if option.name != "" {
+		args = append(args, "-s", strings.TrimPrefix(option.name, "-"))
+	}
+	if option.nameAlias != "" {
+		args = append(args, "-l", strings.TrimPrefix(option.nameAlias, "--"))
+	}
+	switch option.optionType {
+	case OptionTypeAlternative:
+		args = append(args, "-x", "-a", fishQuote(strings.Join(d.alternativeValues(name), " ")))
+	case OptionTypeFlagTrue:
+	default:
+		args = append(args, "-r")
+	}
+	args = append(args, "-d", fishQuote(d.description(option.getHelp(d.language))))
+	return strings.Join(args, " ")
+}
+
+func fishQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return "'" + strings.Replace(s, "'", `\'`, -1) + "'"
+}
+
+func fishQuoteAll(ss []string) []string {
+	quoted := make([]string, 0, len(ss))
+	for _, s := range ss {
+		quoted = append(quoted, fishQuote(s))
+	}
+	return quoted
+}
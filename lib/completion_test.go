@@ -0,0 +1,88 @@
+package lib
+
+import (
+	"os"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *OssutilCommandSuite) runCompletion(args []string, c *C) string {
+	str := ""
+	options := OptionMapType{
+		"endpoint":        &endpoint,
+		"accessKeyID":     &accessKeyID,
+		"accessKeySecret": &accessKeySecret,
+		"stsToken":        &str,
+		"configFile":      &configFile,
+	}
+	out := os.Stdout
+	testResultFile, _ = os.OpenFile(resultPath, os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0664)
+	os.Stdout = testResultFile
+	_, err := cm.RunCommand("completion", args, options)
+	os.Stdout = out
+	c.Assert(err, IsNil)
+	return s.readFile(resultPath, c)
+}
+
+func (s *OssutilCommandSuite) TestCompletionScript(c *C) {
+	for _, shell := range []string{CompletionBash, CompletionZsh, CompletionFish} {
+		script := s.runCompletion([]string{shell}, c)
+		for _, name := range []string{"set-storage-class", "completion", "checkpoint-dir", "bigfile-threshold", "Expedited", "completion " + CompletionListMode} {
+			c.Assert(strings.Contains(script, name), Equals, true)
+		}
+	}
+
+	script := s.runCompletion([]string{CompletionBash}, c)
+	c.Assert(strings.Contains(script, "--storage-class)\n        COMPREPLY=($(compgen -W \"Standard IA Archive\""), Equals, true)
+	c.Assert(strings.Contains(script, "-c|--config-file|-e|--endpoint|-i|--access-key-id"), Equals, true)
+	c.Assert(strings.Contains(script, "complete -o filenames -F "), Equals, true)
+
+	script = s.runCompletion([]string{CompletionFish}, c)
+	c.Assert(strings.Contains(script, "-l storage-class -x -a 'Standard IA Archive'"), Equals, true)
+	c.Assert(strings.Contains(script, "-s j -l jobs -r"), Equals, true)
+
+	// invalid shell
+	_, err := cm.RunCommand("completion", []string{"tcsh"}, OptionMapType{})
+	c.Assert(err, NotNil)
+	_, err = cm.RunCommand("completion", []string{CompletionBash, "abc"}, OptionMapType{})
+	c.Assert(err, NotNil)
+}
+
+func (s *OssutilCommandSuite) TestCompletionListURL(c *C) {
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+	s.createFile(uploadFileName, "abc", c)
+	s.putObject(bucketName, "dir/a", uploadFileName, c)
+	s.putObject(bucketName, "dir/b/c", uploadFileName, c)
+	s.putObject(bucketName, "dir2", uploadFileName, c)
+
+	urls := s.runCompletion([]string{CompletionListMode, SchemePrefix + bucketName[:len(bucketName)-2]}, c)
+	c.Assert(strings.Contains(urls, SchemePrefix+bucketName+"/\n"), Equals, true)
+
+	urls = s.runCompletion([]string{CompletionListMode, CloudURLToString(bucketName, "dir")}, c)
+	c.Assert(urls, Equals, CloudURLToString(bucketName, "dir/")+"\n"+CloudURLToString(bucketName, "dir2")+"\n")
+
+	urls = s.runCompletion([]string{CompletionListMode, CloudURLToString(bucketName, "dir/")}, c)
+	c.Assert(urls, Equals, CloudURLToString(bucketName, "dir/a")+"\n"+CloudURLToString(bucketName, "dir/b/")+"\n")
+
+	// the listing is cached
+	var listed int
+	if fakeOSS != nil {
+		listed = fakeOSS.requestCount("ListObjects")
+	}
+	s.putObject(bucketName, "dir/d", uploadFileName, c)
+	urls = s.runCompletion([]string{CompletionListMode, CloudURLToString(bucketName, "dir/")}, c)
+	c.Assert(urls, Equals, CloudURLToString(bucketName, "dir/a")+"\n"+CloudURLToString(bucketName, "dir/b/")+"\n")
+	if fakeOSS != nil {
+		c.Assert(fakeOSS.requestCount("ListObjects"), Equals, listed)
+	}
+
+	// nothing is completed if listing fails, or the url is not cloud url
+	urls = s.runCompletion([]string{CompletionListMode, CloudURLToString(bucketNamePrefix+"notexist"+randLowStr(5), "")}, c)
+	c.Assert(urls, Equals, "")
+	urls = s.runCompletion([]string{CompletionListMode, "abc"}, c)
+	c.Assert(urls, Equals, "")
+
+	s.removeBucket(bucketName, true, c)
+}
@@ -2,11 +2,15 @@ package lib
 
 import (
 	"fmt"
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
 	configparser "github.com/alyu/configparser"
 	"os"
+	"strconv"
 	"strings"
 )
 
+var endpointCacheTTLText = strconv.FormatInt(EndpointCacheTTL, 10)
+
 var specChineseConfig = SpecText{
 
 	synopsisText: "创建配置文件用以存储配置项",
@@ -14,7 +18,7 @@ var specChineseConfig = SpecText{
 	paramText: "[options]",
 
 	syntaxText: ` 
-    ossutil config [-e endpoint] [-i id] [-k key] [-t token] [-L language] [--output-dir outdir] [-c file] 
+    ossutil config [-e endpoint] [-i id] [-k key] [-t token] [-L language] [--output-dir outdir] [--proxy-host host] [--proxy-user user] [--proxy-pwd pwd] [--ca-file file] [--insecure-skip-verify] [--force-https] [--connect-timeout sec] [--read-timeout sec] [--discover] [-c file] 
 `,
 
 	detailHelpText: ` 
@@ -80,10 +84,17 @@ var specChineseConfig = SpecText{
             关于outputDir和report文件的更多信息请参见cp命令的帮助。
             注意：outputDir如果不存在，ossutil在产生输出文件时会自动创建该目录，
         如果outputDir存在且并非目录，将会报错。
+        (6) proxyHost, proxyUser, proxyPwd, caFile, insecureSkipVerify, forceHTTPS,
+            connectTimeout, readTimeout
+            这些配置项对应同名的选项（见--proxy-host、--ca-file、--insecure-skip-verify、
+        --force-https、--connect-timeout等选项的帮助），用于通过代理服务器访问oss、校验
+        https证书和设置超时时间。交互式模式时不提供这些配置项的配置，但配置文件中的
+        配置起效，insecureSkipVerify和forceHTTPS的值为true或false。选项的优先级高于
+        配置文件。
 
         下述交互式Bucket-Endpoint和Bucket-Cname配置被取消，但配置文件中该两项
         配置仍然起效。
-        (6) Bucket-Endpoint
+        (7) Bucket-Endpoint
             Bucket-Endpoint对每个指定的bucket单独配置endpoint，此配置会优先于
         配置文件中关于默认endpoint的配置。
             在该版本中，ossutil取消了交互式配置中，关于Bucket-Endpoint配对的配
@@ -94,7 +105,13 @@ var specChineseConfig = SpecText{
         该选项中寻找该bucket对应的endpoint，如果找到，该endpoint会覆盖基本配置
         中endpoint。但是运行命令时如果指定了--endpoint选项，--endpoint选项为最
         高优先级。
-        (7) Bucket-Cname
+            如果没有为bucket配置Bucket-Endpoint，并且基本配置中的endpoint为oss地域
+        的endpoint（例如oss-cn-hangzhou.aliyuncs.com），ossutil通过GetBucketLocation
+        查询bucket所在的地域，使用该地域的endpoint访问bucket（在ECS上，该地域的内网
+        endpoint可以访问时使用内网endpoint）。查询结果缓存在` + EndpointCacheDir + `目录下
+        ` + endpointCacheTTLText + `秒，查询失败（例如没有GetBucketLocation权限）时同样缓存，缓存
+        期间使用基本配置中的endpoint。
+        (8) Bucket-Cname
             Bucket-Cname为每个指定的bucket单独配置CNAME域名（CDN加速域名），此
         配置会优先于配置文件中Bucket-Endpoint及endpoint的配置。
             关于CNAME域名的更多信息见：https://help.aliyun.com/document_detail/27112.html?spm=5176.product27099.4.6.Lf06oS#1.
@@ -106,12 +123,17 @@ var specChineseConfig = SpecText{
         Bucket-Endpoint选项和基本配置中的endpoint。运行命令时如果指定了--endpoint
         选项，--endpoint选项为最高优先级。
         
-        优先级：--endpoint > Bucket-Cname > Bucket-Endpoint > endpoint > 默认endpoint
+        优先级：--endpoint > Bucket-Cname > Bucket-Endpoint > bucket所在地域的endpoint > endpoint > 默认endpoint
 
     2) ossutil config options
         如果用户使用命令时输入了除--language和--config-file之外的任何选项，则
     该命令进入非交互式模式。所有的配置项应当使用选项指定。
 
+    3) ossutil config --discover [-c file]
+        该用法列举账号的所有bucket，将每个bucket所在地域的endpoint添加到配置文件
+    的Bucket-Endpoint中（已配置的bucket不变），配置文件需要已经存在。列举使用配置
+    文件中的配置，也可以通过-e、-i、-k、-t等选项指定。
+
 
 配置文件格式：
 
@@ -122,6 +144,13 @@ var specChineseConfig = SpecText{
         accessKeySecret = your_key_secret
         stsToken = your_sts_token
         outputDir = your_output_dir
+        proxyHost = http://proxy.com:3128
+        proxyUser = your_proxy_user
+        proxyPwd = your_proxy_password
+        caFile = your_ca_file
+        forceHTTPS = true
+        connectTimeout = 120
+        readTimeout = 1200
     [Bucket-Endpoint]
         bucket1 = endpoint1
         bucket2 = endpoint2
@@ -135,6 +164,7 @@ var specChineseConfig = SpecText{
 	sampleText: ` 
     ossutil config
     ossutil config -e oss-cn-hangzhou.aliyuncs.com -c ~/.myconfig
+    ossutil config --discover
 `,
 }
 
@@ -145,7 +175,7 @@ var specEnglishConfig = SpecText{
 	paramText: "[options]",
 
 	syntaxText: ` 
-    ossutil config [-e endpoint] [-i id] [-k key] [-t token] [-L language] [--output-dir outdir] [-c file] 
+    ossutil config [-e endpoint] [-i id] [-k key] [-t token] [-L language] [--output-dir outdir] [--proxy-host host] [--proxy-user user] [--proxy-pwd pwd] [--ca-file file] [--insecure-skip-verify] [--force-https] [--connect-timeout sec] [--read-timeout sec] [--discover] [-c file] 
 `,
 
 	detailHelpText: ` 
@@ -224,10 +254,19 @@ Usage:
             Note: if outputDir is not exist, ossutil will create the directory 
         automatically, if outputDir you specified exists and is not a directory, 
         ossutil will return an error. 
+        (6) proxyHost, proxyUser, proxyPwd, caFile, insecureSkipVerify, forceHTTPS,
+            connectTimeout, readTimeout
+            These configurations are the same as the options with the same name(see 
+        help of --proxy-host, --ca-file, --insecure-skip-verify, --force-https, 
+        --connect-timeout etc.), which are used to access oss through proxy server, 
+        verify https certificate and set timeouts. They can not be configed through 
+        interactive mode, but the configurations in config file are effective, the 
+        value of insecureSkipVerify and forceHTTPS is true or false. The options 
+        have priority over config file.
 
         In interactive config, Bucket-Endpoint and Bucket-Cname is removed, but 
         the two sections in config file are still effective. 
-        (6) Bucket-Endpoint
+        (7) Bucket-Endpoint
             Bucket-Endpoint specify endpoint for every individual bucket, the 
         section is prior to endpoint in base section.  
             In current version, Bucket-Endpoint interactive configuration is 
@@ -237,7 +276,14 @@ Usage:
         to the bucket in this section, if found, the endpoint is prior to the 
         endpoint in the base section. If --endpoint option is specified, --endpoint 
         option has the highest priority.
-        (7) Bucket-Cname
+            If Bucket-Endpoint is not configured for the bucket, and the endpoint in 
+        the base section is an endpoint of oss region(e.g. oss-cn-hangzhou.aliyuncs.com), 
+        ossutil gets the region of the bucket by GetBucketLocation, and accesses the 
+        bucket with the endpoint of the region(on ECS, the internal endpoint of the 
+        region is used if it's reachable). The result is cached in ` + EndpointCacheDir + ` 
+        directory for ` + endpointCacheTTLText + ` seconds, the failure(e.g. without the permission 
+        of GetBucketLocation) is cached too, and the endpoint in the base section is used.
+        (8) Bucket-Cname
             Bucket-Cname specify CNAME host for every individual bucket, the section 
         is prior to Bucket-Endpoint and endpoint in the base section.
             More information about CNAME host see: https://help.aliyun.com/document_detail/27112.html?spm=5176.product27099.4.6.Lf06oS#1. 
@@ -248,13 +294,20 @@ Usage:
         the endpoint in Bucket-Endpoint and the endpoint in the base section. If 
         --endpoint option is specified, --endpoint option has the highest priority.
 
-        PRI: --endpoint option > Bucket-Cname > Bucket-Endpoint > endpoint > default endpoint
+        PRI: --endpoint option > Bucket-Cname > Bucket-Endpoint > endpoint of the region where bucket is > endpoint > default endpoint
 
     2) ossutil config options
         If any options except --language and --config-file is specified, the 
     command enter the non interactive mode. All the configurations should be 
     specified by options.
 
+    3) ossutil config --discover [-c file]
+        The usage lists all the buckets of the account, and adds the endpoint of 
+    the region where each bucket is to Bucket-Endpoint section of config file(the 
+    buckets configured are not changed), the config file should exist. The listing 
+    uses the configurations in config file, which can also be specified by -e, -i, 
+    -k, -t etc. options.
+
 
 Credential File Format:
 
@@ -265,6 +318,13 @@ Credential File Format:
         accessKeySecret = your_key_secret
         stsToken = your_sts_token
         outputDir = your_output_dir
+        proxyHost = http://proxy.com:3128
+        proxyUser = your_proxy_user
+        proxyPwd = your_proxy_password
+        caFile = your_ca_file
+        forceHTTPS = true
+        connectTimeout = 120
+        readTimeout = 1200
     [Bucket-Endpoint]
         bucket1 = endpoint1
         bucket2 = endpoint2
@@ -278,6 +338,7 @@ Credential File Format:
 	sampleText: ` 
     ossutil config
     ossutil config -e oss-cn-hangzhou.aliyuncs.com -c ~/.myconfig
+    ossutil config --discover
 `,
 }
 
@@ -302,6 +363,15 @@ var configCommand = ConfigCommand{
 			OptionAccessKeySecret,
 			OptionSTSToken,
 			OptionOutputDir,
+			OptionProxyHost,
+			OptionProxyUser,
+			OptionProxyPwd,
+			OptionCAFile,
+			OptionSkipVerify,
+			OptionForceHTTPS,
+			OptionConnectTimeout,
+			OptionReadTimeout,
+			OptionDiscover,
 			OptionLanguage,
 		},
 	},
@@ -350,6 +420,10 @@ func (cc *ConfigCommand) Init(args []string, options OptionMapType) error {
 
 // RunCommand simulate inheritance, and polymorphism
 func (cc *ConfigCommand) RunCommand() error {
+	if discover, _ := GetBool(OptionDiscover, cc.command.options); discover {
+		return cc.runCommandDiscover()
+	}
+
 	configFile, _ := GetString(OptionConfigFile, cc.command.options)
 	delete(cc.command.options, OptionConfigFile)
 	language, _ := GetString(OptionLanguage, cc.command.options)
@@ -369,6 +443,9 @@ func (cc *ConfigCommand) RunCommand() error {
 
 func (cc *ConfigCommand) filterNonInputOptions() {
 	for name := range cc.command.options {
+		if val, _ := GetBool(name, cc.command.options); val {
+			continue
+		}
 		if val, err := GetString(name, cc.command.options); err != nil || val == "" {
 			delete(cc.command.options, name)
 		}
@@ -390,7 +467,7 @@ func (cc *ConfigCommand) runCommandInteractive(configFile, language string) erro
 			fmt.Printf("\n请输入配置文件路径（默认为：" + DecideConfigFile("") + "，回车将使用默认路径。如果用户设置为其它路径，在使用命令时需要将--config-file选项设置为该路径）：")
 		}
 
-		if _, err := fmt.Scanln(&configFile); err != nil {
+		if _, err := scanln(&configFile); err != nil {
 			if llanguage == LEnglishLanguage {
 				fmt.Println("No config file entered, will use the default config file " + DecideConfigFile("") + "\n")
 			} else {
@@ -426,7 +503,7 @@ func (cc *ConfigCommand) configInteractive(configFile, language string) error {
 		} else {
 			fmt.Printf("请输入语言(%s，默认为：%s，该配置项将在此次config命令成功结束后生效)：", OptionMap[OptionLanguage].minVal, DefaultLanguage)
 		}
-		if _, err := fmt.Scanln(&val); err == nil {
+		if _, err := scanln(&val); err == nil {
 			vals := strings.Split(OptionMap[OptionLanguage].minVal, "/")
 			if FindPosCaseInsen(val, vals) == -1 {
 				return fmt.Errorf("invalid option value of %s, the value: %s is not anyone of %s", OptionLanguage, val, OptionMap[OptionLanguage].minVal)
@@ -451,7 +528,7 @@ func (cc *ConfigCommand) configInteractive(configFile, language string) error {
 			}
 			fmt.Printf("请输入%s%s：", name, str)
 		}
-		if _, err := fmt.Scanln(&val); err == nil {
+		if _, err := scanln(&val); err == nil {
 			section.Add(name, val)
 		} else if OptionMap[name].def != "" {
 			section.Add(name, OptionMap[name].def)
@@ -473,9 +550,69 @@ func (cc *ConfigCommand) runCommandNonInteractive(configFile, language string) e
 		if val, _ := GetString(name, cc.command.options); val != "" {
 			section.Add(name, val)
 		}
+		if val, _ := GetBool(name, cc.command.options); val {
+			section.Add(name, strconv.FormatBool(val))
+		}
 	}
 	if err := configparser.Save(config, configFile); err != nil {
 		return err
 	}
 	return nil
 }
+
+// runCommandDiscover adds the endpoints of the regions where the buckets are to Bucket-Endpoint section
+func (cc *ConfigCommand) runCommandDiscover() error {
+	configFile, _ := GetString(OptionConfigFile, cc.command.options)
+	configFile = DecideConfigFile(configFile)
+	config, err := configparser.Read(configFile)
+	if err != nil {
+		return fmt.Errorf("read config file error: %s, please config the credentials first", err)
+	}
+	section, err := config.Section(BucketEndpointSection)
+	if err != nil {
+		section = config.NewSection(BucketEndpointSection)
+	}
+
+	// the credentials in config file are used if not specified
+	cc.command.assembleOptions(nil)
+	endpoint, _ := GetString(OptionEndpoint, cc.command.options)
+	client, err := cc.command.ossClient("")
+	if err != nil {
+		return err
+	}
+
+	buckets, added := 0, 0
+	marker := ""
+	for {
+		lbr, err := client.ListBuckets(oss.Marker(marker))
+		if err != nil {
+			return err
+		}
+		for _, bucket := range lbr.Buckets {
+			buckets++
+			if _, ok := section.Options()[bucket.Name]; ok || bucket.Location == "" {
+				continue
+			}
+			section.Add(bucket.Name, locationEndpoint(bucket.Location, endpoint))
+			added++
+		}
+		if !lbr.IsTruncated {
+			break
+		}
+		marker = lbr.NextMarker
+	}
+
+	if added > 0 {
+		if err := configparser.Save(config, configFile); err != nil {
+			return err
+		}
+	}
+
+	language, _ := GetString(OptionLanguage, cc.command.options)
+	if strings.ToLower(language) == LEnglishLanguage {
+		fmt.Printf("%d buckets are found, the endpoints of %d buckets are added to %s section of %s.\n", buckets, added, BucketEndpointSection, configFile)
+	} else {
+		fmt.Printf("共找到%d个bucket，%d个bucket的endpoint添加到了%s的%s中。\n", buckets, added, configFile, BucketEndpointSection)
+	}
+	return nil
+}
@@ -35,6 +35,14 @@ var CredOptionList = []string{
 	OptionAccessKeySecret,
 	OptionSTSToken,
 	OptionOutputDir,
+	OptionProxyHost,
+	OptionProxyUser,
+	OptionProxyPwd,
+	OptionCAFile,
+	OptionSkipVerify,
+	OptionForceHTTPS,
+	OptionConnectTimeout,
+	OptionReadTimeout,
 }
 
 // CredOptionMap allows alias name for options in Credentials section
@@ -46,6 +54,14 @@ var CredOptionMap = map[string]configOption{
 	OptionAccessKeySecret: configOption{[]string{"accessKeySecret", "AccessKeySecret", "access_key_secret", "access_key", "accesskey", "access-key-secret", "access-key"}, true, false, "", ""},
 	OptionSTSToken:        configOption{[]string{"stsToken", "ststoken", "STSToken", "sts_token", "sts-token"}, true, false, "", ""},
 	OptionOutputDir:       configOption{[]string{"outputDir", "output-dir", "output_dir", "output_directory"}, false, true, "ossutil生成的文件的输出目录, ", "the directory to store files generated by ossutil, "},
+	OptionProxyHost:       configOption{[]string{"proxyHost", "proxy-host", "proxy_host"}, false, true, "", ""},
+	OptionProxyUser:       configOption{[]string{"proxyUser", "proxy-user", "proxy_user"}, false, true, "", ""},
+	OptionProxyPwd:        configOption{[]string{"proxyPwd", "proxy-pwd", "proxy_pwd", "proxyPassword", "proxy-password", "proxy_password"}, false, false, "", ""},
+	OptionCAFile:          configOption{[]string{"caFile", "ca-file", "ca_file"}, false, true, "", ""},
+	OptionSkipVerify:      configOption{[]string{"insecureSkipVerify", "insecure-skip-verify", "insecure_skip_verify"}, false, true, "", ""},
+	OptionForceHTTPS:      configOption{[]string{"forceHTTPS", "force-https", "force_https"}, false, true, "", ""},
+	OptionConnectTimeout:  configOption{[]string{"connectTimeout", "connect-timeout", "connect_timeout"}, false, true, "", ""},
+	OptionReadTimeout:     configOption{[]string{"readTimeout", "read-timeout", "read_timeout"}, false, true, "", ""},
 }
 
 // DecideConfigFile return the config file, if user not specified, return default one
@@ -130,6 +146,11 @@ func checkConfig(configMap OptionMapType) error {
 					return fmt.Errorf("error value of option \"%s\", the value is: %s in config file, which needs int64 type", name, opval)
 				}
 			}
+			if option.optionType == OptionTypeFlagTrue {
+				if _, err := strconv.ParseBool(opval.(string)); err != nil {
+					return fmt.Errorf("error value of option \"%s\", the value is: %s in config file, which needs bool type", name, opval)
+				}
+			}
 			if option.optionType == OptionTypeAlternative {
 				vals := strings.Split(option.minVal, "/")
 				if FindPosCaseInsen(opval.(string), vals) == -1 {
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	configparser "github.com/alyu/configparser"
 	. "gopkg.in/check.v1"
 )
 
@@ -243,3 +245,91 @@ func (s *OssutilConfigSuite) TestConfigInvalidOption(c *C) {
 	c.Assert(showElapse, Equals, false)
 	c.Assert(err, Equals, CommandError{command: "config", reason: fmt.Sprintf("the command does not support option: \"%s\"", "shortFormat")})
 }
+
+func (s *OssutilConfigSuite) TestConfigTransportOptions(c *C) {
+	proxyHost := "http://proxy.com:3128"
+	proxyPwd := "pwd"
+	readTimeout := "60"
+	forceHTTPS := true
+	options := OptionMapType{
+		"endpoint":    &endpoint,
+		"proxyHost":   &proxyHost,
+		"proxyPwd":    &proxyPwd,
+		"readTimeout": &readTimeout,
+		"forceHTTPS":  &forceHTTPS,
+		"configFile":  &configFile,
+	}
+	_, err := cm.RunCommand("config", []string{}, options)
+	c.Assert(err, IsNil)
+
+	opts, err := LoadConfig(configFile)
+	c.Assert(err, IsNil)
+	c.Assert(opts[OptionProxyHost], Equals, proxyHost)
+	c.Assert(opts[OptionProxyPwd], Equals, proxyPwd)
+	c.Assert(opts[OptionReadTimeout], Equals, readTimeout)
+	c.Assert(opts[OptionForceHTTPS], Equals, "true")
+
+	// the flag in config file is assembled into options
+	cmd := Command{name: "test", validOptionNames: []string{OptionConfigFile, OptionEndpoint}}
+	notSet := false
+	err = cmd.Init([]string{}, OptionMapType{OptionConfigFile: &configFile, OptionForceHTTPS: &notSet}, &cmd)
+	c.Assert(err, IsNil)
+	c.Assert(cmd.httpsEndpoint("abc.com"), Equals, "https://abc.com")
+
+	// invalid value in config file
+	for _, line := range []string{"force-https = yes", "connect_timeout = 1s"} {
+		fd, _ := os.OpenFile(configFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		fd.WriteString("[Credentials]\n" + line + "\n")
+		fd.Close()
+		_, err = LoadConfig(configFile)
+		c.Assert(err, NotNil)
+	}
+}
+
+func (s *OssutilConfigSuite) TestConfigDiscover(c *C) {
+	probe := probeEndpoint
+	defer func() {
+		probeEndpoint = probe
+	}()
+	probeEndpoint = func(host string) bool { return false }
+	resetDiscoveredEndpoints("")
+
+	discover := true
+	notSet := ""
+	options := OptionMapType{
+		"endpoint":        &notSet,
+		"accessKeyID":     &notSet,
+		"accessKeySecret": &notSet,
+		"discover":        &discover,
+		"configFile":      &configFile,
+	}
+	_, err := cm.RunCommand("config", []string{}, options)
+	c.Assert(err, NotNil)
+
+	client, err := oss.New(endpoint, accessKeyID, accessKeySecret)
+	c.Assert(err, IsNil)
+	bucketName := bucketNamePrefix + randLowStr(10)
+	configuredBucket := bucketNamePrefix + randLowStr(10)
+	for _, name := range []string{bucketName, configuredBucket} {
+		c.Assert(client.CreateBucket(name), IsNil)
+		defer client.DeleteBucket(name)
+	}
+
+	fd, _ := os.OpenFile(configFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	configStr := fmt.Sprintf("[Credentials]\nendpoint = %s\naccessKeyID = %s\naccessKeySecret = %s\n[Bucket-Endpoint]\n%s = abc.com\n", endpoint, accessKeyID, accessKeySecret, configuredBucket)
+	fd.WriteString(configStr)
+	fd.Close()
+
+	_, err = cm.RunCommand("config", []string{}, options)
+	c.Assert(err, IsNil)
+
+	config, err := configparser.Read(configFile)
+	c.Assert(err, IsNil)
+	section, err := config.Section(BucketEndpointSection)
+	c.Assert(err, IsNil)
+	c.Assert(section.ValueOf(configuredBucket), Equals, "abc.com")
+	c.Assert(section.ValueOf(bucketName) != "", Equals, true)
+	if fakeOSS != nil {
+		c.Assert(section.ValueOf(bucketName), Equals, fakeOSS.location+".aliyuncs.com")
+	}
+}
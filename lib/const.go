@@ -41,6 +41,55 @@ const (
 	OptionVersion                 = "version"
 	OptionPartSize                = "partSize"
 	OptionDisableCRC64            = "disableCRC64"
+	OptionChecksum                = "checksum"
+	OptionSizeOnly                = "sizeOnly"
+	OptionVerify                  = "verify"
+	OptionVerifyRetry             = "verifyRetry"
+	OptionOutputFormat            = "outputFormat"
+	OptionExitCode                = "exitCode"
+	OptionPreserve                = "preserve"
+	OptionSymlinks                = "symlinks"
+	OptionManifest                = "manifest"
+	OptionRestoreDays             = "restoreDays"
+	OptionRestoreTier             = "restoreTier"
+	OptionWait                    = "wait"
+	OptionAutoRestore             = "autoRestore"
+	OptionInclude                 = "include"
+	OptionExclude                 = "exclude"
+	OptionDryRun                  = "dryRun"
+	OptionSummary                 = "summary"
+	OptionProxyHost               = "proxyHost"
+	OptionProxyUser               = "proxyUser"
+	OptionProxyPwd                = "proxyPwd"
+	OptionCAFile                  = "caFile"
+	OptionSkipVerify              = "insecureSkipVerify"
+	OptionForceHTTPS              = "forceHTTPS"
+	OptionConnectTimeout          = "connectTimeout"
+	OptionReadTimeout             = "readTimeout"
+	OptionDiscover                = "discover"
+	OptionDestConfigFile          = "destConfigFile"
+	OptionDestEndpoint            = "destEndpoint"
+	OptionDestKeyID               = "destAccessKeyID"
+	OptionDestKeySecret           = "destAccessKeySecret"
+	OptionDestSTSToken            = "destStsToken"
+	OptionS3Endpoint              = "s3Endpoint"
+	OptionS3KeyID                 = "s3AccessKeyID"
+	OptionS3KeySecret             = "s3AccessKeySecret"
+	OptionS3Token                 = "s3SessionToken"
+	OptionS3Region                = "s3Region"
+	OptionWatch                   = "watch"
+	OptionQuietPeriod             = "quietPeriod"
+	OptionPollInterval            = "pollInterval"
+	OptionOnSuccess               = "onSuccess"
+	OptionOnError                 = "onError"
+	OptionOnFinish                = "onFinish"
+	OptionHookRoutines            = "hookRoutines"
+	OptionHookTimeout             = "hookTimeout"
+	OptionNotifyURL               = "notifyURL"
+	OptionNotifySecret            = "notifySecret"
+	OptionNotifyRetryTimes        = "notifyRetryTimes"
+	OptionNotifyHeartbeat         = "notifyHeartbeat"
+	OptionMetricsAddr             = "metricsAddr"
 )
 
 // the elements show in stat object
@@ -56,6 +105,7 @@ const (
 	StatContentMD5              = "Content-Md5"
 	StatCRC64                   = "X-Oss-Hash-Crc64ecma"
 	StatStorageClass            = "StorageClass"
+	StatRestoreStatus           = "RestoreStatus"
 )
 
 // the elements show in hash file
@@ -96,6 +146,8 @@ const (
 	DefaultOutputDir               = "ossutil_output"
 	CheckpointDir                  = ".ossutil_checkpoint"
 	CheckpointSep                  = "---"
+	JobJournalPrefix               = "ossutil_job_"
+	ChecksumCacheDir               = "~" + string(os.PathSeparator) + ".ossutil_checksum"
 	SnapshotConnector              = "==>"
 	SnapshotSep                    = "#"
 	MaxPartNum                     = 10000
@@ -121,12 +173,75 @@ const (
 	MinParallel             int64  = 1
 	DefaultHashType         string = "crc64"
 	MD5HashType             string = "md5"
+	OutputFormatText        string = "text"
+	OutputFormatJSON        string = "json"
+	SymlinkSkip             string = "skip"
+	SymlinkFollow           string = "follow"
+	SymlinkPreserve         string = "preserve"
+	SymlinkObjectType       string = "Symlink"
+	OssObjectTypeHeader     string = "X-Oss-Object-Type"
+	OssRestoreHeader        string = "X-Oss-Restore"
+	OssStorageClassHeader   string = "X-Oss-Storage-Class"
+	RestoreTierExpedited    string = "Expedited"
+	RestoreTierStandard     string = "Standard"
+	RestoreTierBulk         string = "Bulk"
+	RestoreStatusOngoing    string = "ongoing"
+	RestoreStatusRestored   string = "restored"
+	RestoreStatusFrozen     string = "frozen"
+	RestoreStatusUnknown    string = "unknown"
+	RestoreStatusRoutines   int    = 10
+	MaxRestoreDays          int64  = 365
+	MinRestoreDays          int64  = 1
+	RestoreWaitInterval     int64  = 5
+	MaxRestoreWaitInterval  int64  = 120
+	ListEntryBucket         string = "bucket"
+	ListEntryObject         string = "object"
+	ListEntryDirectory      string = "directory"
+	ListEntryMultipart      string = "multipart"
 	LogFilePrefix                  = "ossutil_log_"
 	URLEncodingType                = "url"
 	StorageStandard                = string(oss.StorageStandard)
 	StorageIA                      = string(oss.StorageIA)
 	StorageArchive                 = string(oss.StorageArchive)
 	DefaultStorageClass            = StorageStandard
+	CompletionBash          string = "bash"
+	CompletionZsh           string = "zsh"
+	CompletionFish          string = "fish"
+	CompletionListMode      string = "__list"
+	CompletionCacheDir             = "~" + string(os.PathSeparator) + ".ossutil_completion"
+	CompletionCacheTTL      int64  = 30
+	CompletionMaxKeys              = 1000
+	ShellHistoryFile               = "~" + string(os.PathSeparator) + ".ossutil_history"
+	ShellHistorySize               = 1000
+	DefaultConnectTimeout   int64  = 120
+	DefaultReadTimeout      int64  = 1200
+	MinTimeout              int64  = 1
+	MaxTimeout              int64  = 86400
+	EndpointCacheDir               = "~" + string(os.PathSeparator) + ".ossutil_endpoint"
+	EndpointCacheTTL        int64  = 86400
+	EndpointProbeTimeout    int64  = 1
+	DefaultS3Region         string = "us-east-1"
+	S3MaxKeys                      = 1000
+	S3MetaPrefix            string = "X-Amz-Meta-"
+	DefaultQuietPeriod      int64  = 2
+	MinQuietPeriod          int64  = 0
+	MaxQuietPeriod          int64  = 3600
+	DefaultPollInterval     int64  = 5
+	MinPollInterval         int64  = 1
+	MaxPollInterval         int64  = 3600
+	WatchMaxRetry                  = 10
+	WatchMaxRetryInterval   int64  = 60
+	DefaultHookRoutines     int64  = 5
+	MinHookRoutines         int64  = 1
+	MaxHookRoutines         int64  = 100
+	DefaultHookTimeout      int64  = 60
+	MinHookTimeout          int64  = 1
+	MaxHookTimeout          int64  = 86400
+	DefaultNotifyRetryTimes int64  = 3
+	MinNotifyRetryTimes     int64  = 0
+	MaxNotifyRetryTimes     int64  = 100
+	NotifyMaxRetryInterval  int64  = 60
+	NotifyTimeout           int64  = 30
 )
 
 const (
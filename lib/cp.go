@@ -5,10 +5,13 @@ import (
 	"math"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
@@ -21,6 +24,7 @@ const (
 	operationTypePut operationType = iota
 	operationTypeGet
 	operationTypeCopy
+	operationTypeMigrate
 )
 
 const (
@@ -30,18 +34,34 @@ const (
 )
 
 type copyOptionType struct {
+	batchOptionType
 	recursive    bool
 	force        bool
 	update       bool
 	threshold    int64
 	cpDir        string
+	outputDir    string
 	routines     int64
-	ctnu         bool
-	reporter     *Reporter
+	partSize     int64
+	parallel     int64
 	snapshotPath string
 	snapshotldb  *leveldb.DB
 	vrange       string
 	encodingType string
+	checksum     bool
+	sizeOnly     bool
+	crcCache     *checksumCache
+	verify       bool
+	verifyRetry  int64
+	preserve     bool
+	symlinks     string
+	autoRestore  bool
+	destCommand  *Command
+	streamCopy   bool
+	watch        bool
+	quietPeriod  int64
+	pollInterval int64
+	watchDelete  bool
 }
 
 type fileInfoType struct {
@@ -53,13 +73,13 @@ type objectInfoType struct {
 	key          string
 	size         int64
 	lastModified time.Time
+	etag         string
+	objectType   string
 }
 
 var (
-	mu               sync.RWMutex // mu is the mutex for interacting with user
-	snapmu           sync.RWMutex
-	chProgressSignal chan chProgressSignalType
-	signalNum        = 0
+	mu     sync.RWMutex // mu is the mutex for interacting with user
+	snapmu sync.RWMutex
 )
 
 type chProgressSignalType struct {
@@ -67,15 +87,9 @@ type chProgressSignalType struct {
 	exitStat int
 }
 
-func freshProgress() {
-	if len(chProgressSignal) <= signalNum {
-		chProgressSignal <- chProgressSignalType{false, normalExit}
-	}
-}
-
 // OssProgressListener progress listener
 type OssProgressListener struct {
-	monitor  *CPMonitor
+	cc       *CopyCommand
 	lastSize int64
 	currSize int64
 }
@@ -85,8 +99,8 @@ func (l *OssProgressListener) ProgressChanged(event *oss.ProgressEvent) {
 	if event.EventType == oss.TransferDataEvent {
 		l.lastSize = l.currSize
 		l.currSize = event.ConsumedBytes
-		l.monitor.updateTransferSize(l.currSize - l.lastSize)
-		freshProgress()
+		l.cc.monitor.updateTransferSize(l.currSize - l.lastSize)
+		l.cc.freshProgress()
 	}
 }
 
@@ -98,8 +112,8 @@ var specChineseCopy = SpecText{
 
 	syntaxText: ` 
     ossutil cp file_url cloud_url  [-r] [-f] [-u] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--snapshot-path=sdir] 
-    ossutil cp cloud_url file_url  [-r] [-f] [-u] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--range=x-y] 
-    ossutil cp cloud_url cloud_url [-r] [-f] [-u] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] 
+    ossutil cp cloud_url file_url  [-r] [-f] [-u] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--range=x-y] [--snapshot-path=sdir] 
+    ossutil cp cloud_url cloud_url [-r] [-f] [-u] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--snapshot-path=sdir] 
 `,
 
 	detailHelpText: ` 
@@ -164,10 +178,150 @@ var specChineseCopy = SpecText{
     否指定了，在目标文件存在时，ossutil都不会提示，直接采取上述策略。
     该选项可用于当批量拷贝失败时，重传时跳过已经成功的文件。实现增量上传。
 
+--checksum选项
+
+    该选项需要和--update选项一起使用，指定后ossutil不再比较lastModifiedTime，而是根据文件内容
+    判断是否跳过：只有当源和目标的大小相同，并且crc64值相同时，才跳过该文件（或object）。适用于
+    文件被touch但内容未改变、本地时钟不准确、或从新checkout的目录上传等场景。上传和下载时，
+    ossutil计算本地文件的crc64，并与object的` + StatCRC64 + `比较；拷贝时，比较源object与目标
+    object的` + StatCRC64 + `。本地文件的crc64按照路径、大小和修改时间缓存在` + ChecksumCacheDir + `
+    目录下，文件未改变时，再次执行不需要重新计算。若object不存在crc64值（例如在oss支持crc64
+    功能之前上传的object），则不会跳过。该选项不能和--range选项同时使用。
+
+--size-only选项
+
+    该选项需要和--update选项一起使用，指定后ossutil只比较源和目标的大小，大小相同时即跳过该文件
+    （或object）。该选项不能和--checksum选项同时使用。
+
+文件属性：
+
+    下载时，ossutil会将本地文件的修改时间设置为object的lastModifiedTime，以便--update选项
+    能正确判断文件是否更新。
+
+--preserve选项
+
+    如果指定了该选项，上传时ossutil将本地文件的权限、属主（uid/gid）、mtime和atime分别记录到
+    object的x-oss-meta-mode、x-oss-meta-uid、x-oss-meta-gid、x-oss-meta-mtime、x-oss-meta-atime
+    中；下载时，ossutil读取这些meta，并恢复本地文件的权限、属主、mtime和atime。修改属主需要相应
+    的权限，没有权限时会忽略属主。属主和atime目前只在linux上记录。
+
+--symlinks选项
+
+    指定上传时本地符号链接的处理方式。未指定该选项时，ossutil上传符号链接指向的文件内容，
+    但不进入链接指向的目录。该选项的取值如下：
+        skip：忽略所有的符号链接。
+        follow：上传符号链接指向的文件，并进入链接指向的目录，链接形成循环或指向不存在的文件时
+            会被忽略。
+        preserve：将符号链接上传为oss上的symlink object，目标object为链接目标相对于上传目录
+            对应的object（链接目标不能超出bucket）；下载时，symlink object被还原为指向对应本地
+            文件的相对符号链接。
+
+--auto-restore选项
+
+    ` + StorageArchive + `类型的object需要解冻后才能下载或拷贝。如果指定了该选项，下载或拷贝前，ossutil找出源中
+    所有` + StorageArchive + `类型的object并发起解冻请求，然后定时查询它们的解冻状态（见restore命令的帮助），
+    等待所有object解冻完成后，再进行下载或拷贝。该选项只用于下载和拷贝。
+
+跨账号或跨地域拷贝：
+
+--dest-config-file、--dest-endpoint、--dest-access-key-id、--dest-access-key-secret、--dest-sts-token选项
+
+    oss间拷贝时，默认使用同一个endpoint和AccessKey访问源和目标bucket。如果目标bucket属于其他账号
+    或在其他地域，可以通过这些选项指定访问目标bucket的配置文件、endpoint和AccessKey，选项的值会覆
+    盖--dest-config-file中的相应设置，未指定的endpoint或AccessKey与源相同。
+    当源和目标的AccessKeyID或地域不同时，无法在oss端拷贝，ossutil从源下载数据并直接上传到目标，
+    数据不落本地磁盘。大文件同样使用分片上传，并在--checkpoint-dir中记录断点，进度、--verify校验
+    和report文件与普通拷贝相同。
+
+从s3兼容存储迁移：
+
+--s3-endpoint、--s3-access-key-id、--s3-access-key-secret、--s3-session-token、--s3-region选项
+
+    源url为s3://bucket[/prefix]时，ossutil使用s3签名（Signature Version 4）从s3兼容存储读取object，
+    并直接上传到oss，数据不落本地磁盘。这些选项指定s3兼容存储的endpoint、AccessKey和region，未指
+    定AccessKey或region时使用环境变量AWS_ACCESS_KEY_ID、AWS_SECRET_ACCESS_KEY、AWS_SESSION_TOKEN
+    和AWS_REGION。目标必须为oss url，大文件使用分片上传，并在--checkpoint-dir中为每个object记录断
+    点，迁移中断后重新运行命令即可继续。配合--update选项可以增量迁移。迁移不支持--checksum和
+    --auto-restore选项，--verify只比较大小。
+
+持续上传：
+
+--watch、--quiet-period、--poll-interval、--delete选项
+
+    如果指定了--watch选项，ossutil上传目录后不退出，而是持续监听目录的变化，将新建或修改的文件
+    上传到oss，覆盖之前上传的object，直到命令被中断（Ctrl+C）。该选项只用于上传目录，必须和
+    --recursive选项一起使用。
+    文件在--quiet-period指定的时间（默认2秒）内没有再变化后才会上传，避免上传正在写入的文件。
+    上传失败的文件会在稍后重试，重试间隔逐次加倍，最长60秒，最多重试10次，失败记录在report文件中。
+    在linux上，ossutil通过inotify监听目录，其他系统或inotify不可用时，每--poll-interval秒（默认
+    5秒）扫描一次目录，指定了--poll-interval时总是使用扫描。
+    如果同时指定了--delete选项，本地删除的文件或目录对应的object也会被删除。
+
+钩子：
+
+--on-success、--on-error、--on-finish、--hook-routines、--hook-timeout选项
+
+    指定每个文件传输成功或失败后，以及整个任务结束后执行的命令（通过sh -c执行，windows上通过
+    cmd /C执行），跳过的文件不执行命令。set-meta、set-acl、set-storage-class和restore命令的批量
+    操作，rm -r删除object，以及create-symlink --manifest和read-symlink -r也支持这些选项。
+    文件的信息通过环境变量OSSUTIL_EVENT（success或error）、OSSUTIL_COMMAND、OSSUTIL_SOURCE、
+    OSSUTIL_DESTINATION、OSSUTIL_SIZE、OSSUTIL_ETAG、OSSUTIL_CRC64、OSSUTIL_ERROR传递，同样的信息
+    也以json格式写入命令的标准输入。上传和拷贝时，大小、ETag和crc64为目标object的值，下载时为源
+    object的值。任务结束时，成功和失败的数量通过OSSUTIL_SUCCEEDED和OSSUTIL_FAILED传递给--on-finish
+    命令，OSSUTIL_EVENT为finish。
+    --on-success和--on-error命令在后台执行，同时最多执行--hook-routines个，命令执行超过--hook-timeout
+    秒后被终止（命令启动的子进程同时被终止）。命令返回非0值或超时记为失败，失败信息（包括命令的输出）记录在report文件中，不影
+    响文件的传输结果。
+
+结束通知：
+
+--notify-url、--notify-secret、--notify-retry-times、--notify-heartbeat选项
+
+    如果指定了--notify-url选项，命令结束时（包括失败或被中断），ossutil将执行结果以json格式POST到
+    该url，包括命令行（command、commandLine）、开始和结束时间（startTime、endTime，RFC3339格式）、
+    状态（status：succeeded、failed或interrupted）、退出码（exitStatus）、错误信息（error）、report
+    文件路径（reportFile）以及文件的数量和大小（copy，rm命令为remove）。rm命令也支持这些选项。
+    请求的X-Ossutil-Event头为事件类型（finish或heartbeat），如果指定了--notify-secret，请求body的
+    HMAC-SHA256值以"sha256=<十六进制>"的格式放在X-Ossutil-Signature头中，接收方可以据此验证通知。
+    发送失败或服务端返回429、5xx时重试--notify-retry-times次（默认3次），重试间隔逐次加倍。通知失
+    败不影响命令的结果。
+    如果指定了--notify-heartbeat，每处理该数量的文件，ossutil在后台发送一次status为running的进度
+    通知，进度通知不重试，上一次通知未完成时跳过。
+
+指标：
+
+--metrics-addr选项
+
+    如果指定了该选项，命令运行期间，ossutil在该地址（如:9100）的/metrics路径上以prometheus文本格式
+    提供指标，供prometheus采集和告警，命令结束后停止服务。rm命令也支持该选项。指标包括：
+    ossutil_start_time_seconds：命令开始的时间（unix时间戳）；
+    ossutil_items_scanned和ossutil_scan_end：扫描到的文件数，以及扫描是否结束；
+    ossutil_items_total：按结果（result为succeeded、skipped或failed）统计的文件数；
+    ossutil_transferred_bytes_total和ossutil_skipped_bytes_total：传输和跳过的字节数；
+    ossutil_throughput_bytes_per_second：最近5秒的平均传输速度；
+    ossutil_retries_total：因--retry-times重试的次数；
+    ossutil_request_duration_seconds和ossutil_request_errors_total：按操作（operation，如PutObject、
+    UploadPart）统计的oss请求延时直方图和失败的请求数。
+    所有指标带有command标签，值为命令名。
+
+校验：
+
+--verify选项
+
+    ossutil在每次请求中会进行crc64校验，但当指定了--disable-crc64，或者大文件经过了断点续传时，
+    并不能确保整个文件完整传输。如果指定了该选项，每个文件（或object）传输完成后，ossutil重新
+    获取目标的大小和crc64值（oss上的object通过HEAD请求获取，本地文件计算crc64），并与源进行比
+    较。若源或目标不存在crc64值，则只比较大小；指定了--range选项时，只比较下载的大小。校验不一
+    致时，该文件记为失败，错误信息会记录到report文件中。
+
+--verify-retry选项
+
+    和--verify选项一起使用，指定校验不一致时自动重新传输的次数，默认为0，即不重新传输。
+
 --snapshot-path选项
 
-    该选项用于在某些场景下加速增量上传批量文件（目前，下载和拷贝不支持该选项）。此场景为：
-    文件数较多且两次上传期间没有其他用户更改了oss上的对应object。
+    该选项用于在某些场景下加速增量上传、下载或拷贝批量文件。此场景为：文件数较多且两次上
+    传期间没有其他用户更改了oss上的对应object。
 
     在cp上传文件时使用该选项，ossutil在指定的目录下生成文件记录文件上传的快照信息，在下一
     次指定该选项上传时，ossutil会读取指定路径下的快照信息进行增量上传。用户指定的snapshot-path
@@ -175,6 +329,13 @@ var specChineseCopy = SpecText{
     如果该路径文件已存在，ossutil会读取里面的快照信息，根据快照信息进行增量上传（只上传上次
     未成功上传的文件和本地进行过修改的文件），并更新快照信息。
 
+    在cp下载object时使用该选项，ossutil记录object的ETag和lastModifiedTime，再次下载时，如果
+    object未发生变化且本地文件存在，则跳过该object的下载。在cp拷贝object时使用该选项，ossutil
+    记录源object的ETag，再次拷贝时，如果源object未发生变化，则跳过该object的拷贝。批量下载
+    和拷贝时，是否跳过根据列举object的结果判断，不需要对每个object发送额外的请求。
+
+    可以使用snapshot命令查看、清理和导出snapshot-path下的快照信息。
+
     注意：
     （1）因为该命令通过在本地记录成功上传的文件的本地lastModifiedTime，从而在下次上传时通过
     比较lastModifiedTime来决定是否跳过相同文件的上传，所以在使用该选项时，请确保两次上传期
@@ -379,6 +540,9 @@ var specChineseCopy = SpecText{
     ossutil cp local_dir oss://bucket1/b -r -u
     使用--update策略进行增量上传
 
+    ossutil cp local_dir oss://bucket1/b -r -u --checksum
+    根据文件大小和crc64进行增量上传
+
     ossutil cp local_dir oss://bucket1/b -r --snapshot-path=your_local_path
     使用--snapshot-path策略进行增量上传
 
@@ -513,8 +677,8 @@ var specEnglishCopy = SpecText{
 
 	syntaxText: ` 
     ossutil cp file_url cloud_url  [-r] [-f] [-u] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--snapshot-path=sdir]
-    ossutil cp cloud_url file_url  [-r] [-f] [-u] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--range=x-y] 
-    ossutil cp cloud_url cloud_url [-r] [-f] [-u] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] 
+    ossutil cp cloud_url file_url  [-r] [-f] [-u] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--range=x-y] [--snapshot-path=sdir] 
+    ossutil cp cloud_url cloud_url [-r] [-f] [-u] [--output-dir=odir] [--bigfile-threshold=size] [--checkpoint-dir=cdir] [--snapshot-path=sdir] 
 `,
 
 	detailHelpText: ` 
@@ -593,11 +757,187 @@ Incremental Upload/Download/Copy:
     specified or not.
     The option can be used when batch copy failed, skip the succeed files in retry.
 
+--checksum option
+
+    The option must be used with --update option, if it is specified, ossutil decides whether to 
+    skip by content instead of lastModifiedTime: only when the source and destination have the 
+    same size and crc64, the file(or object) is skipped. It is useful when files are touched without 
+    being changed, or the local clock is skewed, or upload from a fresh checkout. When upload or 
+    download, ossutil computes crc64 of local file and compares it with ` + StatCRC64 + ` of object, 
+    when copy, ossutil compares ` + StatCRC64 + ` of source object and destination object. The crc64 
+    of local files are cached by path, size and modify time under ` + ChecksumCacheDir + `, so they 
+    will not be computed again if the files are not changed. If the object has no crc64(e.g. the 
+    object was uploaded before oss support crc64), it will not be skipped. The option can not be 
+    used together with --range option.
+
+--size-only option
+
+    The option must be used with --update option, if it is specified, ossutil only compares the 
+    size of source and destination, the file(or object) is skipped when sizes are equal. The option 
+    can not be used together with --checksum option.
+
+File Attributes:
+
+    When download, ossutil sets modify time of local file to lastModifiedTime of object, so that 
+    --update option can decide whether the file is updated correctly.
+
+--preserve option
+
+    If the option is specified, when upload, ossutil records mode, owner(uid/gid), mtime and atime 
+    of local file in x-oss-meta-mode, x-oss-meta-uid, x-oss-meta-gid, x-oss-meta-mtime and 
+    x-oss-meta-atime of object; when download, ossutil reads the meta, and restores mode, owner, 
+    mtime and atime of local file. Changing owner needs privilege, owner is ignored if not 
+    permitted. Owner and atime are only recorded on linux currently.
+
+--symlinks option
+
+    Specify the way to deal local symlinks when upload. If the option is not specified, ossutil 
+    uploads the content of the files the links point to, but does not walk into the linked 
+    directories. The value of the option can be:
+        skip: ignore all symlinks.
+        follow: upload the files the links point to, and walk into the linked directories, links 
+            which make a loop or point to nonexistent files are ignored.
+        preserve: upload symlinks as symlink objects in oss, the target object is the object 
+            corresponding to the link target(the target can not be out of bucket); when download, 
+            symlink objects are restored to relative local symlinks pointing to the corresponding 
+            files.
+
+--auto-restore option
+
+    Objects of ` + StorageArchive + ` storage class must be restored before download or copy. If the option is 
+    specified, before download or copy, ossutil finds all the objects of ` + StorageArchive + ` storage class in 
+    source and sends restore requests, then queries their restore status periodically(see help of 
+    restore command), and downloads or copies after all of them are restored. The option is only 
+    used for download and copy.
+
+Copy across accounts or regions:
+
+--dest-config-file, --dest-endpoint, --dest-access-key-id, --dest-access-key-secret, --dest-sts-token option
+
+    When copy between oss, the source and destination buckets are accessed with the same endpoint and 
+    AccessKey by default. If the destination bucket belongs to another account or is in another region, 
+    these options specify the config file, endpoint and AccessKey to access the destination bucket, 
+    the values of the options cover the values in --dest-config-file, the endpoint or AccessKey not 
+    specified is the same as the source.
+    When the AccessKeyID or region of source and destination are different, copy can not be done in 
+    oss, ossutil downloads the data from source and uploads it to destination directly, without saving 
+    to local disk. Big files are uploaded by multipart too, and the checkpoints are recorded in 
+    --checkpoint-dir, the progress, --verify and report file are the same as ordinary copy.
+
+Migrate from s3 compatible storage:
+
+--s3-endpoint, --s3-access-key-id, --s3-access-key-secret, --s3-session-token, --s3-region option
+
+    When the source url is s3://bucket[/prefix], ossutil reads the objects from s3 compatible storage 
+    with s3 signature(Signature Version 4), and uploads them to oss directly, without saving to local 
+    disk. These options specify the endpoint, AccessKey and region of s3 compatible storage, the 
+    environment variables AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN and AWS_REGION 
+    are used if the AccessKey or region is not specified. The destination must be oss url, big files 
+    are uploaded by multipart, and the checkpoint of each object is recorded in --checkpoint-dir, run 
+    the command again to continue an interrupted migration. Use --update option to migrate 
+    incrementally. Migration does not support --checksum and --auto-restore option, --verify only 
+    compares the size.
+
+Upload continuously:
+
+--watch, --quiet-period, --poll-interval, --delete option
+
+    If --watch option is specified, ossutil does not exit after uploading the directory, but keeps 
+    watching the changes of the directory, and uploads the files created or modified to oss, which 
+    overwrite the objects uploaded before, until the command is interrupted(Ctrl+C). The option is 
+    only used to upload directory, and must be used with --recursive option.
+    A file is uploaded after it's not changed for the period specified by --quiet-period(2 seconds by 
+    default), so that the files being written are not uploaded. The files failed to upload are 
+    retried later, the interval doubles for each retry up to 60 seconds, and a file is retried 10 
+    times at most, the failures are recorded in report file.
+    On linux, ossutil watches the directory by inotify. On other systems, or if inotify is not 
+    available, the directory is scanned every --poll-interval seconds(5 seconds by default), the 
+    directory is always scanned if --poll-interval is specified.
+    If --delete option is specified too, the objects of the files or directories deleted locally are 
+    deleted.
+
+Hooks:
+
+--on-success, --on-error, --on-finish, --hook-routines, --hook-timeout option
+
+    Specify the commands run after each file succeeds or fails to transfer, and after the whole job 
+    finishes(run by sh -c, or cmd /C on windows), the commands are not run for skipped files. The 
+    batch operations of set-meta, set-acl, set-storage-class and restore command, the objects removed 
+    by rm -r, create-symlink --manifest and read-symlink -r support these options too.
+    The detail of file is passed by environment variables OSSUTIL_EVENT(success or error), 
+    OSSUTIL_COMMAND, OSSUTIL_SOURCE, OSSUTIL_DESTINATION, OSSUTIL_SIZE, OSSUTIL_ETAG, OSSUTIL_CRC64 
+    and OSSUTIL_ERROR, and the same detail is written to the stdin of command in json. The size, ETag 
+    and crc64 are those of the destination object for upload and copy, and those of the source object 
+    for download. When the job finishes, the numbers of succeeded and failed files are passed to 
+    --on-finish command by OSSUTIL_SUCCEEDED and OSSUTIL_FAILED, and OSSUTIL_EVENT is finish.
+    The --on-success and --on-error commands run in background, at most --hook-routines of them at 
+    the same time, and a command is killed(with the processes started by it) after it runs for 
+    --hook-timeout seconds. A command fails if it exits with non-zero or times out, the 
+    failures(including the output of command) are recorded in report file, and do not affect the 
+    result of the files.
+
+Notification:
+
+--notify-url, --notify-secret, --notify-retry-times, --notify-heartbeat option
+
+    If --notify-url option is specified, ossutil POSTs the result in json to the url when the command 
+    ends(including failed or interrupted), which includes the command line(command, commandLine), 
+    the start and end time(startTime, endTime, in RFC3339), the status(status: succeeded, failed or 
+    interrupted), the exit status(exitStatus), the error message(error), the path of report 
+    file(reportFile), and the numbers and sizes of files(copy, or remove for rm command). rm command 
+    supports these options too.
+    The X-Ossutil-Event header of request is the event(finish or heartbeat). If --notify-secret is 
+    specified, the HMAC-SHA256 of request body is put in X-Ossutil-Signature header in the format of 
+    "sha256=<hex>", so that the receiver can verify the notification. The notification is retried 
+    for --notify-retry-times times(3 by default) if it fails to send or the server responds 429 or 
+    5xx, and the interval doubles for each retry. The failure of notification does not affect the 
+    result of command.
+    If --notify-heartbeat is specified, ossutil sends a notification of progress with status running 
+    in background each time the number of files is dealt with, which is not retried, and is skipped 
+    if the previous one has not finished.
+
+Metrics:
+
+--metrics-addr option
+
+    If the option is specified, ossutil serves the metrics in prometheus text format on /metrics of 
+    the address(such as :9100) while the command runs, so that they can be scraped and alerted on by 
+    prometheus, the server stops after the command ends. rm command supports the option too. The 
+    metrics are:
+    ossutil_start_time_seconds: the start time of command(unix timestamp);
+    ossutil_items_scanned and ossutil_scan_end: the number of files scanned, and whether the scan 
+    is finished;
+    ossutil_items_total: the number of files by result(result is succeeded, skipped or failed);
+    ossutil_transferred_bytes_total and ossutil_skipped_bytes_total: the bytes transferred and 
+    skipped;
+    ossutil_throughput_bytes_per_second: the average transfer speed in the last 5 seconds;
+    ossutil_retries_total: the number of retries by --retry-times;
+    ossutil_request_duration_seconds and ossutil_request_errors_total: the histogram of latency and 
+    the number of failed oss requests by operation(operation label, such as PutObject, UploadPart).
+    All the metrics have command label, which is the name of command.
+
+Verify:
+
+--verify option
+
+    Ossutil checks crc64 in each request, but it can not make sure the whole file is transferred 
+    intact when --disable-crc64 is specified, or a big file is transferred by resume. If the option 
+    is specified, after each file(or object) is transferred, ossutil gets size and crc64 of the 
+    destination again(by HEAD request for oss object, compute crc64 for local file) and compares them 
+    with the source. If source or destination has no crc64, only size is compared, if --range option 
+    is specified, only the size downloaded is compared. When they are different, the file is counted 
+    as failed, and the error message will be recorded in report file.
+
+--verify-retry option
+
+    Used with --verify option, specify the times to transfer again automatically when verify failed, 
+    default is 0, which means not to transfer again.
+
 --snapshot-path option
 
-    This option is used to accelerate the incremental upload of batch files in certain scenarios(
-    currently, download and copy do not support this option). The scenarios is: lots of files and 
-    no other user updated the corresponding object in oss during the two uploads.
+    This option is used to accelerate the incremental upload, download or copy of batch files in 
+    certain scenarios. The scenarios is: lots of files and no other user updated the corresponding 
+    object in oss during the two uploads.
     
     If you use the option when batch copy files, ossutil will generate files to record the snapshot 
     information in the specified directory. When the next time you upload files with the option, 
@@ -607,6 +947,15 @@ Incremental Upload/Download/Copy:
     ossutil will read snapshot information from the directory for incremental upload(ossutil will 
     only upload the files which has not been successfully upload to oss and the files has been locally 
     modified), and update the snapshot information to the directory. 
+
+    If you use the option when download objects, ossutil records ETag and lastModifiedTime of the 
+    objects, when download again, if the object is unchanged and the local file exists, ossutil will 
+    skip the download of the object. If you use the option when copy objects, ossutil records ETag 
+    of the source objects, when copy again, if the source object is unchanged, ossutil will skip the 
+    copy of the object. When batch download or copy, ossutil decides whether to skip by the result of 
+    listing objects, no extra request is sent for each object. 
+
+    Use snapshot command to inspect, prune or export the snapshot information under snapshot-path. 
     
     Note: 
     (1) The option record the lastModifiedTime of local files which has been successfully upload in 
@@ -834,6 +1183,9 @@ Usage:
     ossutil cp local_dir oss://bucket1/b -r -u
     Use --update policy for incremental upload
 
+    ossutil cp local_dir oss://bucket1/b -r -u --checksum
+    Use size and crc64 for incremental upload
+
     ossutil cp local_dir oss://bucket1/b -r --snapshot-path=your_local_path
     Use --snapshot-path policy for incremental upload
 
@@ -961,6 +1313,10 @@ type CopyCommand struct {
 	command  Command
 	cpOption copyOptionType
 	monitor  CPMonitor
+
+	// chProgressSignal refreshes the progress bar, signalNum is -1 after the progress bar is closed
+	chProgressSignal chan chProgressSignalType
+	signalNum        int32
 }
 
 var copyCommand = CopyCommand{
@@ -988,11 +1344,50 @@ var copyCommand = CopyCommand{
 			OptionAccessKeyID,
 			OptionAccessKeySecret,
 			OptionSTSToken,
+			OptionProxyHost,
+			OptionProxyUser,
+			OptionProxyPwd,
+			OptionCAFile,
+			OptionSkipVerify,
+			OptionForceHTTPS,
+			OptionConnectTimeout,
+			OptionReadTimeout,
 			OptionRetryTimes,
 			OptionRoutines,
 			OptionParallel,
 			OptionSnapshotPath,
 			OptionDisableCRC64,
+			OptionChecksum,
+			OptionSizeOnly,
+			OptionVerify,
+			OptionVerifyRetry,
+			OptionPreserve,
+			OptionSymlinks,
+			OptionAutoRestore,
+			OptionDestConfigFile,
+			OptionDestEndpoint,
+			OptionDestKeyID,
+			OptionDestKeySecret,
+			OptionDestSTSToken,
+			OptionS3Endpoint,
+			OptionS3KeyID,
+			OptionS3KeySecret,
+			OptionS3Token,
+			OptionS3Region,
+			OptionWatch,
+			OptionQuietPeriod,
+			OptionPollInterval,
+			OptionDelete,
+			OptionOnSuccess,
+			OptionOnError,
+			OptionOnFinish,
+			OptionHookRoutines,
+			OptionHookTimeout,
+			OptionNotifyURL,
+			OptionNotifySecret,
+			OptionNotifyRetryTimes,
+			OptionNotifyHeartbeat,
+			OptionMetricsAddr,
 		},
 	},
 }
@@ -1013,44 +1408,108 @@ func (cc *CopyCommand) Init(args []string, options OptionMapType) error {
 
 // RunCommand simulate inheritance, and polymorphism
 func (cc *CopyCommand) RunCommand() error {
+	cc.loadOptions()
+	n := len(cc.command.args)
+	return cc.copy(cc.command.args[0:n-1], cc.command.args[n-1])
+}
+
+// loadOptions gets the options of cp from the option map of command
+func (cc *CopyCommand) loadOptions() {
 	cc.cpOption.recursive, _ = GetBool(OptionRecursion, cc.command.options)
 	cc.cpOption.force, _ = GetBool(OptionForce, cc.command.options)
 	cc.cpOption.update, _ = GetBool(OptionUpdate, cc.command.options)
 	cc.cpOption.threshold, _ = GetInt(OptionBigFileThreshold, cc.command.options)
 	cc.cpOption.cpDir, _ = GetString(OptionCheckpointDir, cc.command.options)
+	cc.cpOption.outputDir, _ = GetString(OptionOutputDir, cc.command.options)
 	cc.cpOption.routines, _ = GetInt(OptionRoutines, cc.command.options)
-	cc.cpOption.ctnu = false
-	if cc.cpOption.recursive {
-		cc.cpOption.ctnu = true
-	}
-	outputDir, _ := GetString(OptionOutputDir, cc.command.options)
+	cc.cpOption.partSize, _ = GetInt(OptionPartSize, cc.command.options)
+	cc.cpOption.parallel, _ = GetInt(OptionParallel, cc.command.options)
+	cc.cpOption.ctnu = cc.cpOption.recursive
 	cc.cpOption.snapshotPath, _ = GetString(OptionSnapshotPath, cc.command.options)
 	cc.cpOption.vrange, _ = GetString(OptionRange, cc.command.options)
 	cc.cpOption.encodingType, _ = GetString(OptionEncodingType, cc.command.options)
+	cc.cpOption.checksum, _ = GetBool(OptionChecksum, cc.command.options)
+	cc.cpOption.sizeOnly, _ = GetBool(OptionSizeOnly, cc.command.options)
+	cc.cpOption.verify, _ = GetBool(OptionVerify, cc.command.options)
+	cc.cpOption.verifyRetry, _ = GetInt(OptionVerifyRetry, cc.command.options)
+	cc.cpOption.preserve, _ = GetBool(OptionPreserve, cc.command.options)
+	cc.cpOption.symlinks, _ = GetString(OptionSymlinks, cc.command.options)
+	cc.cpOption.autoRestore, _ = GetBool(OptionAutoRestore, cc.command.options)
+	cc.cpOption.watch, _ = GetBool(OptionWatch, cc.command.options)
+	cc.cpOption.watchDelete, _ = GetBool(OptionDelete, cc.command.options)
+	cc.cpOption.pollInterval, _ = GetInt(OptionPollInterval, cc.command.options)
+	quietPeriod, err := GetInt(OptionQuietPeriod, cc.command.options)
+	if err != nil {
+		quietPeriod = DefaultQuietPeriod
+	}
+	cc.cpOption.quietPeriod = quietPeriod
+}
+
+// copy uploads, downloads or copies srcURLs to destURL with cpOption, the state of the job is
+// kept in cc, so that the jobs of different CopyCommands can run at the same time
+func (cc *CopyCommand) copy(srcURLs []string, destURL string) (err error) {
+	// notify the result when the command ends, the statistic is included after the job starts
+	var started int32
+	cc.cpOption.reporter = nil
+	cc.cpOption.notifier, err = cc.command.newNotifier(func(msg *notifyMessage) {
+		if atomic.LoadInt32(&started) == 1 {
+			msg.Copy = cc.monitor.getNotifyStat()
+		}
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		cc.cpOption.notifier.finish(err, cc.cpOption.reporter.writtenPath())
+	}()
+
+	stopMetrics, err := cc.command.startMetrics(func() metricsStat {
+		if atomic.LoadInt32(&started) == 1 {
+			return cc.monitor.getMetricsStat()
+		}
+		return metricsStat{}
+	})
+	if err != nil {
+		return err
+	}
+	defer stopMetrics()
 
 	//get file list
-	srcURLList, err := cc.getStorageURLs(cc.command.args[0 : len(cc.command.args)-1])
+	srcURLList, err := cc.getStorageURLs(srcURLs)
 	if err != nil {
 		return err
 	}
 
-	destURL, err := StorageURLFromString(cc.command.args[len(cc.command.args)-1], cc.cpOption.encodingType)
+	destStorageURL, err := StorageURLFromString(destURL, cc.cpOption.encodingType)
 	if err != nil {
 		return err
 	}
 
-	opType := cc.getCommandType(srcURLList, destURL)
-	if err := cc.checkCopyArgs(srcURLList, destURL, opType); err != nil {
+	// the command to access destination bucket of copy
+	if cc.cpOption.destCommand, err = cc.newDestCommand(); err != nil {
+		return err
+	}
+
+	opType := cc.getCommandType(srcURLList, destStorageURL)
+	if err := cc.checkCopyArgs(srcURLList, destStorageURL, opType); err != nil {
 		return err
 	}
 	if err := cc.checkCopyOptions(opType); err != nil {
 		return err
 	}
 
+	// restore archive objects before download or copy
+	if cc.cpOption.autoRestore && opType != operationTypePut {
+		if err := cc.restoreArchiveObjects(srcURLList[0].(CloudURL)); err != nil {
+			return err
+		}
+	}
+
 	// init reporter
-	if cc.cpOption.reporter, err = GetReporter(cc.cpOption.ctnu, outputDir, commandLine); err != nil {
+	if cc.cpOption.reporter, err = GetReporter(cc.cpOption.ctnu, cc.cpOption.outputDir, commandLine); err != nil {
 		return err
 	}
+	cc.cpOption.hooks = cc.command.newItemHooks()
 
 	// create ckeckpoint dir
 	if err := os.MkdirAll(cc.cpOption.cpDir, 0755); err != nil {
@@ -1065,21 +1524,59 @@ func (cc *CopyCommand) RunCommand() error {
 		defer cc.cpOption.snapshotldb.Close()
 	}
 
-	cc.monitor.init(opType)
+	// load checksum cache of local files
+	cc.cpOption.crcCache = nil
+	if (cc.cpOption.checksum || cc.cpOption.verify) && opType != operationTypeCopy && opType != operationTypeMigrate {
+		cc.cpOption.crcCache = openChecksumCache()
+		defer cc.cpOption.crcCache.close()
+	}
+
+	// load job journal, the changed files are uploaded again when watching
+	cc.cpOption.journal = nil
+	if cc.cpOption.recursive && !cc.cpOption.watch {
+		if cc.cpOption.journal, err = cc.command.openJobJournal(); err != nil {
+			return err
+		}
+	}
 
-	chProgressSignal = make(chan chProgressSignalType, 10)
-	go cc.progressBar()
+	cc.monitor.init(opType)
+	atomic.StoreInt32(&started, 1)
+
+	cc.chProgressSignal = make(chan chProgressSignalType, 10)
+	atomic.StoreInt32(&cc.signalNum, 0)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		cc.progressBar(cc.chProgressSignal, done)
+	}()
+	// the progress is not reported after copy returns
+	defer wg.Wait()
+	defer close(done)
 
 	switch opType {
 	case operationTypePut:
-		err = cc.uploadFiles(srcURLList, destURL.(CloudURL))
+		if cc.cpOption.watch {
+			err = cc.watchFiles(srcURLList[0].(FileURL), destStorageURL.(CloudURL))
+		} else {
+			err = cc.uploadFiles(srcURLList, destStorageURL.(CloudURL))
+		}
 	case operationTypeGet:
-		err = cc.downloadFiles(srcURLList[0].(CloudURL), destURL.(FileURL))
+		err = cc.downloadFiles(srcURLList[0].(CloudURL), destStorageURL.(FileURL))
+	case operationTypeMigrate:
+		err = cc.migrateFiles(srcURLList[0].(S3URL), destStorageURL.(CloudURL))
 	default:
-		err = cc.copyFiles(srcURLList[0].(CloudURL), destURL.(CloudURL))
+		err = cc.copyFiles(srcURLList[0].(CloudURL), destStorageURL.(CloudURL))
 	}
 
+	// keep the checkpoints and journal of an interrupted job, so that it can continue when run again
+	if err == nil {
+		err = cc.command.cancelError()
+	}
+	cc.cpOption.hooks.finish(&cc.cpOption.batchOptionType, err)
 	cc.cpOption.reporter.Clear()
+	cc.cpOption.journal.close(err == nil)
 
 	if err == nil {
 		os.RemoveAll(cc.cpOption.cpDir)
@@ -1103,6 +1600,9 @@ func (cc *CopyCommand) getStorageURLs(urls []string) ([]StorageURLer, error) {
 }
 
 func (cc *CopyCommand) getCommandType(srcURLList []StorageURLer, destURL StorageURLer) operationType {
+	if _, ok := srcURLList[0].(S3URL); ok {
+		return operationTypeMigrate
+	}
 	if srcURLList[0].IsCloudURL() {
 		if destURL.IsFileURL() {
 			return operationTypeGet
@@ -1117,10 +1617,16 @@ func (cc *CopyCommand) checkCopyArgs(srcURLList []StorageURLer, destURL StorageU
 		if url.IsCloudURL() && url.(CloudURL).bucket == "" {
 			return fmt.Errorf("invalid cloud url: %s, miss bucket", url.ToString())
 		}
+		if s3URL, ok := url.(S3URL); ok && s3URL.bucket == "" {
+			return fmt.Errorf("invalid s3 url: %s, miss bucket", url.ToString())
+		}
 	}
 	if destURL.IsCloudURL() && destURL.(CloudURL).bucket == "" {
 		return fmt.Errorf("invalid cloud url: %s, miss bucket", destURL.ToString())
 	}
+	if _, ok := destURL.(S3URL); ok {
+		return fmt.Errorf("invalid url: %s, s3 url can only be used as source", destURL.ToString())
+	}
 
 	switch opType {
 	case operationTypePut:
@@ -1131,6 +1637,9 @@ func (cc *CopyCommand) checkCopyArgs(srcURLList []StorageURLer, destURL StorageU
 			if url.IsCloudURL() {
 				return fmt.Errorf("invalid url: %s, copy between oss operation appear in upload operation, multi-type operations is not supported in one command", url.ToString())
 			}
+			if _, ok := url.(S3URL); ok {
+				return fmt.Errorf("invalid url: %s, migration from s3 appear in upload operation, multi-type operations is not supported in one command", url.ToString())
+			}
 		}
 		if len(srcURLList) > 1 {
 			return fmt.Errorf("invalid url: %s, multiple source url in upload operation", srcURLList[1].ToString())
@@ -1139,6 +1648,13 @@ func (cc *CopyCommand) checkCopyArgs(srcURLList []StorageURLer, destURL StorageU
 		if len(srcURLList) > 1 {
 			return fmt.Errorf("invalid url: %s, multiple source url in download operation", srcURLList[1].ToString())
 		}
+	case operationTypeMigrate:
+		if !destURL.IsCloudURL() {
+			return fmt.Errorf("invalid url: %s, objects of s3 can only be migrated to oss, please make sure dest_url starts with \"%s\"", destURL.ToString(), SchemePrefix)
+		}
+		if len(srcURLList) > 1 {
+			return fmt.Errorf("invalid url: %s, multiple source url in migrate operation", srcURLList[1].ToString())
+		}
 	default:
 		if len(srcURLList) > 1 {
 			return fmt.Errorf("invalid url: %s, multiple source url in copy operation", srcURLList[1].ToString())
@@ -1148,26 +1664,76 @@ func (cc *CopyCommand) checkCopyArgs(srcURLList []StorageURLer, destURL StorageU
 }
 
 func (cc *CopyCommand) checkCopyOptions(opType operationType) error {
-	if operationTypePut != opType && cc.cpOption.snapshotPath != "" {
-		msg := fmt.Sprintf("only upload support option: \"%s\"", OptionSnapshotPath)
-		return CommandError{cc.command.name, msg}
-	}
 	if operationTypeGet != opType && cc.cpOption.vrange != "" {
 		msg := fmt.Sprintf("only download support option: \"%s\"", OptionRange)
 		return CommandError{cc.command.name, msg}
 	}
+	if (cc.cpOption.checksum || cc.cpOption.sizeOnly) && !cc.cpOption.update {
+		msg := fmt.Sprintf("option \"%s\" and \"%s\" must be used with option \"%s\"", OptionChecksum, OptionSizeOnly, OptionUpdate)
+		return CommandError{cc.command.name, msg}
+	}
+	if cc.cpOption.checksum && cc.cpOption.sizeOnly {
+		msg := fmt.Sprintf("option \"%s\" and \"%s\" can not be used together", OptionChecksum, OptionSizeOnly)
+		return CommandError{cc.command.name, msg}
+	}
+	if cc.cpOption.checksum && cc.cpOption.vrange != "" {
+		msg := fmt.Sprintf("option \"%s\" and \"%s\" can not be used together", OptionChecksum, OptionRange)
+		return CommandError{cc.command.name, msg}
+	}
+	if operationTypePut == opType && cc.cpOption.autoRestore {
+		msg := fmt.Sprintf("only download and copy support option: \"%s\"", OptionAutoRestore)
+		return CommandError{cc.command.name, msg}
+	}
+	if operationTypeMigrate == opType && (cc.cpOption.autoRestore || cc.cpOption.checksum) {
+		msg := fmt.Sprintf("migration from s3 does not support option: \"%s\" and \"%s\"", OptionAutoRestore, OptionChecksum)
+		return CommandError{cc.command.name, msg}
+	}
+	if cc.cpOption.watch && (operationTypePut != opType || !cc.cpOption.recursive) {
+		msg := fmt.Sprintf("option \"%s\" can only be used to upload directory with option \"%s\"", OptionWatch, OptionRecursion)
+		return CommandError{cc.command.name, msg}
+	}
+	if cc.cpOption.watchDelete && !cc.cpOption.watch {
+		msg := fmt.Sprintf("option \"%s\" must be used with option \"%s\"", OptionDelete, OptionWatch)
+		return CommandError{cc.command.name, msg}
+	}
+	if operationTypeCopy != opType && cc.cpOption.destCommand != &cc.command {
+		msg := fmt.Sprintf("only copy between oss support option: \"%s\", \"%s\", \"%s\", \"%s\" and \"%s\"", OptionDestConfigFile, OptionDestEndpoint, OptionDestKeyID, OptionDestKeySecret, OptionDestSTSToken)
+		return CommandError{cc.command.name, msg}
+	}
+
+	// the AccessKey of destination can not be mixed with the one of source
+	destConfigFile, _ := GetString(OptionDestConfigFile, cc.command.options)
+	destKeyID, _ := GetString(OptionDestKeyID, cc.command.options)
+	destKeySecret, _ := GetString(OptionDestKeySecret, cc.command.options)
+	if destConfigFile == "" && (destKeyID == "") != (destKeySecret == "") {
+		msg := fmt.Sprintf("option \"%s\" and \"%s\" must be used together", OptionDestKeyID, OptionDestKeySecret)
+		return CommandError{cc.command.name, msg}
+	}
 	return nil
 }
 
-func (cc *CopyCommand) progressBar() {
+func (cc *CopyCommand) progressBar(chSignal <-chan chProgressSignalType, done <-chan struct{}) {
 	// fetch all reveal
-	for signal := range chProgressSignal {
-		fmt.Printf(cc.monitor.progressBar(signal.finish, signal.exitStat))
+	for {
+		select {
+		case signal := <-chSignal:
+			cc.command.printf(cc.monitor.progressBar(signal.finish, signal.exitStat))
+			cc.command.hooks.progress(cc.monitor.getProgress())
+			cc.cpOption.notifier.progress(cc.monitor.getSnapshot().dealNum)
+		case <-done:
+			return
+		}
+	}
+}
+
+func (cc *CopyCommand) freshProgress() {
+	if int32(len(cc.chProgressSignal)) <= atomic.LoadInt32(&cc.signalNum) {
+		cc.chProgressSignal <- chProgressSignalType{false, normalExit}
 	}
 }
 
 func (cc *CopyCommand) closeProgress() {
-	signalNum = -1
+	atomic.StoreInt32(&cc.signalNum, -1)
 }
 
 //function for upload files
@@ -1187,40 +1753,12 @@ func (cc *CopyCommand) uploadFiles(srcURLList []StorageURLer, destURL CloudURL)
 		return err
 	}
 
-	// producer list files
-	// consumer set acl
-	chFiles := make(chan fileInfoType, ChannelBuf)
-	chError := make(chan error, cc.cpOption.routines)
-	chListError := make(chan error, 1)
 	go cc.fileStatistic(srcURLList)
-	go cc.fileProducer(srcURLList, chFiles, chListError)
-	for i := 0; int64(i) < cc.cpOption.routines; i++ {
-		go cc.uploadConsumer(bucket, destURL, chFiles, chError)
-	}
-
-	completed := 0
-	for int64(completed) <= cc.cpOption.routines {
-		select {
-		case err := <-chListError:
-			if err != nil {
-				return err
-			}
-			completed++
-		case err := <-chError:
-			if err == nil {
-				completed++
-			} else {
-				if !cc.cpOption.ctnu {
-					cc.closeProgress()
-					fmt.Printf(cc.monitor.progressBar(true, errExit))
-					return err
-				}
-			}
-		}
-	}
-	cc.closeProgress()
-	fmt.Printf(cc.monitor.progressBar(true, normalExit))
-	return nil
+	engine := cc.command.newBatchEngine(&cc.cpOption.batchOptionType, cc.cpOption.routines, cc.fileSource(srcURLList), func(item batchItem) (string, error) {
+		return cc.uploadItem(bucket, destURL, item.value.(fileInfoType))
+	})
+	engine.finish = cc.finishProgress
+	return engine.run()
 }
 
 func (cc *CopyCommand) adjustDestURLForUpload(srcURLList []StorageURLer, destURL CloudURL) (CloudURL, error) {
@@ -1242,6 +1780,9 @@ func (cc *CopyCommand) adjustDestURLForUpload(srcURLList []StorageURLer, destURL
 
 func (cc *CopyCommand) fileStatistic(srcURLList []StorageURLer) {
 	for _, url := range srcURLList {
+		if cc.command.canceled() {
+			break
+		}
 		name := url.ToString()
 		f, err := os.Stat(name)
 		if err != nil {
@@ -1266,14 +1807,17 @@ func (cc *CopyCommand) fileStatistic(srcURLList []StorageURLer) {
 	}
 
 	cc.monitor.setScanEnd()
-	freshProgress()
+	cc.freshProgress()
 }
 
 func (cc *CopyCommand) getFileListStatistic(dpath string) error {
-	err := filepath.Walk(dpath, func(fpath string, f os.FileInfo, err error) error {
+	err := cc.walkFiles(dpath, func(fpath string, f os.FileInfo, err error) error {
 		if f == nil {
 			return err
 		}
+		if cc.command.canceled() {
+			return cc.command.cancelError()
+		}
 
 		if !cc.filterPath(fpath, cc.cpOption.cpDir) {
 			return nil
@@ -1298,37 +1842,45 @@ func (cc *CopyCommand) getFileListStatistic(dpath string) error {
 	return err
 }
 
-func (cc *CopyCommand) fileProducer(srcURLList []StorageURLer, chFiles chan<- fileInfoType, chListError chan<- error) {
-	for _, url := range srcURLList {
-		name := url.ToString()
-		f, err := os.Stat(name)
-		if err != nil {
-			chListError <- err
-			return
-		}
-		if f.IsDir() {
-			if !cc.cpOption.recursive {
-				chListError <- fmt.Errorf("omitting directory \"%s\", please use --recursive option", name)
-				return
-			}
-			err := cc.getFileList(name, chFiles)
+// fileSource walks the files to upload, the files under checkpoint dir are skipped
+func (cc *CopyCommand) fileSource(srcURLList []StorageURLer) batchSource {
+	return func(send func(batchItem) bool) error {
+		for _, url := range srcURLList {
+			name := url.ToString()
+			f, err := os.Stat(name)
 			if err != nil {
-				chListError <- err
-				return
+				return err
+			}
+			if f.IsDir() {
+				if !cc.cpOption.recursive {
+					return fmt.Errorf("omitting directory \"%s\", please use --recursive option", name)
+				}
+				if err := cc.getFileList(name, send); err != nil {
+					return err
+				}
+			} else {
+				dir, fname := filepath.Split(name)
+				if !cc.sendFile(fileInfoType{fname, dir}, send) {
+					return nil
+				}
 			}
-		} else {
-			dir, fname := filepath.Split(name)
-			chFiles <- fileInfoType{fname, dir}
 		}
+		return nil
 	}
+}
 
-	defer close(chFiles)
-	chListError <- nil
+// sendFile sends the file to upload as item, the journal key of the file is its absolute path
+func (cc *CopyCommand) sendFile(file fileInfoType, send func(batchItem) bool) bool {
+	if !cc.filterFile(file, cc.cpOption.cpDir) {
+		return true
+	}
+	absPath, _ := filepath.Abs(filepath.Join(file.dir, file.filePath))
+	return send(batchItem{absPath, file})
 }
 
-func (cc *CopyCommand) getFileList(dpath string, chFiles chan<- fileInfoType) error {
+func (cc *CopyCommand) getFileList(dpath string, send func(batchItem) bool) error {
 	name := dpath
-	err := filepath.Walk(dpath, func(fpath string, f os.FileInfo, err error) error {
+	err := cc.walkFiles(dpath, func(fpath string, f os.FileInfo, err error) error {
 		if f == nil {
 			return err
 		}
@@ -1340,37 +1892,115 @@ func (cc *CopyCommand) getFileList(dpath string, chFiles chan<- fileInfoType) er
 			return fmt.Errorf("list file error: %s, info: %s", fpath, err.Error())
 		}
 
+		file := fileInfoType{fileName, name}
 		if f.IsDir() {
-			if fpath != dpath {
-				if strings.HasSuffix(fileName, "\\") || strings.HasSuffix(fileName, "/") {
-					chFiles <- fileInfoType{fileName, name}
-				} else {
-					chFiles <- fileInfoType{fileName + string(os.PathSeparator), name}
-				}
+			if fpath == dpath {
+				return nil
 			}
-			return nil
+			if !strings.HasSuffix(fileName, "\\") && !strings.HasSuffix(fileName, "/") {
+				file.filePath = fileName + string(os.PathSeparator)
+			}
+		}
+		if !cc.sendFile(file, send) {
+			return errBatchStopped
 		}
-		chFiles <- fileInfoType{fileName, name}
 		return nil
 	})
+	if cc.command.canceled() {
+		return nil
+	}
 	return err
 }
 
-func (cc *CopyCommand) uploadConsumer(bucket *oss.Bucket, destURL CloudURL, chFiles <-chan fileInfoType, chError chan<- error) {
-	for file := range chFiles {
-		if cc.filterFile(file, cc.cpOption.cpDir) {
-			err := cc.uploadFileWithReport(bucket, destURL, file)
-			if err != nil {
-				chError <- err
-				if !cc.cpOption.ctnu {
-					return
-				}
+// walkFiles walks the directory like filepath.Walk, and deals symlinks according to --symlinks:
+// skip ignores them, follow walks into the linked directories with loop detection, preserve
+// reports them without following. Without the option, it is the same as filepath.Walk.
+func (cc *CopyCommand) walkFiles(root string, walkFn filepath.WalkFunc) error {
+	if cc.cpOption.symlinks == "" {
+		return filepath.Walk(root, walkFn)
+	}
+
+	f, err := os.Lstat(root)
+	if err != nil {
+		return walkFn(root, nil, err)
+	}
+	realPath, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return walkFn(root, nil, err)
+	}
+	realPath, _ = filepath.Abs(realPath)
+	if f.Mode()&os.ModeSymlink != 0 {
+		if f, err = os.Stat(root); err != nil {
+			return walkFn(root, nil, err)
+		}
+	}
+	err = cc.walkDir(root, f, []string{realPath}, walkFn)
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
+
+// walkDir walks the directory, ancestors are the real paths of the directories walking in,
+// which are used to detect loop of symlinks
+func (cc *CopyCommand) walkDir(dpath string, f os.FileInfo, ancestors []string, walkFn filepath.WalkFunc) error {
+	if !f.IsDir() {
+		return walkFn(dpath, f, nil)
+	}
+	if err := walkFn(dpath, f, nil); err != nil {
+		return err
+	}
+
+	dir, err := os.Open(dpath)
+	if err != nil {
+		return walkFn(dpath, nil, err)
+	}
+	names, err := dir.Readdirnames(-1)
+	dir.Close()
+	if err != nil {
+		return walkFn(dpath, nil, err)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fpath := filepath.Join(dpath, name)
+		sf, err := os.Lstat(fpath)
+		if err != nil {
+			if err := walkFn(fpath, nil, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		realPath := filepath.Join(ancestors[len(ancestors)-1], name)
+		if sf.Mode()&os.ModeSymlink != 0 {
+			if cc.cpOption.symlinks == SymlinkSkip {
 				continue
 			}
+			if cc.cpOption.symlinks == SymlinkFollow {
+				tf, err := os.Stat(fpath)
+				if err != nil {
+					// dangling symlink
+					continue
+				}
+				if realPath, err = filepath.EvalSymlinks(fpath); err != nil {
+					continue
+				}
+				realPath, _ = filepath.Abs(realPath)
+				if tf.IsDir() && FindPos(realPath, ancestors) != -1 {
+					// loop of symlinks
+					continue
+				}
+				sf = tf
+			}
 		}
-	}
 
-	chError <- nil
+		err = cc.walkDir(fpath, sf, append(ancestors, realPath), walkFn)
+		if err != nil && (err != filepath.SkipDir || !sf.IsDir()) {
+			return err
+		}
+	}
+	return nil
 }
 
 func (cc *CopyCommand) filterFile(file fileInfoType, cpDir string) bool {
@@ -1390,11 +2020,14 @@ func (cc *CopyCommand) filterPath(filePath string, cpDir string) bool {
 	return !strings.Contains(absFile, absCPDir)
 }
 
-func (cc *CopyCommand) uploadFileWithReport(bucket *oss.Bucket, destURL CloudURL, file fileInfoType) error {
+// uploadItem is the batch action of upload
+func (cc *CopyCommand) uploadItem(bucket *oss.Bucket, destURL CloudURL, file fileInfoType) (string, error) {
 	skip, err, isDir, size, msg := cc.uploadFile(bucket, destURL, file)
 	cc.updateMonitor(skip, err, isDir, size)
-	cc.report(msg, err)
-	return err
+	objectName := cc.makeObjectName(destURL, file)
+	item := hookItem{Source: filepath.Join(file.dir, file.filePath), Destination: CloudURLToString(bucket.BucketName, objectName)}
+	cc.transferHook(skip, err, item, bucket, objectName)
+	return msg, err
 }
 
 func (cc *CopyCommand) uploadFile(bucket *oss.Bucket, destURL CloudURL, file fileInfoType) (skip bool, rerr error, isDir bool, size int64, msg string) {
@@ -1410,6 +2043,13 @@ func (cc *CopyCommand) uploadFile(bucket *oss.Bucket, destURL CloudURL, file fil
 	size = 0 // the size update to monitor
 	msg = fmt.Sprintf("%s %s to %s", opUpload, filePath, CloudURLToString(bucket.BucketName, objectName))
 
+	if cc.cpOption.symlinks == SymlinkPreserve {
+		if f, err := os.Lstat(filePath); err == nil && f.Mode()&os.ModeSymlink != 0 {
+			rerr = cc.uploadSymlink(bucket, objectName, filePath)
+			return
+		}
+	}
+
 	//get file size and last modify time
 	f, err := os.Stat(filePath)
 	if err != nil {
@@ -1423,8 +2063,13 @@ func (cc *CopyCommand) uploadFile(bucket *oss.Bucket, destURL CloudURL, file fil
 
 	srct := f.ModTime().Unix()
 	absPath, _ := filepath.Abs(filePath)
-	spath := cc.formatSnapshotKey(absPath, destURL.bucket, objectName)
-	if skip, rerr = cc.skipUpload(spath, bucket, objectName, destURL, srct); rerr != nil || skip {
+	if cc.cpOption.journal.isDone(absPath) {
+		skip = true
+		return
+	}
+
+	spath := cc.formatSnapshotKey(absPath, CloudURLToString(destURL.bucket, objectName))
+	if skip, rerr = cc.skipUpload(spath, bucket, objectName, destURL, filePath, size, srct); rerr != nil || skip {
 		return
 	}
 
@@ -1439,10 +2084,20 @@ func (cc *CopyCommand) uploadFile(bucket *oss.Bucket, destURL CloudURL, file fil
 	}
 
 	size = 0
-	var listener *OssProgressListener = &OssProgressListener{&cc.monitor, 0, 0}
+	var listener *OssProgressListener = &OssProgressListener{cc, 0, 0}
+	ossOptions := []oss.Option{oss.Progress(listener)}
+	if cc.cpOption.preserve {
+		ossOptions = append(ossOptions, getPreserveOptions(f)...)
+	}
+
 	//decide whether to use resume upload
+	verify := func() error {
+		return cc.verifyUpload(bucket, objectName, filePath)
+	}
 	if f.Size() < cc.cpOption.threshold {
-		rerr = cc.ossUploadFileRetry(bucket, objectName, filePath, oss.Progress(listener))
+		rerr = cc.transferWithVerify(func() error {
+			return cc.ossUploadFileRetry(bucket, objectName, filePath, ossOptions...)
+		}, verify)
 		if err := cc.updateSnapshot(rerr, spath, srct); err != nil {
 			rerr = err
 		}
@@ -1454,13 +2109,36 @@ func (cc *CopyCommand) uploadFile(bucket *oss.Bucket, destURL CloudURL, file fil
 	partSize, rt := cc.preparePartOption(f.Size())
 	//checkpoint file
 	cp := oss.Checkpoint(true, cc.formatCPFileName(cc.cpOption.cpDir, absPath, CloudURLToString(bucket.BucketName, objectName)))
-	rerr = cc.ossResumeUploadRetry(bucket, objectName, filePath, partSize, oss.Routines(rt), cp, oss.Progress(listener))
+	rerr = cc.transferWithVerify(func() error {
+		return cc.ossResumeUploadRetry(bucket, objectName, filePath, partSize, append(ossOptions, oss.Routines(rt), cp)...)
+	}, verify)
 	if err := cc.updateSnapshot(rerr, spath, srct); err != nil {
 		rerr = err
 	}
 	return
 }
 
+// uploadSymlink creates symlink object in oss for local symlink, the target of local symlink
+// is converted to object name relative to the symlink object
+func (cc *CopyCommand) uploadSymlink(bucket *oss.Bucket, objectName, filePath string) error {
+	target, err := os.Readlink(filePath)
+	if err != nil {
+		return FileError{err, filePath}
+	}
+	if filepath.IsAbs(target) {
+		dir, _ := filepath.Abs(filepath.Dir(filePath))
+		if target, err = filepath.Rel(dir, target); err != nil {
+			return FileError{err, filePath}
+		}
+	}
+
+	targetObject := path.Clean(path.Join(path.Dir(objectName), filepath.ToSlash(target)))
+	if targetObject == ".." || strings.HasPrefix(targetObject, "../") {
+		return FileError{fmt.Errorf("the target of symlink is out of bucket: %s", target), filePath}
+	}
+	return cc.command.ossCreateSymlinkRetry(bucket, objectName, targetObject)
+}
+
 func (cc *CopyCommand) makeObjectName(destURL CloudURL, file fileInfoType) string {
 	if destURL.object == "" || strings.HasSuffix(destURL.object, "/") || strings.HasSuffix(destURL.object, "\\") || strings.HasSuffix(destURL.object, string(os.PathSeparator)) {
 		// replace "\" of file.filePath to "/"
@@ -1472,7 +2150,7 @@ func (cc *CopyCommand) makeObjectName(destURL CloudURL, file fileInfoType) strin
 	return destURL.object
 }
 
-func (cc *CopyCommand) skipUpload(spath string, bucket *oss.Bucket, objectName string, destURL CloudURL, srct int64) (bool, error) {
+func (cc *CopyCommand) skipUpload(spath string, bucket *oss.Bucket, objectName string, destURL CloudURL, filePath string, size, srct int64) (bool, error) {
 	if cc.cpOption.snapshotPath != "" || cc.cpOption.update {
 		if cc.cpOption.snapshotPath != "" {
 			tstr, err := cc.cpOption.snapshotldb.Get([]byte(spath), nil)
@@ -1485,6 +2163,9 @@ func (cc *CopyCommand) skipUpload(spath string, bucket *oss.Bucket, objectName s
 		}
 		if cc.cpOption.update {
 			if props, err := cc.command.ossGetObjectStatRetry(bucket, objectName); err == nil {
+				if cc.cpOption.checksum || cc.cpOption.sizeOnly {
+					return cc.isSameSize(props, size) && cc.isSameLocalCRC64(props, filePath), nil
+				}
 				destt, err := time.Parse(http.TimeFormat, props.Get(oss.HTTPHeaderLastModified))
 				if err == nil && destt.Unix() >= srct {
 					return true, nil
@@ -1501,8 +2182,136 @@ func (cc *CopyCommand) skipUpload(spath string, bucket *oss.Bucket, objectName s
 	return false, nil
 }
 
-func (cc *CopyCommand) formatSnapshotKey(absPath, bucket, object string) string {
-	return absPath + SnapshotConnector + CloudURLToString(bucket, object)
+// isSameSize shows if the object has the size
+func (cc *CopyCommand) isSameSize(props http.Header, size int64) bool {
+	osize, err := strconv.ParseInt(props.Get(oss.HTTPHeaderContentLength), 10, 64)
+	return err == nil && osize == size
+}
+
+// isSameLocalCRC64 shows if the object has the same crc64 as local file, always true with --size-only
+func (cc *CopyCommand) isSameLocalCRC64(props http.Header, filePath string) bool {
+	if !cc.cpOption.checksum {
+		return true
+	}
+	ocrc := props.Get(oss.HTTPHeaderOssCRC64)
+	if ocrc == "" {
+		return false
+	}
+	crc, err := cc.cpOption.crcCache.fileCRC64(filePath)
+	return err == nil && strconv.FormatUint(crc, 10) == ocrc
+}
+
+// transferWithVerify runs transfer, and verifies the destination after transfer if --verify is specified.
+// When verify failed, transfer again at most --verify-retry times.
+func (cc *CopyCommand) transferWithVerify(transfer func() error, verify func() error) error {
+	err := transfer()
+	for i := int64(0); err == nil && cc.cpOption.verify; i++ {
+		if err = verify(); err == nil || i >= cc.cpOption.verifyRetry {
+			break
+		}
+		err = transfer()
+	}
+	return err
+}
+
+func (cc *CopyCommand) verifyUpload(bucket *oss.Bucket, objectName, filePath string) error {
+	dsize, dcrc, err := cc.getObjectVerifyInfo(bucket, objectName)
+	if err != nil {
+		return err
+	}
+	ssize, scrc, err := cc.getFileVerifyInfo(filePath, dcrc != "")
+	if err != nil {
+		return err
+	}
+	return cc.checkVerifyInfo(filePath, CloudURLToString(bucket.BucketName, objectName), ssize, dsize, scrc, dcrc)
+}
+
+func (cc *CopyCommand) verifyDownload(bucket *oss.Bucket, object, fileName string, rsize int64) error {
+	if cc.cpOption.vrange != "" {
+		// only part of object is downloaded, check size only
+		dsize, _, err := cc.getFileVerifyInfo(fileName, false)
+		if err != nil {
+			return err
+		}
+		return cc.checkVerifyInfo(CloudURLToString(bucket.BucketName, object), fileName, rsize, dsize, "", "")
+	}
+
+	ssize, scrc, err := cc.getObjectVerifyInfo(bucket, object)
+	if err != nil {
+		return err
+	}
+	dsize, dcrc, err := cc.getFileVerifyInfo(fileName, scrc != "")
+	if err != nil {
+		return err
+	}
+	return cc.checkVerifyInfo(CloudURLToString(bucket.BucketName, object), fileName, ssize, dsize, scrc, dcrc)
+}
+
+func (cc *CopyCommand) verifyCopy(bucket *oss.Bucket, srcObject string, destURL CloudURL, destObject string) error {
+	destBucket, err := cc.cpOption.destCommand.ossBucket(destURL.bucket)
+	if err != nil {
+		return err
+	}
+	ssize, scrc, err := cc.getObjectVerifyInfo(bucket, srcObject)
+	if err != nil {
+		return err
+	}
+	dsize, dcrc, err := cc.getObjectVerifyInfo(destBucket, destObject)
+	if err != nil {
+		return err
+	}
+	return cc.checkVerifyInfo(CloudURLToString(bucket.BucketName, srcObject), CloudURLToString(destURL.bucket, destObject), ssize, dsize, scrc, dcrc)
+}
+
+func (cc *CopyCommand) getObjectVerifyInfo(bucket *oss.Bucket, object string) (int64, string, error) {
+	props, err := cc.command.ossGetObjectStatRetry(bucket, object)
+	if err != nil {
+		return 0, "", err
+	}
+	size, err := strconv.ParseInt(props.Get(oss.HTTPHeaderContentLength), 10, 64)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, props.Get(oss.HTTPHeaderOssCRC64), nil
+}
+
+func (cc *CopyCommand) getFileVerifyInfo(filePath string, withCRC bool) (int64, string, error) {
+	f, err := os.Stat(filePath)
+	if err != nil {
+		return 0, "", err
+	}
+	if !withCRC {
+		return f.Size(), "", nil
+	}
+	crc, err := cc.cpOption.crcCache.computeCRC64(filePath)
+	if err != nil {
+		return 0, "", err
+	}
+	return f.Size(), strconv.FormatUint(crc, 10), nil
+}
+
+// checkVerifyInfo compares size and crc64 of source and destination, crc64 is compared only when both exist
+func (cc *CopyCommand) checkVerifyInfo(src, dest string, ssize, dsize int64, scrc, dcrc string) error {
+	if ssize != dsize {
+		return VerifyError{src, dest, fmt.Sprintf("size of source is %d, size of destination is %d", ssize, dsize)}
+	}
+	if scrc != "" && dcrc != "" && scrc != dcrc {
+		return VerifyError{src, dest, fmt.Sprintf("crc64 of source is %s, crc64 of destination is %s", scrc, dcrc)}
+	}
+	return nil
+}
+
+func (cc *CopyCommand) formatSnapshotKey(src, dest string) string {
+	return src + SnapshotConnector + dest
+}
+
+// skipSnapshot shows if the snapshot records the same value as the source now has
+func (cc *CopyCommand) skipSnapshot(spath, value string) bool {
+	if cc.cpOption.snapshotPath == "" || value == "" {
+		return false
+	}
+	val, err := cc.cpOption.snapshotldb.Get([]byte(spath), nil)
+	return err == nil && string(val) == value
 }
 
 func (cc *CopyCommand) confirm(str string) bool {
@@ -1511,7 +2320,7 @@ func (cc *CopyCommand) confirm(str string) bool {
 
 	var val string
 	fmt.Printf(getClearStr(fmt.Sprintf("cp: overwrite \"%s\"(y or N)? ", str)))
-	if _, err := fmt.Scanln(&val); err != nil || (strings.ToLower(val) != "yes" && strings.ToLower(val) != "y") {
+	if _, err := scanln(&val); err != nil || (strings.ToLower(val) != "yes" && strings.ToLower(val) != "y") {
 		return false
 	}
 	return true
@@ -1527,6 +2336,7 @@ func (cc *CopyCommand) ossPutObjectRetry(bucket *oss.Bucket, objectName string,
 		if int64(i) >= retryTimes {
 			return ObjectError{err, bucket.BucketName, objectName}
 		}
+		cc.command.metrics.retry()
 	}
 }
 
@@ -1540,11 +2350,12 @@ func (cc *CopyCommand) ossUploadFileRetry(bucket *oss.Bucket, objectName string,
 		if int64(i) >= retryTimes {
 			return FileError{err, filePath}
 		}
+		cc.command.metrics.retry()
 	}
 }
 
 func (cc *CopyCommand) preparePartOption(fileSize int64) (int64, int) {
-	partSize, _ := GetInt(OptionPartSize, cc.command.options)
+	partSize := cc.cpOption.partSize
 	var partNum int64
 	if partSize < MinPartSize {
 		partSize, partNum = cc.calcPartSize(fileSize)
@@ -1552,8 +2363,8 @@ func (cc *CopyCommand) preparePartOption(fileSize int64) (int64, int) {
 		partNum = (fileSize-1)/partSize + 1
 	}
 
-	if parallel, err := GetInt(OptionParallel, cc.command.options); err == nil {
-		return partSize, int(parallel)
+	if cc.cpOption.parallel > 0 {
+		return partSize, int(cc.cpOption.parallel)
 	}
 
 	var rt int
@@ -1608,14 +2419,25 @@ func (cc *CopyCommand) ossResumeUploadRetry(bucket *oss.Bucket, objectName strin
 		if int64(i) >= retryTimes {
 			return FileError{err, filePath}
 		}
+		cc.command.metrics.retry()
 	}
 }
 
-func (cc *CopyCommand) report(msg string, err error) {
-	if cc.filterError(err) {
-		cc.cpOption.reporter.ReportError(fmt.Sprintf("%s error, info: %s", msg, err.Error()))
-		cc.cpOption.reporter.Prompt(err)
+// transferHook runs the hooks of a file transferred, the skipped files have no hooks. The size, etag
+// and crc64 of the object are got after success, which is the destination of upload and copy, or the
+// source of download.
+func (cc *CopyCommand) transferHook(skip bool, err error, item hookItem, bucket *oss.Bucket, object string) {
+	if cc.cpOption.hooks == nil || skip {
+		return
+	}
+	if err == nil && bucket != nil {
+		if props, serr := cc.command.ossGetObjectStatRetry(bucket, object); serr == nil {
+			item.Size, _ = strconv.ParseInt(props.Get(oss.HTTPHeaderContentLength), 10, 64)
+			item.ETag = strings.Trim(props.Get(oss.HTTPHeaderEtag), "\"")
+			item.CRC64 = props.Get(oss.HTTPHeaderOssCRC64)
+		}
 	}
+	cc.cpOption.hooks.done(&cc.cpOption.batchOptionType, item, err)
 }
 
 func (cc *CopyCommand) updateMonitor(skip bool, err error, isDir bool, size int64) {
@@ -1628,35 +2450,7 @@ func (cc *CopyCommand) updateMonitor(skip bool, err error, isDir bool, size int6
 	} else {
 		cc.monitor.updateFile(size, 1)
 	}
-	freshProgress()
-}
-
-func (cc *CopyCommand) filterError(err error) bool {
-	if err == nil {
-		return false
-	}
-
-	switch err.(type) {
-	case FileError:
-		err = err.(FileError).err
-	case ObjectError:
-		err = err.(ObjectError).err
-	case BucketError:
-		err = err.(BucketError).err
-	}
-
-	switch err.(type) {
-	case oss.ServiceError:
-		code := err.(oss.ServiceError).Code
-		if code == "NoSuchBucket" || code == "InvalidAccessKeyId" || code == "SignatureDoesNotMatch" || code == "AccessDenied" || code == "RequestTimeTooSkewed" || code == "InvalidBucketName" {
-			cc.cpOption.ctnu = false
-			return false
-		}
-	case CopyError:
-		cc.cpOption.ctnu = false
-		return false
-	}
-	return true
+	cc.freshProgress()
 }
 
 //function for download files
@@ -1677,19 +2471,16 @@ func (cc *CopyCommand) downloadFiles(srcURL CloudURL, destURL FileURL) error {
 		}
 
 		go cc.objectStatistic(bucket, srcURL)
-		err := cc.downloadSingleFileWithReport(bucket, objectInfoType{srcURL.object, -1, time.Now()}, filePath)
-		return cc.formatResultPrompt(err)
+		item := batchItem{srcURL.object, objectInfoType{srcURL.object, -1, time.Now(), "", ""}}
+		return cc.downloadObjects(bucket, sliceSource(item), filePath)
 	}
 	return cc.batchDownloadFiles(bucket, srcURL, filePath)
 }
 
-func (cc *CopyCommand) formatResultPrompt(err error) error {
+// finishProgress stops the progress bar and shows the finish bar
+func (cc *CopyCommand) finishProgress(exitStat int) {
 	cc.closeProgress()
-	fmt.Printf(cc.monitor.progressBar(true, normalExit))
-	if err != nil && cc.cpOption.ctnu {
-		return nil
-	}
-	return err
+	cc.command.printf(cc.monitor.progressBar(true, exitStat))
 }
 
 func (cc *CopyCommand) adjustDestURLForDownload(destURL FileURL) (string, error) {
@@ -1713,11 +2504,13 @@ func (cc *CopyCommand) adjustDestURLForDownload(destURL FileURL) (string, error)
 	return filePath, nil
 }
 
-func (cc *CopyCommand) downloadSingleFileWithReport(bucket *oss.Bucket, objectInfo objectInfoType, filePath string) error {
+// downloadItem is the batch action of download
+func (cc *CopyCommand) downloadItem(bucket *oss.Bucket, objectInfo objectInfoType, filePath string) (string, error) {
 	skip, err, size, msg := cc.downloadSingleFile(bucket, objectInfo, filePath)
 	cc.updateMonitor(skip, err, false, size)
-	cc.report(msg, err)
-	return err
+	item := hookItem{Source: CloudURLToString(bucket.BucketName, objectInfo.key), Destination: cc.makeFileName(objectInfo.key, filePath)}
+	cc.transferHook(skip, err, item, bucket, objectInfo.key)
+	return msg, err
 }
 
 func (cc *CopyCommand) downloadSingleFile(bucket *oss.Bucket, objectInfo objectInfoType, filePath string) (bool, error, int64, string) {
@@ -1728,6 +2521,8 @@ func (cc *CopyCommand) downloadSingleFile(bucket *oss.Bucket, objectInfo objectI
 	object := objectInfo.key
 	size := objectInfo.size
 	srct := objectInfo.lastModified
+	etag := objectInfo.etag
+	objectType := objectInfo.objectType
 
 	msg := fmt.Sprintf("%s %s to %s", opDownload, CloudURLToString(bucket.BucketName, object), fileName)
 
@@ -1743,15 +2538,38 @@ func (cc *CopyCommand) downloadSingleFile(bucket *oss.Bucket, objectInfo objectI
 		if srct, err = time.Parse(http.TimeFormat, props.Get(oss.HTTPHeaderLastModified)); err != nil {
 			return false, err, size, msg
 		}
+		etag = props.Get(oss.HTTPHeaderEtag)
+		objectType = props.Get(OssObjectTypeHeader)
 	}
 
 	rsize := cc.getRangeSize(size)
-	if cc.skipDownload(fileName, srct) {
+	if cc.cpOption.journal.isDone(object) {
 		return true, nil, rsize, msg
 	}
 
+	absPath, _ := filepath.Abs(fileName)
+	spath := cc.formatSnapshotKey(CloudURLToString(bucket.BucketName, object), absPath)
+	sval := cc.formatDownloadSnapshotValue(etag, srct)
+	if cc.skipSnapshot(spath, sval) {
+		if _, err := os.Stat(fileName); err == nil {
+			return true, nil, rsize, msg
+		}
+	}
+
+	if cc.skipDownload(bucket, object, fileName, rsize, srct) {
+		return true, nil, rsize, msg
+	}
+
+	if cc.cpOption.symlinks == SymlinkPreserve && objectType == SymlinkObjectType {
+		return false, cc.downloadSymlink(bucket, object, fileName), rsize, msg
+	}
+
 	if size == 0 && (strings.HasSuffix(object, "/") || strings.HasSuffix(object, "\\")) {
-		return false, os.MkdirAll(fileName, 0755), rsize, msg
+		err := os.MkdirAll(fileName, 0755)
+		if serr := cc.updateSnapshotValue(err, spath, sval); serr != nil {
+			err = serr
+		}
+		return false, err, rsize, msg
 	}
 
 	//create parent directory
@@ -1759,21 +2577,93 @@ func (cc *CopyCommand) downloadSingleFile(bucket *oss.Bucket, objectInfo objectI
 		return false, err, rsize, msg
 	}
 
-	var listener *OssProgressListener = &OssProgressListener{&cc.monitor, 0, 0}
+	var listener *OssProgressListener = &OssProgressListener{cc, 0, 0}
 	ossOptions := []oss.Option{oss.Progress(listener)}
 	if cc.cpOption.vrange != "" {
 		ossOptions = append(ossOptions, oss.NormalizedRange(cc.cpOption.vrange))
 	}
 
+	verify := func() error {
+		return cc.verifyDownload(bucket, object, fileName, rsize)
+	}
 	if rsize < cc.cpOption.threshold {
-		return false, cc.ossDownloadFileRetry(bucket, object, fileName, ossOptions...), 0, msg
+		err := cc.transferWithVerify(func() error {
+			if err := cc.ossDownloadFileRetry(bucket, object, fileName, ossOptions...); err != nil {
+				return err
+			}
+			return cc.setFileAttributes(bucket, object, fileName, srct)
+		}, verify)
+		if serr := cc.updateSnapshotValue(err, spath, sval); serr != nil {
+			err = serr
+		}
+		return false, err, 0, msg
 	}
 
 	partSize, rt := cc.preparePartOption(size)
-	absPath, _ := filepath.Abs(fileName)
 	cp := oss.Checkpoint(true, cc.formatCPFileName(cc.cpOption.cpDir, CloudURLToString(bucket.BucketName, object), absPath))
 	ossOptions = append(ossOptions, oss.Routines(rt), cp)
-	return false, cc.ossResumeDownloadRetry(bucket, object, fileName, size, partSize, ossOptions...), 0, msg
+	err := cc.transferWithVerify(func() error {
+		if err := cc.ossResumeDownloadRetry(bucket, object, fileName, size, partSize, ossOptions...); err != nil {
+			return err
+		}
+		return cc.setFileAttributes(bucket, object, fileName, srct)
+	}, verify)
+	if serr := cc.updateSnapshotValue(err, spath, sval); serr != nil {
+		err = serr
+	}
+	return false, err, 0, msg
+}
+
+// downloadSymlink creates local symlink for symlink object, the link points to the
+// local path of target object relative to the symlink
+func (cc *CopyCommand) downloadSymlink(bucket *oss.Bucket, object, fileName string) error {
+	props, err := cc.command.ossGetSymlinkRetry(bucket, object)
+	if err != nil {
+		return err
+	}
+	targetObject := props.Get(oss.HTTPHeaderOssSymlinkTarget)
+	target, err := filepath.Rel(filepath.FromSlash(path.Dir(object)), filepath.FromSlash(targetObject))
+	if err != nil {
+		return FileError{err, fileName}
+	}
+
+	if err := cc.createParentDirectory(fileName); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(fileName); err == nil {
+		if err := os.Remove(fileName); err != nil {
+			return FileError{err, fileName}
+		}
+	}
+	if err := os.Symlink(target, fileName); err != nil {
+		return FileError{err, fileName}
+	}
+	return nil
+}
+
+// setFileAttributes sets mtime of downloaded file to last modified time of object,
+// and restores the attributes recorded in meta of object with --preserve
+func (cc *CopyCommand) setFileAttributes(bucket *oss.Bucket, object, fileName string, srct time.Time) error {
+	var props http.Header
+	if cc.cpOption.preserve {
+		var err error
+		if props, err = cc.command.ossGetObjectStatRetry(bucket, object); err != nil {
+			return err
+		}
+	}
+	if err := restoreFileAttributes(fileName, props, srct); err != nil {
+		return FileError{err, fileName}
+	}
+	return nil
+}
+
+// formatDownloadSnapshotValue records etag and last modified time of object, and the range downloaded
+func (cc *CopyCommand) formatDownloadSnapshotValue(etag string, srct time.Time) string {
+	value := etag + SnapshotSep + strconv.FormatInt(srct.Unix(), 10)
+	if cc.cpOption.vrange != "" {
+		value += SnapshotSep + cc.cpOption.vrange
+	}
+	return value
 }
 
 func (cc *CopyCommand) makeFileName(object, filePath string) string {
@@ -1783,20 +2673,39 @@ func (cc *CopyCommand) makeFileName(object, filePath string) string {
 	return filePath
 }
 
-func (cc *CopyCommand) skipDownload(fileName string, srct time.Time) bool {
+func (cc *CopyCommand) skipDownload(bucket *oss.Bucket, object, fileName string, size int64, srct time.Time) bool {
 	if cc.cpOption.update {
 		if f, err := os.Stat(fileName); err == nil {
+			if cc.cpOption.checksum || cc.cpOption.sizeOnly {
+				if f.Size() != size {
+					return false
+				}
+				if cc.cpOption.sizeOnly {
+					return true
+				}
+				props, err := cc.command.ossGetObjectStatRetry(bucket, object)
+				return err == nil && cc.isSameLocalCRC64(props, fileName)
+			}
 			destt := f.ModTime()
 			if destt.Unix() >= srct.Unix() {
 				return true
 			}
-		}
-	} else {
-		if !cc.cpOption.force {
-			if _, err := os.Stat(fileName); err == nil {
-				if !cc.confirm(fileName) {
-					return true
+			// with --preserve, mtime of downloaded file is the mtime recorded in meta
+			// of object, which is earlier than last modified time of object
+			if cc.cpOption.preserve {
+				props, err := cc.command.ossGetObjectStatRetry(bucket, object)
+				if err != nil {
+					return false
 				}
+				if mtime, ok := getPreserveTime(props, preserveMetaMtime); ok {
+					return destt.Unix() >= mtime.Unix()
+				}
+			}
+		}
+	} else if !cc.cpOption.force {
+		if _, err := os.Stat(fileName); err == nil {
+			if !cc.confirm(fileName) {
+				return true
 			}
 		}
 	}
@@ -1812,7 +2721,41 @@ func (cc *CopyCommand) createParentDirectory(fileName string) error {
 	return os.MkdirAll(dir, 0755)
 }
 
+// downloadingFiles are the files being downloaded without checkpoint, their temp files are
+// removed when the process is aborted
+var downloadingFiles = struct {
+	sync.Mutex
+	files map[string]int
+}{files: map[string]int{}}
+
+func addDownloadingFile(fileName string) {
+	downloadingFiles.Lock()
+	downloadingFiles.files[fileName]++
+	downloadingFiles.Unlock()
+}
+
+func doneDownloadingFile(fileName string) {
+	downloadingFiles.Lock()
+	if downloadingFiles.files[fileName]--; downloadingFiles.files[fileName] <= 0 {
+		delete(downloadingFiles.files, fileName)
+	}
+	downloadingFiles.Unlock()
+}
+
+// removeDownloadingFiles removes the partial temp files, the temp files of resumable downloads
+// are kept with their checkpoints
+func removeDownloadingFiles() {
+	downloadingFiles.Lock()
+	defer downloadingFiles.Unlock()
+	for fileName := range downloadingFiles.files {
+		os.Remove(fileName + oss.TempFileSuffix)
+	}
+}
+
 func (cc *CopyCommand) ossDownloadFileRetry(bucket *oss.Bucket, objectName, fileName string, options ...oss.Option) error {
+	addDownloadingFile(fileName)
+	defer doneDownloadingFile(fileName)
+
 	retryTimes, _ := GetInt(OptionRetryTimes, cc.command.options)
 	for i := 1; ; i++ {
 		err := bucket.GetObjectToFile(objectName, fileName, options...)
@@ -1822,6 +2765,7 @@ func (cc *CopyCommand) ossDownloadFileRetry(bucket *oss.Bucket, objectName, file
 		if int64(i) >= retryTimes {
 			return ObjectError{err, bucket.BucketName, objectName}
 		}
+		cc.command.metrics.retry()
 	}
 }
 
@@ -1835,6 +2779,7 @@ func (cc *CopyCommand) ossResumeDownloadRetry(bucket *oss.Bucket, objectName str
 		if int64(i) >= retryTimes {
 			return ObjectError{err, bucket.BucketName, objectName}
 		}
+		cc.command.metrics.retry()
 	}
 }
 
@@ -1850,9 +2795,12 @@ func (cc *CopyCommand) truncateFile(filePath string, size int64) error {
 }
 
 func (cc *CopyCommand) updateSnapshot(err error, spath string, srct int64) error {
+	return cc.updateSnapshotValue(err, spath, fmt.Sprintf("%d", srct))
+}
+
+func (cc *CopyCommand) updateSnapshotValue(err error, spath, value string) error {
 	if cc.cpOption.snapshotPath != "" && err == nil {
-		srctstr := fmt.Sprintf("%d", srct)
-		err := cc.cpOption.snapshotldb.Put([]byte(spath), []byte(srctstr), nil)
+		err := cc.cpOption.snapshotldb.Put([]byte(spath), []byte(value), nil)
 		if err != nil {
 			return fmt.Errorf("dump snapshot error: %s", err.Error())
 		}
@@ -1861,22 +2809,22 @@ func (cc *CopyCommand) updateSnapshot(err error, spath string, srct int64) error
 }
 
 func (cc *CopyCommand) batchDownloadFiles(bucket *oss.Bucket, srcURL CloudURL, filePath string) error {
-	chObjects := make(chan objectInfoType, ChannelBuf)
-	chError := make(chan error, cc.cpOption.routines)
-	chListError := make(chan error, 1)
 	go cc.objectStatistic(bucket, srcURL)
-	go cc.objectProducer(bucket, srcURL, chObjects, chListError)
-	for i := 0; int64(i) < cc.cpOption.routines; i++ {
-		go cc.downloadConsumer(bucket, filePath, chObjects, chError)
-	}
+	return cc.downloadObjects(bucket, cc.objectSource(bucket, srcURL), filePath)
+}
 
-	return cc.waitRoutinueComplete(chError, chListError, opDownload)
+func (cc *CopyCommand) downloadObjects(bucket *oss.Bucket, source batchSource, filePath string) error {
+	engine := cc.command.newBatchEngine(&cc.cpOption.batchOptionType, cc.cpOption.routines, source, func(item batchItem) (string, error) {
+		return cc.downloadItem(bucket, item.value.(objectInfoType), filePath)
+	})
+	engine.finish = cc.finishProgress
+	return engine.run()
 }
 
 func (cc *CopyCommand) objectStatistic(bucket *oss.Bucket, cloudURL CloudURL) {
 	if cc.cpOption.recursive {
 		pre := oss.Prefix(cloudURL.object)
-		marker := oss.Marker("")
+		marker := oss.Marker(cc.cpOption.journal.marker())
 		for {
 			lor, err := cc.command.ossListObjectsRetry(bucket, marker, pre)
 			if err != nil {
@@ -1909,7 +2857,7 @@ func (cc *CopyCommand) objectStatistic(bucket *oss.Bucket, cloudURL CloudURL) {
 	}
 
 	cc.monitor.setScanEnd()
-	freshProgress()
+	cc.freshProgress()
 }
 
 func (cc *CopyCommand) getRangeSize(size int64) int64 {
@@ -1963,69 +2911,93 @@ func (cc *CopyCommand) parseRange(str string, size int64) (int64, error) {
 	return size, nil
 }
 
-func (cc *CopyCommand) objectProducer(bucket *oss.Bucket, cloudURL CloudURL, chObjects chan<- objectInfoType, chError chan<- error) {
-	pre := oss.Prefix(cloudURL.object)
-	marker := oss.Marker("")
-	for {
-		lor, err := cc.command.ossListObjectsRetry(bucket, marker, pre)
-		if err != nil {
-			chError <- err
-			break
-		}
-
-		for _, object := range lor.Objects {
-			chObjects <- objectInfoType{object.Key, int64(object.Size), object.LastModified}
-		}
+// objectSource lists the source objects from the marker of journal
+func (cc *CopyCommand) objectSource(bucket *oss.Bucket, cloudURL CloudURL) batchSource {
+	return cc.command.objectSource(bucket, cloudURL, cc.cpOption.journal, func(object oss.ObjectProperties) (batchItem, bool) {
+		return batchItem{object.Key, objectInfoType{object.Key, int64(object.Size), object.LastModified, object.ETag, object.Type}}, true
+	})
+}
 
-		pre = oss.Prefix(lor.Prefix)
-		marker = oss.Marker(lor.NextMarker)
-		if !lor.IsTruncated {
-			break
-		}
+// restoreArchiveObjects restores the archive objects of source, and waits until all of them
+// are readable, so that they can be downloaded or copied
+func (cc *CopyCommand) restoreArchiveObjects(srcURL CloudURL) error {
+	bucket, err := cc.command.ossBucket(srcURL.bucket)
+	if err != nil {
+		return err
 	}
-	defer close(chObjects)
-	chError <- nil
-}
 
-func (cc *CopyCommand) downloadConsumer(bucket *oss.Bucket, filePath string, chObjects <-chan objectInfoType, chError chan<- error) {
-	for objectInfo := range chObjects {
-		err := cc.downloadSingleFileWithReport(bucket, objectInfo, filePath)
+	objects := []string{}
+	if !cc.cpOption.recursive {
+		props, err := cc.command.ossGetObjectStatRetry(bucket, srcURL.object)
 		if err != nil {
-			chError <- err
-			if !cc.cpOption.ctnu {
-				return
-			}
-			continue
+			return err
 		}
-	}
-
-	chError <- nil
-}
-
-func (cc *CopyCommand) waitRoutinueComplete(chError, chListError <-chan error, opStr string) error {
-	completed := 0
-	var ferr error
-	for int64(completed) <= cc.cpOption.routines {
-		select {
-		case err := <-chListError:
+		if props.Get(OssStorageClassHeader) == StorageArchive {
+			objects = append(objects, srcURL.object)
+		}
+	} else {
+		pre := oss.Prefix(srcURL.object)
+		marker := oss.Marker("")
+		for {
+			lor, err := cc.command.ossListObjectsRetry(bucket, marker, pre)
 			if err != nil {
 				return err
 			}
-			completed++
-		case err := <-chError:
-			if err == nil {
-				completed++
-			} else {
-				ferr = err
-				if !cc.cpOption.ctnu {
-					cc.closeProgress()
-					fmt.Printf(cc.monitor.progressBar(true, errExit))
-					return err
+			for _, object := range lor.Objects {
+				if object.StorageClass == StorageArchive {
+					objects = append(objects, object.Key)
 				}
 			}
+			marker = oss.Marker(lor.NextMarker)
+			if !lor.IsTruncated {
+				break
+			}
 		}
 	}
-	return cc.formatResultPrompt(ferr)
+	if len(objects) == 0 {
+		return nil
+	}
+
+	cc.command.printf("restore %d %s objects.\n", len(objects), StorageArchive)
+	chObjects := make(chan string, len(objects))
+	for _, object := range objects {
+		chObjects <- object
+	}
+	close(chObjects)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for i := 0; int64(i) < cc.cpOption.routines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for object := range chObjects {
+				if rerr := cc.restoreFrozenObject(bucket, object); rerr != nil {
+					mu.Lock()
+					err = rerr
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	return cc.command.waitObjectsRestored(bucket, objects)
+}
+
+// restoreFrozenObject restores the object only if it's frozen, the objects being restored or
+// restored by the last run are left as they are, so that cp --auto-restore can be run again
+func (cc *CopyCommand) restoreFrozenObject(bucket *oss.Bucket, object string) error {
+	props, err := cc.command.ossGetObjectStatRetry(bucket, object)
+	if err != nil {
+		return err
+	}
+	if status, _ := getRestoreStatus(props); status != RestoreStatusFrozen {
+		return nil
+	}
+	return cc.command.ossRestoreObjectRetry(bucket, object, 0, "")
 }
 
 //function for copy objects
@@ -2039,14 +3011,18 @@ func (cc *CopyCommand) copyFiles(srcURL, destURL CloudURL) error {
 		return err
 	}
 
+	if cc.cpOption.streamCopy, err = cc.needStreamCopy(srcURL.bucket, destURL.bucket); err != nil {
+		return err
+	}
+
 	if !cc.cpOption.recursive {
 		if srcURL.object == "" {
 			return fmt.Errorf("copy object invalid url: %s, object empty. If you mean batch copy objects, please use --recursive option", srcURL.ToString())
 		}
 
 		go cc.objectStatistic(bucket, srcURL)
-		err := cc.copySingleFileWithReport(bucket, objectInfoType{srcURL.object, -1, time.Now()}, srcURL, destURL)
-		return cc.formatResultPrompt(err)
+		item := batchItem{srcURL.object, objectInfoType{srcURL.object, -1, time.Now(), "", ""}}
+		return cc.copyObjects(bucket, sliceSource(item), srcURL, destURL)
 	}
 	return cc.batchCopyFiles(bucket, srcURL, destURL)
 }
@@ -2074,11 +3050,17 @@ func (cc *CopyCommand) checkCopyFileArgs(srcURL, destURL CloudURL) error {
 	return nil
 }
 
-func (cc *CopyCommand) copySingleFileWithReport(bucket *oss.Bucket, objectInfo objectInfoType, srcURL, destURL CloudURL) error {
+// copyItem is the batch action of copy
+func (cc *CopyCommand) copyItem(bucket *oss.Bucket, objectInfo objectInfoType, srcURL, destURL CloudURL) (string, error) {
 	skip, err, size, msg := cc.copySingleFile(bucket, objectInfo, srcURL, destURL)
 	cc.updateMonitor(skip, err, false, size)
-	cc.report(msg, err)
-	return err
+	if cc.cpOption.hooks != nil && !skip {
+		destObject := cc.makeCopyObjectName(objectInfo.key, srcURL.object, destURL)
+		item := hookItem{Source: CloudURLToString(srcURL.bucket, objectInfo.key), Destination: CloudURLToString(destURL.bucket, destObject)}
+		destBucket, _ := cc.cpOption.destCommand.ossBucket(destURL.bucket)
+		cc.transferHook(skip, err, item, destBucket, destObject)
+	}
+	return msg, err
 }
 
 func (cc *CopyCommand) copySingleFile(bucket *oss.Bucket, objectInfo objectInfoType, srcURL, destURL CloudURL) (bool, error, int64, string) {
@@ -2087,6 +3069,7 @@ func (cc *CopyCommand) copySingleFile(bucket *oss.Bucket, objectInfo objectInfoT
 	destObject := cc.makeCopyObjectName(objectInfo.key, srcURL.object, destURL)
 	size := objectInfo.size
 	srct := objectInfo.lastModified
+	etag := objectInfo.etag
 
 	msg := fmt.Sprintf("%s %s to %s", opCopy, CloudURLToString(srcURL.bucket, srcObject), CloudURLToString(destURL.bucket, destObject))
 
@@ -2111,20 +3094,56 @@ func (cc *CopyCommand) copySingleFile(bucket *oss.Bucket, objectInfo objectInfoT
 		if srct, err = time.Parse(http.TimeFormat, props.Get(oss.HTTPHeaderLastModified)); err != nil {
 			return false, err, size, msg
 		}
+		etag = props.Get(oss.HTTPHeaderEtag)
+	}
+
+	if cc.cpOption.journal.isDone(srcObject) {
+		return true, nil, size, msg
+	}
+
+	spath := cc.formatSnapshotKey(CloudURLToString(srcURL.bucket, srcObject), CloudURLToString(destURL.bucket, destObject))
+	if cc.skipSnapshot(spath, etag) {
+		return true, nil, size, msg
 	}
 
-	if skip, err := cc.skipCopy(destURL, destObject, srct); err != nil || skip {
+	if skip, err := cc.skipCopy(bucket, srcObject, destURL, destObject, size, srct); err != nil || skip {
 		return skip, err, size, msg
 	}
 
+	verify := func() error {
+		return cc.verifyCopy(bucket, srcObject, destURL, destObject)
+	}
 	if size < cc.cpOption.threshold {
-		return false, cc.ossCopyObjectRetry(bucket, srcObject, destURL.bucket, destObject), size, msg
+		err := cc.transferWithVerify(func() error {
+			if cc.cpOption.streamCopy {
+				return cc.ossStreamObjectRetry(ossStreamSource{&cc.command, bucket}, srcObject, destURL.bucket, destObject)
+			}
+			return cc.command.ossCopyObjectRetry(bucket, srcObject, destURL.bucket, destObject)
+		}, verify)
+		if serr := cc.updateSnapshotValue(err, spath, etag); serr != nil {
+			err = serr
+		}
+		return false, err, size, msg
 	}
 
-	var listener *OssProgressListener = &OssProgressListener{&cc.monitor, 0, 0}
+	var listener *OssProgressListener = &OssProgressListener{cc, 0, 0}
 	partSize, rt := cc.preparePartOption(size)
-	cp := oss.Checkpoint(true, cc.formatCPFileName(cc.cpOption.cpDir, CloudURLToString(srcURL.bucket, srcObject), CloudURLToString(destURL.bucket, destObject)))
-	return false, cc.ossResumeCopyRetry(srcURL.bucket, srcObject, destURL.bucket, destObject, partSize, oss.Routines(rt), cp, oss.Progress(listener)), 0, msg
+	cpDir := cc.formatCPFileName(cc.cpOption.cpDir, CloudURLToString(srcURL.bucket, srcObject), CloudURLToString(destURL.bucket, destObject))
+	cp := oss.Checkpoint(true, cpDir)
+	err := cc.transferWithVerify(func() error {
+		if cc.cpOption.streamCopy {
+			return cc.ossStreamCopyRetry(ossStreamSource{&cc.command, bucket}, srcObject, destURL.bucket, destObject, partSize, rt, cpDir)
+		}
+		destBucket, err := cc.cpOption.destCommand.ossBucket(destURL.bucket)
+		if err != nil {
+			return err
+		}
+		return cc.command.ossResumeCopyRetry(destBucket, srcURL.bucket, srcObject, destObject, partSize, oss.Routines(rt), cp, oss.Progress(listener))
+	}, verify)
+	if serr := cc.updateSnapshotValue(err, spath, etag); serr != nil {
+		err = serr
+	}
+	return false, err, 0, msg
 }
 
 func (cc *CopyCommand) makeCopyObjectName(srcObject, srcPrefix string, destURL CloudURL) string {
@@ -2143,85 +3162,49 @@ func (cc *CopyCommand) makeCopyObjectName(srcObject, srcPrefix string, destURL C
 	return destURL.object + srcObject[len(srcPrefix):]
 }
 
-func (cc *CopyCommand) skipCopy(destURL CloudURL, destObject string, srct time.Time) (bool, error) {
-	destBucket, err := cc.command.ossBucket(destURL.bucket)
+func (cc *CopyCommand) skipCopy(bucket *oss.Bucket, srcObject string, destURL CloudURL, destObject string, size int64, srct time.Time) (bool, error) {
+	destBucket, err := cc.cpOption.destCommand.ossBucket(destURL.bucket)
 	if err != nil {
 		return false, err
 	}
 
 	if cc.cpOption.update {
 		if props, err := cc.command.ossGetObjectStatRetry(destBucket, destObject); err == nil {
+			if cc.cpOption.checksum || cc.cpOption.sizeOnly {
+				if !cc.isSameSize(props, size) {
+					return false, nil
+				}
+				if cc.cpOption.sizeOnly {
+					return true, nil
+				}
+				dcrc := props.Get(oss.HTTPHeaderOssCRC64)
+				sprops, err := cc.command.ossGetObjectStatRetry(bucket, srcObject)
+				return err == nil && dcrc != "" && sprops.Get(oss.HTTPHeaderOssCRC64) == dcrc, nil
+			}
 			destt, err := time.Parse(http.TimeFormat, props.Get(oss.HTTPHeaderLastModified))
 			if err == nil && destt.Unix() >= srct.Unix() {
 				return true, nil
 			}
 		}
-	} else {
-		if !cc.cpOption.force {
-			if _, err := cc.command.ossGetObjectMetaRetry(destBucket, destObject); err == nil {
-				if !cc.confirm(CloudURLToString(destURL.bucket, destObject)) {
-					return true, nil
-				}
+	} else if !cc.cpOption.force {
+		if _, err := cc.command.ossGetObjectMetaRetry(destBucket, destObject); err == nil {
+			if !cc.confirm(CloudURLToString(destURL.bucket, destObject)) {
+				return true, nil
 			}
 		}
 	}
 	return false, nil
 }
 
-func (cc *CopyCommand) ossCopyObjectRetry(bucket *oss.Bucket, objectName, destBucketName, destObjectName string) error {
-	retryTimes, _ := GetInt(OptionRetryTimes, cc.command.options)
-	for i := 1; ; i++ {
-		_, err := bucket.CopyObjectTo(destBucketName, destObjectName, objectName)
-		if err == nil {
-			return err
-		}
-		if int64(i) >= retryTimes {
-			return ObjectError{err, bucket.BucketName, objectName}
-		}
-	}
-}
-
-func (cc *CopyCommand) ossResumeCopyRetry(bucketName, objectName, destBucketName, destObjectName string, partSize int64, options ...oss.Option) error {
-	bucket, err := cc.command.ossBucket(destBucketName)
-	if err != nil {
-		return err
-	}
-	retryTimes, _ := GetInt(OptionRetryTimes, cc.command.options)
-	for i := 1; ; i++ {
-		err := bucket.CopyFile(bucketName, objectName, destObjectName, partSize, options...)
-		if err == nil {
-			return err
-		}
-		if int64(i) >= retryTimes {
-			return ObjectError{err, bucket.BucketName, objectName}
-		}
-	}
-}
-
 func (cc *CopyCommand) batchCopyFiles(bucket *oss.Bucket, srcURL, destURL CloudURL) error {
-	chObjects := make(chan objectInfoType, ChannelBuf)
-	chError := make(chan error, cc.cpOption.routines)
-	chListError := make(chan error, 1)
 	go cc.objectStatistic(bucket, srcURL)
-	go cc.objectProducer(bucket, srcURL, chObjects, chListError)
-	for i := 0; int64(i) < cc.cpOption.routines; i++ {
-		go cc.copyConsumer(bucket, srcURL, destURL, chObjects, chError)
-	}
-
-	return cc.waitRoutinueComplete(chError, chListError, opDownload)
+	return cc.copyObjects(bucket, cc.objectSource(bucket, srcURL), srcURL, destURL)
 }
 
-func (cc *CopyCommand) copyConsumer(bucket *oss.Bucket, srcURL, destURL CloudURL, chObjects <-chan objectInfoType, chError chan<- error) {
-	for objectInfo := range chObjects {
-		err := cc.copySingleFileWithReport(bucket, objectInfo, srcURL, destURL)
-		if err != nil {
-			chError <- err
-			if !cc.cpOption.ctnu {
-				return
-			}
-			continue
-		}
-	}
-
-	chError <- nil
+func (cc *CopyCommand) copyObjects(bucket *oss.Bucket, source batchSource, srcURL, destURL CloudURL) error {
+	engine := cc.command.newBatchEngine(&cc.cpOption.batchOptionType, cc.cpOption.routines, source, func(item batchItem) (string, error) {
+		return cc.copyItem(bucket, item.value.(objectInfoType), srcURL, destURL)
+	})
+	engine.finish = cc.finishProgress
+	return engine.run()
 }
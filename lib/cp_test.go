@@ -1,6 +1,7 @@
 package lib
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"os"
@@ -647,6 +648,7 @@ func (s *OssutilCommandSuite) TestErrCopy(c *C) {
 }
 
 func (s *OssutilCommandSuite) TestPreparePartOption(c *C) {
+	copyCommand.loadOptions()
 	partSize, routines := copyCommand.preparePartOption(0)
 	c.Assert(partSize, Equals, int64(oss.MinPartSize))
 	c.Assert(routines, Equals, 1)
@@ -686,6 +688,7 @@ func (s *OssutilCommandSuite) TestPreparePartOption(c *C) {
 	p := 7
 	parallel := strconv.Itoa(p)
 	copyCommand.command.options[OptionParallel] = &parallel
+	copyCommand.loadOptions()
 	partSize, routines = copyCommand.preparePartOption(1)
 	c.Assert(routines, Equals, p)
 
@@ -695,6 +698,7 @@ func (s *OssutilCommandSuite) TestPreparePartOption(c *C) {
 	psStr := strconv.Itoa(ps)
 	copyCommand.command.options[OptionParallel] = &parallel
 	copyCommand.command.options[OptionPartSize] = &psStr
+	copyCommand.loadOptions()
 	partSize, routines = copyCommand.preparePartOption(1)
 	c.Assert(routines, Equals, p)
 	c.Assert(partSize, Equals, int64(ps))
@@ -1237,13 +1241,10 @@ func (s *OssutilCommandSuite) TestCopyFunction(c *C) {
 	c.Assert(copyCommand.monitor.seekAheadEnd, Equals, true)
 	c.Assert(copyCommand.monitor.seekAheadError, NotNil)
 
-	// test fileProducer
-	chFiles := make(chan fileInfoType, ChannelBuf)
-	chListError := make(chan error, 1)
+	// test fileSource
 	storageURL, err = StorageURLFromString("&~", "")
 	c.Assert(err, IsNil)
-	copyCommand.fileProducer([]StorageURLer{storageURL}, chFiles, chListError)
-	err = <-chListError
+	err = copyCommand.fileSource([]StorageURLer{storageURL})(func(batchItem) bool { return true })
 	c.Assert(err, NotNil)
 
 	// test put object error
@@ -1253,13 +1254,17 @@ func (s *OssutilCommandSuite) TestCopyFunction(c *C) {
 	err = copyCommand.ossPutObjectRetry(bucket, "object", "")
 	c.Assert(err, NotNil)
 
-	// test formatResultPrompt
+	// test the result of batch job which continues on error
 	testResultFile, _ = os.OpenFile(resultPath, os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0664)
 	out := os.Stdout
 	os.Stdout = testResultFile
-	err = fmt.Errorf("test error")
 	copyCommand.cpOption.ctnu = true
-	err = copyCommand.formatResultPrompt(err)
+	copyCommand.cpOption.reporter = nil
+	engine := copyCommand.command.newBatchEngine(&copyCommand.cpOption.batchOptionType, 1, sliceSource(batchItem{}), func(item batchItem) (string, error) {
+		return "test", fmt.Errorf("test error")
+	})
+	engine.finish = copyCommand.finishProgress
+	err = engine.run()
 	c.Assert(err, IsNil)
 	os.Stdout = out
 	str := strings.ToLower(s.readFile(resultPath, c))
@@ -1360,8 +1365,7 @@ func (s *OssutilCommandSuite) TestCPFunction(c *C) {
 	err = copyCommand.getFileListStatistic("notexistdir")
 	c.Assert(err, NotNil)
 
-	chFiles := make(chan fileInfoType, 100)
-	err = copyCommand.getFileList("notexistdir", chFiles)
+	err = copyCommand.getFileList("notexistdir", func(batchItem) bool { return true })
 
 	bucketName := bucketNamePrefix + randLowStr(10)
 	bucket, err := copyCommand.command.ossBucket(bucketName)
@@ -1373,3 +1377,349 @@ func (s *OssutilCommandSuite) TestCPFunction(c *C) {
 	_, err, _, _, _ = copyCommand.uploadFile(bucket, destURL, fileInfo)
 	c.Assert(err, NotNil)
 }
+
+func (s *OssutilCommandSuite) initCopyWithUpdateMode(srcURL, destURL string, update, checksum, sizeOnly bool) error {
+	err := s.initCopyCommand(srcURL, destURL, false, true, update, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	if err != nil {
+		return err
+	}
+	copyCommand.command.options[OptionChecksum] = &checksum
+	copyCommand.command.options[OptionSizeOnly] = &sizeOnly
+	return nil
+}
+
+func (s *OssutilCommandSuite) TestCPObjectChecksum(c *C) {
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+
+	data := "same data"
+	fileName := "checksumFile" + randStr(5)
+	s.createFile(fileName, data, c)
+	object := "testobject"
+	s.putObject(bucketName, object, fileName, c)
+
+	// touch local file without change
+	time.Sleep(2 * time.Second)
+	s.createFile(fileName, data, c)
+	err := s.initCopyWithUpdateMode(fileName, CloudURLToString(bucketName, object), true, true, false)
+	c.Assert(err, IsNil)
+	err = copyCommand.RunCommand()
+	c.Assert(err, IsNil)
+	c.Assert(copyCommand.monitor.skipNum, Equals, int64(1))
+
+	// download with checksum skips too
+	s.createFile(downloadFileName, data, c)
+	err = s.initCopyWithUpdateMode(CloudURLToString(bucketName, object), downloadFileName, true, true, false)
+	c.Assert(err, IsNil)
+	err = copyCommand.RunCommand()
+	c.Assert(err, IsNil)
+	c.Assert(copyCommand.monitor.skipNum, Equals, int64(1))
+
+	// same size and different content
+	newData := "diff data"
+	s.createFile(fileName, newData, c)
+	err = s.initCopyWithUpdateMode(fileName, CloudURLToString(bucketName, object), true, false, true)
+	c.Assert(err, IsNil)
+	err = copyCommand.RunCommand()
+	c.Assert(err, IsNil)
+	c.Assert(copyCommand.monitor.skipNum, Equals, int64(1))
+
+	err = s.initCopyWithUpdateMode(fileName, CloudURLToString(bucketName, object), true, true, false)
+	c.Assert(err, IsNil)
+	err = copyCommand.RunCommand()
+	c.Assert(err, IsNil)
+	c.Assert(copyCommand.monitor.skipNum, Equals, int64(0))
+	c.Assert(copyCommand.monitor.fileNum, Equals, int64(1))
+
+	s.getObject(bucketName, object, downloadFileName, c)
+	str := s.readFile(downloadFileName, c)
+	c.Assert(str, Equals, newData)
+
+	// invalid combinations
+	err = s.initCopyWithUpdateMode(fileName, CloudURLToString(bucketName, object), false, true, false)
+	c.Assert(err, IsNil)
+	err = copyCommand.RunCommand()
+	c.Assert(err, NotNil)
+
+	err = s.initCopyWithUpdateMode(fileName, CloudURLToString(bucketName, object), true, true, true)
+	c.Assert(err, IsNil)
+	err = copyCommand.RunCommand()
+	c.Assert(err, NotNil)
+
+	os.Remove(fileName)
+	s.removeBucket(bucketName, true, c)
+}
+
+func (s *OssutilCommandSuite) TestTransferWithVerify(c *C) {
+	transferNum := 0
+	transfer := func() error {
+		transferNum++
+		return nil
+	}
+	verify := func() error {
+		return copyCommand.checkVerifyInfo("src", "dest", 10, 9, "", "")
+	}
+
+	copyCommand.cpOption.verify = false
+	c.Assert(copyCommand.transferWithVerify(transfer, verify), IsNil)
+	c.Assert(transferNum, Equals, 1)
+
+	transferNum = 0
+	copyCommand.cpOption.verify = true
+	copyCommand.cpOption.verifyRetry = 2
+	err := copyCommand.transferWithVerify(transfer, verify)
+	c.Assert(err, NotNil)
+	_, ok := err.(VerifyError)
+	c.Assert(ok, Equals, true)
+	c.Assert(transferNum, Equals, 3)
+
+	c.Assert(copyCommand.checkVerifyInfo("src", "dest", 10, 10, "123", ""), IsNil)
+	c.Assert(copyCommand.checkVerifyInfo("src", "dest", 10, 10, "123", "123"), IsNil)
+	c.Assert(copyCommand.checkVerifyInfo("src", "dest", 10, 10, "123", "456"), NotNil)
+
+	copyCommand.cpOption.verify = false
+	copyCommand.cpOption.verifyRetry = 0
+}
+
+func (s *OssutilCommandSuite) TestCPObjectVerify(c *C) {
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+
+	data := randStr(100)
+	fileName := "verifyFile" + randStr(5)
+	s.createFile(fileName, data, c)
+	object := "testobject"
+	verify := true
+
+	// upload with verify
+	err := s.initCopyCommand(fileName, CloudURLToString(bucketName, object), false, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	copyCommand.command.options[OptionVerify] = &verify
+	err = copyCommand.RunCommand()
+	c.Assert(err, IsNil)
+	c.Assert(copyCommand.monitor.fileNum, Equals, int64(1))
+	c.Assert(copyCommand.monitor.errNum, Equals, int64(0))
+
+	// resume download with verify
+	err = s.initCopyCommand(CloudURLToString(bucketName, object), downloadFileName, false, true, false, 1, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	copyCommand.command.options[OptionVerify] = &verify
+	err = copyCommand.RunCommand()
+	c.Assert(err, IsNil)
+	c.Assert(copyCommand.monitor.fileNum, Equals, int64(1))
+	c.Assert(copyCommand.monitor.errNum, Equals, int64(0))
+	str := s.readFile(downloadFileName, c)
+	c.Assert(str, Equals, data)
+
+	// copy with verify
+	err = s.initCopyCommand(CloudURLToString(bucketName, object), CloudURLToString(bucketName, object+"copy"), false, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	copyCommand.command.options[OptionVerify] = &verify
+	err = copyCommand.RunCommand()
+	c.Assert(err, IsNil)
+	c.Assert(copyCommand.monitor.fileNum, Equals, int64(1))
+	c.Assert(copyCommand.monitor.errNum, Equals, int64(0))
+
+	os.Remove(fileName)
+	s.removeBucket(bucketName, true, c)
+}
+
+func (s *OssutilCommandSuite) TestCPObjectPreserve(c *C) {
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+
+	fileName := "preserveFile" + randStr(5)
+	s.createFile(fileName, randStr(20), c)
+	mtime := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	c.Assert(os.Chtimes(fileName, mtime, mtime), IsNil)
+	c.Assert(os.Chmod(fileName, 0600), IsNil)
+	object := "testobject"
+	preserve := true
+
+	// upload with preserve
+	err := s.initCopyCommand(fileName, CloudURLToString(bucketName, object), false, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	copyCommand.command.options[OptionPreserve] = &preserve
+	err = copyCommand.RunCommand()
+	c.Assert(err, IsNil)
+
+	// download without preserve sets mtime from last modified
+	os.Remove(downloadFileName)
+	err = s.initCopyCommand(CloudURLToString(bucketName, object), downloadFileName, false, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	err = copyCommand.RunCommand()
+	c.Assert(err, IsNil)
+	f, err := os.Stat(downloadFileName)
+	c.Assert(err, IsNil)
+	c.Assert(f.ModTime().Unix() > mtime.Unix(), Equals, true)
+	c.Assert(f.ModTime().Unix() <= time.Now().Unix(), Equals, true)
+
+	// download with preserve
+	os.Remove(downloadFileName)
+	err = s.initCopyCommand(CloudURLToString(bucketName, object), downloadFileName, false, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	copyCommand.command.options[OptionPreserve] = &preserve
+	err = copyCommand.RunCommand()
+	c.Assert(err, IsNil)
+	f, err = os.Stat(downloadFileName)
+	c.Assert(err, IsNil)
+	c.Assert(f.ModTime().Unix(), Equals, mtime.Unix())
+	c.Assert(f.Mode().Perm(), Equals, os.FileMode(0600))
+
+	os.Remove(fileName)
+	s.removeBucket(bucketName, true, c)
+}
+
+func (s *OssutilCommandSuite) TestCPObjectPreserveUpdate(c *C) {
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+
+	dir := "preserveDir" + randStr(5)
+	c.Assert(os.MkdirAll(dir, 0755), IsNil)
+	mtime := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	for i := 0; i < 3; i++ {
+		fileName := dir + "/file" + strconv.Itoa(i)
+		s.createFile(fileName, randStr(20), c)
+		c.Assert(os.Chtimes(fileName, mtime, mtime), IsNil)
+	}
+	preserve := true
+
+	err := s.initCopyCommand(dir, CloudURLToString(bucketName, "dir/"), true, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	copyCommand.command.options[OptionPreserve] = &preserve
+	err = copyCommand.RunCommand()
+	c.Assert(err, IsNil)
+
+	// the second download with update skips the files whose mtime is restored from meta
+	downDir := "preserveDownDir" + randStr(5)
+	for i, skipNum := range []int64{0, 3} {
+		err = s.initCopyCommand(CloudURLToString(bucketName, "dir/"), downDir, true, false, true, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+		c.Assert(err, IsNil)
+		copyCommand.command.options[OptionPreserve] = &preserve
+		err = copyCommand.RunCommand()
+		c.Assert(err, IsNil)
+		c.Assert(copyCommand.monitor.skipNum, Equals, skipNum, Commentf("run %d", i))
+		f, err := os.Stat(downDir + "/dir/file0")
+		c.Assert(err, IsNil)
+		c.Assert(f.ModTime().Unix(), Equals, mtime.Unix())
+	}
+
+	os.RemoveAll(dir)
+	os.RemoveAll(downDir)
+	s.removeBucket(bucketName, true, c)
+}
+
+func (s *OssutilCommandSuite) TestCPWalkFilesSymlinks(c *C) {
+	dir := "symlinkDir" + randStr(5)
+	c.Assert(os.MkdirAll(dir+"/sub", 0755), IsNil)
+	s.createFile(dir+"/file", randStr(10), c)
+	s.createFile(dir+"/sub/file", randStr(10), c)
+	c.Assert(os.Symlink("file", dir+"/link"), IsNil)
+	c.Assert(os.Symlink("sub", dir+"/sublink"), IsNil)
+	c.Assert(os.Symlink("..", dir+"/sub/loop"), IsNil)
+	c.Assert(os.Symlink("nonexist", dir+"/dangling"), IsNil)
+
+	walk := func(symlinks string) []string {
+		cc := CopyCommand{}
+		cc.cpOption.symlinks = symlinks
+		var names []string
+		err := cc.walkFiles(dir, func(fpath string, f os.FileInfo, err error) error {
+			if f == nil {
+				return err
+			}
+			if fpath != dir {
+				names = append(names, strings.TrimPrefix(fpath, dir+string(os.PathSeparator)))
+			}
+			return nil
+		})
+		c.Assert(err, IsNil)
+		return names
+	}
+	join := func(elem ...string) string {
+		return strings.Join(elem, string(os.PathSeparator))
+	}
+
+	c.Assert(walk(SymlinkSkip), DeepEquals, []string{"file", "sub", join("sub", "file")})
+	c.Assert(walk(SymlinkPreserve), DeepEquals, []string{"dangling", "file", "link", "sub", join("sub", "file"), join("sub", "loop"), "sublink"})
+	c.Assert(walk(SymlinkFollow), DeepEquals, []string{"file", "link", "sub", join("sub", "file"), "sublink", join("sublink", "file")})
+
+	os.RemoveAll(dir)
+}
+
+func (s *OssutilCommandSuite) TestCPObjectSymlinks(c *C) {
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+
+	dir := "symlinkDir" + randStr(5)
+	c.Assert(os.MkdirAll(dir+"/sub", 0755), IsNil)
+	data := randStr(20)
+	s.createFile(dir+"/sub/file", data, c)
+	c.Assert(os.Symlink("sub/file", dir+"/link"), IsNil)
+
+	// upload with preserve
+	err := s.initCopyCommand(dir, CloudURLToString(bucketName, "dir/"), true, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	symlinks := SymlinkPreserve
+	copyCommand.command.options[OptionSymlinks] = &symlinks
+	err = copyCommand.RunCommand()
+	c.Assert(err, IsNil)
+
+	bucket, err := copyCommand.command.ossBucket(bucketName)
+	c.Assert(err, IsNil)
+	props, err := bucket.GetSymlink("dir/link")
+	c.Assert(err, IsNil)
+	c.Assert(props.Get(oss.HTTPHeaderOssSymlinkTarget), Equals, "dir/sub/file")
+
+	// download with preserve
+	downDir := "symlinkDownDir" + randStr(5)
+	err = s.initCopyCommand(CloudURLToString(bucketName, "dir/"), downDir, true, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	copyCommand.command.options[OptionSymlinks] = &symlinks
+	err = copyCommand.RunCommand()
+	c.Assert(err, IsNil)
+
+	// the objects are downloaded with the whole object name
+	target, err := os.Readlink(downDir + "/dir/link")
+	c.Assert(err, IsNil)
+	c.Assert(target, Equals, "sub"+string(os.PathSeparator)+"file")
+	c.Assert(s.readFile(downDir+"/dir/link", c), Equals, data)
+
+	os.RemoveAll(dir)
+	os.RemoveAll(downDir)
+	s.removeBucket(bucketName, true, c)
+}
+
+func (s *OssutilCommandSuite) TestBatchUploadInterrupted(c *C) {
+	udir := randStr(10)
+	os.RemoveAll(udir)
+	err := os.MkdirAll(udir, 0755)
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(udir)
+
+	num := 3
+	for i := 0; i < num; i++ {
+		s.createFile(udir+"/"+randStr(10), fmt.Sprintf("测试文件：%d内容", i), c)
+	}
+
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+
+	// interrupted before dealing any file
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	commandContext = ctx
+	_, err = s.rawCPWithOutputDir(udir, CloudURLToString(bucketName, udir+"/"), true, true, false, 1, "")
+	commandContext = context.Background()
+	c.Assert(err, Equals, context.Canceled)
+
+	objects := s.listObjects(bucketName, udir+"/", "ls - ", c)
+	c.Assert(len(objects), Equals, 0)
+
+	// run again to continue
+	_, err = s.rawCPWithOutputDir(udir, CloudURLToString(bucketName, udir+"/"), true, true, false, 1, "")
+	c.Assert(err, IsNil)
+	objects = s.listObjects(bucketName, udir+"/", "ls - ", c)
+	c.Assert(len(objects), Equals, num)
+
+	s.removeBucket(bucketName, true, c)
+}
@@ -1,19 +1,21 @@
 package lib
 
 import (
+	"bufio"
 	"fmt"
-
-	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"os"
+	"strings"
 )
 
 var specChineseCreateSymlink = SpecText{
 
 	synopsisText: "创建符号链接",
 
-	paramText: "cloud_url target_url [options]",
+	paramText: "cloud_url [target_url] [options]",
 
 	syntaxText: ` 
     ossutil create-symlink cloud_url target_object [--encoding-type url] [-c file] 
+    ossutil create-symlink cloud_url --manifest file [-j num] [--output-dir dir] [--encoding-type url] [-c file] 
 `,
 
 	detailHelpText: ` 
@@ -29,16 +31,33 @@ var specChineseCreateSymlink = SpecText{
 
     通过stat命令可以查看符号链接的目标文件。
 
+--manifest选项
+
+    如果指定了该选项，ossutil从指定的文件中读取符号链接，并发地批量创建，此时cloud_url必须为
+    形如oss://bucket[/prefix]的cloud_url，不能指定target_object。文件的每行包含符号链接object
+    名和目标object名，以空白字符分隔，名称中含有空格时，以tab分隔；空行和以#开头的行会被忽略。
+    如果cloud_url中指定了prefix，prefix会被加到符号链接和目标object名的前面。
+
+    批量创建时，某个符号链接创建失败不会中止其他符号链接的创建，出错信息会记录到report文件中
+    （report文件的说明见cp命令的帮助），--output-dir选项指定report文件所在的目录，-j选项
+    指定并发数。
+
     更多信息见官网文档：https://help.aliyun.com/document_detail/45126.html?spm=5176.doc31979.6.870.x3Tqsh
 
 用法：
 
     ossutil create-symlink oss://bucket/symlink-object target-object
+    ossutil create-symlink oss://bucket[/prefix] --manifest file
 `,
 
 	sampleText: ` 
-    ossutil create-symlink oss://bucket1/object1 object2 
+    1) ossutil create-symlink oss://bucket1/object1 object2 
         创建从指向object2的符号链接object1。
+
+    2) ossutil create-symlink oss://bucket1/dir/ --manifest links.txt
+        按照links.txt中的描述，在bucket1的dir/下批量创建符号链接。links.txt的内容形如：
+            link1 object1
+            sub/link2 sub/object2
 `,
 }
 
@@ -46,10 +65,11 @@ var specEnglishCreateSymlink = SpecText{
 
 	synopsisText: "Create symlink of object",
 
-	paramText: "cloud_url target_url [options]",
+	paramText: "cloud_url [target_url] [options]",
 
 	syntaxText: ` 
     ossutil create-symlink cloud_url target_object [--encoding-type url] [-c file] 
+    ossutil create-symlink cloud_url --manifest file [-j num] [--output-dir dir] [--encoding-type url] [-c file] 
 `,
 
 	detailHelpText: ` 
@@ -69,29 +89,58 @@ var specEnglishCreateSymlink = SpecText{
 
     We can use stat command to query the target object of symlink object.
 
+--manifest option
+
+    If the option is specified, ossutil reads symlinks from the file, and creates them 
+    concurrently in batch, cloud_url must be in format: oss://bucket[/prefix], and target_object 
+    can not be specified. Each line of the file contains the symlink object name and the target 
+    object name, separated by blank characters, or by tab if the names contain spaces; empty lines 
+    and lines begin with # are ignored. If prefix is specified in cloud_url, the prefix is added 
+    before the symlink and target object names.
+
+    When create in batch, error of a symlink will not stop creating other symlinks, the error 
+    message is recorded in report file(for more information about report file, see help of cp 
+    command), --output-dir option specify the directory of report file, -j option specify the 
+    concurrency.
+
     More information about symlink see: https://help.aliyun.com/document_detail/45126.html?spm=5176.doc31979.6.870.x3Tqsh
 
 Usage:
 
     ossutil create-symlink oss://bucket/symlink-object target-object
+    ossutil create-symlink oss://bucket[/prefix] --manifest file
 `,
 
 	sampleText: ` 
-    ossutil create-symlink oss://bucket1/object1 object2 
+    1) ossutil create-symlink oss://bucket1/object1 object2 
         Create symlink object named object1, which point to object2.
+
+    2) ossutil create-symlink oss://bucket1/dir/ --manifest links.txt
+        Create symlinks under dir/ of bucket1 in batch as links.txt describes. The content of 
+        links.txt is like:
+            link1 object1
+            sub/link2 sub/object2
 `,
 }
 
 // CreateSymlinkCommand is the command list buckets or objects
 type CreateSymlinkCommand struct {
-	command Command
+	command  Command
+	monitor  Monitor
+	csOption batchOptionType
+}
+
+// symlinkInfoType is a symlink to create, read from manifest
+type symlinkInfoType struct {
+	symlink string
+	target  string
 }
 
 var createSymlinkCommand = CreateSymlinkCommand{
 	command: Command{
 		name:        "create-symlink",
 		nameAlias:   []string{},
-		minArgc:     2,
+		minArgc:     1,
 		maxArgc:     2,
 		specChinese: specChineseCreateSymlink,
 		specEnglish: specEnglishCreateSymlink,
@@ -103,7 +152,23 @@ var createSymlinkCommand = CreateSymlinkCommand{
 			OptionAccessKeyID,
 			OptionAccessKeySecret,
 			OptionSTSToken,
+			OptionProxyHost,
+			OptionProxyUser,
+			OptionProxyPwd,
+			OptionCAFile,
+			OptionSkipVerify,
+			OptionForceHTTPS,
+			OptionConnectTimeout,
+			OptionReadTimeout,
 			OptionRetryTimes,
+			OptionManifest,
+			OptionRoutines,
+			OptionOutputDir,
+			OptionOnSuccess,
+			OptionOnError,
+			OptionOnFinish,
+			OptionHookRoutines,
+			OptionHookTimeout,
 		},
 	},
 }
@@ -130,6 +195,14 @@ func (cc *CreateSymlinkCommand) RunCommand() error {
 		return err
 	}
 
+	manifest, _ := GetString(OptionManifest, cc.command.options)
+	if manifest != "" {
+		return cc.batchCreateSymlinks(cloudURL, manifest)
+	}
+	if len(cc.command.args) < 2 {
+		return fmt.Errorf("create-symlink need target_object, or --manifest option to create symlinks in batch")
+	}
+
 	targetURL, err := StorageURLFromString(cc.command.args[1], encodingType)
 	if err != nil {
 		return err
@@ -149,7 +222,7 @@ func (cc *CreateSymlinkCommand) RunCommand() error {
 		return err
 	}
 
-	return cc.ossCreateSymlinkRetry(bucket, cloudURL.object, targetObject)
+	return cc.command.ossCreateSymlinkRetry(bucket, cloudURL.object, targetObject)
 }
 
 func (cc *CreateSymlinkCommand) checkArgs(symlinkURL CloudURL, targetURL StorageURLer) error {
@@ -173,15 +246,90 @@ func (cc *CreateSymlinkCommand) checkArgs(symlinkURL CloudURL, targetURL Storage
 	return nil
 }
 
-func (cc *CreateSymlinkCommand) ossCreateSymlinkRetry(bucket *oss.Bucket, symlinkObject, targetObject string) error {
-	retryTimes, _ := GetInt(OptionRetryTimes, cc.command.options)
-	for i := 1; ; i++ {
-		err := bucket.PutSymlink(symlinkObject, targetObject)
-		if err == nil {
+func (cc *CreateSymlinkCommand) batchCreateSymlinks(cloudURL CloudURL, manifest string) error {
+	if cloudURL.bucket == "" {
+		return fmt.Errorf("invalid cloud url: %s, miss bucket", cc.command.args[0])
+	}
+	if len(cc.command.args) > 1 {
+		return fmt.Errorf("target_object can not be specified together with --manifest option")
+	}
+	if _, err := os.Stat(manifest); err != nil {
+		return fmt.Errorf("invalid manifest: %s, reason: %s", manifest, err.Error())
+	}
+
+	bucket, err := cc.command.ossBucket(cloudURL.bucket)
+	if err != nil {
+		return err
+	}
+
+	cc.monitor.init("Created")
+	cc.csOption.ctnu = true
+	outputDir, _ := GetString(OptionOutputDir, cc.command.options)
+	if cc.csOption.reporter, err = GetReporter(cc.csOption.ctnu, outputDir, commandLine); err != nil {
+		return err
+	}
+	defer cc.csOption.reporter.Clear()
+	cc.csOption.hooks = cc.command.newItemHooks()
+
+	routines, _ := GetInt(OptionRoutines, cc.command.options)
+	source := scanSource(&cc.monitor, cc.manifestSource(manifest, cloudURL.object))
+	engine := cc.command.newBatchEngine(&cc.csOption, routines, source, func(item batchItem) (string, error) {
+		link := item.value.(symlinkInfoType)
+		msg := fmt.Sprintf("create symlink %s to %s", CloudURLToString(bucket.BucketName, link.symlink), link.target)
+		return msg, cc.command.ossCreateSymlinkRetry(bucket, link.symlink, link.target)
+	})
+	engine.monitor = &cc.monitor
+	engine.hookItem = func(item batchItem) hookItem {
+		link := item.value.(symlinkInfoType)
+		return hookItem{Source: CloudURLToString(bucket.BucketName, link.symlink), Destination: link.target}
+	}
+	err = engine.run()
+	cc.csOption.hooks.finish(&cc.csOption, err)
+	return err
+}
+
+// manifestSource reads symlinks from manifest, prefix is added before the names
+func (cc *CreateSymlinkCommand) manifestSource(manifest, prefix string) batchSource {
+	return func(send func(batchItem) bool) error {
+		f, err := os.Open(manifest)
+		if err != nil {
 			return err
 		}
-		if int64(i) >= retryTimes {
-			return ObjectError{err, bucket.BucketName, symlinkObject}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for line := 1; scanner.Scan(); line++ {
+			symlink, target, ok := cc.parseManifestLine(scanner.Text())
+			if !ok {
+				continue
+			}
+			if symlink == "" || target == "" {
+				return fmt.Errorf("invalid manifest: %s, line %d: %s, need symlink object and target object", manifest, line, scanner.Text())
+			}
+			cc.monitor.updateScanNum(1)
+			if !send(batchItem{value: symlinkInfoType{prefix + symlink, prefix + target}}) {
+				return nil
+			}
 		}
+		return scanner.Err()
+	}
+}
+
+// parseManifestLine splits the line into symlink and target, ok is false for empty and comment lines
+func (cc *CreateSymlinkCommand) parseManifestLine(line string) (symlink, target string, ok bool) {
+	line = strings.TrimRight(line, "\r")
+	if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+		return "", "", false
+	}
+
+	var fields []string
+	if strings.Contains(line, "\t") {
+		fields = strings.Split(strings.Trim(line, "\t"), "\t")
+	} else {
+		fields = strings.Fields(line)
+	}
+	if len(fields) != 2 {
+		return "", "", true
 	}
+	return fields[0], fields[1], true
 }
@@ -0,0 +1,519 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+var specChineseDiff = SpecText{
+
+	synopsisText: "比较本地目录与oss前缀，或两个oss前缀下的文件",
+
+	paramText: "left_url right_url [options]",
+
+	syntaxText: `
+    ossutil diff file_url cloud_url [--checksum] [--output-format=text|json] [--exit-code] [-j num]
+    ossutil diff cloud_url file_url [--checksum] [--output-format=text|json] [--exit-code] [-j num]
+    ossutil diff cloud_url cloud_url [--checksum] [--output-format=text|json] [--exit-code] [-j num]
+`,
+
+	detailHelpText: `
+    该命令比较left_url和right_url下的文件，left_url和right_url可以是本地目录或者
+    oss://bucket[/prefix]。对于本地目录，比较的是目录下文件的相对路径；对于oss前缀，比较的
+    是object名去掉前缀后的部分，前缀不以/结尾时，会被当作目录处理，即自动在末尾加上/。本地
+    目录以及oss上以/结尾的空object会被忽略。
+
+    ossutil会并行列举两端的文件，并输出：
+        只存在于left_url的文件，以<开头；
+        只存在于right_url的文件，以>开头；
+        两端都存在但不相同的文件，以!开头，并给出不同的原因。
+
+    判断两端的文件是否相同的规则为：
+        （1）大小不同，原因为size；
+        （2）指定了--checksum选项时，比较两端的crc64值（oss上的object通过HEAD请求获取，本地
+        文件计算crc64），不同时原因为crc64；若某一端不存在crc64值，则按照（3）判断；
+        （3）两端都为oss时，ETag不同，原因为etag；left_url的lastModifiedTime新于right_url
+        时，原因为mtime。
+
+--output-format选项
+
+    输出的格式，取值为text或json，默认为text。json格式输出一个对象，包含onlyLeft、onlyRight、
+    differ和same字段。
+
+--exit-code选项
+
+    如果指定了该选项，当存在差异时，命令以非0值退出，可用于脚本中判断两端是否一致，例如在确认
+    上传完整后再删除本地数据。
+
+用法:
+
+    ossutil diff left_url right_url [--checksum] [--output-format=text|json] [--exit-code] [-j num]
+`,
+
+	sampleText: `
+    1) 比较本地目录与oss前缀
+        ossutil diff local_dir oss://bucket1/dir
+
+    2) 使用crc64比较两个bucket，并以json格式输出
+        ossutil diff oss://bucket1/dir oss://bucket2/dir --checksum --output-format=json
+
+    3) 在脚本中确认上传完整
+        ossutil diff local_dir oss://bucket1/dir --checksum --exit-code && rm -rf local_dir
+`,
+}
+
+var specEnglishDiff = SpecText{
+
+	synopsisText: "Compare files of local directory and oss prefix, or of two oss prefixes",
+
+	paramText: "left_url right_url [options]",
+
+	syntaxText: `
+    ossutil diff file_url cloud_url [--checksum] [--output-format=text|json] [--exit-code] [-j num]
+    ossutil diff cloud_url file_url [--checksum] [--output-format=text|json] [--exit-code] [-j num]
+    ossutil diff cloud_url cloud_url [--checksum] [--output-format=text|json] [--exit-code] [-j num]
+`,
+
+	detailHelpText: `
+    The command compares files under left_url and right_url, each of them can be a local
+    directory or oss://bucket[/prefix]. For local directory, the relative paths of files under
+    the directory are compared, for oss prefix, the object names without the prefix are compared.
+    If the prefix does not end with /, it is treated as a directory, which means / is appended
+    to it. Local directories and empty objects end with / are ignored.
+
+    Ossutil lists the two sides in parallel, and prints:
+        the files only exist in left_url, start with <;
+        the files only exist in right_url, start with >;
+        the files exist in both sides but differ, start with !, and the reasons.
+
+    The rules to decide whether the files are the same:
+        (1) If sizes differ, reason is size;
+        (2) If --checksum option is specified, compare crc64 of the two sides(by HEAD request for
+        oss object, compute crc64 for local file), if they differ, reason is crc64; if any side
+        has no crc64, decide by (3);
+        (3) If both sides are oss, and ETags differ, reason is etag; if lastModifiedTime of
+        left_url is newer than right_url, reason is mtime.
+
+--output-format option
+
+    Format of output, value is text or json, default is text. Output of json format is an
+    object, which contains onlyLeft, onlyRight, differ and same fields.
+
+--exit-code option
+
+    If the option is specified, the command exits with non-zero value when there are
+    differences. It can be used in scripts to make sure the two sides are consistent, for
+    example, before deleting local data after upload.
+
+Usage:
+
+    ossutil diff left_url right_url [--checksum] [--output-format=text|json] [--exit-code] [-j num]
+`,
+
+	sampleText: `
+    1) Compare local directory and oss prefix
+        ossutil diff local_dir oss://bucket1/dir
+
+    2) Compare two buckets by crc64, and output in json format
+        ossutil diff oss://bucket1/dir oss://bucket2/dir --checksum --output-format=json
+
+    3) Make sure upload is complete in scripts
+        ossutil diff local_dir oss://bucket1/dir --checksum --exit-code && rm -rf local_dir
+`,
+}
+
+const (
+	diffReasonSize  string = "size"
+	diffReasonCRC64        = "crc64"
+	diffReasonETag         = "etag"
+	diffReasonMtime        = "mtime"
+)
+
+type diffItem struct {
+	path  string
+	size  int64
+	mtime time.Time
+	etag  string
+	crc64 string
+}
+
+type diffSide struct {
+	url    StorageURLer
+	bucket *oss.Bucket
+	prefix string
+	items  map[string]*diffItem
+}
+
+type diffEntry struct {
+	Key     string   `json:"key"`
+	Reasons []string `json:"reasons"`
+}
+
+type diffResult struct {
+	Left      string      `json:"left"`
+	Right     string      `json:"right"`
+	OnlyLeft  []string    `json:"onlyLeft"`
+	OnlyRight []string    `json:"onlyRight"`
+	Differ    []diffEntry `json:"differ"`
+	Same      int64       `json:"same"`
+}
+
+type diffOptionType struct {
+	checksum bool
+	format   string
+	exitCode bool
+	routines int64
+	crcCache *checksumCache
+}
+
+// DiffCommand is the command to compare files of two sides
+type DiffCommand struct {
+	command    Command
+	diffOption diffOptionType
+}
+
+var diffCommand = DiffCommand{
+	command: Command{
+		name:        "diff",
+		nameAlias:   []string{"verify"},
+		minArgc:     2,
+		maxArgc:     2,
+		specChinese: specChineseDiff,
+		specEnglish: specEnglishDiff,
+		group:       GroupTypeNormalCommand,
+		validOptionNames: []string{
+			OptionChecksum,
+			OptionOutputFormat,
+			OptionExitCode,
+			OptionEncodingType,
+			OptionConfigFile,
+			OptionEndpoint,
+			OptionAccessKeyID,
+			OptionAccessKeySecret,
+			OptionSTSToken,
+			OptionProxyHost,
+			OptionProxyUser,
+			OptionProxyPwd,
+			OptionCAFile,
+			OptionSkipVerify,
+			OptionForceHTTPS,
+			OptionConnectTimeout,
+			OptionReadTimeout,
+			OptionRetryTimes,
+			OptionRoutines,
+		},
+	},
+}
+
+// function for FormatHelper interface
+func (dc *DiffCommand) formatHelpForWhole() string {
+	return dc.command.formatHelpForWhole()
+}
+
+func (dc *DiffCommand) formatIndependHelp() string {
+	return dc.command.formatIndependHelp()
+}
+
+// Init simulate inheritance, and polymorphism
+func (dc *DiffCommand) Init(args []string, options OptionMapType) error {
+	return dc.command.Init(args, options, dc)
+}
+
+// RunCommand simulate inheritance, and polymorphism
+func (dc *DiffCommand) RunCommand() error {
+	dc.diffOption.checksum, _ = GetBool(OptionChecksum, dc.command.options)
+	dc.diffOption.format, _ = GetString(OptionOutputFormat, dc.command.options)
+	dc.diffOption.exitCode, _ = GetBool(OptionExitCode, dc.command.options)
+	dc.diffOption.routines, _ = GetInt(OptionRoutines, dc.command.options)
+	if dc.diffOption.routines <= 0 {
+		dc.diffOption.routines = int64(Routines)
+	}
+	encodingType, _ := GetString(OptionEncodingType, dc.command.options)
+
+	left, err := dc.newDiffSide(dc.command.args[0], encodingType)
+	if err != nil {
+		return err
+	}
+	right, err := dc.newDiffSide(dc.command.args[1], encodingType)
+	if err != nil {
+		return err
+	}
+
+	dc.diffOption.crcCache = nil
+	if dc.diffOption.checksum && (left.bucket == nil || right.bucket == nil) {
+		dc.diffOption.crcCache = openChecksumCache()
+		defer dc.diffOption.crcCache.close()
+	}
+
+	// list the two sides in parallel
+	chError := make(chan error, 2)
+	for _, side := range []*diffSide{left, right} {
+		go func(side *diffSide) {
+			chError <- dc.listSide(side)
+		}(side)
+	}
+	for i := 0; i < 2; i++ {
+		if lerr := <-chError; lerr != nil && err == nil {
+			err = lerr
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	result, err := dc.compare(left, right)
+	if err != nil {
+		return err
+	}
+
+	if err := dc.printResult(result); err != nil {
+		return err
+	}
+
+	diffNum := len(result.OnlyLeft) + len(result.OnlyRight) + len(result.Differ)
+	if dc.diffOption.exitCode && diffNum > 0 {
+		return fmt.Errorf("%d differences found between %s and %s", diffNum, result.Left, result.Right)
+	}
+	return nil
+}
+
+func (dc *DiffCommand) newDiffSide(urlStr, encodingType string) (*diffSide, error) {
+	storageURL, err := StorageURLFromString(urlStr, encodingType)
+	if err != nil {
+		return nil, err
+	}
+	side := &diffSide{url: storageURL, items: map[string]*diffItem{}}
+
+	if storageURL.IsFileURL() {
+		f, err := os.Stat(storageURL.ToString())
+		if err != nil {
+			return nil, err
+		}
+		if !f.IsDir() {
+			return nil, fmt.Errorf("invalid url: %s, local url of diff must be a directory", urlStr)
+		}
+		return side, nil
+	}
+
+	if _, ok := storageURL.(S3URL); ok {
+		return nil, fmt.Errorf("invalid url: %s, diff does not support s3 url", urlStr)
+	}
+	cloudURL := storageURL.(CloudURL)
+	if cloudURL.bucket == "" {
+		return nil, fmt.Errorf("invalid cloud url: %s, miss bucket", urlStr)
+	}
+	if side.bucket, err = dc.command.ossBucket(cloudURL.bucket); err != nil {
+		return nil, err
+	}
+	side.prefix = cloudURL.object
+	if side.prefix != "" && !strings.HasSuffix(side.prefix, "/") {
+		side.prefix += "/"
+	}
+	return side, nil
+}
+
+func (dc *DiffCommand) listSide(side *diffSide) error {
+	if side.bucket == nil {
+		return dc.listFiles(side)
+	}
+	return dc.listObjects(side)
+}
+
+func (dc *DiffCommand) listFiles(side *diffSide) error {
+	dpath := filepath.Clean(side.url.ToString())
+	return filepath.Walk(dpath, func(fpath string, f os.FileInfo, err error) error {
+		if f == nil {
+			return err
+		}
+		if err := dc.command.cancelError(); err != nil {
+			return err
+		}
+		if f.IsDir() {
+			return nil
+		}
+
+		fileName, err := filepath.Rel(dpath, filepath.Clean(fpath))
+		if err != nil {
+			return fmt.Errorf("list file error: %s, info: %s", fpath, err.Error())
+		}
+		key := filepath.ToSlash(fileName)
+		side.items[key] = &diffItem{path: fpath, size: f.Size(), mtime: f.ModTime()}
+		return nil
+	})
+}
+
+func (dc *DiffCommand) listObjects(side *diffSide) error {
+	pre := oss.Prefix(side.prefix)
+	marker := oss.Marker("")
+	for {
+		lor, err := dc.command.ossListObjectsRetry(side.bucket, marker, pre)
+		if err != nil {
+			return err
+		}
+
+		for _, object := range lor.Objects {
+			if object.Size == 0 && strings.HasSuffix(object.Key, "/") {
+				continue
+			}
+			key := object.Key[len(side.prefix):]
+			side.items[key] = &diffItem{path: object.Key, size: object.Size, mtime: object.LastModified, etag: object.ETag}
+		}
+
+		pre = oss.Prefix(lor.Prefix)
+		marker = oss.Marker(lor.NextMarker)
+		if !lor.IsTruncated {
+			break
+		}
+		if err := dc.command.cancelError(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (dc *DiffCommand) compare(left, right *diffSide) (diffResult, error) {
+	result := diffResult{Left: left.url.ToString(), Right: right.url.ToString(), OnlyLeft: []string{}, OnlyRight: []string{}, Differ: []diffEntry{}}
+
+	commonKeys := []string{}
+	for key := range left.items {
+		if _, ok := right.items[key]; ok {
+			commonKeys = append(commonKeys, key)
+		} else {
+			result.OnlyLeft = append(result.OnlyLeft, key)
+		}
+	}
+	for key := range right.items {
+		if _, ok := left.items[key]; !ok {
+			result.OnlyRight = append(result.OnlyRight, key)
+		}
+	}
+	sort.Strings(result.OnlyLeft)
+	sort.Strings(result.OnlyRight)
+	sort.Strings(commonKeys)
+
+	if dc.diffOption.checksum {
+		if err := dc.fetchCRC64(left, right, commonKeys); err != nil {
+			return result, err
+		}
+	}
+
+	for _, key := range commonKeys {
+		if reasons := dc.compareItem(left.items[key], right.items[key]); len(reasons) > 0 {
+			result.Differ = append(result.Differ, diffEntry{key, reasons})
+		} else {
+			result.Same++
+		}
+	}
+	return result, nil
+}
+
+func (dc *DiffCommand) compareItem(l, r *diffItem) []string {
+	if l.size != r.size {
+		return []string{diffReasonSize}
+	}
+	if dc.diffOption.checksum && l.crc64 != "" && r.crc64 != "" {
+		if l.crc64 != r.crc64 {
+			return []string{diffReasonCRC64}
+		}
+		return nil
+	}
+
+	reasons := []string{}
+	if l.etag != "" && r.etag != "" && l.etag != r.etag {
+		reasons = append(reasons, diffReasonETag)
+	}
+	if l.mtime.Unix() > r.mtime.Unix() {
+		reasons = append(reasons, diffReasonMtime)
+	}
+	return reasons
+}
+
+// fetchCRC64 gets crc64 of the items with the same size in both sides concurrently
+func (dc *DiffCommand) fetchCRC64(left, right *diffSide, keys []string) error {
+	chKeys := make(chan string, ChannelBuf)
+	chError := make(chan error, dc.diffOption.routines)
+	for i := 0; int64(i) < dc.diffOption.routines; i++ {
+		go func() {
+			var err error
+			for key := range chKeys {
+				if err != nil {
+					continue
+				}
+				if err = dc.fetchItemCRC64(left, left.items[key]); err == nil {
+					err = dc.fetchItemCRC64(right, right.items[key])
+				}
+			}
+			chError <- err
+		}()
+	}
+
+	for _, key := range keys {
+		if dc.command.canceled() {
+			break
+		}
+		if left.items[key].size == right.items[key].size {
+			chKeys <- key
+		}
+	}
+	close(chKeys)
+
+	var err error
+	for i := 0; int64(i) < dc.diffOption.routines; i++ {
+		if ferr := <-chError; ferr != nil && err == nil {
+			err = ferr
+		}
+	}
+	if err == nil {
+		err = dc.command.cancelError()
+	}
+	return err
+}
+
+func (dc *DiffCommand) fetchItemCRC64(side *diffSide, item *diffItem) error {
+	if side.bucket == nil {
+		crc, err := dc.diffOption.crcCache.fileCRC64(item.path)
+		if err != nil {
+			return err
+		}
+		item.crc64 = strconv.FormatUint(crc, 10)
+		return nil
+	}
+
+	props, err := dc.command.ossGetObjectStatRetry(side.bucket, item.path)
+	if err != nil {
+		return err
+	}
+	item.crc64 = props.Get(oss.HTTPHeaderOssCRC64)
+	return nil
+}
+
+func (dc *DiffCommand) printResult(result diffResult) error {
+	if dc.diffOption.format == OutputFormatJSON {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	for _, key := range result.OnlyLeft {
+		fmt.Printf("< %s\n", key)
+	}
+	for _, key := range result.OnlyRight {
+		fmt.Printf("> %s\n", key)
+	}
+	for _, entry := range result.Differ {
+		fmt.Printf("! %s (%s)\n", entry.Key, strings.Join(entry.Reasons, ","))
+	}
+	fmt.Printf("\nonly in %s: %d, only in %s: %d, differ: %d, same: %d\n\n", result.Left, len(result.OnlyLeft), result.Right, len(result.OnlyRight), len(result.Differ), result.Same)
+	return nil
+}
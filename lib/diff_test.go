@@ -0,0 +1,96 @@
+package lib
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *OssutilCommandSuite) rawDiff(left, right string, checksum, exitCode bool, format string, c *C) (string, error) {
+	args := []string{left, right}
+	str := ""
+	routines := "2"
+	options := OptionMapType{
+		"endpoint":        &str,
+		"accessKeyID":     &str,
+		"accessKeySecret": &str,
+		"stsToken":        &str,
+		"configFile":      &configFile,
+		"checksum":        &checksum,
+		"exitCode":        &exitCode,
+		"outputFormat":    &format,
+		"routines":        &routines,
+	}
+	out := os.Stdout
+	testResultFile, _ = os.OpenFile(resultPath, os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0664)
+	os.Stdout = testResultFile
+	_, err := cm.RunCommand("diff", args, options)
+	os.Stdout = out
+	return s.readFile(resultPath, c), err
+}
+
+func (s *OssutilCommandSuite) TestDiffLocal(c *C) {
+	leftDir := "ossutil_test_diff_left" + randStr(5)
+	rightDir := "ossutil_test_diff_right" + randStr(5)
+	os.MkdirAll(leftDir+"/sub", 0755)
+	os.MkdirAll(rightDir+"/sub", 0755)
+	defer os.RemoveAll(leftDir)
+	defer os.RemoveAll(rightDir)
+
+	s.createFile(leftDir+"/same", "same", c)
+	s.createFile(rightDir+"/same", "same", c)
+	s.createFile(leftDir+"/sub/left", "left", c)
+	s.createFile(rightDir+"/right", "right", c)
+	s.createFile(leftDir+"/sub/size", "size1", c)
+	s.createFile(rightDir+"/sub/size", "size12", c)
+	s.createFile(rightDir+"/content", "abc", c)
+	s.createFile(leftDir+"/content", "abd", c)
+
+	str, err := s.rawDiff(leftDir, rightDir, true, false, OutputFormatText, c)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(str, "< sub/left"), Equals, true)
+	c.Assert(strings.Contains(str, "> right"), Equals, true)
+	c.Assert(strings.Contains(str, "! sub/size (size)"), Equals, true)
+	c.Assert(strings.Contains(str, "! content (crc64)"), Equals, true)
+	c.Assert(strings.Contains(str, "same: 1"), Equals, true)
+
+	str, err = s.rawDiff(leftDir, rightDir, true, true, OutputFormatJSON, c)
+	c.Assert(err, NotNil)
+	var result diffResult
+	c.Assert(json.Unmarshal([]byte(str), &result), IsNil)
+	c.Assert(result.OnlyLeft, DeepEquals, []string{"sub/left"})
+	c.Assert(result.OnlyRight, DeepEquals, []string{"right"})
+	c.Assert(len(result.Differ), Equals, 2)
+	c.Assert(result.Same, Equals, int64(1))
+
+	_, err = s.rawDiff(leftDir, leftDir, true, true, OutputFormatText, c)
+	c.Assert(err, IsNil)
+
+	// local url must be a directory
+	_, err = s.rawDiff(leftDir+"/same", rightDir, false, false, OutputFormatText, c)
+	c.Assert(err, NotNil)
+}
+
+func (s *OssutilCommandSuite) TestDiffObjects(c *C) {
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+
+	dir := "ossutil_test_diff_dir" + randStr(5)
+	os.MkdirAll(dir, 0755)
+	defer os.RemoveAll(dir)
+	s.createFile(dir+"/a", "aaa", c)
+	s.createFile(dir+"/b", "bbb", c)
+
+	s.putObject(bucketName, "dir/a", dir+"/a", c)
+	str, err := s.rawDiff(dir, CloudURLToString(bucketName, "dir"), true, true, OutputFormatText, c)
+	c.Assert(err, NotNil)
+	c.Assert(strings.Contains(str, "< b"), Equals, true)
+
+	s.putObject(bucketName, "dir/b", dir+"/b", c)
+	_, err = s.rawDiff(dir, CloudURLToString(bucketName, "dir/"), true, true, OutputFormatText, c)
+	c.Assert(err, IsNil)
+
+	s.removeBucket(bucketName, true, c)
+}
@@ -0,0 +1,169 @@
+package lib
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// ossEndpointRegexp matches the public and internal endpoints of oss regions, e.g:
+// oss-cn-hangzhou.aliyuncs.com, oss-cn-hangzhou-internal.aliyuncs.com, oss.aliyuncs.com
+var ossEndpointRegexp = regexp.MustCompile(`^(oss(-[a-z0-9-]+?)?)(-internal)?\.aliyuncs\.com$`)
+
+// discoveredEndpoints caches the endpoints discovered in process, the key is bucket and endpoint
+// configured, an empty value means the endpoint configured is used
+var discoveredEndpoints = struct {
+	sync.Mutex
+	m map[string]string
+}{m: map[string]string{}}
+
+// internalReachable caches whether the internal endpoints are reachable, the key is region
+var internalReachable = struct {
+	sync.Mutex
+	m map[string]bool
+}{m: map[string]bool{}}
+
+// probeEndpoint checks if host is reachable, internal endpoints are reachable only on ecs
+var probeEndpoint = func(host string) bool {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, "80"), time.Duration(EndpointProbeTimeout)*time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// splitEndpoint splits endpoint into scheme(with ://) and host
+func splitEndpoint(endpoint string) (string, string) {
+	if pos := strings.Index(endpoint, "://"); pos != -1 {
+		return endpoint[:pos+3], endpoint[pos+3:]
+	}
+	return "", endpoint
+}
+
+// endpointRegion returns the region of oss endpoint, and whether it's internal
+func endpointRegion(endpoint string) (string, bool, bool) {
+	_, host := splitEndpoint(endpoint)
+	match := ossEndpointRegexp.FindStringSubmatch(strings.ToLower(host))
+	if match == nil || strings.HasPrefix(match[1], "oss-accelerate") {
+		return "", false, false
+	}
+	return match[1], match[3] != "", true
+}
+
+// locationEndpoint returns the endpoint of location, the internal one is preferred if it's
+// reachable. The scheme of endpoint is kept.
+func locationEndpoint(location, endpoint string) string {
+	scheme, _ := splitEndpoint(endpoint)
+	_, internal, _ := endpointRegion(endpoint)
+	host := location + "-internal.aliyuncs.com"
+	if !internal {
+		internalReachable.Lock()
+		reachable, ok := internalReachable.m[location]
+		if !ok {
+			reachable = probeEndpoint(host)
+			internalReachable.m[location] = reachable
+		}
+		internalReachable.Unlock()
+		if !reachable {
+			host = location + ".aliyuncs.com"
+		}
+	}
+	return scheme + host
+}
+
+// discoverEndpoint returns the endpoint of the region where bucket is, it's discovered by
+// GetBucketLocation and cached, the endpoint configured is returned if it's not an endpoint of
+// oss region, or the discovery fails
+func (cmd *Command) discoverEndpoint(bucket, endpoint string) string {
+	region, _, ok := endpointRegion(endpoint)
+	if bucket == "" || !ok {
+		return endpoint
+	}
+
+	key := bucket + "\n" + endpoint
+	discoveredEndpoints.Lock()
+	discovered, ok := discoveredEndpoints.m[key]
+	discoveredEndpoints.Unlock()
+	if !ok {
+		discovered = cmd.discoverLocation(bucket, endpoint, region)
+		discoveredEndpoints.Lock()
+		discoveredEndpoints.m[key] = discovered
+		discoveredEndpoints.Unlock()
+	}
+	if discovered == "" {
+		return endpoint
+	}
+	scheme, _ := splitEndpoint(endpoint)
+	return scheme + discovered
+}
+
+// discoverLocation returns the host of endpoint discovered, or empty if the bucket is in region or
+// the discovery fails. Only the location is cached in file, the host is chosen for the endpoint
+// configured each time, since the internal and public endpoints may be configured on different hosts.
+func (cmd *Command) discoverLocation(bucket, endpoint, region string) string {
+	location, ok := readEndpointCache(bucket)
+	if !ok {
+		client, err := cmd.ossClient("")
+		if err != nil {
+			return ""
+		}
+		location, err = client.GetBucketLocation(bucket)
+		if err != nil {
+			// the failure answered by oss is cached too, e.g. the user has no permission of
+			// GetBucketLocation, so that it's not requested by every command
+			if _, ok := err.(oss.ServiceError); !ok {
+				return ""
+			}
+			location = ""
+		}
+		writeEndpointCache(bucket, location)
+	}
+
+	if location == "" || location == region {
+		return ""
+	}
+	_, host := splitEndpoint(locationEndpoint(location, endpoint))
+	return host
+}
+
+func endpointCacheDir() string {
+	dir := EndpointCacheDir
+	if usr, err := user.Current(); err == nil {
+		dir = strings.Replace(dir, "~", usr.HomeDir, 1)
+	}
+	return dir
+}
+
+// readEndpointCache returns the location of bucket, the location is empty if the discovery failed
+func readEndpointCache(bucket string) (string, bool) {
+	path := filepath.Join(endpointCacheDir(), bucket)
+	f, err := os.Stat(path)
+	if err != nil || time.Since(f.ModTime()) > time.Duration(EndpointCacheTTL)*time.Second {
+		return "", false
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	return lines[0], true
+}
+
+// writeEndpointCache writes the location of bucket, empty if the discovery failed. The cache is
+// only used to speed up, so errors are ignored.
+func writeEndpointCache(bucket, location string) {
+	dir := endpointCacheDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return
+	}
+	ioutil.WriteFile(filepath.Join(dir, bucket), []byte(location+"\n"), 0600)
+}
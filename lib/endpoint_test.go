@@ -0,0 +1,171 @@
+package lib
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+
+	. "gopkg.in/check.v1"
+)
+
+// resetDiscoveredEndpoints forgets the endpoints discovered in process and in cache file of bucket
+func resetDiscoveredEndpoints(bucket string) {
+	discoveredEndpoints.Lock()
+	discoveredEndpoints.m = map[string]string{}
+	discoveredEndpoints.Unlock()
+	internalReachable.Lock()
+	internalReachable.m = map[string]bool{}
+	internalReachable.Unlock()
+	os.Remove(filepath.Join(endpointCacheDir(), bucket))
+}
+
+func (s *OssutilCommandSuite) TestEndpointRegion(c *C) {
+	cases := []struct {
+		endpoint string
+		region   string
+		internal bool
+		ok       bool
+	}{
+		{"oss-cn-hangzhou.aliyuncs.com", "oss-cn-hangzhou", false, true},
+		{"https://oss-cn-hangzhou-internal.aliyuncs.com", "oss-cn-hangzhou", true, true},
+		{"OSS-US-WEST-1.aliyuncs.com", "oss-us-west-1", false, true},
+		{"oss.aliyuncs.com", "oss", false, true},
+		{"oss-accelerate.aliyuncs.com", "", false, false},
+		{"127.0.0.1:8080", "", false, false},
+		{"cname.example.com", "", false, false},
+	}
+	for _, cs := range cases {
+		region, internal, ok := endpointRegion(cs.endpoint)
+		c.Assert(region, Equals, cs.region)
+		c.Assert(internal, Equals, cs.internal)
+		c.Assert(ok, Equals, cs.ok)
+	}
+
+	probe := probeEndpoint
+	defer func() {
+		probeEndpoint = probe
+	}()
+	probeEndpoint = func(host string) bool { return false }
+	resetDiscoveredEndpoints("")
+	c.Assert(locationEndpoint("oss-cn-beijing", "https://oss-cn-hangzhou.aliyuncs.com"), Equals, "https://oss-cn-beijing.aliyuncs.com")
+	c.Assert(locationEndpoint("oss-cn-beijing", "oss-cn-hangzhou-internal.aliyuncs.com"), Equals, "oss-cn-beijing-internal.aliyuncs.com")
+	probeEndpoint = func(host string) bool { return true }
+	resetDiscoveredEndpoints("")
+	c.Assert(locationEndpoint("oss-cn-beijing", "oss-cn-hangzhou.aliyuncs.com"), Equals, "oss-cn-beijing-internal.aliyuncs.com")
+}
+
+func (s *OssutilCommandSuite) TestEndpointDiscover(c *C) {
+	server := newFakeOSSServer("discoverID", "discoverSecret")
+	defer server.close()
+
+	// the proxy forwards the requests for any host to oss emulator, and records the hosts
+	var mu sync.Mutex
+	hosts := map[string]int{}
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hosts[r.Host]++
+		mu.Unlock()
+		r.RequestURI = ""
+		r.URL.Host = server.endpoint()
+		resp, err := (&http.Transport{}).RoundTrip(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		for name, vals := range resp.Header {
+			w.Header()[name] = vals
+		}
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	}))
+	defer proxy.Close()
+
+	probe := probeEndpoint
+	defer func() {
+		probeEndpoint = probe
+	}()
+	probeEndpoint = func(host string) bool { return false }
+
+	bucketName := bucketNamePrefix + randLowStr(10)
+	resetDiscoveredEndpoints(bucketName)
+	defer resetDiscoveredEndpoints(bucketName)
+	options := map[string]string{
+		OptionEndpoint:        "oss-cn-beijing.aliyuncs.com",
+		OptionAccessKeyID:     "discoverID",
+		OptionAccessKeySecret: "discoverSecret",
+		OptionProxyHost:       proxy.URL,
+	}
+	client, err := transportCommand(options).ossClient("")
+	c.Assert(err, IsNil)
+	c.Assert(client.CreateBucket(bucketName), IsNil)
+
+	// the bucket is accessed with the endpoint of its region
+	bucket, err := transportCommand(options).ossBucket(bucketName)
+	c.Assert(err, IsNil)
+	_, err = bucket.ListObjects()
+	c.Assert(err, IsNil)
+	c.Assert(server.requestCount("GetBucketLocation"), Equals, 1)
+	c.Assert(hosts[bucketName+".oss-cn-hangzhou.aliyuncs.com"], Equals, 1)
+
+	// the location is read from cache file
+	resetDiscoveredEndpoints("")
+	bucket, err = transportCommand(options).ossBucket(bucketName)
+	c.Assert(err, IsNil)
+	_, err = bucket.ListObjects()
+	c.Assert(err, IsNil)
+	c.Assert(server.requestCount("GetBucketLocation"), Equals, 1)
+	c.Assert(hosts[bucketName+".oss-cn-hangzhou.aliyuncs.com"], Equals, 2)
+
+	// only the location is cached, the internal endpoint is chosen for the internal endpoint configured
+	resetDiscoveredEndpoints("")
+	internalOptions := map[string]string{}
+	for name, val := range options {
+		internalOptions[name] = val
+	}
+	internalOptions[OptionEndpoint] = "oss-cn-beijing-internal.aliyuncs.com"
+	client, err = transportCommand(internalOptions).ossClient(bucketName)
+	c.Assert(err, IsNil)
+	c.Assert(client.Config.Endpoint, Equals, "oss-cn-hangzhou-internal.aliyuncs.com")
+	c.Assert(server.requestCount("GetBucketLocation"), Equals, 1)
+
+	// the endpoint specified by option is used as it is
+	cmd := transportCommand(options)
+	cmd.explicitEndpoint = true
+	bucket, err = cmd.ossBucket(bucketName)
+	c.Assert(err, IsNil)
+	_, err = bucket.ListObjects()
+	c.Assert(err, IsNil)
+	c.Assert(hosts[bucketName+".oss-cn-beijing.aliyuncs.com"], Equals, 3)
+
+	// the bucket in the region configured
+	options[OptionEndpoint] = "oss-cn-hangzhou.aliyuncs.com"
+	bucket, err = transportCommand(options).ossBucket(bucketName)
+	c.Assert(err, IsNil)
+	_, err = bucket.ListObjects()
+	c.Assert(err, IsNil)
+	c.Assert(server.requestCount("GetBucketLocation"), Equals, 1)
+	c.Assert(hosts[bucketName+".oss-cn-hangzhou.aliyuncs.com"], Equals, 3)
+
+	// the endpoint configured is used if discovery fails, the failure is cached too
+	notExist := bucketNamePrefix + "notexist" + randLowStr(5)
+	resetDiscoveredEndpoints(notExist)
+	defer resetDiscoveredEndpoints(notExist)
+	options[OptionEndpoint] = "oss-cn-beijing.aliyuncs.com"
+	client, err = transportCommand(options).ossClient(notExist)
+	c.Assert(err, IsNil)
+	c.Assert(client.Config.Endpoint, Equals, "oss-cn-beijing.aliyuncs.com")
+	location, ok := readEndpointCache(notExist)
+	c.Assert(ok, Equals, true)
+	c.Assert(location, Equals, "")
+	c.Assert(server.requestCount("GetBucketLocation"), Equals, 2)
+
+	resetDiscoveredEndpoints("")
+	client, err = transportCommand(options).ossClient(notExist)
+	c.Assert(err, IsNil)
+	c.Assert(client.Config.Endpoint, Equals, "oss-cn-beijing.aliyuncs.com")
+	c.Assert(server.requestCount("GetBucketLocation"), Equals, 2)
+}
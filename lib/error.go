@@ -52,3 +52,14 @@ type CopyError struct {
 func (e CopyError) Error() string {
 	return e.err.Error()
 }
+
+// VerifyError happens when destination differs from source after transfer
+type VerifyError struct {
+	src    string
+	dest   string
+	reason string
+}
+
+func (e VerifyError) Error() string {
+	return fmt.Sprintf("verify failed, %s, Source=%s, Destination=%s", e.reason, e.src, e.dest)
+}
@@ -0,0 +1,1475 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash/crc64"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	. "gopkg.in/check.v1"
+)
+
+// fakeOSSServer is an in-process oss emulator, which serves the oss apis used by ossutil with
+// buckets and objects in memory, so that the suites can run without oss service. The requests
+// are authenticated with signature v1, and the faults injected make the matched requests fail.
+type fakeOSSServer struct {
+	server          *httptest.Server
+	accessKeyID     string
+	accessKeySecret string
+	location        string
+
+	// restoreDuration is the time that a restore request takes
+	restoreDuration time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*fakeBucket
+	faults    []*fakeOSSFault
+	requests  map[string]int
+	requestID int64
+	uploadID  int64
+}
+
+// fakeOSSFault makes the requests matched fail, a request matches the fault if each of op, bucket
+// and object is empty or equal to that of the request. The fault is removed after it fails times
+// requests, it's never removed if times is 0.
+type fakeOSSFault struct {
+	op     string
+	bucket string
+	object string
+
+	// status and code are the error returned, no error is returned if status is 0
+	status int
+	code   string
+
+	// delay is the time waited before the request is served
+	delay time.Duration
+
+	// drop closes the connection without response
+	drop bool
+
+	times int
+}
+
+type fakeBucket struct {
+	name         string
+	acl          string
+	storageClass string
+	created      time.Time
+	objects      map[string]*fakeObject
+	uploads      map[string]*fakeUpload
+}
+
+type fakeObject struct {
+	data         []byte
+	etag         string
+	objectType   string
+	storageClass string
+	acl          string
+	modified     time.Time
+
+	// header is the content type, the user meta and the other headers specified in upload
+	header http.Header
+
+	symlinkTarget string
+	restoreExpiry time.Time
+	restoreDays   int64
+}
+
+type fakeUpload struct {
+	key       string
+	id        string
+	initiated time.Time
+	header    http.Header
+	parts     map[int]*fakePart
+}
+
+type fakePart struct {
+	data     []byte
+	etag     string
+	modified time.Time
+}
+
+type fakeOSSError struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string   `xml:"Code"`
+	Message   string   `xml:"Message"`
+	RequestID string   `xml:"RequestId"`
+	HostID    string   `xml:"HostId"`
+}
+
+type fakeDeleteRequest struct {
+	XMLName xml.Name `xml:"Delete"`
+	Quiet   bool     `xml:"Quiet"`
+	Keys    []string `xml:"Object>Key"`
+}
+
+type fakeCompleteRequest struct {
+	XMLName xml.Name         `xml:"CompleteMultipartUpload"`
+	Parts   []oss.UploadPart `xml:"Part"`
+}
+
+type fakeCreateBucketRequest struct {
+	XMLName      xml.Name `xml:"CreateBucketConfiguration"`
+	StorageClass string   `xml:"StorageClass"`
+}
+
+type fakeLocationResult struct {
+	XMLName  xml.Name `xml:"LocationConstraint"`
+	Location string   `xml:",chardata"`
+}
+
+const (
+	fakeOwnerID        = "fake-owner"
+	fakeMinPartSize    = 100 * 1024
+	fakeDefaultMaxKeys = 100
+	fakeMaxKeys        = 1000
+)
+
+var fakeSignParams = []string{"acl", "uploads", "location", "cors", "logging", "website", "referer", "lifecycle",
+	"delete", "append", "tagging", "objectMeta", "uploadId", "partNumber", "security-token", "position", "bucketInfo",
+	"symlink", "restore", "response-content-type", "response-content-language", "response-expires",
+	"response-cache-control", "response-content-disposition", "response-content-encoding"}
+
+var fakeUploadHeaders = []string{oss.HTTPHeaderContentType, oss.HTTPHeaderCacheControl, oss.HTTPHeaderContentDisposition,
+	oss.HTTPHeaderContentEncoding, oss.HTTPHeaderContentLanguage, oss.HTTPHeaderExpires}
+
+// newFakeOSSServer starts an oss emulator which accepts the requests signed by the access key
+func newFakeOSSServer(accessKeyID, accessKeySecret string) *fakeOSSServer {
+	s := &fakeOSSServer{
+		accessKeyID:     accessKeyID,
+		accessKeySecret: accessKeySecret,
+		location:        "oss-cn-hangzhou",
+		restoreDuration: time.Minute,
+		buckets:         map[string]*fakeBucket{},
+		requests:        map[string]int{},
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// endpoint returns the endpoint of the emulator, which is an ip address, so that the bucket is
+// put in the path of url by sdk
+func (s *fakeOSSServer) endpoint() string {
+	return strings.TrimPrefix(s.server.URL, "http://")
+}
+
+func (s *fakeOSSServer) close() {
+	s.server.Close()
+}
+
+// inject adds a fault, the faults are matched in the order they are injected
+func (s *fakeOSSServer) inject(fault fakeOSSFault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault)
+}
+
+// injectServerError makes the next times requests of op fail with 500 InternalError
+func (s *fakeOSSServer) injectServerError(op string, times int) {
+	s.inject(fakeOSSFault{op: op, status: http.StatusInternalServerError, code: "InternalError", times: times})
+}
+
+// injectThrottle makes the next times requests of op fail with 503 SlowDown
+func (s *fakeOSSServer) injectThrottle(op string, times int) {
+	s.inject(fakeOSSFault{op: op, status: http.StatusServiceUnavailable, code: "SlowDown", times: times})
+}
+
+// injectTimeout delays the next times requests of op, the connection is closed without response
+// after the delay, like the server hangs up
+func (s *fakeOSSServer) injectTimeout(op string, delay time.Duration, times int) {
+	s.inject(fakeOSSFault{op: op, delay: delay, drop: true, times: times})
+}
+
+func (s *fakeOSSServer) clearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// requestCount returns the number of requests of op received, including the failed ones
+func (s *fakeOSSServer) requestCount(op string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[op]
+}
+
+// reset removes all the buckets, faults and request counts
+func (s *fakeOSSServer) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buckets = map[string]*fakeBucket{}
+	s.faults = nil
+	s.requests = map[string]int{}
+}
+
+func (s *fakeOSSServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requestID++
+	requestID := fmt.Sprintf("%024X", s.requestID)
+	s.mu.Unlock()
+	w.Header().Set(oss.HTTPHeaderOssRequestID, requestID)
+	w.Header().Set(oss.HTTPHeaderServer, "AliyunOSS")
+	w.Header().Set(oss.HTTPHeaderDate, time.Now().UTC().Format(http.TimeFormat))
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return
+	}
+	bucket, object := s.parsePath(r)
+	query := r.URL.Query()
+	op := fakeOperation(r.Method, bucket, object, query, r.Header)
+
+	if fault := s.matchFault(op, bucket, object); fault != nil {
+		if fault.delay > 0 {
+			time.Sleep(fault.delay)
+		}
+		if fault.drop {
+			if hj, ok := w.(http.Hijacker); ok {
+				if conn, _, err := hj.Hijack(); err == nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+		if fault.status != 0 {
+			s.writeError(w, r, fault.status, fault.code, "fault injected", requestID)
+			return
+		}
+	}
+
+	if status, code, msg := s.authenticate(r, bucket, object); status != 0 {
+		s.writeError(w, r, status, code, msg, requestID)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if bucket != "" && op != "PutBucket" && s.buckets[bucket] == nil {
+		s.writeError(w, r, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist.", requestID)
+		return
+	}
+	if status, code, msg := s.checkAnonymous(r, bucket, object); status != 0 {
+		s.writeError(w, r, status, code, msg, requestID)
+		return
+	}
+
+	ctx := &fakeRequest{w: w, r: r, body: body, query: query, bucket: bucket, object: object, requestID: requestID}
+	if b := s.buckets[bucket]; b != nil {
+		ctx.b = b
+	}
+	handler, ok := fakeHandlers[op]
+	if !ok {
+		s.writeError(w, r, http.StatusNotImplemented, "NotImplemented", "The operation "+op+" is not supported by the emulator.", requestID)
+		return
+	}
+	handler(s, ctx)
+}
+
+// parsePath gets bucket and object from request, both path style and virtual hosted style are
+// supported
+func (s *fakeOSSServer) parsePath(r *http.Request) (string, string) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if net.ParseIP(host) == nil && strings.Contains(host, ".") && host != "localhost" {
+		return host[:strings.Index(host, ".")], path
+	}
+	if pos := strings.Index(path, "/"); pos != -1 {
+		return path[:pos], path[pos+1:]
+	}
+	return path, ""
+}
+
+func (s *fakeOSSServer) matchFault(op, bucket, object string) *fakeOSSFault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[op]++
+	for i, fault := range s.faults {
+		if (fault.op != "" && fault.op != op) || (fault.bucket != "" && fault.bucket != bucket) ||
+			(fault.object != "" && fault.object != object) {
+			continue
+		}
+		if fault.times > 0 {
+			fault.times--
+			if fault.times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return fault
+	}
+	return nil
+}
+
+// authenticate checks the signature v1 of request, the request without authorization is anonymous
+// and checked with acl later
+func (s *fakeOSSServer) authenticate(r *http.Request, bucket, object string) (int, string, string) {
+	auth := r.Header.Get(oss.HTTPHeaderAuthorization)
+	if auth == "" {
+		return 0, "", ""
+	}
+	if !strings.HasPrefix(auth, "OSS ") || !strings.Contains(auth, ":") {
+		return http.StatusBadRequest, "InvalidArgument", "Authorization header is invalid."
+	}
+	auth = auth[len("OSS "):]
+	pos := strings.LastIndex(auth, ":")
+	if auth[:pos] != s.accessKeyID {
+		return http.StatusForbidden, "InvalidAccessKeyId", "The OSS Access Key Id you provided does not exist in our records."
+	}
+	if auth[pos+1:] != s.signature(r, bucket, object) {
+		return http.StatusForbidden, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided."
+	}
+	return 0, "", ""
+}
+
+func (s *fakeOSSServer) signature(r *http.Request, bucket, object string) string {
+	keys := []string{}
+	for k := range r.Header {
+		if strings.HasPrefix(strings.ToLower(k), "x-oss-") {
+			keys = append(keys, strings.ToLower(k))
+		}
+	}
+	sort.Strings(keys)
+	ossHeaders := ""
+	for _, k := range keys {
+		ossHeaders += k + ":" + r.Header.Get(k) + "\n"
+	}
+
+	resource := "/"
+	if bucket != "" {
+		resource = "/" + bucket + "/" + object
+	}
+	params := []string{}
+	for _, param := range strings.Split(r.URL.RawQuery, "&") {
+		kv := strings.SplitN(param, "=", 2)
+		key, _ := url.QueryUnescape(kv[0])
+		if FindPos(key, fakeSignParams) == -1 {
+			continue
+		}
+		if len(kv) == 2 {
+			value, _ := url.QueryUnescape(kv[1])
+			key += "=" + value
+		}
+		params = append(params, key)
+	}
+	if len(params) > 0 {
+		sort.Strings(params)
+		resource += "?" + strings.Join(params, "&")
+	}
+
+	str := r.Method + "\n" + r.Header.Get(oss.HTTPHeaderContentMD5) + "\n" + r.Header.Get(oss.HTTPHeaderContentType) + "\n" +
+		r.Header.Get(oss.HTTPHeaderDate) + "\n" + ossHeaders + resource
+	h := hmac.New(sha1.New, []byte(s.accessKeySecret))
+	h.Write([]byte(str))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// checkAnonymous allows anonymous requests to read public-read buckets and objects, and to write
+// public-read-write ones
+func (s *fakeOSSServer) checkAnonymous(r *http.Request, bucket, object string) (int, string, string) {
+	if r.Header.Get(oss.HTTPHeaderAuthorization) != "" {
+		return 0, "", ""
+	}
+	acl := "private"
+	if b := s.buckets[bucket]; b != nil {
+		acl = b.acl
+		if obj := b.objects[object]; obj != nil && obj.acl != "default" {
+			acl = obj.acl
+		}
+	}
+	read := r.Method == "GET" || r.Method == "HEAD"
+	if acl == "public-read-write" || (acl == "public-read" && read && object != "") {
+		return 0, "", ""
+	}
+	return http.StatusForbidden, "AccessDenied", "You have no right to access this object because of bucket acl."
+}
+
+// fakeOperation names the oss api of request
+func fakeOperation(method, bucket, object string, query url.Values, header http.Header) string {
+	has := func(param string) bool {
+		_, ok := query[param]
+		return ok
+	}
+	if bucket == "" {
+		return "ListBuckets"
+	}
+	if object == "" {
+		switch {
+		case method == "PUT" && has("acl"):
+			return "PutBucketACL"
+		case method == "PUT":
+			return "PutBucket"
+		case method == "GET" && has("acl"):
+			return "GetBucketACL"
+		case method == "GET" && has("location"):
+			return "GetBucketLocation"
+		case method == "GET" && has("bucketInfo"):
+			return "GetBucketInfo"
+		case method == "GET" && has("uploads"):
+			return "ListMultipartUploads"
+		case method == "GET":
+			return "ListObjects"
+		case method == "DELETE":
+			return "DeleteBucket"
+		case method == "POST" && has("delete"):
+			return "DeleteMultipleObjects"
+		}
+		return method + "Bucket"
+	}
+
+	switch {
+	case method == "PUT" && has("acl"):
+		return "PutObjectACL"
+	case method == "PUT" && has("symlink"):
+		return "PutSymlink"
+	case method == "PUT" && has("uploadId") && header.Get(oss.HTTPHeaderOssCopySource) != "":
+		return "UploadPartCopy"
+	case method == "PUT" && has("uploadId"):
+		return "UploadPart"
+	case method == "PUT" && header.Get(oss.HTTPHeaderOssCopySource) != "":
+		return "CopyObject"
+	case method == "PUT":
+		return "PutObject"
+	case method == "GET" && has("acl"):
+		return "GetObjectACL"
+	case method == "GET" && has("symlink"):
+		return "GetSymlink"
+	case method == "GET" && has("uploadId"):
+		return "ListParts"
+	case method == "GET":
+		return "GetObject"
+	case method == "HEAD" && has("objectMeta"):
+		return "GetObjectMeta"
+	case method == "HEAD":
+		return "HeadObject"
+	case method == "DELETE" && has("uploadId"):
+		return "AbortMultipartUpload"
+	case method == "DELETE":
+		return "DeleteObject"
+	case method == "POST" && has("uploads"):
+		return "InitiateMultipartUpload"
+	case method == "POST" && has("uploadId"):
+		return "CompleteMultipartUpload"
+	case method == "POST" && has("restore"):
+		return "RestoreObject"
+	}
+	return method + "Object"
+}
+
+type fakeRequest struct {
+	w         http.ResponseWriter
+	r         *http.Request
+	body      []byte
+	query     url.Values
+	bucket    string
+	object    string
+	b         *fakeBucket
+	requestID string
+}
+
+var fakeHandlers = map[string]func(s *fakeOSSServer, req *fakeRequest){
+	"ListBuckets":             (*fakeOSSServer).listBuckets,
+	"PutBucket":               (*fakeOSSServer).putBucket,
+	"PutBucketACL":            (*fakeOSSServer).putBucketACL,
+	"GetBucketACL":            (*fakeOSSServer).getBucketACL,
+	"GetBucketLocation":       (*fakeOSSServer).getBucketLocation,
+	"GetBucketInfo":           (*fakeOSSServer).getBucketInfo,
+	"DeleteBucket":            (*fakeOSSServer).deleteBucket,
+	"ListObjects":             (*fakeOSSServer).listObjects,
+	"ListMultipartUploads":    (*fakeOSSServer).listMultipartUploads,
+	"DeleteMultipleObjects":   (*fakeOSSServer).deleteMultipleObjects,
+	"PutObject":               (*fakeOSSServer).putObject,
+	"CopyObject":              (*fakeOSSServer).copyObject,
+	"PutObjectACL":            (*fakeOSSServer).putObjectACL,
+	"GetObjectACL":            (*fakeOSSServer).getObjectACL,
+	"PutSymlink":              (*fakeOSSServer).putSymlink,
+	"GetSymlink":              (*fakeOSSServer).getSymlink,
+	"GetObject":               (*fakeOSSServer).getObject,
+	"HeadObject":              (*fakeOSSServer).getObject,
+	"GetObjectMeta":           (*fakeOSSServer).getObjectMeta,
+	"DeleteObject":            (*fakeOSSServer).deleteObject,
+	"RestoreObject":           (*fakeOSSServer).restoreObject,
+	"InitiateMultipartUpload": (*fakeOSSServer).initiateMultipartUpload,
+	"UploadPart":              (*fakeOSSServer).uploadPart,
+	"UploadPartCopy":          (*fakeOSSServer).uploadPart,
+	"ListParts":               (*fakeOSSServer).listParts,
+	"CompleteMultipartUpload": (*fakeOSSServer).completeMultipartUpload,
+	"AbortMultipartUpload":    (*fakeOSSServer).abortMultipartUpload,
+}
+
+func (s *fakeOSSServer) writeError(w http.ResponseWriter, r *http.Request, status int, code, msg, requestID string) {
+	if r.Method == "HEAD" {
+		w.WriteHeader(status)
+		return
+	}
+	data, _ := xml.Marshal(fakeOSSError{Code: code, Message: msg, RequestID: requestID, HostID: s.endpoint()})
+	w.Header().Set(oss.HTTPHeaderContentType, "application/xml")
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+}
+
+func (req *fakeRequest) error(s *fakeOSSServer, status int, code, msg string) {
+	s.writeError(req.w, req.r, status, code, msg, req.requestID)
+}
+
+func (req *fakeRequest) writeXML(status int, v interface{}) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		req.w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	req.w.Header().Set(oss.HTTPHeaderContentType, "application/xml")
+	req.w.WriteHeader(status)
+	req.w.Write([]byte(xml.Header))
+	req.w.Write(data)
+}
+
+func (req *fakeRequest) encode(str string) string {
+	if req.query.Get("encoding-type") == "url" {
+		return url.QueryEscape(str)
+	}
+	return str
+}
+
+func (req *fakeRequest) intParam(name string, def int) (int, bool) {
+	value := req.query.Get(name)
+	if value == "" {
+		return def, true
+	}
+	i, err := strconv.Atoi(value)
+	return i, err == nil && i >= 0
+}
+
+func fakeOwner() oss.Owner {
+	return oss.Owner{ID: fakeOwnerID, DisplayName: fakeOwnerID}
+}
+
+func fakeETag(data []byte) string {
+	sum := md5.Sum(data)
+	return "\"" + strings.ToUpper(hex.EncodeToString(sum[:])) + "\""
+}
+
+func fakeValidACL(acl string) bool {
+	return acl == "private" || acl == "public-read" || acl == "public-read-write"
+}
+
+func fakeValidStorageClass(class string) bool {
+	return class == StorageStandard || class == StorageIA || class == StorageArchive
+}
+
+func fakeValidBucketName(name string) bool {
+	if len(name) < 3 || len(name) > 63 || name[0] == '-' || name[len(name)-1] == '-' {
+		return false
+	}
+	for _, ch := range name {
+		if !(ch >= 'a' && ch <= 'z') && !(ch >= '0' && ch <= '9') && ch != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *fakeOSSServer) listBuckets(req *fakeRequest) {
+	prefix := req.query.Get("prefix")
+	marker := req.query.Get("marker")
+	maxKeys, ok := req.intParam("max-keys", fakeDefaultMaxKeys)
+	if !ok || maxKeys > fakeMaxKeys {
+		req.error(s, http.StatusBadRequest, "InvalidArgument", "max-keys is invalid.")
+		return
+	}
+
+	names := []string{}
+	for name := range s.buckets {
+		if strings.HasPrefix(name, prefix) && name > marker {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	result := oss.ListBucketsResult{Prefix: prefix, Marker: marker, MaxKeys: maxKeys, Owner: fakeOwner()}
+	if len(names) > maxKeys {
+		names = names[:maxKeys]
+		result.IsTruncated = true
+		result.NextMarker = names[maxKeys-1]
+	}
+	for _, name := range names {
+		b := s.buckets[name]
+		result.Buckets = append(result.Buckets, oss.BucketProperties{Name: name, Location: s.location, CreationDate: b.created, StorageClass: b.storageClass})
+	}
+	req.writeXML(http.StatusOK, result)
+}
+
+func (s *fakeOSSServer) putBucket(req *fakeRequest) {
+	if !fakeValidBucketName(req.bucket) {
+		req.error(s, http.StatusBadRequest, "InvalidBucketName", "The specified bucket is not valid.")
+		return
+	}
+	acl := req.r.Header.Get(oss.HTTPHeaderOssACL)
+	if acl == "" {
+		acl = "private"
+	}
+	if !fakeValidACL(acl) {
+		req.error(s, http.StatusBadRequest, "InvalidArgument", "no such bucket access control exists")
+		return
+	}
+	config := fakeCreateBucketRequest{StorageClass: StorageStandard}
+	if len(req.body) > 0 {
+		if err := xml.Unmarshal(req.body, &config); err != nil {
+			req.error(s, http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed.")
+			return
+		}
+	}
+	if !fakeValidStorageClass(config.StorageClass) {
+		req.error(s, http.StatusBadRequest, "InvalidArgument", "The storage class is invalid.")
+		return
+	}
+
+	if b := s.buckets[req.bucket]; b != nil {
+		b.acl = acl
+	} else {
+		s.buckets[req.bucket] = &fakeBucket{
+			name:         req.bucket,
+			acl:          acl,
+			storageClass: config.StorageClass,
+			created:      time.Now().UTC(),
+			objects:      map[string]*fakeObject{},
+			uploads:      map[string]*fakeUpload{},
+		}
+	}
+	req.w.WriteHeader(http.StatusOK)
+}
+
+func (s *fakeOSSServer) putBucketACL(req *fakeRequest) {
+	acl := req.r.Header.Get(oss.HTTPHeaderOssACL)
+	if !fakeValidACL(acl) {
+		req.error(s, http.StatusBadRequest, "InvalidArgument", "no such bucket access control exists")
+		return
+	}
+	req.b.acl = acl
+	req.w.WriteHeader(http.StatusOK)
+}
+
+func (s *fakeOSSServer) getBucketACL(req *fakeRequest) {
+	req.writeXML(http.StatusOK, oss.GetBucketACLResult{ACL: req.b.acl, Owner: fakeOwner()})
+}
+
+func (s *fakeOSSServer) getBucketLocation(req *fakeRequest) {
+	req.writeXML(http.StatusOK, fakeLocationResult{Location: s.location})
+}
+
+func (s *fakeOSSServer) getBucketInfo(req *fakeRequest) {
+	info := oss.BucketInfo{
+		Name:             req.bucket,
+		Location:         s.location,
+		CreationDate:     req.b.created,
+		ExtranetEndpoint: s.endpoint(),
+		IntranetEndpoint: s.endpoint(),
+		ACL:              req.b.acl,
+		Owner:            fakeOwner(),
+		StorageClass:     req.b.storageClass,
+	}
+	req.writeXML(http.StatusOK, oss.GetBucketInfoResult{BucketInfo: info})
+}
+
+func (s *fakeOSSServer) deleteBucket(req *fakeRequest) {
+	if len(req.b.objects) > 0 || len(req.b.uploads) > 0 {
+		req.error(s, http.StatusConflict, "BucketNotEmpty", "The bucket you tried to delete is not empty.")
+		return
+	}
+	delete(s.buckets, req.bucket)
+	req.w.WriteHeader(http.StatusNoContent)
+}
+
+// fakeListEntry returns the common prefix of key if it has delimiter after prefix, otherwise key
+func fakeListEntry(key, prefix, delimiter string) (string, bool) {
+	if delimiter == "" {
+		return key, false
+	}
+	if pos := strings.Index(key[len(prefix):], delimiter); pos != -1 {
+		return key[:len(prefix)+pos+len(delimiter)], true
+	}
+	return key, false
+}
+
+func (s *fakeOSSServer) listObjects(req *fakeRequest) {
+	prefix := req.query.Get("prefix")
+	marker := req.query.Get("marker")
+	delimiter := req.query.Get("delimiter")
+	maxKeys, ok := req.intParam("max-keys", fakeDefaultMaxKeys)
+	if !ok || maxKeys > fakeMaxKeys {
+		req.error(s, http.StatusBadRequest, "InvalidArgument", "max-keys is invalid.")
+		return
+	}
+
+	keys := []string{}
+	for key := range req.b.objects {
+		if strings.HasPrefix(key, prefix) && key > marker {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	result := oss.ListObjectsResult{Prefix: req.encode(prefix), Marker: req.encode(marker), MaxKeys: maxKeys, Delimiter: req.encode(delimiter)}
+	count := 0
+	last := ""
+	for _, key := range keys {
+		entry, isPrefix := fakeListEntry(key, prefix, delimiter)
+		if entry <= marker || (isPrefix && entry == last) {
+			continue
+		}
+		if count == maxKeys {
+			result.IsTruncated = true
+			result.NextMarker = req.encode(last)
+			break
+		}
+		count++
+		last = entry
+		if isPrefix {
+			result.CommonPrefixes = append(result.CommonPrefixes, req.encode(entry))
+			continue
+		}
+		obj := req.b.objects[key]
+		result.Objects = append(result.Objects, oss.ObjectProperties{
+			Key:          req.encode(key),
+			Type:         obj.objectType,
+			Size:         int64(len(obj.data)),
+			ETag:         obj.etag,
+			Owner:        fakeOwner(),
+			LastModified: obj.modified,
+			StorageClass: obj.storageClass,
+		})
+	}
+	req.writeXML(http.StatusOK, result)
+}
+
+func (s *fakeOSSServer) listMultipartUploads(req *fakeRequest) {
+	prefix := req.query.Get("prefix")
+	delimiter := req.query.Get("delimiter")
+	keyMarker := req.query.Get("key-marker")
+	idMarker := req.query.Get("upload-id-marker")
+	maxUploads, ok := req.intParam("max-uploads", fakeMaxKeys)
+	if !ok || maxUploads > fakeMaxKeys {
+		req.error(s, http.StatusBadRequest, "InvalidArgument", "max-uploads is invalid.")
+		return
+	}
+
+	uploads := []*fakeUpload{}
+	for _, upload := range req.b.uploads {
+		if !strings.HasPrefix(upload.key, prefix) {
+			continue
+		}
+		if upload.key > keyMarker || (upload.key == keyMarker && idMarker != "" && upload.id > idMarker) {
+			uploads = append(uploads, upload)
+		}
+	}
+	sort.Sort(fakeUploads(uploads))
+
+	result := oss.ListMultipartUploadResult{
+		Bucket:         req.bucket,
+		Prefix:         req.encode(prefix),
+		Delimiter:      req.encode(delimiter),
+		KeyMarker:      req.encode(keyMarker),
+		UploadIDMarker: idMarker,
+		MaxUploads:     maxUploads,
+	}
+	count := 0
+	lastKey, lastID := "", ""
+	for _, upload := range uploads {
+		entry, isPrefix := fakeListEntry(upload.key, prefix, delimiter)
+		if isPrefix && (entry <= keyMarker || entry == lastKey) {
+			continue
+		}
+		if count == maxUploads {
+			result.IsTruncated = true
+			result.NextKeyMarker = req.encode(lastKey)
+			result.NextUploadIDMarker = lastID
+			break
+		}
+		count++
+		if isPrefix {
+			lastKey, lastID = entry, ""
+			result.CommonPrefixes = append(result.CommonPrefixes, req.encode(entry))
+			continue
+		}
+		lastKey, lastID = upload.key, upload.id
+		result.Uploads = append(result.Uploads, oss.UncompletedUpload{Key: req.encode(upload.key), UploadID: upload.id, Initiated: upload.initiated})
+	}
+	req.writeXML(http.StatusOK, result)
+}
+
+type fakeUploads []*fakeUpload
+
+func (u fakeUploads) Len() int      { return len(u) }
+func (u fakeUploads) Swap(i, j int) { u[i], u[j] = u[j], u[i] }
+func (u fakeUploads) Less(i, j int) bool {
+	if u[i].key != u[j].key {
+		return u[i].key < u[j].key
+	}
+	return u[i].id < u[j].id
+}
+
+func (s *fakeOSSServer) deleteMultipleObjects(req *fakeRequest) {
+	if !req.checkMD5(s) {
+		return
+	}
+	var del fakeDeleteRequest
+	if err := xml.Unmarshal(req.body, &del); err != nil || len(del.Keys) == 0 || len(del.Keys) > fakeMaxKeys {
+		req.error(s, http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed.")
+		return
+	}
+
+	result := oss.DeleteObjectsResult{}
+	for _, key := range del.Keys {
+		delete(req.b.objects, key)
+		if !del.Quiet {
+			result.DeletedObjects = append(result.DeletedObjects, req.encode(key))
+		}
+	}
+	req.writeXML(http.StatusOK, result)
+}
+
+// checkMD5 checks the Content-MD5 of request if it's specified
+func (req *fakeRequest) checkMD5(s *fakeOSSServer) bool {
+	contentMD5 := req.r.Header.Get(oss.HTTPHeaderContentMD5)
+	if contentMD5 == "" {
+		return true
+	}
+	sum := md5.Sum(req.body)
+	if contentMD5 != base64.StdEncoding.EncodeToString(sum[:]) {
+		req.error(s, http.StatusBadRequest, "InvalidDigest", "The Content-MD5 you specified is not valid.")
+		return false
+	}
+	return true
+}
+
+// uploadHeader gets the headers stored with object from request
+func (req *fakeRequest) uploadHeader() http.Header {
+	header := http.Header{}
+	for _, name := range fakeUploadHeaders {
+		if value := req.r.Header.Get(name); value != "" {
+			header.Set(name, value)
+		}
+	}
+	if header.Get(oss.HTTPHeaderContentType) == "" {
+		header.Set(oss.HTTPHeaderContentType, "application/octet-stream")
+	}
+	for name, values := range req.r.Header {
+		if strings.HasPrefix(strings.ToLower(name), strings.ToLower(oss.HTTPHeaderOssMetaPrefix)) {
+			header[name] = values
+		}
+	}
+	return header
+}
+
+// objectOptions gets acl and storage class of object from request
+func (req *fakeRequest) objectOptions(s *fakeOSSServer) (string, string, bool) {
+	acl := req.r.Header.Get(oss.HTTPHeaderOssObjectACL)
+	if acl == "" {
+		acl = "default"
+	}
+	if acl != "default" && !fakeValidACL(acl) {
+		req.error(s, http.StatusBadRequest, "InvalidArgument", "no such object access control exists")
+		return "", "", false
+	}
+	class := req.r.Header.Get(oss.HTTPHeaderOssStorageClass)
+	if class == "" {
+		class = req.b.storageClass
+	}
+	if !fakeValidStorageClass(class) {
+		req.error(s, http.StatusBadRequest, "InvalidArgument", "The storage class is invalid.")
+		return "", "", false
+	}
+	return acl, class, true
+}
+
+func (s *fakeOSSServer) putObject(req *fakeRequest) {
+	if !req.checkMD5(s) {
+		return
+	}
+	acl, class, ok := req.objectOptions(s)
+	if !ok {
+		return
+	}
+	obj := &fakeObject{
+		data:         req.body,
+		etag:         fakeETag(req.body),
+		objectType:   "Normal",
+		storageClass: class,
+		acl:          acl,
+		modified:     time.Now().UTC(),
+		header:       req.uploadHeader(),
+	}
+	req.b.objects[req.object] = obj
+	req.w.Header().Set(oss.HTTPHeaderEtag, obj.etag)
+	req.w.Header().Set(oss.HTTPHeaderOssCRC64, obj.crc64())
+	req.w.WriteHeader(http.StatusOK)
+}
+
+// copySource gets the source object of copy, the error is written if it does not exist or is
+// not readable
+func (s *fakeOSSServer) copySource(req *fakeRequest) (*fakeObject, bool) {
+	source := strings.TrimPrefix(req.r.Header.Get(oss.HTTPHeaderOssCopySource), "/")
+	pos := strings.Index(source, "/")
+	if pos == -1 {
+		req.error(s, http.StatusBadRequest, "InvalidArgument", "Copy Source must mention the source bucket and key: /sourcebucket/sourcekey.")
+		return nil, false
+	}
+	srcObject, err := url.QueryUnescape(source[pos+1:])
+	if err != nil {
+		req.error(s, http.StatusBadRequest, "InvalidArgument", "Copy Source is invalid.")
+		return nil, false
+	}
+	srcBucket := s.buckets[source[:pos]]
+	if srcBucket == nil {
+		req.error(s, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist.")
+		return nil, false
+	}
+	obj := srcBucket.objects[srcObject]
+	if obj == nil {
+		req.error(s, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return nil, false
+	}
+	if !obj.readable() {
+		req.error(s, http.StatusForbidden, "InvalidObjectState", "The operation is not valid for the object's state.")
+		return nil, false
+	}
+	return obj, true
+}
+
+func (s *fakeOSSServer) copyObject(req *fakeRequest) {
+	src, ok := s.copySource(req)
+	if !ok {
+		return
+	}
+	acl, class, ok := req.objectOptions(s)
+	if !ok {
+		return
+	}
+	if req.r.Header.Get(oss.HTTPHeaderOssStorageClass) == "" {
+		class = src.storageClass
+	}
+	// the acl is kept if an object is copied to itself without acl
+	self := src == req.b.objects[req.object]
+	if self && req.r.Header.Get(oss.HTTPHeaderOssObjectACL) == "" {
+		acl = src.acl
+	}
+
+	obj := *src
+	obj.acl = acl
+	obj.storageClass = class
+	obj.modified = time.Now().UTC()
+	obj.restoreExpiry = time.Time{}
+	// the meta is replaced if an object is copied to itself without directive, like oss does
+	directive := req.r.Header.Get(oss.HTTPHeaderOssMetadataDirective)
+	if strings.EqualFold(directive, string(oss.MetaReplace)) || (directive == "" && self) {
+		obj.header = req.uploadHeader()
+	}
+	req.b.objects[req.object] = &obj
+	req.writeXML(http.StatusOK, oss.CopyObjectResult{LastModified: obj.modified, ETag: obj.etag})
+}
+
+// lookupObject gets the object of request, the error is written if it does not exist
+func (s *fakeOSSServer) lookupObject(req *fakeRequest) (*fakeObject, bool) {
+	obj := req.b.objects[req.object]
+	if obj == nil {
+		req.error(s, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return nil, false
+	}
+	return obj, true
+}
+
+func (s *fakeOSSServer) putObjectACL(req *fakeRequest) {
+	obj, ok := s.lookupObject(req)
+	if !ok {
+		return
+	}
+	acl := req.r.Header.Get(oss.HTTPHeaderOssObjectACL)
+	if acl != "default" && !fakeValidACL(acl) {
+		req.error(s, http.StatusBadRequest, "InvalidArgument", "no such object access control exists")
+		return
+	}
+	obj.acl = acl
+	req.w.WriteHeader(http.StatusOK)
+}
+
+func (s *fakeOSSServer) getObjectACL(req *fakeRequest) {
+	obj, ok := s.lookupObject(req)
+	if !ok {
+		return
+	}
+	req.writeXML(http.StatusOK, oss.GetObjectACLResult{ACL: obj.acl, Owner: fakeOwner()})
+}
+
+func (s *fakeOSSServer) putSymlink(req *fakeRequest) {
+	target, err := url.QueryUnescape(req.r.Header.Get(oss.HTTPHeaderOssSymlinkTarget))
+	if err != nil || target == "" {
+		req.error(s, http.StatusBadRequest, "InvalidArgument", "The symlink target is invalid.")
+		return
+	}
+	acl, class, ok := req.objectOptions(s)
+	if !ok {
+		return
+	}
+	obj := &fakeObject{
+		etag:          fakeETag([]byte(target)),
+		objectType:    "Symlink",
+		storageClass:  class,
+		acl:           acl,
+		modified:      time.Now().UTC(),
+		header:        req.uploadHeader(),
+		symlinkTarget: target,
+	}
+	req.b.objects[req.object] = obj
+	req.w.Header().Set(oss.HTTPHeaderEtag, obj.etag)
+	req.w.WriteHeader(http.StatusOK)
+}
+
+func (s *fakeOSSServer) getSymlink(req *fakeRequest) {
+	obj, ok := s.lookupObject(req)
+	if !ok {
+		return
+	}
+	if obj.objectType != "Symlink" {
+		req.error(s, http.StatusBadRequest, "NotSymlink", "The specified object is not a symlink.")
+		return
+	}
+	req.w.Header().Set(oss.HTTPHeaderOssSymlinkTarget, url.QueryEscape(obj.symlinkTarget))
+	req.w.Header().Set(oss.HTTPHeaderEtag, obj.etag)
+	req.w.Header().Set(oss.HTTPHeaderLastModified, obj.modified.Format(http.TimeFormat))
+	req.w.WriteHeader(http.StatusOK)
+}
+
+// getObject serves GetObject and HeadObject, a symlink is resolved to its target
+func (s *fakeOSSServer) getObject(req *fakeRequest) {
+	obj, ok := s.lookupObject(req)
+	if !ok {
+		return
+	}
+	objectType := obj.objectType
+	if obj.objectType == "Symlink" {
+		target := req.b.objects[obj.symlinkTarget]
+		if target == nil || target.objectType == "Symlink" {
+			req.error(s, http.StatusNotFound, "SymlinkTargetNotExist", "The symlink target object does not exist.")
+			return
+		}
+		obj = target
+	}
+	if req.r.Method == "GET" && !obj.readable() {
+		req.error(s, http.StatusForbidden, "InvalidObjectState", "The operation is not valid for the object's state.")
+		return
+	}
+	if status := req.checkConditions(obj); status != 0 {
+		if status == http.StatusPreconditionFailed {
+			req.error(s, status, "PreconditionFailed", "At least one of the pre-conditions you specified did not hold.")
+		} else {
+			req.w.WriteHeader(status)
+		}
+		return
+	}
+
+	header := req.w.Header()
+	for name, values := range obj.header {
+		header[name] = values
+	}
+	header.Set(oss.HTTPHeaderEtag, obj.etag)
+	header.Set(oss.HTTPHeaderLastModified, obj.modified.Format(http.TimeFormat))
+	header.Set("X-Oss-Object-Type", objectType)
+	header.Set(oss.HTTPHeaderOssStorageClass, obj.storageClass)
+	header.Set(oss.HTTPHeaderOssCRC64, obj.crc64())
+	header.Set("Accept-Ranges", "bytes")
+	if restore := obj.restoreHeader(); restore != "" {
+		header.Set(OssRestoreHeader, restore)
+	}
+
+	data := obj.data
+	status := http.StatusOK
+	rng := req.r.Header.Get(oss.HTTPHeaderRange)
+	// the range of the last 0 byte can not be satisfied
+	if strings.SplitN(rng, ",", 2)[0] == "bytes=-0" {
+		req.error(s, http.StatusRequestedRangeNotSatisfiable, "InvalidRange", "The requested range cannot be satisfied.")
+		return
+	}
+	if start, end, ok := fakeParseRange(rng, int64(len(data))); ok {
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		header.Del(oss.HTTPHeaderOssCRC64)
+		data = data[start : end+1]
+		status = http.StatusPartialContent
+	}
+	header.Set(oss.HTTPHeaderContentLength, strconv.Itoa(len(data)))
+	req.w.WriteHeader(status)
+	if req.r.Method == "GET" {
+		req.w.Write(data)
+	}
+}
+
+// checkConditions checks the conditional headers, it returns the status if not satisfied
+func (req *fakeRequest) checkConditions(obj *fakeObject) int {
+	header := req.r.Header
+	if match := header.Get(oss.HTTPHeaderIfMatch); match != "" && match != obj.etag {
+		return http.StatusPreconditionFailed
+	}
+	if since, err := http.ParseTime(header.Get(oss.HTTPHeaderIfUnmodifiedSince)); err == nil && obj.modified.Truncate(time.Second).After(since) {
+		return http.StatusPreconditionFailed
+	}
+	if match := header.Get(oss.HTTPHeaderIfNoneMatch); match != "" && match == obj.etag {
+		return http.StatusNotModified
+	}
+	if since, err := http.ParseTime(header.Get(oss.HTTPHeaderIfModifiedSince)); err == nil && !obj.modified.Truncate(time.Second).After(since) {
+		return http.StatusNotModified
+	}
+	return 0
+}
+
+// fakeParseRange parses the range like bytes=0-9, bytes=10- or bytes=-10, only the first range is
+// used if multiple ranges are specified. ok is false if the range is invalid or out of the object,
+// which is ignored like oss does, then the whole object is returned
+func fakeParseRange(value string, size int64) (int64, int64, bool) {
+	if !strings.HasPrefix(value, "bytes=") {
+		return 0, 0, false
+	}
+	value = strings.SplitN(strings.TrimPrefix(value, "bytes="), ",", 2)[0]
+	parts := strings.SplitN(value, "-", 2)
+	if len(parts) != 2 || (parts[0] == "" && parts[1] == "") {
+		return 0, 0, false
+	}
+	if parts[0] == "" {
+		n, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, false
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, size > 0
+	}
+	start, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || start >= size {
+		return 0, 0, false
+	}
+	end := size - 1
+	if parts[1] != "" {
+		if end, err = strconv.ParseInt(parts[1], 10, 64); err != nil || end < start || end >= size {
+			return 0, 0, false
+		}
+	}
+	return start, end, true
+}
+
+func (s *fakeOSSServer) getObjectMeta(req *fakeRequest) {
+	obj, ok := s.lookupObject(req)
+	if !ok {
+		return
+	}
+	header := req.w.Header()
+	header.Set(oss.HTTPHeaderEtag, obj.etag)
+	header.Set(oss.HTTPHeaderLastModified, obj.modified.Format(http.TimeFormat))
+	header.Set(oss.HTTPHeaderContentLength, strconv.Itoa(len(obj.data)))
+	req.w.WriteHeader(http.StatusOK)
+}
+
+func (s *fakeOSSServer) deleteObject(req *fakeRequest) {
+	delete(req.b.objects, req.object)
+	req.w.WriteHeader(http.StatusNoContent)
+}
+
+// restoreObject starts restoring an archive object, it's restored after restoreDuration and
+// readable for the days in request, 1 day by default
+func (s *fakeOSSServer) restoreObject(req *fakeRequest) {
+	obj, ok := s.lookupObject(req)
+	if !ok {
+		return
+	}
+	if obj.storageClass != StorageArchive {
+		req.error(s, http.StatusBadRequest, "OperationNotSupported", "The operation is not supported for this resource.")
+		return
+	}
+	now := time.Now().UTC()
+	if !obj.restoreExpiry.IsZero() && obj.restoreStart().After(now) {
+		req.error(s, http.StatusConflict, "RestoreAlreadyInProgress", "The restore operation is in progress.")
+		return
+	}
+
+	config := restoreConfiguration{Days: 1}
+	if len(req.body) > 0 {
+		if err := xml.Unmarshal(req.body, &config); err != nil {
+			req.error(s, http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed.")
+			return
+		}
+	}
+	if config.Days <= 0 {
+		config.Days = 1
+	}
+
+	status := http.StatusAccepted
+	start := now.Add(s.restoreDuration)
+	if !obj.restoreExpiry.IsZero() && obj.restoreExpiry.After(now) {
+		// extend the restored object
+		status = http.StatusOK
+		start = now
+	}
+	obj.restoreExpiry = start.Add(time.Duration(config.Days) * 24 * time.Hour)
+	obj.restoreDays = config.Days
+	req.w.WriteHeader(status)
+}
+
+func (obj *fakeObject) restoreStart() time.Time {
+	return obj.restoreExpiry.Add(-time.Duration(obj.restoreDays) * 24 * time.Hour)
+}
+
+// readable returns false if the object is archive and not restored
+func (obj *fakeObject) readable() bool {
+	if obj.storageClass != StorageArchive {
+		return true
+	}
+	now := time.Now().UTC()
+	return !obj.restoreExpiry.IsZero() && !obj.restoreStart().After(now) && obj.restoreExpiry.After(now)
+}
+
+func (obj *fakeObject) restoreHeader() string {
+	now := time.Now().UTC()
+	if obj.restoreExpiry.IsZero() || !obj.restoreExpiry.After(now) {
+		return ""
+	}
+	if obj.restoreStart().After(now) {
+		return `ongoing-request="true"`
+	}
+	return fmt.Sprintf(`ongoing-request="false", expiry-date="%s"`, obj.restoreExpiry.Format(http.TimeFormat))
+}
+
+func (obj *fakeObject) crc64() string {
+	return strconv.FormatUint(crc64.Checksum(obj.data, crc64.MakeTable(crc64.ECMA)), 10)
+}
+
+func (s *fakeOSSServer) initiateMultipartUpload(req *fakeRequest) {
+	if _, _, ok := req.objectOptions(s); !ok {
+		return
+	}
+	s.uploadID++
+	upload := &fakeUpload{
+		key:       req.object,
+		id:        fmt.Sprintf("%032X", s.uploadID),
+		initiated: time.Now().UTC(),
+		header:    req.uploadHeader(),
+		parts:     map[int]*fakePart{},
+	}
+	for _, name := range []string{oss.HTTPHeaderOssObjectACL, oss.HTTPHeaderOssStorageClass} {
+		if value := req.r.Header.Get(name); value != "" {
+			upload.header.Set(name, value)
+		}
+	}
+	req.b.uploads[upload.id] = upload
+	req.writeXML(http.StatusOK, oss.InitiateMultipartUploadResult{Bucket: req.bucket, Key: req.encode(req.object), UploadID: upload.id})
+}
+
+// lookupUpload gets the upload of request, the error is written if it does not exist
+func (s *fakeOSSServer) lookupUpload(req *fakeRequest) (*fakeUpload, bool) {
+	upload := req.b.uploads[req.query.Get("uploadId")]
+	if upload == nil || upload.key != req.object {
+		req.error(s, http.StatusNotFound, "NoSuchUpload", "The specified upload does not exist.")
+		return nil, false
+	}
+	return upload, true
+}
+
+// uploadPart serves UploadPart and UploadPartCopy
+func (s *fakeOSSServer) uploadPart(req *fakeRequest) {
+	upload, ok := s.lookupUpload(req)
+	if !ok {
+		return
+	}
+	number, err := strconv.Atoi(req.query.Get("partNumber"))
+	if err != nil || number < 1 || number > 10000 {
+		req.error(s, http.StatusBadRequest, "InvalidArgument", "Part number must be an integer between 1 and 10000, inclusive.")
+		return
+	}
+
+	data := req.body
+	copied := req.r.Header.Get(oss.HTTPHeaderOssCopySource) != ""
+	if copied {
+		src, ok := s.copySource(req)
+		if !ok {
+			return
+		}
+		data = src.data
+		if start, end, ok := fakeParseRange(req.r.Header.Get(oss.HTTPHeaderOssCopySourceRange), int64(len(data))); ok {
+			data = data[start : end+1]
+		}
+	} else if !req.checkMD5(s) {
+		return
+	}
+
+	part := &fakePart{data: data, etag: fakeETag(data), modified: time.Now().UTC()}
+	upload.parts[number] = part
+	if copied {
+		req.writeXML(http.StatusOK, oss.UploadPartCopyResult{LastModified: part.modified, ETag: part.etag})
+		return
+	}
+	req.w.Header().Set(oss.HTTPHeaderEtag, part.etag)
+	req.w.WriteHeader(http.StatusOK)
+}
+
+func (s *fakeOSSServer) listParts(req *fakeRequest) {
+	upload, ok := s.lookupUpload(req)
+	if !ok {
+		return
+	}
+	marker, ok := req.intParam("part-number-marker", 0)
+	maxParts, ok2 := req.intParam("max-parts", fakeMaxKeys)
+	if !ok || !ok2 || maxParts > fakeMaxKeys {
+		req.error(s, http.StatusBadRequest, "InvalidArgument", "The part number marker or max parts is invalid.")
+		return
+	}
+
+	numbers := []int{}
+	for number := range upload.parts {
+		if number > marker {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Ints(numbers)
+
+	result := oss.ListUploadedPartsResult{Bucket: req.bucket, Key: req.encode(upload.key), UploadID: upload.id, MaxParts: maxParts}
+	if len(numbers) > maxParts {
+		numbers = numbers[:maxParts]
+		result.IsTruncated = true
+		result.NextPartNumberMarker = strconv.Itoa(numbers[maxParts-1])
+	}
+	for _, number := range numbers {
+		part := upload.parts[number]
+		result.UploadedParts = append(result.UploadedParts, oss.UploadedPart{PartNumber: number, LastModified: part.modified, ETag: part.etag, Size: len(part.data)})
+	}
+	req.writeXML(http.StatusOK, result)
+}
+
+func (s *fakeOSSServer) completeMultipartUpload(req *fakeRequest) {
+	upload, ok := s.lookupUpload(req)
+	if !ok {
+		return
+	}
+	var complete fakeCompleteRequest
+	if err := xml.Unmarshal(req.body, &complete); err != nil || len(complete.Parts) == 0 {
+		req.error(s, http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed.")
+		return
+	}
+
+	var data bytes.Buffer
+	sums := []byte{}
+	for i, p := range complete.Parts {
+		part := upload.parts[p.PartNumber]
+		if part == nil || !strings.EqualFold(strings.Trim(p.ETag, "\""), strings.Trim(part.etag, "\"")) {
+			req.error(s, http.StatusBadRequest, "InvalidPart", "One or more of the specified parts could not be found or the specified entity tag might not have matched the part's entity tag.")
+			return
+		}
+		if i > 0 && p.PartNumber <= complete.Parts[i-1].PartNumber {
+			req.error(s, http.StatusBadRequest, "InvalidPartOrder", "The list of parts was not in ascending order.")
+			return
+		}
+		if i < len(complete.Parts)-1 && len(part.data) < fakeMinPartSize {
+			req.error(s, http.StatusBadRequest, "EntityTooSmall", "Your proposed upload is smaller than the minimum allowed size.")
+			return
+		}
+		data.Write(part.data)
+		sum := md5.Sum(part.data)
+		sums = append(sums, sum[:]...)
+	}
+
+	sum := md5.Sum(sums)
+	obj := &fakeObject{
+		data:         data.Bytes(),
+		etag:         fmt.Sprintf("\"%s-%d\"", strings.ToUpper(hex.EncodeToString(sum[:])), len(complete.Parts)),
+		objectType:   "Multipart",
+		storageClass: req.b.storageClass,
+		acl:          "default",
+		modified:     time.Now().UTC(),
+		header:       upload.header,
+	}
+	if acl := upload.header.Get(oss.HTTPHeaderOssObjectACL); acl != "" {
+		obj.acl = acl
+		upload.header.Del(oss.HTTPHeaderOssObjectACL)
+	}
+	if class := upload.header.Get(oss.HTTPHeaderOssStorageClass); class != "" {
+		obj.storageClass = class
+		upload.header.Del(oss.HTTPHeaderOssStorageClass)
+	}
+	req.b.objects[upload.key] = obj
+	delete(req.b.uploads, upload.id)
+
+	req.w.Header().Set(oss.HTTPHeaderOssCRC64, obj.crc64())
+	req.writeXML(http.StatusOK, oss.CompleteMultipartUploadResult{
+		Location: "http://" + s.endpoint() + "/" + req.bucket + "/" + url.QueryEscape(upload.key),
+		Bucket:   req.bucket,
+		ETag:     obj.etag,
+		Key:      req.encode(upload.key),
+	})
+}
+
+func (s *fakeOSSServer) abortMultipartUpload(req *fakeRequest) {
+	upload, ok := s.lookupUpload(req)
+	if !ok {
+		return
+	}
+	delete(req.b.uploads, upload.id)
+	req.w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *OssutilCommandSuite) TestFakeOSSFaults(c *C) {
+	server := newFakeOSSServer("faultID", "faultSecret")
+	defer server.close()
+
+	client, err := oss.New(server.endpoint(), "faultID", "faultSecret", oss.Timeout(1, 1))
+	c.Assert(err, IsNil)
+	bucketName := bucketNamePrefix + randLowStr(10)
+	err = client.CreateBucket(bucketName)
+	c.Assert(err, IsNil)
+	bucket, err := client.Bucket(bucketName)
+	c.Assert(err, IsNil)
+	err = bucket.PutObject("object", strings.NewReader("data"))
+	c.Assert(err, IsNil)
+
+	// 5xx and throttling are retried by command
+	server.injectServerError("ListObjects", 1)
+	server.injectThrottle("ListObjects", 1)
+	retryTimes := "3"
+	cmd := Command{options: OptionMapType{OptionRetryTimes: &retryTimes}}
+	lor, err := cmd.ossListObjectsRetry(bucket)
+	c.Assert(err, IsNil)
+	c.Assert(len(lor.Objects), Equals, 1)
+	c.Assert(server.requestCount("ListObjects"), Equals, 3)
+
+	server.injectThrottle("GetObject", 0)
+	_, err = bucket.GetObject("object")
+	c.Assert(err.(oss.ServiceError).StatusCode, Equals, http.StatusServiceUnavailable)
+	c.Assert(err.(oss.ServiceError).Code, Equals, "SlowDown")
+	server.clearFaults()
+
+	// timeout
+	server.injectTimeout("GetObjectMeta", 2*time.Second, 1)
+	_, err = bucket.GetObjectMeta("object")
+	c.Assert(err, NotNil)
+	_, err = bucket.GetObjectMeta("object")
+	c.Assert(err, IsNil)
+
+	// wrong key
+	client, err = oss.New(server.endpoint(), "faultID", "wrongSecret")
+	c.Assert(err, IsNil)
+	_, err = client.GetBucketACL(bucketName)
+	c.Assert(err.(oss.ServiceError).Code, Equals, "SignatureDoesNotMatch")
+}
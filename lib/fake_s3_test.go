@@ -0,0 +1,286 @@
+package lib
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// fakeS3Server is an in-process s3 emulator serving the read apis used by migration, the requests
+// are authenticated with signature version 4. Like s3, the next marker of list objects is returned
+// only if delimiter is specified.
+type fakeS3Server struct {
+	server          *httptest.Server
+	accessKeyID     string
+	accessKeySecret string
+	region          string
+
+	// maxKeys limits the keys of each page, so that the suites can list in pages with few objects
+	maxKeys int
+
+	mu       sync.Mutex
+	buckets  map[string]map[string]*fakeObject
+	faults   []*fakeS3Fault
+	requests map[string]int
+}
+
+// fakeS3Fault makes the requests of op fail with status, the range of request must be equal to rng
+// if it's not empty. The fault is removed after it fails times requests.
+type fakeS3Fault struct {
+	op     string
+	rng    string
+	status int
+	times  int
+}
+
+func newFakeS3Server(accessKeyID, accessKeySecret, region string) *fakeS3Server {
+	s := &fakeS3Server{
+		accessKeyID:     accessKeyID,
+		accessKeySecret: accessKeySecret,
+		region:          region,
+		maxKeys:         S3MaxKeys,
+		buckets:         map[string]map[string]*fakeObject{},
+		requests:        map[string]int{},
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func (s *fakeS3Server) endpoint() string {
+	return s.server.URL
+}
+
+func (s *fakeS3Server) close() {
+	s.server.Close()
+}
+
+func (s *fakeS3Server) putBucket(bucket string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buckets[bucket] = map[string]*fakeObject{}
+}
+
+// putObject puts the object with the user meta, which is set as X-Amz-Meta- headers
+func (s *fakeS3Server) putObject(bucket, object, data string, meta map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	header := http.Header{}
+	header.Set(oss.HTTPHeaderContentType, "text/plain")
+	for name, val := range meta {
+		header.Set(S3MetaPrefix+name, val)
+	}
+	s.buckets[bucket][object] = &fakeObject{data: []byte(data), etag: fakeETag([]byte(data)), storageClass: "STANDARD", modified: time.Now(), header: header}
+}
+
+func (s *fakeS3Server) inject(fault fakeS3Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault)
+}
+
+func (s *fakeS3Server) requestCount(op string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[op]
+}
+
+func (s *fakeS3Server) writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set(s3HeaderRequstID, "fake-s3-request")
+	w.WriteHeader(status)
+	body, _ := xml.Marshal(s3Error{Code: code, Message: code})
+	w.Write(body)
+}
+
+func (s *fakeS3Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	sli := strings.SplitN(path, "/", 2)
+	bucket, object := sli[0], ""
+	if len(sli) > 1 {
+		object = sli[1]
+	}
+	op := "GetObject"
+	switch {
+	case bucket == "":
+		op = "ListBuckets"
+	case object == "":
+		op = "ListObjects"
+	case r.Method == "HEAD":
+		op = "HeadObject"
+	}
+
+	if !s.authenticate(r) {
+		s.writeError(w, http.StatusForbidden, "SignatureDoesNotMatch")
+		return
+	}
+	for i, fault := range s.faults {
+		if fault.op == op && (fault.rng == "" || fault.rng == r.Header.Get("Range")) {
+			if fault.times--; fault.times <= 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+			s.writeError(w, fault.status, http.StatusText(fault.status))
+			return
+		}
+	}
+	s.requests[op]++
+
+	if op == "ListBuckets" {
+		s.listBuckets(w)
+		return
+	}
+	objects, ok := s.buckets[bucket]
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	if op == "ListObjects" {
+		s.listObjects(w, r, objects)
+		return
+	}
+	obj, ok := objects[object]
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+	s.getObject(w, r, obj)
+}
+
+// authenticate checks the signature of request by signing the signed headers again
+func (s *fakeS3Server) authenticate(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	now, err := time.Parse(s3DateFormat, r.Header.Get(s3HeaderDate))
+	if err != nil {
+		return false
+	}
+	pos := strings.Index(auth, "SignedHeaders=")
+	if pos == -1 {
+		return false
+	}
+	signedHeaders := strings.SplitN(auth[pos+len("SignedHeaders="):], ",", 2)[0]
+
+	req, err := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+	if err != nil {
+		return false
+	}
+	for _, name := range strings.Split(signedHeaders, ";") {
+		if name != "host" {
+			req.Header[http.CanonicalHeaderKey(name)] = r.Header[http.CanonicalHeaderKey(name)]
+		}
+	}
+	client := &s3Client{accessKeyID: s.accessKeyID, accessKeySecret: s.accessKeySecret, sessionToken: r.Header.Get(s3HeaderToken), region: s.region}
+	client.sign(req, now)
+	return req.Header.Get("Authorization") == auth
+}
+
+func (s *fakeS3Server) writeXML(w http.ResponseWriter, v interface{}) {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "InternalError")
+		return
+	}
+	w.Header().Set(oss.HTTPHeaderContentType, "application/xml")
+	w.Write([]byte(xml.Header))
+	w.Write(body)
+}
+
+func (s *fakeS3Server) listBuckets(w http.ResponseWriter) {
+	names := []string{}
+	for name := range s.buckets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := oss.ListBucketsResult{Owner: fakeOwner()}
+	for _, name := range names {
+		result.Buckets = append(result.Buckets, oss.BucketProperties{Name: name, CreationDate: time.Now().UTC()})
+	}
+	s.writeXML(w, result)
+}
+
+func (s *fakeS3Server) listObjects(w http.ResponseWriter, r *http.Request, objects map[string]*fakeObject) {
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	marker := query.Get("marker")
+	delimiter := query.Get("delimiter")
+	maxKeys, err := strconv.Atoi(query.Get("max-keys"))
+	if err != nil || maxKeys > s.maxKeys {
+		maxKeys = s.maxKeys
+	}
+
+	keys := []string{}
+	for key := range objects {
+		if strings.HasPrefix(key, prefix) && key > marker {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	result := oss.ListObjectsResult{Prefix: prefix, Marker: marker, MaxKeys: maxKeys, Delimiter: delimiter}
+	count := 0
+	last := ""
+	for _, key := range keys {
+		entry, isPrefix := fakeListEntry(key, prefix, delimiter)
+		if entry <= marker || (isPrefix && entry == last) {
+			continue
+		}
+		if count == maxKeys {
+			result.IsTruncated = true
+			if delimiter != "" {
+				result.NextMarker = last
+			}
+			break
+		}
+		count++
+		last = entry
+		if isPrefix {
+			result.CommonPrefixes = append(result.CommonPrefixes, entry)
+			continue
+		}
+		obj := objects[key]
+		result.Objects = append(result.Objects, oss.ObjectProperties{
+			Key:          key,
+			Size:         int64(len(obj.data)),
+			ETag:         obj.etag,
+			Owner:        fakeOwner(),
+			LastModified: obj.modified,
+			StorageClass: obj.storageClass,
+		})
+	}
+	s.writeXML(w, result)
+}
+
+func (s *fakeS3Server) getObject(w http.ResponseWriter, r *http.Request, obj *fakeObject) {
+	if match := r.Header.Get(oss.HTTPHeaderIfMatch); match != "" && match != obj.etag {
+		s.writeError(w, http.StatusPreconditionFailed, "PreconditionFailed")
+		return
+	}
+
+	header := w.Header()
+	for name, values := range obj.header {
+		header[name] = values
+	}
+	header.Set(oss.HTTPHeaderEtag, obj.etag)
+	header.Set(oss.HTTPHeaderLastModified, obj.modified.UTC().Format(http.TimeFormat))
+
+	data := obj.data
+	status := http.StatusOK
+	if start, end, ok := fakeParseRange(r.Header.Get("Range"), int64(len(data))); ok {
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		data = data[start : end+1]
+		status = http.StatusPartialContent
+	}
+	header.Set(oss.HTTPHeaderContentLength, strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	if r.Method == "GET" {
+		w.Write(data)
+	}
+}
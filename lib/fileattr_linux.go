@@ -0,0 +1,25 @@
+// +build linux
+
+package lib
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileOwner returns uid and gid of local file
+func fileOwner(f os.FileInfo) (int, int, bool) {
+	if st, ok := f.Sys().(*syscall.Stat_t); ok {
+		return int(st.Uid), int(st.Gid), true
+	}
+	return 0, 0, false
+}
+
+// fileAccessTime returns atime of local file
+func fileAccessTime(f os.FileInfo) (time.Time, bool) {
+	if st, ok := f.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(int64(st.Atim.Sec), int64(st.Atim.Nsec)), true
+	}
+	return time.Time{}, false
+}
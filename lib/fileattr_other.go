@@ -0,0 +1,18 @@
+// +build !linux
+
+package lib
+
+import (
+	"os"
+	"time"
+)
+
+// fileOwner returns uid and gid of local file, not supported on the platform
+func fileOwner(f os.FileInfo) (int, int, bool) {
+	return 0, 0, false
+}
+
+// fileAccessTime returns atime of local file, not supported on the platform
+func fileAccessTime(f os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
@@ -0,0 +1,371 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+var specChineseGetACL = SpecText{
+
+	synopsisText: "查看bucket或者objects的acl，审计公开访问的objects",
+
+	paramText: "cloud_url [options]",
+
+	syntaxText: `
+    ossutil get-acl oss://bucket[/prefix] [-r] [--summary] [--output-format=text|json] [--exit-code] [-j num] [-c file]
+`,
+
+	detailHelpText: `
+    该命令查看bucket或者objects的acl。object的acl为default时，表示继承bucket的acl，此时
+    object实际生效的acl为bucket的acl。
+
+    对每个object，ossutil输出其acl，实际生效的acl，以及需要关注的标记：
+        public：实际生效的acl为public-read或public-read-write，任何人都可以读取该object；
+        differ：object设置了与bucket acl不同的acl。
+
+--summary选项
+
+    如果指定了该选项，ossutil只输出带有标记的object，并输出统计信息，适用于定期的安全审计。
+
+--output-format选项
+
+    输出的格式，取值为text或json，默认为text。json格式输出一个对象，包含bucket、prefix、
+    bucketACL、bucketPublic、objects、total、public和differ字段，objects中的每一项包含key、
+    acl、effectiveACL、public和differ字段。
+
+--exit-code选项
+
+    如果指定了该选项，当bucket为公开访问，或者存在带有标记的object时，命令以非0值退出。
+
+用法:
+
+    该命令有三种用法：
+
+    1) ossutil get-acl oss://bucket [--output-format=text|json]
+        查看bucket的acl。
+
+    2) ossutil get-acl oss://bucket/object [--output-format=text|json]
+        查看单个object的acl，当object不存在时，ossutil会提示错误。
+
+    3) ossutil get-acl oss://bucket[/prefix] -r [--summary] [--output-format=text|json] [--exit-code] [-j num]
+        查看所有前缀匹配cloud_url的objects的acl，-j选项指定并发获取acl的数目。
+`,
+
+	sampleText: `
+    1) ossutil get-acl oss://bucket1
+
+    2) ossutil get-acl oss://bucket1/obj1
+
+    3) ossutil get-acl oss://bucket1/dir -r
+
+    4) 审计bucket中公开访问的objects，以json格式输出
+        ossutil get-acl oss://bucket1 -r --summary --output-format=json --exit-code
+`,
+}
+
+var specEnglishGetACL = SpecText{
+
+	synopsisText: "Get acl of bucket or objects, audit publicly accessible objects",
+
+	paramText: "cloud_url [options]",
+
+	syntaxText: `
+    ossutil get-acl oss://bucket[/prefix] [-r] [--summary] [--output-format=text|json] [--exit-code] [-j num] [-c file]
+`,
+
+	detailHelpText: `
+    The command gets acl of the specified bucket or objects. If the acl of an object is
+    default, the object inherits the acl of bucket, and the effective acl of the object is
+    the bucket acl.
+
+    For each object, ossutil prints its acl, effective acl, and the flags:
+        public: the effective acl is public-read or public-read-write, anyone can read the
+        object;
+        differ: the object has an acl different from the bucket acl.
+
+--summary option
+
+    If the option is specified, ossutil only prints the flagged objects and the statistic,
+    which is suitable for scheduled security audit.
+
+--output-format option
+
+    Format of output, value is text or json, default is text. Output of json format is an
+    object, which contains bucket, prefix, bucketACL, bucketPublic, objects, total, public
+    and differ fields, each item of objects contains key, acl, effectiveACL, public and
+    differ fields.
+
+--exit-code option
+
+    If the option is specified, the command exits with non-zero value when the bucket is
+    publicly accessible, or there are flagged objects.
+
+Usage:
+
+    There are three usages:
+
+    1) ossutil get-acl oss://bucket [--output-format=text|json]
+        Get acl of the bucket.
+
+    2) ossutil get-acl oss://bucket/object [--output-format=text|json]
+        Get acl of the single object, if the object does not exist, error occurs.
+
+    3) ossutil get-acl oss://bucket[/prefix] -r [--summary] [--output-format=text|json] [--exit-code] [-j num]
+        Get acl of all prefix-matching objects, -j option specifies the number of concurrent
+    requests to get acl.
+`,
+
+	sampleText: `
+    1) ossutil get-acl oss://bucket1
+
+    2) ossutil get-acl oss://bucket1/obj1
+
+    3) ossutil get-acl oss://bucket1/dir -r
+
+    4) Audit publicly accessible objects of bucket, and output in json format
+        ossutil get-acl oss://bucket1 -r --summary --output-format=json --exit-code
+`,
+}
+
+type aclEntry struct {
+	Key          string `json:"key"`
+	ACL          string `json:"acl"`
+	EffectiveACL string `json:"effectiveACL"`
+	Public       bool   `json:"public"`
+	Differ       bool   `json:"differ"`
+}
+
+type aclEntrySlice []aclEntry
+
+func (s aclEntrySlice) Len() int           { return len(s) }
+func (s aclEntrySlice) Less(i, j int) bool { return s[i].Key < s[j].Key }
+func (s aclEntrySlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+type aclResult struct {
+	Bucket       string     `json:"bucket"`
+	Prefix       string     `json:"prefix"`
+	BucketACL    string     `json:"bucketACL"`
+	BucketPublic bool       `json:"bucketPublic"`
+	Objects      []aclEntry `json:"objects"`
+	Total        int64      `json:"total"`
+	Public       int64      `json:"public"`
+	Differ       int64      `json:"differ"`
+}
+
+type getACLOptionType struct {
+	recursive bool
+	summary   bool
+	format    string
+	exitCode  bool
+	routines  int64
+}
+
+// GetACLCommand is the command to get acl of bucket or objects
+type GetACLCommand struct {
+	command   Command
+	aclOption getACLOptionType
+}
+
+var getACLCommand = GetACLCommand{
+	command: Command{
+		name:        "get-acl",
+		nameAlias:   []string{"getacl", "get_acl"},
+		minArgc:     1,
+		maxArgc:     1,
+		specChinese: specChineseGetACL,
+		specEnglish: specEnglishGetACL,
+		group:       GroupTypeNormalCommand,
+		validOptionNames: []string{
+			OptionRecursion,
+			OptionSummary,
+			OptionOutputFormat,
+			OptionExitCode,
+			OptionEncodingType,
+			OptionConfigFile,
+			OptionEndpoint,
+			OptionAccessKeyID,
+			OptionAccessKeySecret,
+			OptionSTSToken,
+			OptionProxyHost,
+			OptionProxyUser,
+			OptionProxyPwd,
+			OptionCAFile,
+			OptionSkipVerify,
+			OptionForceHTTPS,
+			OptionConnectTimeout,
+			OptionReadTimeout,
+			OptionRetryTimes,
+			OptionRoutines,
+		},
+	},
+}
+
+// function for FormatHelper interface
+func (gc *GetACLCommand) formatHelpForWhole() string {
+	return gc.command.formatHelpForWhole()
+}
+
+func (gc *GetACLCommand) formatIndependHelp() string {
+	return gc.command.formatIndependHelp()
+}
+
+// Init simulate inheritance, and polymorphism
+func (gc *GetACLCommand) Init(args []string, options OptionMapType) error {
+	return gc.command.Init(args, options, gc)
+}
+
+// RunCommand simulate inheritance, and polymorphism
+func (gc *GetACLCommand) RunCommand() error {
+	gc.aclOption.recursive, _ = GetBool(OptionRecursion, gc.command.options)
+	gc.aclOption.summary, _ = GetBool(OptionSummary, gc.command.options)
+	gc.aclOption.format, _ = GetString(OptionOutputFormat, gc.command.options)
+	gc.aclOption.exitCode, _ = GetBool(OptionExitCode, gc.command.options)
+	gc.aclOption.routines, _ = GetInt(OptionRoutines, gc.command.options)
+	if gc.aclOption.routines <= 0 {
+		gc.aclOption.routines = int64(Routines)
+	}
+	encodingType, _ := GetString(OptionEncodingType, gc.command.options)
+
+	cloudURL, err := CloudURLFromString(gc.command.args[0], encodingType)
+	if err != nil {
+		return err
+	}
+	if cloudURL.bucket == "" {
+		return fmt.Errorf("invalid cloud url: %s, miss bucket", gc.command.args[0])
+	}
+
+	bucket, err := gc.command.ossBucket(cloudURL.bucket)
+	if err != nil {
+		return err
+	}
+
+	gbar, err := gc.command.ossGetBucketACLRetry(&bucket.Client, cloudURL.bucket)
+	if err != nil {
+		return err
+	}
+	result := aclResult{Bucket: cloudURL.bucket, Prefix: cloudURL.object, BucketACL: gbar.ACL, BucketPublic: isPublicACL(gbar.ACL), Objects: []aclEntry{}}
+
+	if gc.aclOption.recursive {
+		err = gc.getObjectACLs(bucket, cloudURL, &result)
+	} else if cloudURL.object != "" {
+		err = gc.getObjectACL(bucket, cloudURL.object, &result)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := gc.printResult(result); err != nil {
+		return err
+	}
+
+	if gc.aclOption.exitCode && (result.BucketPublic || result.Public > 0 || result.Differ > 0) {
+		return fmt.Errorf("flagged acls found in %s, bucket acl: %s, public objects: %d, objects differ from bucket acl: %d", CloudURLToString(result.Bucket, result.Prefix), result.BucketACL, result.Public, result.Differ)
+	}
+	return nil
+}
+
+// isPublicACL shows if anyone can read with the acl
+func isPublicACL(acl string) bool {
+	return acl == string(oss.ACLPublicRead) || acl == string(oss.ACLPublicReadWrite)
+}
+
+// newACLEntry computes the effective acl and the flags of object
+func newACLEntry(key, acl, bucketACL string) aclEntry {
+	entry := aclEntry{Key: key, ACL: acl, EffectiveACL: acl}
+	if acl == "" || acl == string(oss.ACLDefault) {
+		entry.EffectiveACL = bucketACL
+	} else {
+		entry.Differ = acl != bucketACL
+	}
+	entry.Public = isPublicACL(entry.EffectiveACL)
+	return entry
+}
+
+func (gc *GetACLCommand) addEntry(result *aclResult, entry aclEntry) {
+	result.Total++
+	if entry.Public {
+		result.Public++
+	}
+	if entry.Differ {
+		result.Differ++
+	}
+	if !gc.aclOption.summary || entry.Public || entry.Differ {
+		result.Objects = append(result.Objects, entry)
+	}
+}
+
+func (gc *GetACLCommand) getObjectACL(bucket *oss.Bucket, object string, result *aclResult) error {
+	goar, err := gc.command.ossGetObjectACLRetry(bucket, object)
+	if err != nil {
+		return err
+	}
+	gc.addEntry(result, newACLEntry(object, goar.ACL, result.BucketACL))
+	return nil
+}
+
+// getObjectACLs gets acl of the prefix-matching objects concurrently, it stops at the first error
+func (gc *GetACLCommand) getObjectACLs(bucket *oss.Bucket, cloudURL CloudURL, result *aclResult) error {
+	var mu sync.Mutex
+	engine := gc.command.newBatchEngine(&batchOptionType{}, gc.aclOption.routines, gc.command.objectSource(bucket, cloudURL, nil, nil), func(item batchItem) (string, error) {
+		msg := fmt.Sprintf("get acl of %s", CloudURLToString(bucket.BucketName, item.key))
+		goar, err := gc.command.ossGetObjectACLRetry(bucket, item.key)
+		if err != nil {
+			// the object is deleted after listed
+			if isNotExist(err) {
+				return msg, nil
+			}
+			return msg, err
+		}
+		mu.Lock()
+		gc.addEntry(result, newACLEntry(item.key, goar.ACL, result.BucketACL))
+		mu.Unlock()
+		return msg, nil
+	})
+
+	err := engine.run()
+	if err == nil {
+		err = gc.command.cancelError()
+	}
+
+	sort.Sort(aclEntrySlice(result.Objects))
+	return err
+}
+
+func (gc *GetACLCommand) printResult(result aclResult) error {
+	if gc.aclOption.format == OutputFormatJSON {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	bucketFlag := ""
+	if result.BucketPublic {
+		bucketFlag = " (public)"
+	}
+	fmt.Printf("%-18s: %s%s\n", "Bucket ACL", result.BucketACL, bucketFlag)
+	if !gc.aclOption.recursive && result.Prefix == "" {
+		return nil
+	}
+
+	fmt.Printf("\n%-18s %-18s %-14s %s\n", "ACL", "EffectiveACL", "Flags", "ObjectName")
+	for _, entry := range result.Objects {
+		flags := []string{}
+		if entry.Public {
+			flags = append(flags, "public")
+		}
+		if entry.Differ {
+			flags = append(flags, "differ")
+		}
+		fmt.Printf("%-18s %-18s %-14s %s\n", entry.ACL, entry.EffectiveACL, strings.Join(flags, ","), CloudURLToString(result.Bucket, entry.Key))
+	}
+	fmt.Printf("\ntotal: %d, public: %d, differ from bucket acl: %d\n\n", result.Total, result.Public, result.Differ)
+	return nil
+}
@@ -0,0 +1,127 @@
+package lib
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	. "gopkg.in/check.v1"
+)
+
+func (s *OssutilCommandSuite) rawGetACL(args []string, recursive, summary, exitCode bool, format string) (string, error) {
+	command := "get-acl"
+	str := ""
+	routines := strconv.Itoa(Routines)
+	options := OptionMapType{
+		"endpoint":        &str,
+		"accessKeyID":     &str,
+		"accessKeySecret": &str,
+		"stsToken":        &str,
+		"configFile":      &configFile,
+		"recursive":       &recursive,
+		"summary":         &summary,
+		"exitCode":        &exitCode,
+		"outputFormat":    &format,
+		"routines":        &routines,
+	}
+
+	testResultFile, _ = os.OpenFile(resultPath, os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0664)
+	out := os.Stdout
+	os.Stdout = testResultFile
+	_, err := cm.RunCommand(command, args, options)
+	os.Stdout = out
+
+	data, _ := ioutil.ReadFile(resultPath)
+	os.Remove(resultPath)
+	return string(data), err
+}
+
+func (s *OssutilCommandSuite) TestGetACL(c *C) {
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+	s.setBucketACL(bucketName, string(oss.ACLPrivate), c)
+
+	prefix := "acl/"
+	s.createFile(uploadFileName, randStr(20), c)
+	for _, name := range []string{"default", "private", "public-read"} {
+		s.putObject(bucketName, prefix+name, uploadFileName, c)
+	}
+	s.setObjectACL(bucketName, prefix+"private", string(oss.ACLPrivate), false, true, c)
+	s.setObjectACL(bucketName, prefix+"public-read", string(oss.ACLPublicRead), false, true, c)
+
+	// bucket acl
+	out, err := s.rawGetACL([]string{CloudURLToString(bucketName, "")}, false, false, false, OutputFormatText)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(out, string(oss.ACLPrivate)), Equals, true)
+
+	// single object
+	out, err = s.rawGetACL([]string{CloudURLToString(bucketName, prefix+"public-read")}, false, false, false, OutputFormatJSON)
+	c.Assert(err, IsNil)
+	var result aclResult
+	c.Assert(json.Unmarshal([]byte(out), &result), IsNil)
+	c.Assert(len(result.Objects), Equals, 1)
+	c.Assert(result.Objects[0], Equals, aclEntry{prefix + "public-read", string(oss.ACLPublicRead), string(oss.ACLPublicRead), true, true})
+
+	// recursive
+	out, err = s.rawGetACL([]string{CloudURLToString(bucketName, prefix)}, true, false, false, OutputFormatJSON)
+	c.Assert(err, IsNil)
+	result = aclResult{}
+	c.Assert(json.Unmarshal([]byte(out), &result), IsNil)
+	c.Assert(result.BucketACL, Equals, string(oss.ACLPrivate))
+	c.Assert(result.BucketPublic, Equals, false)
+	c.Assert(result.Total, Equals, int64(3))
+	c.Assert(result.Public, Equals, int64(1))
+	c.Assert(result.Differ, Equals, int64(1))
+	c.Assert(len(result.Objects), Equals, 3)
+	c.Assert(result.Objects[0], Equals, aclEntry{prefix + "default", string(oss.ACLDefault), string(oss.ACLPrivate), false, false})
+	c.Assert(result.Objects[1], Equals, aclEntry{prefix + "private", string(oss.ACLPrivate), string(oss.ACLPrivate), false, false})
+
+	// summary only shows flagged objects
+	out, err = s.rawGetACL([]string{CloudURLToString(bucketName, prefix)}, true, true, true, OutputFormatText)
+	c.Assert(err, NotNil)
+	c.Assert(strings.Contains(out, CloudURLToString(bucketName, prefix+"public-read")), Equals, true)
+	c.Assert(strings.Contains(out, CloudURLToString(bucketName, prefix+"private")), Equals, false)
+	c.Assert(strings.Contains(out, "total: 3, public: 1, differ from bucket acl: 1"), Equals, true)
+
+	// no flagged object
+	s.setObjectACL(bucketName, prefix+"public-read", string(oss.ACLDefault), false, true, c)
+	_, err = s.rawGetACL([]string{CloudURLToString(bucketName, prefix)}, true, true, true, OutputFormatText)
+	c.Assert(err, IsNil)
+
+	// public bucket
+	s.setBucketACL(bucketName, string(oss.ACLPublicRead), c)
+	out, err = s.rawGetACL([]string{CloudURLToString(bucketName, prefix)}, true, true, false, OutputFormatJSON)
+	c.Assert(err, IsNil)
+	result = aclResult{}
+	c.Assert(json.Unmarshal([]byte(out), &result), IsNil)
+	c.Assert(result.BucketPublic, Equals, true)
+	c.Assert(result.Public, Equals, int64(2))
+	c.Assert(result.Differ, Equals, int64(1))
+
+	s.removeBucket(bucketName, true, c)
+}
+
+func (s *OssutilCommandSuite) TestGetACLErrArgs(c *C) {
+	_, err := s.rawGetACL([]string{CloudURLToString("", "")}, false, false, false, OutputFormatText)
+	c.Assert(err, NotNil)
+
+	_, err = s.rawGetACL([]string{CloudURLToString(bucketNamePrefix+randLowStr(10), randStr(5))}, false, false, false, OutputFormatText)
+	c.Assert(err, NotNil)
+}
+
+func (s *OssutilCommandSuite) TestNewACLEntry(c *C) {
+	private := string(oss.ACLPrivate)
+	publicRead := string(oss.ACLPublicRead)
+	publicReadWrite := string(oss.ACLPublicReadWrite)
+	def := string(oss.ACLDefault)
+
+	c.Assert(newACLEntry("a", def, private), Equals, aclEntry{"a", def, private, false, false})
+	c.Assert(newACLEntry("a", def, publicReadWrite), Equals, aclEntry{"a", def, publicReadWrite, true, false})
+	c.Assert(newACLEntry("a", private, private), Equals, aclEntry{"a", private, private, false, false})
+	c.Assert(newACLEntry("a", private, publicRead), Equals, aclEntry{"a", private, private, false, true})
+	c.Assert(newACLEntry("a", publicRead, private), Equals, aclEntry{"a", publicRead, publicRead, true, true})
+	c.Assert(newACLEntry("a", publicRead, publicRead), Equals, aclEntry{"a", publicRead, publicRead, true, false})
+}
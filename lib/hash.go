@@ -185,13 +185,19 @@ func hashMD5(f io.Reader) error {
 }
 
 func hashCRC64(f io.Reader) error {
-	crc64Ins := crc64.New(crc64.MakeTable(crc64.ECMA))
-	w, _ := crc64Ins.(hash.Hash)
-	if _, err := io.Copy(w, f); err != nil {
+	result, err := calcCRC64(f)
+	if err != nil {
 		return err
 	}
-
-	result := crc64Ins.Sum64()
 	fmt.Printf("%-28s: %d\n", HashCRC64, result)
 	return nil
 }
+
+func calcCRC64(f io.Reader) (uint64, error) {
+	crc64Ins := crc64.New(crc64.MakeTable(crc64.ECMA))
+	w, _ := crc64Ins.(hash.Hash)
+	if _, err := io.Copy(w, f); err != nil {
+		return 0, err
+	}
+	return crc64Ins.Sum64(), nil
+}
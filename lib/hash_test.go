@@ -79,3 +79,56 @@ func (s *OssutilCommandSuite) TestMd5(c *C) {
 
 	os.Remove(inputFileName)
 }
+
+func (s *OssutilCommandSuite) TestChecksumCache(c *C) {
+	fileName := "ossutil_test_checksum_" + randStr(5)
+	s.createFile(fileName, "this is content", c)
+	defer os.Remove(fileName)
+
+	f, _ := os.Open(fileName)
+	expect, err := calcCRC64(f)
+	f.Close()
+	c.Assert(err, IsNil)
+
+	cache := openChecksumCache()
+	crc, err := cache.fileCRC64(fileName)
+	c.Assert(err, IsNil)
+	c.Assert(crc, Equals, expect)
+
+	// read from cache
+	crc, err = cache.fileCRC64(fileName)
+	c.Assert(err, IsNil)
+	c.Assert(crc, Equals, expect)
+
+	// content changed with the same size and modify time, as a corrupt download
+	st, err := os.Stat(fileName)
+	c.Assert(err, IsNil)
+	s.createFile(fileName, "this is CONTENT", c)
+	c.Assert(os.Chtimes(fileName, st.ModTime(), st.ModTime()), IsNil)
+	crc, err = cache.fileCRC64(fileName)
+	c.Assert(err, IsNil)
+	c.Assert(crc, Equals, expect)
+
+	// compute ignores the cache and updates it
+	f, _ = os.Open(fileName)
+	changed, err := calcCRC64(f)
+	f.Close()
+	c.Assert(err, IsNil)
+	c.Assert(changed != expect, Equals, true)
+	crc, err = cache.computeCRC64(fileName)
+	c.Assert(err, IsNil)
+	c.Assert(crc, Equals, changed)
+	crc, err = cache.fileCRC64(fileName)
+	c.Assert(err, IsNil)
+	c.Assert(crc, Equals, changed)
+	cache.close()
+
+	// nil cache computes every time
+	var nilCache *checksumCache
+	crc, err = nilCache.fileCRC64(fileName)
+	c.Assert(err, IsNil)
+	c.Assert(crc, Equals, changed)
+
+	_, err = nilCache.fileCRC64(fileName + "notexist")
+	c.Assert(err, NotNil)
+}
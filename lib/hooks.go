@@ -0,0 +1,244 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// the events of hooks
+const (
+	hookEventSuccess = "success"
+	hookEventError   = "error"
+	hookEventFinish  = "finish"
+)
+
+// hookOutputLimit is the max length of hook output recorded when the hook fails
+const hookOutputLimit = 512
+
+// hookItem is the detail of an item passed to --on-success and --on-error hooks, by environment
+// variables and json on stdin
+type hookItem struct {
+	Event       string `json:"event"`
+	Command     string `json:"command"`
+	Source      string `json:"source"`
+	Destination string `json:"destination,omitempty"`
+	Size        int64  `json:"size"`
+	ETag        string `json:"etag,omitempty"`
+	CRC64       string `json:"crc64,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+func (item hookItem) env() []string {
+	return []string{
+		"OSSUTIL_EVENT=" + item.Event,
+		"OSSUTIL_COMMAND=" + item.Command,
+		"OSSUTIL_SOURCE=" + item.Source,
+		"OSSUTIL_DESTINATION=" + item.Destination,
+		"OSSUTIL_SIZE=" + strconv.FormatInt(item.Size, 10),
+		"OSSUTIL_ETAG=" + item.ETag,
+		"OSSUTIL_CRC64=" + item.CRC64,
+		"OSSUTIL_ERROR=" + item.Error,
+	}
+}
+
+// hookSummary is passed to --on-finish hook when the job finishes
+type hookSummary struct {
+	Event     string `json:"event"`
+	Command   string `json:"command"`
+	Succeeded int64  `json:"succeeded"`
+	Failed    int64  `json:"failed"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (summary hookSummary) env() []string {
+	return []string{
+		"OSSUTIL_EVENT=" + summary.Event,
+		"OSSUTIL_COMMAND=" + summary.Command,
+		"OSSUTIL_SUCCEEDED=" + strconv.FormatInt(summary.Succeeded, 10),
+		"OSSUTIL_FAILED=" + strconv.FormatInt(summary.Failed, 10),
+		"OSSUTIL_ERROR=" + summary.Error,
+	}
+}
+
+// itemHooks runs the hooks of a batch job. The item hooks run in background, at most --hook-routines
+// of them at the same time, the item waits if there are too many. The failures of hooks are
+// recorded in the reporter of job, or shown when the job finishes if there is no reporter.
+type itemHooks struct {
+	command   *Command
+	onSuccess string
+	onError   string
+	onFinish  string
+	timeout   time.Duration
+	sem       chan struct{}
+	wg        sync.WaitGroup
+
+	succeeded  int64
+	failed     int64
+	hookFailed int64
+
+	// failures are the failures of hooks kept to show if there is no reporter
+	mu       sync.Mutex
+	failures []string
+}
+
+// newItemHooks returns nil if no hook is specified, the methods of nil hooks do nothing
+func (cmd *Command) newItemHooks() *itemHooks {
+	onSuccess, _ := GetString(OptionOnSuccess, cmd.options)
+	onError, _ := GetString(OptionOnError, cmd.options)
+	onFinish, _ := GetString(OptionOnFinish, cmd.options)
+	if onSuccess == "" && onError == "" && onFinish == "" {
+		return nil
+	}
+
+	routines, err := GetInt(OptionHookRoutines, cmd.options)
+	if err != nil {
+		routines = DefaultHookRoutines
+	}
+	timeout, err := GetInt(OptionHookTimeout, cmd.options)
+	if err != nil {
+		timeout = DefaultHookTimeout
+	}
+	return &itemHooks{
+		command:   cmd,
+		onSuccess: onSuccess,
+		onError:   onError,
+		onFinish:  onFinish,
+		timeout:   time.Duration(timeout) * time.Second,
+		sem:       make(chan struct{}, routines),
+	}
+}
+
+// objectHookItem makes the hook item of the commands on objects, the key of item is the object
+func objectHookItem(bucket *oss.Bucket) func(item batchItem) hookItem {
+	return func(item batchItem) hookItem {
+		return hookItem{Source: CloudURLToString(bucket.BucketName, item.key)}
+	}
+}
+
+// done runs --on-success or --on-error hook for the item according to err
+func (h *itemHooks) done(option *batchOptionType, item hookItem, err error) {
+	if h == nil {
+		return
+	}
+
+	item.Command = h.command.name
+	item.Event = hookEventSuccess
+	cmdline := h.onSuccess
+	if err != nil {
+		atomic.AddInt64(&h.failed, 1)
+		item.Event = hookEventError
+		item.Error = err.Error()
+		cmdline = h.onError
+	} else {
+		atomic.AddInt64(&h.succeeded, 1)
+	}
+	if cmdline == "" {
+		return
+	}
+
+	h.sem <- struct{}{}
+	h.wg.Add(1)
+	go func() {
+		defer func() {
+			<-h.sem
+			h.wg.Done()
+		}()
+		h.run(option, cmdline, item.env(), item, fmt.Sprintf("hook %s of %s", item.Event, item.Source))
+	}()
+}
+
+// finish waits for the item hooks, then runs --on-finish hook with the result of job
+func (h *itemHooks) finish(option *batchOptionType, err error) {
+	if h == nil {
+		return
+	}
+	h.wg.Wait()
+
+	if h.onFinish != "" {
+		summary := hookSummary{
+			Event:     hookEventFinish,
+			Command:   h.command.name,
+			Succeeded: atomic.LoadInt64(&h.succeeded),
+			Failed:    atomic.LoadInt64(&h.failed),
+		}
+		if err != nil {
+			summary.Error = err.Error()
+		}
+		h.run(option, h.onFinish, summary.env(), summary, fmt.Sprintf("hook %s of %s", hookEventFinish, h.command.name))
+	}
+
+	failed := atomic.LoadInt64(&h.hookFailed)
+	if failed == 0 {
+		return
+	}
+	if option.reporter != nil {
+		h.command.printf("\n%d hooks failed, see more information in file: %s\n", failed, option.reporter.path)
+		return
+	}
+	for _, failure := range h.failures {
+		h.command.printf("\n%s\n", failure)
+	}
+}
+
+// run runs the hook by shell with the environment variables and json of v on stdin, the hook is
+// killed after timeout
+func (h *itemHooks) run(option *batchOptionType, cmdline string, env []string, v interface{}, name string) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		h.fail(option, fmt.Sprintf("%s error, info: %s", name, err.Error()))
+		return
+	}
+
+	var out bytes.Buffer
+	cmd := hookCommand(cmdline)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		h.fail(option, fmt.Sprintf("%s error, info: %s", name, err.Error()))
+		return
+	}
+
+	chErr := make(chan error, 1)
+	go func() {
+		chErr <- cmd.Wait()
+	}()
+	timer := time.NewTimer(h.timeout)
+	defer timer.Stop()
+	select {
+	case err = <-chErr:
+	case <-timer.C:
+		// the children of hook are killed too, otherwise the output held by them blocks Wait forever
+		killHook(cmd)
+		h.fail(option, fmt.Sprintf("%s error, info: timeout after %s", name, h.timeout))
+		return
+	}
+	if err != nil {
+		output := strings.TrimSpace(out.String())
+		if len(output) > hookOutputLimit {
+			output = "..." + output[len(output)-hookOutputLimit:]
+		}
+		h.fail(option, fmt.Sprintf("%s error, info: %s, output: %s", name, err.Error(), output))
+	}
+}
+
+func (h *itemHooks) fail(option *batchOptionType, msg string) {
+	atomic.AddInt64(&h.hookFailed, 1)
+	if option.reporter != nil {
+		option.reporter.ReportError(msg)
+		return
+	}
+	h.mu.Lock()
+	h.failures = append(h.failures, msg)
+	h.mu.Unlock()
+}
@@ -0,0 +1,21 @@
+// +build !windows
+
+package lib
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// hookCommand runs the hook by sh in a new process group, so that the children of hook can be
+// killed with it
+func hookCommand(cmdline string) *exec.Cmd {
+	cmd := exec.Command("sh", "-c", cmdline)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd
+}
+
+// killHook kills the process group of hook, the children holding the output of hook are killed too
+func killHook(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,307 @@
+package lib
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	. "gopkg.in/check.v1"
+)
+
+// appendHook returns the hook appending the json on stdin to logFile as a line, in one write since
+// the hooks run concurrently
+func appendHook(logFile string) string {
+	return `echo "$(cat)" >> ` + logFile
+}
+
+// setHookOptions sets the hook options, the hooks append the json on stdin to logFile
+func setHookOptions(options OptionMapType, logFile string) {
+	for _, name := range []string{OptionOnSuccess, OptionOnError, OptionOnFinish} {
+		cmdline := appendHook(logFile)
+		options[name] = &cmdline
+	}
+}
+
+// readHookLog returns the hook items and summaries in logFile, the items are sorted by source
+func (s *OssutilCommandSuite) readHookLog(logFile string, c *C) ([]hookItem, []hookSummary) {
+	data, err := ioutil.ReadFile(logFile)
+	c.Assert(err, IsNil)
+	items := []hookItem{}
+	summaries := []hookSummary{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if strings.Contains(line, `"event":"finish"`) {
+			var summary hookSummary
+			c.Assert(json.Unmarshal([]byte(line), &summary), IsNil)
+			summaries = append(summaries, summary)
+			continue
+		}
+		var item hookItem
+		c.Assert(json.Unmarshal([]byte(line), &item), IsNil)
+		items = append(items, item)
+	}
+	sort.Sort(hookItems(items))
+	return items, summaries
+}
+
+type hookItems []hookItem
+
+func (items hookItems) Len() int           { return len(items) }
+func (items hookItems) Swap(i, j int)      { items[i], items[j] = items[j], items[i] }
+func (items hookItems) Less(i, j int) bool { return items[i].Source < items[j].Source }
+
+func (s *OssutilCommandSuite) TestCopyHooks(c *C) {
+	dir := randStr(10)
+	c.Assert(os.MkdirAll(dir, 0755), IsNil)
+	defer os.RemoveAll(dir)
+	s.createFile(dir+"/a", "aaa", c)
+	s.createFile(dir+"/b", "bbbbb", c)
+	logFile, _ := filepath.Abs(randStr(10) + ".log")
+	defer os.Remove(logFile)
+
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+	defer s.removeBucket(bucketName, true, c)
+	bucket := s.getStreamSourceBucket(bucketName, c)
+
+	err := s.initCopyCommand(dir, CloudURLToString(bucketName, "hook/"), true, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	setHookOptions(copyCommand.command.options, logFile)
+	c.Assert(copyCommand.RunCommand(), IsNil)
+
+	items, summaries := s.readHookLog(logFile, c)
+	c.Assert(len(items), Equals, 2)
+	for i, name := range []string{"a", "b"} {
+		props, err := bucket.GetObjectDetailedMeta("hook/" + name)
+		c.Assert(err, IsNil)
+		c.Assert(items[i].Event, Equals, hookEventSuccess)
+		c.Assert(items[i].Command, Equals, "cp")
+		c.Assert(items[i].Source, Equals, filepath.Join(dir, name))
+		c.Assert(items[i].Destination, Equals, CloudURLToString(bucketName, "hook/"+name))
+		c.Assert(items[i].ETag, Equals, strings.Trim(props.Get(oss.HTTPHeaderEtag), "\""))
+		c.Assert(items[i].CRC64, Equals, props.Get(oss.HTTPHeaderOssCRC64))
+	}
+	c.Assert(items[0].Size, Equals, int64(3))
+	c.Assert(items[1].Size, Equals, int64(5))
+	c.Assert(summaries, DeepEquals, []hookSummary{{Event: hookEventFinish, Command: "cp", Succeeded: 2}})
+
+	// the skipped files have no hooks
+	os.Remove(logFile)
+	err = s.initCopyCommand(dir, CloudURLToString(bucketName, "hook/"), true, true, true, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	setHookOptions(copyCommand.command.options, logFile)
+	c.Assert(copyCommand.RunCommand(), IsNil)
+	items, summaries = s.readHookLog(logFile, c)
+	c.Assert(len(items), Equals, 0)
+	c.Assert(summaries, DeepEquals, []hookSummary{{Event: hookEventFinish, Command: "cp"}})
+
+	// download
+	os.Remove(logFile)
+	downDir := randStr(10)
+	defer os.RemoveAll(downDir)
+	err = s.initCopyCommand(CloudURLToString(bucketName, "hook/"), downDir, true, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	setHookOptions(copyCommand.command.options, logFile)
+	c.Assert(copyCommand.RunCommand(), IsNil)
+	items, _ = s.readHookLog(logFile, c)
+	c.Assert(len(items), Equals, 2)
+	c.Assert(items[0].Source, Equals, CloudURLToString(bucketName, "hook/a"))
+	c.Assert(items[0].Destination, Equals, filepath.Join(downDir, "hook", "a"))
+	c.Assert(items[0].Size, Equals, int64(3))
+	c.Assert(items[0].CRC64 != "", Equals, true)
+}
+
+func (s *OssutilCommandSuite) TestCopyHooksFailure(c *C) {
+	server := newFakeOSSServer("hookID", "hookSecret")
+	defer server.close()
+	client, err := oss.New(server.endpoint(), "hookID", "hookSecret")
+	c.Assert(err, IsNil)
+	bucketName := bucketNamePrefix + randLowStr(10)
+	c.Assert(client.CreateBucket(bucketName), IsNil)
+	server.inject(fakeOSSFault{op: "PutObject", object: "bad", status: http.StatusForbidden, code: "Forbidden"})
+
+	dir := randStr(10)
+	c.Assert(os.MkdirAll(dir, 0755), IsNil)
+	defer os.RemoveAll(dir)
+	s.createFile(dir+"/good", "good", c)
+	s.createFile(dir+"/bad", "bad", c)
+	logFile, _ := filepath.Abs(randStr(10) + ".log")
+	defer os.Remove(logFile)
+	outputDir := randStr(10)
+	defer os.RemoveAll(outputDir)
+
+	err = s.initCopyCommand(dir, CloudURLToString(bucketName, ""), true, true, false, DefaultBigFileThreshold, CheckpointDir, outputDir)
+	c.Assert(err, IsNil)
+	str := ""
+	ep, id, secret := server.endpoint(), "hookID", "hookSecret"
+	retryTimes := "1"
+	onSuccess := "exit 3"
+	onError := appendHook(logFile) + " && sleep 10"
+	timeout := "1"
+	for name, val := range map[string]*string{
+		OptionEndpoint:        &ep,
+		OptionAccessKeyID:     &id,
+		OptionAccessKeySecret: &secret,
+		OptionSTSToken:        &str,
+		OptionRetryTimes:      &retryTimes,
+		OptionOnSuccess:       &onSuccess,
+		OptionOnError:         &onError,
+		OptionHookTimeout:     &timeout,
+	} {
+		copyCommand.command.options[name] = val
+	}
+	c.Assert(copyCommand.RunCommand(), IsNil)
+
+	// the error is passed to hook
+	items, _ := s.readHookLog(logFile, c)
+	c.Assert(len(items), Equals, 1)
+	c.Assert(items[0].Event, Equals, hookEventError)
+	c.Assert(items[0].Source, Equals, filepath.Join(dir, "bad"))
+	c.Assert(strings.Contains(items[0].Error, "Forbidden"), Equals, true)
+
+	// the failures of hooks are reported, the results of files are not affected
+	c.Assert(copyCommand.monitor.fileNum, Equals, int64(1))
+	c.Assert(copyCommand.monitor.errNum, Equals, int64(1))
+	files, err := ioutil.ReadDir(outputDir)
+	c.Assert(err, IsNil)
+	c.Assert(len(files), Equals, 1)
+	report := s.readFile(filepath.Join(outputDir, files[0].Name()), c)
+	c.Assert(strings.Contains(report, "hook success of "+filepath.Join(dir, "good")+" error, info: exit status 3"), Equals, true)
+	c.Assert(strings.Contains(report, "hook error of "+filepath.Join(dir, "bad")+" error, info: timeout after 1s"), Equals, true)
+}
+
+func (s *OssutilCommandSuite) TestSetACLHooks(c *C) {
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+	defer s.removeBucket(bucketName, true, c)
+	bucket := s.getStreamSourceBucket(bucketName, c)
+	for _, object := range []string{"acl/1", "acl/2"} {
+		c.Assert(bucket.PutObject(object, strings.NewReader("data")), IsNil)
+	}
+	logFile, _ := filepath.Abs(randStr(10) + ".log")
+	defer os.Remove(logFile)
+
+	err := s.initSetACL(bucketName, "acl/", "private", true, false, true)
+	c.Assert(err, IsNil)
+	setHookOptions(setACLCommand.command.options, logFile)
+	c.Assert(setACLCommand.RunCommand(), IsNil)
+
+	items, summaries := s.readHookLog(logFile, c)
+	c.Assert(items, DeepEquals, []hookItem{
+		{Event: hookEventSuccess, Command: "set-acl", Source: CloudURLToString(bucketName, "acl/1")},
+		{Event: hookEventSuccess, Command: "set-acl", Source: CloudURLToString(bucketName, "acl/2")},
+	})
+	c.Assert(summaries, DeepEquals, []hookSummary{{Event: hookEventFinish, Command: "set-acl", Succeeded: 2}})
+}
+
+func (s *OssutilCommandSuite) TestHookTimeoutKillChildren(c *C) {
+	if runtime.GOOS != "linux" {
+		c.Skip("the process is checked in /proc")
+	}
+	pidFile, _ := filepath.Abs(randStr(10) + ".pid")
+	defer os.Remove(pidFile)
+
+	// the child of hook holds the output of hook
+	h := &itemHooks{command: &Command{name: "cp"}, timeout: time.Second}
+	start := time.Now()
+	h.run(&batchOptionType{}, "sleep 30 & echo $! > "+pidFile+"; wait", nil, hookSummary{}, "hook test")
+	c.Assert(time.Since(start) < 10*time.Second, Equals, true)
+	c.Assert(len(h.failures), Equals, 1)
+	c.Assert(strings.Contains(h.failures[0], "timeout after 1s"), Equals, true)
+
+	// the child is killed, it may be a zombie if nobody waits for it
+	pid := strings.TrimSpace(s.readFile(pidFile, c))
+	alive := true
+	for i := 0; i < 50 && alive; i++ {
+		stat, err := ioutil.ReadFile("/proc/" + pid + "/stat")
+		alive = err == nil && !strings.Contains(string(stat), ") Z ")
+		time.Sleep(100 * time.Millisecond)
+	}
+	c.Assert(alive, Equals, false)
+}
+
+func (s *OssutilCommandSuite) TestRemoveAndSymlinkHooks(c *C) {
+	server := newFakeOSSServer("hookID", "hookSecret")
+	defer server.close()
+	client, err := oss.New(server.endpoint(), "hookID", "hookSecret")
+	c.Assert(err, IsNil)
+	bucketName := bucketNamePrefix + randLowStr(10)
+	c.Assert(client.CreateBucket(bucketName), IsNil)
+	bucket, err := client.Bucket(bucketName)
+	c.Assert(err, IsNil)
+	c.Assert(bucket.PutObject("object", strings.NewReader("data")), IsNil)
+
+	run := func(command string, args []string, options OptionMapType) ([]hookItem, []hookSummary, error) {
+		logFile, _ := filepath.Abs(randStr(10) + ".log")
+		defer os.Remove(logFile)
+		str := ""
+		ep, id, secret := server.endpoint(), "hookID", "hookSecret"
+		retryTimes := "1"
+		for name, val := range map[string]*string{
+			OptionEndpoint:        &ep,
+			OptionAccessKeyID:     &id,
+			OptionAccessKeySecret: &secret,
+			OptionSTSToken:        &str,
+			OptionConfigFile:      &configFile,
+			OptionRetryTimes:      &retryTimes,
+		} {
+			options[name] = val
+		}
+		setHookOptions(options, logFile)
+		_, err := cm.RunCommand(command, args, options)
+		items, summaries := s.readHookLog(logFile, c)
+		return items, summaries, err
+	}
+
+	// create symlinks with manifest
+	manifest := randStr(10)
+	defer os.Remove(manifest)
+	s.createFile(manifest, "link1 object\nlink2 object\n", c)
+	items, summaries, err := run("create-symlink", []string{CloudURLToString(bucketName, "")}, OptionMapType{OptionManifest: &manifest})
+	c.Assert(err, IsNil)
+	c.Assert(items, DeepEquals, []hookItem{
+		{Event: hookEventSuccess, Command: "create-symlink", Source: CloudURLToString(bucketName, "link1"), Destination: "object"},
+		{Event: hookEventSuccess, Command: "create-symlink", Source: CloudURLToString(bucketName, "link2"), Destination: "object"},
+	})
+	c.Assert(summaries, DeepEquals, []hookSummary{{Event: hookEventFinish, Command: "create-symlink", Succeeded: 2}})
+
+	// read symlinks recursively
+	recursive := true
+	items, summaries, err = run("read-symlink", []string{CloudURLToString(bucketName, "link")}, OptionMapType{OptionRecursion: &recursive})
+	c.Assert(err, IsNil)
+	c.Assert(items, DeepEquals, []hookItem{
+		{Event: hookEventSuccess, Command: "read-symlink", Source: CloudURLToString(bucketName, "link1")},
+		{Event: hookEventSuccess, Command: "read-symlink", Source: CloudURLToString(bucketName, "link2")},
+	})
+	c.Assert(summaries, DeepEquals, []hookSummary{{Event: hookEventFinish, Command: "read-symlink", Succeeded: 2}})
+
+	// the failed objects of rm
+	force := true
+	server.inject(fakeOSSFault{op: "DeleteMultipleObjects", status: http.StatusForbidden, code: "AccessDenied", times: 1})
+	items, summaries, err = run("rm", []string{CloudURLToString(bucketName, "link")}, OptionMapType{OptionRecursion: &recursive, OptionForce: &force})
+	c.Assert(err, NotNil)
+	c.Assert(len(items), Equals, 2)
+	for i, object := range []string{"link1", "link2"} {
+		c.Assert(items[i].Event, Equals, hookEventError)
+		c.Assert(items[i].Source, Equals, CloudURLToString(bucketName, object))
+		c.Assert(strings.Contains(items[i].Error, "AccessDenied"), Equals, true)
+	}
+	c.Assert(len(summaries), Equals, 1)
+	c.Assert(summaries[0].Failed, Equals, int64(2))
+
+	// rm objects recursively
+	items, summaries, err = run("rm", []string{CloudURLToString(bucketName, "")}, OptionMapType{OptionRecursion: &recursive, OptionForce: &force})
+	c.Assert(err, IsNil)
+	c.Assert(items, DeepEquals, []hookItem{
+		{Event: hookEventSuccess, Command: "rm", Source: CloudURLToString(bucketName, "link1")},
+		{Event: hookEventSuccess, Command: "rm", Source: CloudURLToString(bucketName, "link2")},
+		{Event: hookEventSuccess, Command: "rm", Source: CloudURLToString(bucketName, "object")},
+	})
+	c.Assert(summaries, DeepEquals, []hookSummary{{Event: hookEventFinish, Command: "rm", Succeeded: 3}})
+}
@@ -0,0 +1,19 @@
+// +build windows
+
+package lib
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// hookCommand runs the hook by cmd
+func hookCommand(cmdline string) *exec.Cmd {
+	return exec.Command("cmd", "/C", cmdline)
+}
+
+// killHook kills the process tree of hook, the children holding the output of hook are killed too
+func killHook(cmd *exec.Cmd) {
+	exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+	cmd.Process.Kill()
+}
@@ -0,0 +1,159 @@
+package lib
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	leveldb "github.com/syndtr/goleveldb/leveldb"
+)
+
+const (
+	journalMarkerKey  = "m"
+	journalDonePrefix = "d"
+)
+
+type journalPage struct {
+	nextMarker string
+	keys       []string
+	pending    int
+	failed     bool
+}
+
+// jobJournal records the progress of a recursive batch job under checkpoint dir,
+// so that an interrupted job can continue where it stopped when run again.
+// The journal records the listing marker before which all objects were dealed,
+// and the items finished after the marker.
+type jobJournal struct {
+	mu     sync.Mutex
+	db     *leveldb.DB
+	path   string
+	cpDir  string
+	pages  []*journalPage
+	keyMap map[string]*journalPage
+}
+
+// openJobJournal open the journal of the job identified by command name and args
+func openJobJournal(cpDir, name string, args []string) (*jobJournal, error) {
+	if cpDir == "" {
+		cpDir = CheckpointDir
+	}
+	if err := os.MkdirAll(cpDir, 0755); err != nil {
+		return nil, err
+	}
+
+	wd, _ := os.Getwd()
+	sum := md5.Sum([]byte(strings.Join(append([]string{wd, name}, args...), "\n")))
+	path := cpDir + string(os.PathSeparator) + JobJournalPrefix + hex.EncodeToString(sum[:])
+
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("load job journal error, reason: %s", err.Error())
+	}
+	return &jobJournal{db: db, path: path, cpDir: cpDir, keyMap: map[string]*journalPage{}}, nil
+}
+
+// marker returns the listing marker to continue the job from
+func (j *jobJournal) marker() string {
+	if j == nil {
+		return ""
+	}
+	val, err := j.db.Get([]byte(journalMarkerKey), nil)
+	if err != nil {
+		return ""
+	}
+	return string(val)
+}
+
+// setMarker records that all items before marker have been dealed
+func (j *jobJournal) setMarker(marker string) {
+	if j == nil {
+		return
+	}
+	j.db.Put([]byte(journalMarkerKey), []byte(marker), nil)
+}
+
+// addPage registers a listed page, the marker moves to nextMarker when all keys of the page
+// and of the pages before it are done successfully
+func (j *jobJournal) addPage(nextMarker string, keys []string) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	page := &journalPage{nextMarker: nextMarker, keys: keys, pending: len(keys)}
+	j.pages = append(j.pages, page)
+	for _, key := range keys {
+		j.keyMap[key] = page
+	}
+	j.commitPages()
+}
+
+// isDone shows if the item has been finished in an earlier run
+func (j *jobJournal) isDone(key string) bool {
+	if j == nil {
+		return false
+	}
+	ok, err := j.db.Has([]byte(journalDonePrefix+key), nil)
+	return err == nil && ok
+}
+
+// done records the result of an item, the journal is only a hint for the next run,
+// so failure of recording does not fail the item
+func (j *jobJournal) done(key string, err error) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err == nil {
+		j.db.Put([]byte(journalDonePrefix+key), []byte{}, nil)
+	}
+
+	if page, ok := j.keyMap[key]; ok {
+		delete(j.keyMap, key)
+		page.pending--
+		page.failed = page.failed || err != nil
+		j.commitPages()
+	}
+}
+
+// commitPages moves marker forward over the leading finished pages, and drops the done
+// records of those pages, which are covered by the marker since then. The last page of
+// listing has no next marker, so it keeps its done records.
+func (j *jobJournal) commitPages() {
+	i := 0
+	for ; i < len(j.pages) && j.pages[i].pending == 0 && !j.pages[i].failed && j.pages[i].nextMarker != ""; i++ {
+	}
+	if i == 0 {
+		return
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Put([]byte(journalMarkerKey), []byte(j.pages[i-1].nextMarker))
+	for _, page := range j.pages[:i] {
+		for _, key := range page.keys {
+			batch.Delete([]byte(journalDonePrefix + key))
+		}
+	}
+	if err := j.db.Write(batch, nil); err == nil {
+		j.pages = j.pages[i:]
+	}
+}
+
+// close closes the journal, and removes it if the job is completed
+func (j *jobJournal) close(completed bool) {
+	if j == nil {
+		return
+	}
+	j.db.Close()
+	if completed {
+		os.RemoveAll(j.path)
+		os.Remove(j.cpDir)
+	}
+}
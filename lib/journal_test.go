@@ -0,0 +1,62 @@
+package lib
+
+import (
+	"errors"
+	"os"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *OssutilCommandSuite) TestJobJournal(c *C) {
+	cpDir := "ossutil_test_journal_" + randLowStr(5)
+	args := []string{"oss://" + randLowStr(10), "--recursive"}
+
+	journal, err := openJobJournal(cpDir, "rm", args)
+	c.Assert(err, IsNil)
+	c.Assert(journal.marker(), Equals, "")
+
+	// first page finished, second page failed on one key
+	journal.addPage("b", []string{"a", "b"})
+	journal.addPage("d", []string{"c", "d"})
+	journal.done("a", nil)
+	c.Assert(journal.marker(), Equals, "")
+	c.Assert(journal.isDone("a"), Equals, true)
+	journal.done("b", nil)
+	c.Assert(journal.marker(), Equals, "b")
+	c.Assert(journal.isDone("a"), Equals, false)
+	journal.done("c", nil)
+	journal.done("d", errors.New("fake error"))
+	c.Assert(journal.marker(), Equals, "b")
+	c.Assert(journal.isDone("c"), Equals, true)
+	c.Assert(journal.isDone("d"), Equals, false)
+	journal.close(false)
+
+	// the same job continues from the journal
+	journal, err = openJobJournal(cpDir, "rm", args)
+	c.Assert(err, IsNil)
+	c.Assert(journal.marker(), Equals, "b")
+	c.Assert(journal.isDone("c"), Equals, true)
+
+	// other job does not share the journal
+	other, err := openJobJournal(cpDir, "set-meta", args)
+	c.Assert(err, IsNil)
+	c.Assert(other.marker(), Equals, "")
+	other.close(true)
+
+	// last page keeps marker
+	journal.addPage("", []string{"c", "d"})
+	journal.done("c", nil)
+	journal.done("d", nil)
+	c.Assert(journal.marker(), Equals, "b")
+	journal.close(true)
+
+	_, err = os.Stat(cpDir)
+	c.Assert(err, NotNil)
+
+	// nil journal does nothing
+	var nilJournal *jobJournal
+	c.Assert(nilJournal.marker(), Equals, "")
+	c.Assert(nilJournal.isDone("a"), Equals, false)
+	nilJournal.done("a", nil)
+	nilJournal.close(true)
+}
@@ -1,9 +1,11 @@
 package lib
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strings"
+	"sync"
 
 	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
 )
@@ -23,6 +25,9 @@ var specChineseList = SpecText{
     ossutil在列举buckets或者objects的同时展示它们的一些附加信息。如果指定了--short-format选
     项，则显示精简格式。
 
+    长格式下，对于` + StorageArchive + `类型的object，ossutil会额外查询其解冻状态，并显示在行末的方括号中，
+    解冻状态的说明见restore命令的帮助。
+
 --encoding-type选项
 
     如果指定了encoding-type为url，则表示输入的object（或prefix）为经过url编码的，此时如果指定了
@@ -33,7 +38,7 @@ var specChineseList = SpecText{
 
 用法：
 
-    该命令有两种用法：
+    该命令有三种用法：
 
     1) ossutil ls [oss://] [-s] [--limited-num num] [--marker marker]
         如果用户列举时缺失cloud_url参数，则ossutil获取用户的身份凭证信息（从配置文件中读取），
@@ -59,6 +64,11 @@ var specChineseList = SpecText{
         在列举objects时，--upload-id-marker选项不起作用。在列举Multipart Uploads事件时，--marker
     和--upload-id-marker选项同时限定了列举的起始位置，更多信息请见oss的官网：
     https://help.aliyun.com/document_detail/31997.html?spm=5176.doc31965.6.887.MK6GVw.
+
+    3) ossutil ls s3://[bucket[/prefix]] [-s] [-d] [--limited-num num] [--marker marker] --s3-endpoint endpoint
+        列举s3兼容存储的buckets或objects，通常在从s3迁移到oss（见cp命令的帮助）前后确认数据。
+    --s3-endpoint、--s3-access-key-id等选项指定s3兼容存储的访问配置，未指定AccessKey时使用环境变
+    量AWS_ACCESS_KEY_ID和AWS_SECRET_ACCESS_KEY。该用法不支持--multipart和--all-type选项。
 `,
 
 	sampleText: ` 
@@ -175,6 +185,10 @@ var specEnglishList = SpecText{
     provider, bucket, subdirectory, or object. If --short-format option is specified, 
     ossutil will show by short format. 
 
+    In long format, for objects of ` + StorageArchive + ` storage class, ossutil queries their restore status 
+    additionally, and shows it in brackets at the end of line, see help of restore command for 
+    more information about restore status.
+
 --encoding-type option
 
     If the --encoding-type option is setted to url, the object/prefix inputted is url 
@@ -188,7 +202,7 @@ var specEnglishList = SpecText{
 
 Usage:
 
-    There are two usages:
+    There are three usages:
 
     1) ossutil ls [oss://] [-s] [--limited-num num] [--marker marker]
         If you list without a cloud_url, ossutil lists all the buckets using the credentials
@@ -217,6 +231,13 @@ Usage:
         --upload-id-marker option is not effective when list objects. When list Multipart Uploads, 
     --marker option and --upload-id-marker option decide the initial position of listing meanwhile,
     for more initial, see: https://help.aliyun.com/document_detail/31997.html?spm=5176.doc31965.6.887.MK6GVw.
+
+    3) ossutil ls s3://[bucket[/prefix]] [-s] [-d] [--limited-num num] [--marker marker] --s3-endpoint endpoint
+        List the buckets or objects of s3 compatible storage, usually to check the data before or 
+    after migrating from s3 to oss(see help of cp command). --s3-endpoint, --s3-access-key-id and 
+    other s3 options specify how to access s3 compatible storage, the environment variables 
+    AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are used if AccessKey is not specified. The usage 
+    does not support --multipart and --all-type option.
 `,
 
 	sampleText: ` 
@@ -317,9 +338,28 @@ Usage:
 `,
 }
 
+// listOptionType is the options of ls
+type listOptionType struct {
+	directory      bool
+	typeSet        int64
+	limitedNum     int64 // negative means no limit
+	marker         string
+	uploadIDMarker string
+	encodingType   string
+	restoreStatus  bool // query the restore status of archive objects
+
+	// onEntry receives the entries listed, onEnd receives the number of entries when the buckets,
+	// objects or multipart uploads are listed, the type is ListEntryBucket, ListEntryObject or
+	// ListEntryMultipart
+	onEntry func(ListEntry)
+	onEnd   func(entryType string, num int64)
+}
+
 // ListCommand is the command list buckets or objects
 type ListCommand struct {
-	command Command
+	command  Command
+	lsOption listOptionType
+	s3       *s3Client // the client of s3 compatible storage if the url is s3://
 }
 
 var listCommand = ListCommand{
@@ -345,7 +385,20 @@ var listCommand = ListCommand{
 			OptionAccessKeyID,
 			OptionAccessKeySecret,
 			OptionSTSToken,
+			OptionProxyHost,
+			OptionProxyUser,
+			OptionProxyPwd,
+			OptionCAFile,
+			OptionSkipVerify,
+			OptionForceHTTPS,
+			OptionConnectTimeout,
+			OptionReadTimeout,
 			OptionRetryTimes,
+			OptionS3Endpoint,
+			OptionS3KeyID,
+			OptionS3KeySecret,
+			OptionS3Token,
+			OptionS3Region,
 		},
 	},
 }
@@ -366,12 +419,47 @@ func (lc *ListCommand) Init(args []string, options OptionMapType) error {
 
 // RunCommand simulate inheritance, and polymorphism
 func (lc *ListCommand) RunCommand() error {
-	if len(lc.command.args) == 0 {
+	urlStr := ""
+	if len(lc.command.args) > 0 {
+		urlStr = lc.command.args[0]
+	}
+
+	shortFormat, _ := GetBool(OptionShortFormat, lc.command.options)
+	lc.assembleOption(shortFormat)
+	printer := &listPrinter{lc: lc, shortFormat: shortFormat, directory: lc.lsOption.directory, headers: map[string]bool{}}
+	lc.lsOption.onEntry = printer.entry
+	lc.lsOption.onEnd = printer.end
+	return lc.list(lc.command.ctx, urlStr)
+}
+
+func (lc *ListCommand) assembleOption(shortFormat bool) {
+	isMultipart, _ := GetBool(OptionMultipart, lc.command.options)
+	isAllType, _ := GetBool(OptionAllType, lc.command.options)
+	lc.lsOption.directory, _ = GetBool(OptionDirectory, lc.command.options)
+	lc.lsOption.typeSet = getSubjectType(isMultipart, isAllType)
+	lc.lsOption.limitedNum, _ = GetInt(OptionLimitedNum, lc.command.options)
+	lc.lsOption.marker, _ = GetString(OptionMarker, lc.command.options)
+	lc.lsOption.uploadIDMarker, _ = GetString(OptionUploadIDMarker, lc.command.options)
+	lc.lsOption.encodingType, _ = GetString(OptionEncodingType, lc.command.options)
+
+	// the restore status is shown in long format, the objects are shown in short format with -d
+	lc.lsOption.restoreStatus = !shortFormat && !lc.lsOption.directory
+}
+
+// list lists the buckets if urlStr is empty, otherwise lists the objects or multipart uploads of
+// urlStr with lsOption. The entries are passed to the callbacks of lsOption, ctx stops listing.
+func (lc *ListCommand) list(ctx context.Context, urlStr string) error {
+	lc.command.ctx = ctx
+	lc.s3 = nil
+	if urlStr == "" {
 		return lc.listBuckets("")
 	}
 
-	encodingType, _ := GetString(OptionEncodingType, lc.command.options)
-	cloudURL, err := CloudURLFromString(lc.command.args[0], encodingType)
+	if strings.HasPrefix(strings.ToLower(urlStr), S3SchemePrefix) {
+		return lc.listS3(urlStr)
+	}
+
+	cloudURL, err := CloudURLFromString(urlStr, lc.lsOption.encodingType)
 	if err != nil {
 		return err
 	}
@@ -383,22 +471,32 @@ func (lc *ListCommand) RunCommand() error {
 	return lc.listFiles(cloudURL)
 }
 
+func (lc *ListCommand) showEntry(entry ListEntry) {
+	if lc.lsOption.onEntry != nil {
+		lc.lsOption.onEntry(entry)
+	}
+}
+
+func (lc *ListCommand) showEnd(entryType string, num int64) {
+	if lc.lsOption.onEnd != nil {
+		lc.lsOption.onEnd(entryType, num)
+	}
+}
+
 func (lc *ListCommand) listBuckets(prefix string) error {
 	var err error
 	if err = lc.lbCheckArgOptions(); err != nil {
 		return err
 	}
 
-	shortFormat, _ := GetBool(OptionShortFormat, lc.command.options)
-	limitedNum, _ := GetInt(OptionLimitedNum, lc.command.options)
-	vmarker, _ := GetString(OptionMarker, lc.command.options)
+	limitedNum := lc.lsOption.limitedNum
+	vmarker := lc.lsOption.marker
 	if vmarker, err = lc.getRawMarker(vmarker); err != nil {
 		return fmt.Errorf("invalid marker: %s, marker is not url encoded, %s", vmarker, err.Error())
 	}
 
 	var num int64
 	num = 0
-
 	client, err := lc.command.ossClient("")
 	if err != nil {
 		return err
@@ -414,31 +512,83 @@ func (lc *ListCommand) listBuckets(prefix string) error {
 		}
 		pre = oss.Prefix(lbr.Prefix)
 		marker = oss.Marker(lbr.NextMarker)
-		if num == 0 && !shortFormat && len(lbr.Buckets) > 0 {
-			fmt.Printf("%-30s %20s%s%12s%s%s\n", "CreationTime", "Region", FormatTAB, "StorageClass", FormatTAB, "BucketName")
-		}
 		for _, bucket := range lbr.Buckets {
 			if limitedNum >= 0 && num >= limitedNum {
 				break
 			}
-			if !shortFormat {
-				fmt.Printf("%-30s %20s%s%12s%s%s\n", utcToLocalTime(bucket.CreationDate), bucket.Location, FormatTAB, bucket.StorageClass, FormatTAB, CloudURLToString(bucket.Name, ""))
-			} else {
-				fmt.Println(CloudURLToString(bucket.Name, ""))
-			}
+			lc.showEntry(ListEntry{Type: ListEntryBucket, Bucket: bucket.Name, LastModified: bucket.CreationDate, StorageClass: bucket.StorageClass, Location: bucket.Location})
 			num++
 		}
 		if !lbr.IsTruncated {
 			break
 		}
+		if err := lc.command.cancelError(); err != nil {
+			return err
+		}
 	}
-	fmt.Printf("Bucket Number is: %d\n", num)
+	lc.showEnd(ListEntryBucket, num)
 	return nil
 }
 
+// listS3 lists the buckets or objects of s3 compatible storage
+func (lc *ListCommand) listS3(urlStr string) error {
+	var s3URL S3URL
+	if err := s3URL.Init(urlStr, lc.lsOption.encodingType); err != nil {
+		return err
+	}
+	if lc.lsOption.typeSet&multipartType != 0 {
+		return fmt.Errorf("list s3 url does not support option: \"%s\" and \"%s\"", OptionMultipart, OptionAllType)
+	}
+	client, err := lc.command.s3Client()
+	if err != nil {
+		return err
+	}
+	lc.s3 = client
+
+	limitedNum := lc.lsOption.limitedNum
+	if s3URL.bucket == "" {
+		return lc.listS3Buckets(limitedNum)
+	}
+
+	list := func(prefix, marker, delimiter string) (oss.ListObjectsResult, error) {
+		return client.listObjects(s3URL.bucket, prefix, marker, delimiter, S3MaxKeys)
+	}
+	_, err = lc.listObjects(list, CloudURL{urlStr: urlStr, bucket: s3URL.bucket, object: s3URL.object}, &limitedNum)
+	return err
+}
+
+// listS3Buckets lists all buckets of s3 compatible storage, which are returned in one page
+func (lc *ListCommand) listS3Buckets(limitedNum int64) error {
+	if err := lc.lbCheckArgOptions(); err != nil {
+		return err
+	}
+	lbr, err := lc.s3.listBuckets()
+	if err != nil {
+		return err
+	}
+
+	var num int64
+	for _, bucket := range lbr.Buckets {
+		if limitedNum >= 0 && num >= limitedNum {
+			break
+		}
+		lc.showEntry(ListEntry{Type: ListEntryBucket, Bucket: bucket.Name, LastModified: bucket.CreationDate})
+		num++
+	}
+	lc.showEnd(ListEntryBucket, num)
+	return nil
+}
+
+// objectURL returns the url of object in oss or s3
+func (lc *ListCommand) objectURL(bucket, object string) string {
+	if lc.s3 != nil {
+		return S3URLToString(bucket, object)
+	}
+	return CloudURLToString(bucket, object)
+}
+
 func (lc *ListCommand) getRawMarker(str string) (string, error) {
-	encodingType, _ := GetString(OptionEncodingType, lc.command.options)
-	if encodingType == URLEncodingType {
+	if lc.lsOption.encodingType == URLEncodingType {
 		unencodedStr, err := url.QueryUnescape(str)
 		if err != nil {
 			return str, err
@@ -449,7 +599,7 @@ func (lc *ListCommand) getRawMarker(str string) (string, error) {
 }
 
 func (lc *ListCommand) lbCheckArgOptions() error {
-	if ok, _ := GetBool(OptionDirectory, lc.command.options); ok {
+	if lc.lsOption.directory {
 		return fmt.Errorf("ListBucket does not support option: \"%s\"", OptionDirectory)
 	}
 	return nil
@@ -471,31 +621,32 @@ func (lc *ListCommand) listFiles(cloudURL CloudURL) error {
 		return err
 	}
 
-	shortFormat, _ := GetBool(OptionShortFormat, lc.command.options)
-	directory, _ := GetBool(OptionDirectory, lc.command.options)
-	limitedNum, _ := GetInt(OptionLimitedNum, lc.command.options)
+	limitedNum := lc.lsOption.limitedNum
+	list := func(prefix, marker, delimiter string) (oss.ListObjectsResult, error) {
+		return lc.command.ossListObjectsRetry(bucket, oss.Prefix(prefix), oss.Marker(marker), oss.Delimiter(delimiter))
+	}
 
-	typeSet := lc.getSubjectType()
+	typeSet := lc.lsOption.typeSet
 	if typeSet&objectType != 0 {
-		if _, err = lc.listObjects(bucket, cloudURL, shortFormat, directory, &limitedNum); err != nil {
+		if _, err = lc.listObjects(list, cloudURL, &limitedNum); err != nil {
 			return err
 		}
 	}
 	if typeSet&multipartType != 0 {
-		if _, err := lc.listMultipartUploads(bucket, cloudURL, shortFormat, directory, &limitedNum); err != nil {
+		if _, err := lc.listMultipartUploads(bucket, cloudURL, &limitedNum); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (lc *ListCommand) getSubjectType() int64 {
+func getSubjectType(isMultipart, isAllType bool) int64 {
 	var typeSet int64
 	typeSet = 0
-	if isMultipart, _ := GetBool(OptionMultipart, lc.command.options); isMultipart {
+	if isMultipart {
 		typeSet |= multipartType
 	}
-	if isAllType, _ := GetBool(OptionAllType, lc.command.options); isAllType {
+	if isAllType {
 		typeSet |= allType
 	}
 	if typeSet&allType == 0 {
@@ -504,82 +655,115 @@ func (lc *ListCommand) getSubjectType() int64 {
 	return typeSet
 }
 
-func (lc *ListCommand) listObjects(bucket *oss.Bucket, cloudURL CloudURL, shortFormat bool, directory bool, limitedNum *int64) (int64, error) {
+// listObjects lists the objects by the list function in pages, so that it can list oss or s3
+func (lc *ListCommand) listObjects(list func(prefix, marker, delimiter string) (oss.ListObjectsResult, error), cloudURL CloudURL, limitedNum *int64) (int64, error) {
 	//list all objects or directories
 	var err error
 	var num int64
 	num = 0
-	pre := oss.Prefix(cloudURL.object)
-	vmarker, _ := GetString(OptionMarker, lc.command.options)
-	if vmarker, err = lc.getRawMarker(vmarker); err != nil {
-		return num, fmt.Errorf("invalid marker: %s, marker is not url encoded, %s", vmarker, err.Error())
+	pre := cloudURL.object
+	marker := lc.lsOption.marker
+	if marker, err = lc.getRawMarker(marker); err != nil {
+		return num, fmt.Errorf("invalid marker: %s, marker is not url encoded, %s", marker, err.Error())
 	}
-	marker := oss.Marker(vmarker)
-	del := oss.Delimiter("")
-	if directory {
-		del = oss.Delimiter("/")
+	del := ""
+	if lc.lsOption.directory {
+		del = "/"
 	}
 
-	var i int64
-	for i = 0; ; i++ {
+	for {
 		if *limitedNum == 0 {
 			break
 		}
-		lor, err := lc.command.ossListObjectsRetry(bucket, marker, pre, del)
+		lor, err := list(pre, marker, del)
 		if err != nil {
 			return num, err
 		}
-		pre = oss.Prefix(lor.Prefix)
-		marker = oss.Marker(lor.NextMarker)
-		num += lc.displayObjectsResult(lor, cloudURL.bucket, shortFormat, directory, i, limitedNum)
+		pre = lor.Prefix
+		marker = lor.NextMarker
+		num += lc.showObjects(lor, cloudURL.bucket, limitedNum)
+		if lc.lsOption.directory {
+			num += lc.showDirectories(lor, cloudURL.bucket, limitedNum)
+		}
 		if !lor.IsTruncated {
 			break
 		}
+		if err := lc.command.cancelError(); err != nil {
+			return num, err
+		}
 	}
 
-	if !directory {
-		fmt.Printf("Object Number is: %d\n", num)
-	} else {
-		fmt.Printf("Object and Directory Number is: %d\n", num)
-	}
-
+	lc.showEnd(ListEntryObject, num)
 	return num, nil
 }
 
-func (lc *ListCommand) displayObjectsResult(lor oss.ListObjectsResult, bucket string, shortFormat bool, directory bool, i int64, limitedNum *int64) int64 {
-	if i == 0 && !shortFormat && !directory && len(lor.Objects) > 0 {
-		fmt.Printf("%-30s%12s%s%12s%s%-36s%s%s\n", "LastModifiedTime", "Size(B)", "  ", "StorageClass", "   ", "ETAG", "  ", "ObjectName")
-	}
-
-	var num int64
-	if !directory {
-		num = lc.showObjects(lor, bucket, shortFormat, limitedNum)
-	} else {
-		num = lc.showObjects(lor, bucket, true, limitedNum)
-		num1 := lc.showDirectories(lor, bucket, limitedNum)
-		num += num1
+func (lc *ListCommand) showObjects(lor oss.ListObjectsResult, bucket string, limitedNum *int64) int64 {
+	var statuses map[string]string
+	if lc.lsOption.restoreStatus {
+		objects := lor.Objects
+		if *limitedNum >= 0 && int64(len(objects)) > *limitedNum {
+			objects = objects[:*limitedNum]
+		}
+		statuses = lc.restoreStatuses(bucket, objects)
 	}
-	return num
-}
 
-func (lc *ListCommand) showObjects(lor oss.ListObjectsResult, bucket string, shortFormat bool, limitedNum *int64) int64 {
 	var num int64
 	num = 0
 	for _, object := range lor.Objects {
 		if *limitedNum == 0 {
 			break
 		}
-		if !shortFormat {
-			fmt.Printf("%-30s%12d%s%12s%s%-36s%s%s\n", utcToLocalTime(object.LastModified), object.Size, "  ", object.StorageClass, "   ", strings.Trim(object.ETag, "\""), "  ", CloudURLToString(bucket, object.Key))
-		} else {
-			fmt.Printf("%s\n", CloudURLToString(bucket, object.Key))
-		}
+		lc.showEntry(ListEntry{Type: ListEntryObject, Bucket: bucket, Key: object.Key, Size: object.Size, LastModified: object.LastModified, StorageClass: object.StorageClass, ETag: strings.Trim(object.ETag, "\""), ObjectType: object.Type, RestoreStatus: statuses[object.Key]})
 		*limitedNum--
 		num++
 	}
 	return num
 }
 
+// restoreStatuses returns restore status of archive objects, the key is object. It needs a HEAD
+// request for every archive object, so the requests are sent concurrently.
+func (lc *ListCommand) restoreStatuses(bucketName string, objects []oss.ObjectProperties) map[string]string {
+	statuses := map[string]string{}
+	if lc.s3 != nil {
+		return statuses
+	}
+	keys := []string{}
+	for _, object := range objects {
+		if object.StorageClass == StorageArchive {
+			keys = append(keys, object.Key)
+		}
+	}
+	if len(keys) == 0 {
+		return statuses
+	}
+
+	bucket, err := lc.command.ossBucket(bucketName)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, RestoreStatusRoutines)
+	for _, key := range keys {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(key string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			status := RestoreStatusUnknown
+			if err == nil {
+				if props, err := lc.command.ossGetObjectStatRetry(bucket, key); err == nil {
+					status = formatRestoreStatus(getRestoreStatus(props))
+				}
+			}
+			mu.Lock()
+			statuses[key] = status
+			mu.Unlock()
+		}(key)
+	}
+	wg.Wait()
+	return statuses
+}
+
 func (lc *ListCommand) showDirectories(lor oss.ListObjectsResult, bucket string, limitedNum *int64) int64 {
 	var num int64
 	num = 0
@@ -587,38 +771,37 @@ func (lc *ListCommand) showDirectories(lor oss.ListObjectsResult, bucket string,
 		if *limitedNum == 0 {
 			break
 		}
-		fmt.Printf("%s\n", CloudURLToString(bucket, prefix))
+		lc.showEntry(ListEntry{Type: ListEntryDirectory, Bucket: bucket, Key: prefix})
 		*limitedNum--
 		num++
 	}
 	return num
 }
 
-func (lc *ListCommand) listMultipartUploads(bucket *oss.Bucket, cloudURL CloudURL, shortFormat bool, directory bool, limitedNum *int64) (int64, error) {
+func (lc *ListCommand) listMultipartUploads(bucket *oss.Bucket, cloudURL CloudURL, limitedNum *int64) (int64, error) {
 	var err error
 	var multipartNum int64
 	multipartNum = 0
 	pre := oss.Prefix(cloudURL.object)
 
-	vmarker, _ := GetString(OptionMarker, lc.command.options)
+	vmarker := lc.lsOption.marker
 	if vmarker, err = lc.getRawMarker(vmarker); err != nil {
 		return multipartNum, fmt.Errorf("invalid marker: %s, marker is not url encoded, %s", vmarker, err.Error())
 	}
 	keyMarker := oss.KeyMarker(vmarker)
 
-	vuploadIdMarker, _ := GetString(OptionUploadIDMarker, lc.command.options)
+	vuploadIdMarker := lc.lsOption.uploadIDMarker
 	if vuploadIdMarker, err = lc.getRawMarker(vuploadIdMarker); err != nil {
 		return multipartNum, fmt.Errorf("invalid uploadIDMarker: %s, uploadIDMarker is not url encoded, %s", vuploadIdMarker, err.Error())
 	}
 	uploadIdMarker := oss.UploadIDMarker(vuploadIdMarker)
 
 	del := oss.Delimiter("")
-	if directory {
+	if lc.lsOption.directory {
 		del = oss.Delimiter("/")
 	}
 
-	var i int64
-	for i = 0; ; i++ {
+	for {
 		if *limitedNum == 0 {
 			break
 		}
@@ -629,46 +812,96 @@ func (lc *ListCommand) listMultipartUploads(bucket *oss.Bucket, cloudURL CloudUR
 		pre = oss.Prefix(lmr.Prefix)
 		keyMarker = oss.Marker(lmr.NextKeyMarker)
 		uploadIdMarker = oss.UploadIDMarker(lmr.NextUploadIDMarker)
-		multipartNum += lc.displayMultipartUploadsResult(lmr, cloudURL.bucket, shortFormat, directory, i, limitedNum)
+		multipartNum += lc.showMultipartUploads(lmr, cloudURL.bucket, limitedNum)
 		if !lmr.IsTruncated {
 			break
 		}
-	}
-	fmt.Printf("UploadID Number is: %d\n", multipartNum)
-	return multipartNum, nil
-}
-
-func (lc *ListCommand) displayMultipartUploadsResult(lmr oss.ListMultipartUploadResult, bucket string, shortFormat bool, directory bool, i int64, limitedNum *int64) int64 {
-	if directory {
-		shortFormat = true
-	}
-
-	if i == 0 && len(lmr.Uploads) > 0 {
-		if shortFormat {
-			fmt.Printf("%-32s%s%s\n", "UploadID", FormatTAB, "ObjectName")
-		} else {
-			fmt.Printf("%-30s%s%-32s%s%s\n", "InitiatedTime", FormatTAB, "UploadID", FormatTAB, "ObjectName")
+		if err := lc.command.cancelError(); err != nil {
+			return multipartNum, err
 		}
 	}
-
-	num := lc.showMultipartUploads(lmr, bucket, shortFormat, limitedNum)
-	return num
+	lc.showEnd(ListEntryMultipart, multipartNum)
+	return multipartNum, nil
 }
 
-func (lc *ListCommand) showMultipartUploads(lmr oss.ListMultipartUploadResult, bucket string, shortFormat bool, limitedNum *int64) int64 {
+func (lc *ListCommand) showMultipartUploads(lmr oss.ListMultipartUploadResult, bucket string, limitedNum *int64) int64 {
 	var num int64
 	num = 0
 	for _, upload := range lmr.Uploads {
 		if *limitedNum == 0 {
 			break
 		}
-		if shortFormat {
-			fmt.Printf("%-32s%s%s\n", upload.UploadID, FormatTAB, CloudURLToString(bucket, upload.Key))
-		} else {
-			fmt.Printf("%-30s%s%-32s%s%s\n", utcToLocalTime(upload.Initiated), FormatTAB, upload.UploadID, FormatTAB, CloudURLToString(bucket, upload.Key))
-		}
+		lc.showEntry(ListEntry{Type: ListEntryMultipart, Bucket: bucket, Key: upload.Key, LastModified: upload.Initiated, UploadID: upload.UploadID})
 		*limitedNum--
 		num++
 	}
 	return num
 }
+
+// listPrinter prints the entries listed by ls, the header of a type of entries is printed before
+// the first entry of the type
+type listPrinter struct {
+	lc          *ListCommand
+	shortFormat bool
+	directory   bool
+	headers     map[string]bool
+}
+
+func (p *listPrinter) header(entryType, format string, a ...interface{}) {
+	if !p.headers[entryType] {
+		p.headers[entryType] = true
+		fmt.Printf(format, a...)
+	}
+}
+
+func (p *listPrinter) entry(entry ListEntry) {
+	url := p.lc.objectURL(entry.Bucket, entry.Key)
+	switch entry.Type {
+	case ListEntryBucket:
+		if p.shortFormat {
+			fmt.Printf("%s\n", url)
+		} else if p.lc.s3 != nil {
+			p.header(entry.Type, "%-30s%s%s\n", "CreationTime", FormatTAB, "BucketName")
+			fmt.Printf("%-30s%s%s\n", utcToLocalTime(entry.LastModified), FormatTAB, url)
+		} else {
+			p.header(entry.Type, "%-30s %20s%s%12s%s%s\n", "CreationTime", "Region", FormatTAB, "StorageClass", FormatTAB, "BucketName")
+			fmt.Printf("%-30s %20s%s%12s%s%s\n", utcToLocalTime(entry.LastModified), entry.Location, FormatTAB, entry.StorageClass, FormatTAB, url)
+		}
+	case ListEntryObject:
+		if p.shortFormat || p.directory {
+			fmt.Printf("%s\n", url)
+			return
+		}
+		status := ""
+		if entry.RestoreStatus != "" {
+			status = fmt.Sprintf("  [%s]", entry.RestoreStatus)
+		}
+		p.header(entry.Type, "%-30s%12s%s%12s%s%-36s%s%s\n", "LastModifiedTime", "Size(B)", "  ", "StorageClass", "   ", "ETAG", "  ", "ObjectName")
+		fmt.Printf("%-30s%12d%s%12s%s%-36s%s%s%s\n", utcToLocalTime(entry.LastModified), entry.Size, "  ", entry.StorageClass, "   ", entry.ETag, "  ", url, status)
+	case ListEntryDirectory:
+		fmt.Printf("%s\n", url)
+	case ListEntryMultipart:
+		if p.shortFormat || p.directory {
+			p.header(entry.Type, "%-32s%s%s\n", "UploadID", FormatTAB, "ObjectName")
+			fmt.Printf("%-32s%s%s\n", entry.UploadID, FormatTAB, url)
+		} else {
+			p.header(entry.Type, "%-30s%s%-32s%s%s\n", "InitiatedTime", FormatTAB, "UploadID", FormatTAB, "ObjectName")
+			fmt.Printf("%-30s%s%-32s%s%s\n", utcToLocalTime(entry.LastModified), FormatTAB, entry.UploadID, FormatTAB, url)
+		}
+	}
+}
+
+func (p *listPrinter) end(entryType string, num int64) {
+	switch entryType {
+	case ListEntryBucket:
+		fmt.Printf("Bucket Number is: %d\n", num)
+	case ListEntryObject:
+		if !p.directory {
+			fmt.Printf("Object Number is: %d\n", num)
+		} else {
+			fmt.Printf("Object and Directory Number is: %d\n", num)
+		}
+	case ListEntryMultipart:
+		fmt.Printf("UploadID Number is: %d\n", num)
+	}
+}
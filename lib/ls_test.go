@@ -136,6 +136,8 @@ func (s *OssutilCommandSuite) TestListWithBucketEndpoint(c *C) {
 }
 
 func (s *OssutilCommandSuite) TestListWithBucketCname(c *C) {
+	skipOnFakeOSS(c, "bucket cname needs dns")
+
 	bucketName := bucketNamePrefix + randLowStr(10)
 	s.putBucket(bucketName, c)
 
@@ -147,6 +147,14 @@ var makeBucketCommand = MakeBucketCommand{
 			OptionAccessKeyID,
 			OptionAccessKeySecret,
 			OptionSTSToken,
+			OptionProxyHost,
+			OptionProxyUser,
+			OptionProxyPwd,
+			OptionCAFile,
+			OptionSkipVerify,
+			OptionForceHTTPS,
+			OptionConnectTimeout,
+			OptionReadTimeout,
 			OptionRetryTimes,
 			OptionLanguage,
 		},
@@ -212,7 +220,7 @@ func (mc *MakeBucketCommand) getACL(aclStr, language string) (oss.ACLType, error
 			fmt.Printf("acl: %s非法\n", aclStr)
 			fmt.Printf("合法的acl有:\n\t%s\n请输入你想设置的acl：", formatACLString(bucketACL, "\n\t"))
 		}
-		if _, err := fmt.Scanln(&aclStr); err != nil {
+		if _, err := scanln(&aclStr); err != nil {
 			return "", fmt.Errorf("invalid acl: %s, please check", aclStr)
 		}
 		acl, err = mc.checkACL(aclStr)
@@ -0,0 +1,339 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// metricsLatencyBuckets are the upper bounds of the histogram of request latency in seconds
+var metricsLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+const (
+	// metricsSampleInterval is the interval to sample the bytes transferred for throughput
+	metricsSampleInterval = time.Second
+
+	// metricsRateWindow is the period which the throughput is averaged over
+	metricsRateWindow = 5 * time.Second
+)
+
+// metricsStat is the statistic of job taken from the snapshot of monitor
+type metricsStat struct {
+	scanned     int64
+	scanEnd     bool
+	succeeded   int64
+	skipped     int64
+	failed      int64
+	transferred int64
+	skippedSize int64
+}
+
+// latencyHistogram is the latency of the requests of an operation, counts are not cumulative
+type latencyHistogram struct {
+	counts []int64
+	count  int64
+	sum    float64
+	errors int64
+}
+
+type metricsSample struct {
+	time  time.Time
+	bytes int64
+}
+
+// metrics serves the metrics of the running command on --metrics-addr in the text format of
+// prometheus, the statistic of items is taken from the monitor when it's scraped
+type metrics struct {
+	command   string
+	stat      func() metricsStat
+	startTime time.Time
+	retries   int64
+
+	mu       sync.Mutex
+	requests map[string]*latencyHistogram
+	samples  []metricsSample
+
+	listener net.Listener
+	server   *http.Server
+	done     chan struct{}
+}
+
+// startMetrics serves the metrics on --metrics-addr until stop is called, it does nothing if the
+// option is not specified
+func (cmd *Command) startMetrics(stat func() metricsStat) (stop func(), err error) {
+	cmd.metrics = nil
+	addr, _ := GetString(OptionMetricsAddr, cmd.options)
+	if addr == "" {
+		return func() {}, nil
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen on metrics address %s error, info: %s", addr, err.Error())
+	}
+	m := &metrics{
+		command:   cmd.name,
+		stat:      stat,
+		startTime: time.Now(),
+		requests:  map[string]*latencyHistogram{},
+		listener:  listener,
+		done:      make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", m.serve)
+	m.server = &http.Server{Handler: mux}
+	go m.server.Serve(listener)
+	go m.sampleLoop()
+
+	cmd.metrics = m
+	return func() {
+		close(m.done)
+		m.server.Close()
+	}, nil
+}
+
+// retry counts a retry of --retry-times
+func (m *metrics) retry() {
+	if m != nil {
+		atomic.AddInt64(&m.retries, 1)
+	}
+}
+
+// observe records the latency of a request, the responses with status 4xx or 5xx are errors
+func (m *metrics) observe(op string, latency time.Duration, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.requests[op]
+	if !ok {
+		h = &latencyHistogram{counts: make([]int64, len(metricsLatencyBuckets))}
+		m.requests[op] = h
+	}
+	seconds := latency.Seconds()
+	if i := sort.SearchFloat64s(metricsLatencyBuckets, seconds); i < len(metricsLatencyBuckets) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += seconds
+	if failed {
+		h.errors++
+	}
+}
+
+// transport observes the requests sent by next
+func (m *metrics) transport(next http.RoundTripper) http.RoundTripper {
+	return &metricsTransport{m, next}
+}
+
+type metricsTransport struct {
+	metrics *metrics
+	next    http.RoundTripper
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	t.metrics.observe(ossOperation(req), time.Since(start), err != nil || resp.StatusCode >= 400)
+	return resp, err
+}
+
+// ossOperation names the oss api of the request by its method, sub resource and headers
+func ossOperation(req *http.Request) string {
+	query := req.URL.Query()
+	has := func(name string) bool {
+		_, ok := query[name]
+		return ok
+	}
+	copySource := req.Header.Get("X-Oss-Copy-Source") != ""
+
+	switch req.Method {
+	case "GET":
+		switch {
+		case has("uploadId"):
+			return "ListParts"
+		case has("uploads"):
+			return "ListMultipartUploads"
+		case has("acl"):
+			return "GetACL"
+		case has("symlink"):
+			return "GetSymlink"
+		case has("objectMeta"):
+			return "GetObjectMeta"
+		case has("location"):
+			return "GetBucketLocation"
+		case req.URL.Path == "/" || has("prefix") || has("marker") || has("max-keys") || has("delimiter"):
+			return "ListObjects"
+		}
+		return "GetObject"
+	case "HEAD":
+		return "HeadObject"
+	case "PUT":
+		switch {
+		case has("uploadId") && copySource:
+			return "UploadPartCopy"
+		case has("uploadId"):
+			return "UploadPart"
+		case has("acl"):
+			return "PutACL"
+		case has("symlink"):
+			return "PutSymlink"
+		case copySource:
+			return "CopyObject"
+		}
+		return "PutObject"
+	case "POST":
+		switch {
+		case has("uploadId"):
+			return "CompleteMultipartUpload"
+		case has("uploads"):
+			return "InitiateMultipartUpload"
+		case has("delete"):
+			return "DeleteMultipleObjects"
+		case has("restore"):
+			return "RestoreObject"
+		case has("append"):
+			return "AppendObject"
+		}
+	case "DELETE":
+		if has("uploadId") {
+			return "AbortMultipartUpload"
+		}
+		return "DeleteObject"
+	}
+	return req.Method
+}
+
+func (m *metrics) sampleLoop() {
+	ticker := time.NewTicker(metricsSampleInterval)
+	defer ticker.Stop()
+	m.sample(time.Now())
+	for {
+		select {
+		case now := <-ticker.C:
+			m.sample(now)
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// sample records the bytes transferred, and drops the samples out of metricsRateWindow
+func (m *metrics) sample(now time.Time) {
+	bytes := m.stat().transferred
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.samples = append(m.samples, metricsSample{now, bytes})
+	for len(m.samples) > 2 && now.Sub(m.samples[0].time) > metricsRateWindow {
+		m.samples = m.samples[1:]
+	}
+}
+
+// throughput returns the bytes transferred per second in the recent samples
+func (m *metrics) throughput() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.samples) < 2 {
+		return 0
+	}
+	first, last := m.samples[0], m.samples[len(m.samples)-1]
+	seconds := last.time.Sub(first.time).Seconds()
+	if seconds <= 0 {
+		return 0
+	}
+	return float64(last.bytes-first.bytes) / seconds
+}
+
+func (m *metrics) serve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(m.render())
+}
+
+// render writes the metrics in the text format of prometheus
+func (m *metrics) render() []byte {
+	var buf bytes.Buffer
+	family := func(name, typ, help string) {
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+	value := func(v float64) string {
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	label := fmt.Sprintf(`command="%s"`, m.command)
+
+	stat := m.stat()
+	family("ossutil_start_time_seconds", "gauge", "Start time of the command since unix epoch in seconds.")
+	fmt.Fprintf(&buf, "ossutil_start_time_seconds{%s} %s\n", label, value(float64(m.startTime.UnixNano())/1e9))
+	family("ossutil_items_scanned", "gauge", "Number of files or objects scanned.")
+	fmt.Fprintf(&buf, "ossutil_items_scanned{%s} %d\n", label, stat.scanned)
+	family("ossutil_scan_end", "gauge", "Whether the scan of files or objects is finished.")
+	scanEnd := 0
+	if stat.scanEnd {
+		scanEnd = 1
+	}
+	fmt.Fprintf(&buf, "ossutil_scan_end{%s} %d\n", label, scanEnd)
+	family("ossutil_items_total", "counter", "Number of files or objects dealt with by result.")
+	fmt.Fprintf(&buf, "ossutil_items_total{%s,result=\"succeeded\"} %d\n", label, stat.succeeded)
+	fmt.Fprintf(&buf, "ossutil_items_total{%s,result=\"skipped\"} %d\n", label, stat.skipped)
+	fmt.Fprintf(&buf, "ossutil_items_total{%s,result=\"failed\"} %d\n", label, stat.failed)
+	family("ossutil_transferred_bytes_total", "counter", "Bytes transferred.")
+	fmt.Fprintf(&buf, "ossutil_transferred_bytes_total{%s} %d\n", label, stat.transferred)
+	family("ossutil_skipped_bytes_total", "counter", "Bytes of the files or objects skipped.")
+	fmt.Fprintf(&buf, "ossutil_skipped_bytes_total{%s} %d\n", label, stat.skippedSize)
+	family("ossutil_throughput_bytes_per_second", "gauge", fmt.Sprintf("Bytes transferred per second in the last %s.", metricsRateWindow))
+	fmt.Fprintf(&buf, "ossutil_throughput_bytes_per_second{%s} %s\n", label, value(m.throughput()))
+	family("ossutil_retries_total", "counter", "Number of operations retried.")
+	fmt.Fprintf(&buf, "ossutil_retries_total{%s} %d\n", label, atomic.LoadInt64(&m.retries))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ops := make([]string, 0, len(m.requests))
+	for op := range m.requests {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	family("ossutil_request_duration_seconds", "histogram", "Latency of oss requests by operation.")
+	for _, op := range ops {
+		h := m.requests[op]
+		opLabel := fmt.Sprintf(`%s,operation="%s"`, label, op)
+		var cumulative int64
+		for i, bound := range metricsLatencyBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(&buf, "ossutil_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n", opLabel, value(bound), cumulative)
+		}
+		fmt.Fprintf(&buf, "ossutil_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", opLabel, h.count)
+		fmt.Fprintf(&buf, "ossutil_request_duration_seconds_sum{%s} %s\n", opLabel, value(h.sum))
+		fmt.Fprintf(&buf, "ossutil_request_duration_seconds_count{%s} %d\n", opLabel, h.count)
+	}
+	family("ossutil_request_errors_total", "counter", "Number of oss requests failed by operation.")
+	for _, op := range ops {
+		fmt.Fprintf(&buf, "ossutil_request_errors_total{%s,operation=\"%s\"} %d\n", label, op, m.requests[op].errors)
+	}
+	return buf.Bytes()
+}
+
+func (m *CPMonitor) getMetricsStat() metricsStat {
+	snap := m.getSnapshot()
+	return metricsStat{
+		scanned:     m.totalNum,
+		scanEnd:     m.seekAheadEnd && m.seekAheadError == nil,
+		succeeded:   snap.fileNum + snap.dirNum,
+		skipped:     snap.skipNum,
+		failed:      snap.errNum,
+		transferred: snap.transferSize,
+		skippedSize: snap.skipSize,
+	}
+}
+
+func (m *RMMonitor) getMetricsStat() metricsStat {
+	snap := m.getSnapshot()
+	return metricsStat{
+		scanned:   m.totalObjectNum + m.totalUploadIdNum,
+		scanEnd:   m.seekAheadEnd && m.seekAheadError == nil,
+		succeeded: snap.objectNum + snap.uploadIdNum,
+		failed:    snap.errNum,
+	}
+}
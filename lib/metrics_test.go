@@ -0,0 +1,205 @@
+package lib
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	. "gopkg.in/check.v1"
+)
+
+// scrapeMetrics gets the metrics from the server of m
+func scrapeMetrics(m *metrics, c *C) string {
+	resp, err := http.Get("http://" + m.listener.Addr().String() + "/metrics")
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain; version=0.0.4"), Equals, true)
+	body, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, IsNil)
+	return string(body)
+}
+
+// assertMetrics asserts that all the lines are in the metrics
+func assertMetrics(text string, lines []string, c *C) {
+	for _, line := range lines {
+		c.Assert(strings.Contains(text, line+"\n"), Equals, true, Commentf("%s not in metrics:\n%s", line, text))
+	}
+}
+
+func (s *OssutilCommandSuite) TestMetricsServe(c *C) {
+	cmd := Command{name: "cp", options: OptionMapType{}}
+	addr := "127.0.0.1:0"
+	cmd.options[OptionMetricsAddr] = &addr
+	stat := metricsStat{scanned: 10, scanEnd: true, succeeded: 5, skipped: 2, failed: 1, transferred: 1000, skippedSize: 200}
+	stop, err := cmd.startMetrics(func() metricsStat { return stat })
+	c.Assert(err, IsNil)
+	m := cmd.metrics
+	c.Assert(m, NotNil)
+
+	// the requests are observed by the transport
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("partNumber") == "2" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: m.transport(http.DefaultTransport)}
+	for _, part := range []string{"1", "2"} {
+		req, err := http.NewRequest("PUT", server.URL+"/object?uploadId=id&partNumber="+part, nil)
+		c.Assert(err, IsNil)
+		resp, err := client.Do(req)
+		c.Assert(err, IsNil)
+		resp.Body.Close()
+	}
+	m.retry()
+	m.retry()
+
+	// the throughput is averaged over the samples
+	now := time.Now()
+	m.mu.Lock()
+	m.samples = nil
+	m.mu.Unlock()
+	m.sample(now)
+	stat.transferred = 3000
+	m.sample(now.Add(2 * time.Second))
+
+	text := scrapeMetrics(m, c)
+	assertMetrics(text, []string{
+		"# TYPE ossutil_items_total counter",
+		`ossutil_items_scanned{command="cp"} 10`,
+		`ossutil_scan_end{command="cp"} 1`,
+		`ossutil_items_total{command="cp",result="succeeded"} 5`,
+		`ossutil_items_total{command="cp",result="skipped"} 2`,
+		`ossutil_items_total{command="cp",result="failed"} 1`,
+		`ossutil_transferred_bytes_total{command="cp"} 3000`,
+		`ossutil_skipped_bytes_total{command="cp"} 200`,
+		`ossutil_throughput_bytes_per_second{command="cp"} 1000`,
+		`ossutil_retries_total{command="cp"} 2`,
+		"# TYPE ossutil_request_duration_seconds histogram",
+		`ossutil_request_duration_seconds_bucket{command="cp",operation="UploadPart",le="60"} 2`,
+		`ossutil_request_duration_seconds_bucket{command="cp",operation="UploadPart",le="+Inf"} 2`,
+		`ossutil_request_duration_seconds_count{command="cp",operation="UploadPart"} 2`,
+		`ossutil_request_errors_total{command="cp",operation="UploadPart"} 1`,
+	}, c)
+
+	// the server stops with the command
+	stop()
+	_, err = http.Get("http://" + m.listener.Addr().String() + "/metrics")
+	c.Assert(err, NotNil)
+
+	// no metrics without address
+	cmd = Command{name: "cp", options: OptionMapType{}}
+	stop, err = cmd.startMetrics(nil)
+	c.Assert(err, IsNil)
+	c.Assert(cmd.metrics, IsNil)
+	cmd.metrics.retry()
+	stop()
+}
+
+func (s *OssutilCommandSuite) TestMetricsHistogram(c *C) {
+	m := &metrics{command: "rm", stat: func() metricsStat { return metricsStat{} }, requests: map[string]*latencyHistogram{}}
+	m.observe("DeleteObject", 3*time.Millisecond, false)
+	m.observe("DeleteObject", 200*time.Millisecond, false)
+	m.observe("DeleteObject", 2*time.Minute, true)
+	assertMetrics(string(m.render()), []string{
+		`ossutil_request_duration_seconds_bucket{command="rm",operation="DeleteObject",le="0.005"} 1`,
+		`ossutil_request_duration_seconds_bucket{command="rm",operation="DeleteObject",le="0.1"} 1`,
+		`ossutil_request_duration_seconds_bucket{command="rm",operation="DeleteObject",le="0.25"} 2`,
+		`ossutil_request_duration_seconds_bucket{command="rm",operation="DeleteObject",le="60"} 2`,
+		`ossutil_request_duration_seconds_bucket{command="rm",operation="DeleteObject",le="+Inf"} 3`,
+		`ossutil_request_duration_seconds_sum{command="rm",operation="DeleteObject"} 120.203`,
+		`ossutil_request_duration_seconds_count{command="rm",operation="DeleteObject"} 3`,
+		`ossutil_request_errors_total{command="rm",operation="DeleteObject"} 1`,
+		`ossutil_throughput_bytes_per_second{command="rm"} 0`,
+	}, c)
+}
+
+func (s *OssutilCommandSuite) TestOssOperation(c *C) {
+	for _, t := range []struct {
+		method, url, copySource, op string
+	}{
+		{"GET", "/", "", "ListObjects"},
+		{"GET", "/?prefix=dir%2F&max-keys=100", "", "ListObjects"},
+		{"GET", "/?uploads", "", "ListMultipartUploads"},
+		{"GET", "/object?uploadId=id", "", "ListParts"},
+		{"GET", "/object?acl", "", "GetACL"},
+		{"GET", "/object?objectMeta", "", "GetObjectMeta"},
+		{"GET", "/object", "", "GetObject"},
+		{"HEAD", "/object", "", "HeadObject"},
+		{"PUT", "/object", "", "PutObject"},
+		{"PUT", "/object", "/bucket/src", "CopyObject"},
+		{"PUT", "/object?partNumber=1&uploadId=id", "", "UploadPart"},
+		{"PUT", "/object?partNumber=1&uploadId=id", "/bucket/src", "UploadPartCopy"},
+		{"PUT", "/object?symlink", "", "PutSymlink"},
+		{"POST", "/object?uploads", "", "InitiateMultipartUpload"},
+		{"POST", "/object?uploadId=id", "", "CompleteMultipartUpload"},
+		{"POST", "/?delete", "", "DeleteMultipleObjects"},
+		{"POST", "/object?restore", "", "RestoreObject"},
+		{"POST", "/object", "", "POST"},
+		{"DELETE", "/object", "", "DeleteObject"},
+		{"DELETE", "/object?uploadId=id", "", "AbortMultipartUpload"},
+	} {
+		req, err := http.NewRequest(t.method, "http://bucket.endpoint"+t.url, nil)
+		c.Assert(err, IsNil)
+		if t.copySource != "" {
+			req.Header.Set("X-Oss-Copy-Source", t.copySource)
+		}
+		c.Assert(ossOperation(req), Equals, t.op, Commentf("%s %s", t.method, t.url))
+	}
+}
+
+func (s *OssutilCommandSuite) TestCopyMetrics(c *C) {
+	server := newFakeOSSServer("metricsID", "metricsSecret")
+	defer server.close()
+	client, err := oss.New(server.endpoint(), "metricsID", "metricsSecret")
+	c.Assert(err, IsNil)
+	bucketName := bucketNamePrefix + randLowStr(10)
+	c.Assert(client.CreateBucket(bucketName), IsNil)
+	server.inject(fakeOSSFault{op: "PutObject", object: "b", status: http.StatusInternalServerError, code: "InternalError", times: 1})
+
+	dir := randStr(10)
+	c.Assert(os.MkdirAll(dir, 0755), IsNil)
+	defer os.RemoveAll(dir)
+	s.createFile(dir+"/a", "aaa", c)
+	s.createFile(dir+"/b", "bbbbb", c)
+
+	err = s.initCopyCommand(dir, CloudURLToString(bucketName, ""), true, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	str := ""
+	ep, id, secret := server.endpoint(), "metricsID", "metricsSecret"
+	retryTimes := "3"
+	addr := "127.0.0.1:0"
+	for name, val := range map[string]*string{
+		OptionEndpoint:        &ep,
+		OptionAccessKeyID:     &id,
+		OptionAccessKeySecret: &secret,
+		OptionSTSToken:        &str,
+		OptionRetryTimes:      &retryTimes,
+		OptionMetricsAddr:     &addr,
+	} {
+		copyCommand.command.options[name] = val
+	}
+	c.Assert(copyCommand.RunCommand(), IsNil)
+
+	assertMetrics(string(copyCommand.command.metrics.render()), []string{
+		`ossutil_items_scanned{command="cp"} 2`,
+		`ossutil_items_total{command="cp",result="succeeded"} 2`,
+		`ossutil_items_total{command="cp",result="failed"} 0`,
+		`ossutil_transferred_bytes_total{command="cp"} 8`,
+		`ossutil_retries_total{command="cp"} 1`,
+		`ossutil_request_duration_seconds_count{command="cp",operation="PutObject"} 3`,
+		`ossutil_request_errors_total{command="cp",operation="PutObject"} 1`,
+	}, c)
+
+	// the address can not be listened on
+	err = s.initCopyCommand(dir, CloudURLToString(bucketName, ""), true, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	addr = "invalid address"
+	copyCommand.command.options[OptionMetricsAddr] = &addr
+	c.Assert(copyCommand.RunCommand(), NotNil)
+}
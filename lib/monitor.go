@@ -11,16 +11,20 @@ const (
 	errExit
 )
 
-var clearStrLen int = 0
-var clearStr string = strings.Repeat(" ", clearStrLen)
+// clearStrLen is the length of the last printed bar, the commands run at the same time by api share it
+var clearStrLen int64
 
 func getClearStr(str string) string {
-	if clearStrLen <= len(str) {
-		clearStrLen = len(str)
+	if atomic.LoadInt64(&clearStrLen) <= int64(len(str)) {
+		atomic.StoreInt64(&clearStrLen, int64(len(str)))
 		return fmt.Sprintf("\r%s", str)
 	}
-	clearStr = strings.Repeat(" ", clearStrLen)
-	return fmt.Sprintf("\r%s\r%s", clearStr, str)
+	return fmt.Sprintf("\r%s\r%s", getClearSpace(), str)
+}
+
+// getClearSpace returns the spaces to clear the last printed bar
+func getClearSpace() string {
+	return strings.Repeat(" ", int(atomic.LoadInt64(&clearStrLen)))
 }
 
 type Monitorer interface {
@@ -251,6 +255,28 @@ func (m *RMMonitor) getSnapshot() *RMMonitorSnap {
 	return &snap
 }
 
+func (m *RMMonitor) getProgress() Progress {
+	snap := m.getSnapshot()
+	return Progress{
+		TotalNum: m.totalObjectNum + m.totalUploadIdNum,
+		ScanEnd:  m.seekAheadEnd && m.seekAheadError == nil,
+		DealNum:  snap.dealNum,
+		OKNum:    snap.objectNum + snap.uploadIdNum,
+		ErrNum:   snap.errNum,
+	}
+}
+
+func (m *RMMonitor) getSummary() RemoveSummary {
+	snap := m.getSnapshot()
+	return RemoveSummary{
+		ObjectNum:      snap.objectNum,
+		UploadIDNum:    snap.uploadIdNum,
+		ErrObjectNum:   snap.errObjectNum,
+		ErrUploadIDNum: snap.errUploadIdNum,
+		RemovedBucket:  snap.removedBucket,
+	}
+}
+
 func (m *RMMonitor) progressBar(finish bool, exitStat int) string {
 	if m.finish {
 		return ""
@@ -462,6 +488,32 @@ func (m *CPMonitor) getSnapshot() *CPMonitorSnap {
 	return &snap
 }
 
+func (m *CPMonitor) getProgress() Progress {
+	snap := m.getSnapshot()
+	return Progress{
+		TotalNum:  m.totalNum,
+		TotalSize: m.totalSize,
+		ScanEnd:   m.seekAheadEnd && m.seekAheadError == nil,
+		DealNum:   snap.dealNum,
+		DealSize:  snap.dealSize,
+		OKNum:     snap.okNum,
+		ErrNum:    snap.errNum,
+		SkipNum:   snap.skipNum,
+	}
+}
+
+func (m *CPMonitor) getSummary() TransferSummary {
+	snap := m.getSnapshot()
+	return TransferSummary{
+		FileNum:      snap.fileNum,
+		DirNum:       snap.dirNum,
+		SkipNum:      snap.skipNum,
+		ErrNum:       snap.errNum,
+		TransferSize: snap.transferSize,
+		SkipSize:     snap.skipSize,
+	}
+}
+
 func (m *CPMonitor) progressBar(finish bool, exitStat int) string {
 	if m.finish {
 		return ""
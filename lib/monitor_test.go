@@ -1403,16 +1403,79 @@ func (s *OssutilCommandSuite) TestSnapshot(c *C) {
 	c.Assert(copyCommand.monitor.errNum, Equals, int64(0))
 
 	// download with snapshot
+	os.Remove(downloadFileName)
 	err = s.initCopyWithSnapshot(CloudURLToString(bucketName, object), downloadFileName, false, false, false, DefaultBigFileThreshold, spath)
 	c.Assert(err, IsNil)
 	err = copyCommand.RunCommand()
-	c.Assert(err, NotNil)
+	c.Assert(err, IsNil)
+	c.Assert(copyCommand.monitor.fileNum, Equals, int64(1))
+	c.Assert(copyCommand.monitor.skipNum, Equals, int64(0))
+
+	// download again
+	err = s.initCopyWithSnapshot(CloudURLToString(bucketName, object), downloadFileName, false, false, false, DefaultBigFileThreshold, spath)
+	c.Assert(err, IsNil)
+	err = copyCommand.RunCommand()
+	c.Assert(err, IsNil)
+	c.Assert(copyCommand.monitor.fileNum, Equals, int64(0))
+	c.Assert(copyCommand.monitor.skipNum, Equals, int64(1))
+
+	// local file removed, download again
+	os.Remove(downloadFileName)
+	err = s.initCopyWithSnapshot(CloudURLToString(bucketName, object), downloadFileName, false, false, false, DefaultBigFileThreshold, spath)
+	c.Assert(err, IsNil)
+	err = copyCommand.RunCommand()
+	c.Assert(err, IsNil)
+	c.Assert(copyCommand.monitor.fileNum, Equals, int64(1))
+	c.Assert(copyCommand.monitor.skipNum, Equals, int64(0))
+	str = s.readFile(downloadFileName, c)
+	c.Assert(str, Equals, data)
+
+	// object changed, the local file is not overwritten without force
+	newData := "new snapshot data"
+	s.createFile(uploadFileName, newData, c)
+	s.putObject(bucketName, object, uploadFileName, c)
+	err = s.initCopyWithSnapshot(CloudURLToString(bucketName, object), downloadFileName, false, false, false, DefaultBigFileThreshold, spath)
+	c.Assert(err, IsNil)
+	err = copyCommand.RunCommand()
+	c.Assert(err, IsNil)
+	c.Assert(copyCommand.monitor.fileNum, Equals, int64(0))
+	c.Assert(copyCommand.monitor.skipNum, Equals, int64(1))
+	str = s.readFile(downloadFileName, c)
+	c.Assert(str, Equals, data)
+
+	err = s.initCopyWithSnapshot(CloudURLToString(bucketName, object), downloadFileName, false, true, false, DefaultBigFileThreshold, spath)
+	c.Assert(err, IsNil)
+	err = copyCommand.RunCommand()
+	c.Assert(err, IsNil)
+	c.Assert(copyCommand.monitor.fileNum, Equals, int64(1))
+	str = s.readFile(downloadFileName, c)
+	c.Assert(str, Equals, newData)
 
 	// copy with snapshot
+	err = s.initCopyWithSnapshot(CloudURLToString(bucketName, object), CloudURLToString(bucketNameDest, object), false, true, false, DefaultBigFileThreshold, spath)
+	c.Assert(err, IsNil)
+	err = copyCommand.RunCommand()
+	c.Assert(err, IsNil)
+	c.Assert(copyCommand.monitor.fileNum, Equals, int64(1))
+	c.Assert(copyCommand.monitor.skipNum, Equals, int64(0))
+
+	// copy again
+	err = s.initCopyWithSnapshot(CloudURLToString(bucketName, object), CloudURLToString(bucketNameDest, object), false, true, false, DefaultBigFileThreshold, spath)
+	c.Assert(err, IsNil)
+	err = copyCommand.RunCommand()
+	c.Assert(err, IsNil)
+	c.Assert(copyCommand.monitor.fileNum, Equals, int64(0))
+	c.Assert(copyCommand.monitor.skipNum, Equals, int64(1))
+
+	// source changed, the dest object is not overwritten without force
+	s.createFile(uploadFileName, data, c)
+	s.putObject(bucketName, object, uploadFileName, c)
 	err = s.initCopyWithSnapshot(CloudURLToString(bucketName, object), CloudURLToString(bucketNameDest, object), false, false, false, DefaultBigFileThreshold, spath)
 	c.Assert(err, IsNil)
 	err = copyCommand.RunCommand()
-	c.Assert(err, NotNil)
+	c.Assert(err, IsNil)
+	c.Assert(copyCommand.monitor.fileNum, Equals, int64(0))
+	c.Assert(copyCommand.monitor.skipNum, Equals, int64(1))
 
 	os.RemoveAll(spath)
 
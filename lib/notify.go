@@ -0,0 +1,293 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// the events of notifications
+const (
+	notifyEventFinish    = "finish"
+	notifyEventHeartbeat = "heartbeat"
+)
+
+// the status of command in notifications
+const (
+	notifyStatusRunning     = "running"
+	notifyStatusSucceeded   = "succeeded"
+	notifyStatusFailed      = "failed"
+	notifyStatusInterrupted = "interrupted"
+)
+
+// the headers of notification request
+const (
+	HTTPHeaderOssutilEvent     = "X-Ossutil-Event"
+	HTTPHeaderOssutilSignature = "X-Ossutil-Signature"
+)
+
+// notifyCopyStat is the statistic of cp in notification, taken from the snapshot of CPMonitor
+type notifyCopyStat struct {
+	TotalNum     int64 `json:"totalNum"`
+	TotalSize    int64 `json:"totalSize"`
+	ScanEnd      bool  `json:"scanEnd"`
+	FileNum      int64 `json:"fileNum"`
+	DirNum       int64 `json:"dirNum"`
+	SkipNum      int64 `json:"skipNum"`
+	ErrNum       int64 `json:"errNum"`
+	TransferSize int64 `json:"transferSize"`
+	SkipSize     int64 `json:"skipSize"`
+}
+
+// notifyRemoveStat is the statistic of rm in notification, taken from the snapshot of RMMonitor
+type notifyRemoveStat struct {
+	TotalNum       int64  `json:"totalNum"`
+	ScanEnd        bool   `json:"scanEnd"`
+	ObjectNum      int64  `json:"objectNum"`
+	UploadIDNum    int64  `json:"uploadIdNum"`
+	ErrObjectNum   int64  `json:"errObjectNum"`
+	ErrUploadIDNum int64  `json:"errUploadIdNum"`
+	RemovedBucket  string `json:"removedBucket,omitempty"`
+}
+
+// notifyMessage is the json body POSTed to --notify-url
+type notifyMessage struct {
+	Event       string            `json:"event"`
+	Command     string            `json:"command"`
+	CommandLine string            `json:"commandLine"`
+	StartTime   string            `json:"startTime"`
+	EndTime     string            `json:"endTime,omitempty"`
+	Status      string            `json:"status"`
+	ExitStatus  int               `json:"exitStatus"`
+	Error       string            `json:"error,omitempty"`
+	ReportFile  string            `json:"reportFile,omitempty"`
+	Copy        *notifyCopyStat   `json:"copy,omitempty"`
+	Remove      *notifyRemoveStat `json:"remove,omitempty"`
+}
+
+// notifier POSTs the result of command to --notify-url when the command ends, and the progress
+// every --notify-heartbeat items. The heartbeats are sent in background and not retried, the
+// heartbeat is skipped if the previous one is still being sent.
+type notifier struct {
+	command    *Command
+	url        string
+	secret     string
+	retryTimes int64
+	heartbeat  int64
+	client     *http.Client
+	startTime  time.Time
+
+	// stat fills the statistic of monitor into the message
+	stat func(msg *notifyMessage)
+
+	beats    int64
+	sending  int32
+	mu       sync.Mutex
+	finished bool
+	wg       sync.WaitGroup
+}
+
+// newNotifier returns nil if --notify-url is not specified, the methods of nil notifier do nothing
+func (cmd *Command) newNotifier(stat func(msg *notifyMessage)) (*notifier, error) {
+	url, _ := GetString(OptionNotifyURL, cmd.options)
+	if url == "" {
+		return nil, nil
+	}
+
+	client, err := cmd.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = &http.Client{Transport: cmd.newTransport(nil, nil)}
+	}
+	client.Timeout = time.Duration(NotifyTimeout) * time.Second
+
+	secret, _ := GetString(OptionNotifySecret, cmd.options)
+	retryTimes, err := GetInt(OptionNotifyRetryTimes, cmd.options)
+	if err != nil {
+		retryTimes = DefaultNotifyRetryTimes
+	}
+	heartbeat, _ := GetInt(OptionNotifyHeartbeat, cmd.options)
+	return &notifier{
+		command:    cmd,
+		url:        url,
+		secret:     secret,
+		retryTimes: retryTimes,
+		heartbeat:  heartbeat,
+		client:     client,
+		startTime:  time.Now(),
+		stat:       stat,
+	}, nil
+}
+
+// progress sends a heartbeat when dealNum reaches the next multiple of --notify-heartbeat
+func (n *notifier) progress(dealNum int64) {
+	if n == nil || n.heartbeat <= 0 {
+		return
+	}
+	beats := dealNum / n.heartbeat
+	last := atomic.LoadInt64(&n.beats)
+	if beats <= last || !atomic.CompareAndSwapInt64(&n.beats, last, beats) {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&n.sending, 0, 1) {
+		return
+	}
+
+	// no heartbeat is sent after the command ends
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.finished {
+		return
+	}
+	msg := n.message(notifyEventHeartbeat)
+	msg.Status = notifyStatusRunning
+	n.wg.Add(1)
+	go func() {
+		defer func() {
+			atomic.StoreInt32(&n.sending, 0)
+			n.wg.Done()
+		}()
+		n.post(msg, 0)
+	}()
+}
+
+// finish sends the result of command with retries, the failure is shown but does not change
+// the result of command
+func (n *notifier) finish(err error, reportFile string) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	n.finished = true
+	n.mu.Unlock()
+	n.wg.Wait()
+
+	msg := n.message(notifyEventFinish)
+	msg.EndTime = time.Now().Format(time.RFC3339)
+	msg.ReportFile = reportFile
+	switch {
+	case err == nil:
+		msg.Status = notifyStatusSucceeded
+	case n.command.canceled():
+		msg.Status = notifyStatusInterrupted
+	default:
+		msg.Status = notifyStatusFailed
+	}
+	if err != nil {
+		msg.ExitStatus = 1
+		msg.Error = err.Error()
+	}
+	if err := n.post(msg, n.retryTimes); err != nil {
+		n.command.printf("\nnotify %s error, info: %s\n", n.url, err.Error())
+	}
+}
+
+func (n *notifier) message(event string) *notifyMessage {
+	msg := &notifyMessage{
+		Event:       event,
+		Command:     n.command.name,
+		CommandLine: commandLine,
+		StartTime:   n.startTime.Format(time.RFC3339),
+	}
+	if n.stat != nil {
+		n.stat(msg)
+	}
+	return msg
+}
+
+// post sends the message, the network errors, 429 and 5xx responses are retried with backoff
+func (n *notifier) post(msg *notifyMessage, retryTimes int64) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	for retries := 1; ; retries++ {
+		retry, err := n.send(msg.Event, body)
+		if err == nil || !retry || int64(retries) > retryTimes {
+			return err
+		}
+		time.Sleep(notifyRetryInterval(retries))
+	}
+}
+
+// send posts the body once, it returns whether the error can be retried
+func (n *notifier) send(event string, body []byte) (bool, error) {
+	req, err := http.NewRequest("POST", n.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", getUserAgent())
+	req.Header.Set(HTTPHeaderOssutilEvent, event)
+	if n.secret != "" {
+		req.Header.Set(HTTPHeaderOssutilSignature, "sha256="+notifySignature(n.secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5
+	return retry, fmt.Errorf("the server responds %s", resp.Status)
+}
+
+// notifySignature returns the hex HMAC-SHA256 of body with secret
+func notifySignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// notifyRetryInterval doubles the interval from 1 second for each retry, up to NotifyMaxRetryInterval
+func notifyRetryInterval(retries int) time.Duration {
+	interval := time.Second << uint(retries-1)
+	if max := time.Duration(NotifyMaxRetryInterval) * time.Second; retries > 16 || interval > max {
+		return max
+	}
+	return interval
+}
+
+func (m *CPMonitor) getNotifyStat() *notifyCopyStat {
+	snap := m.getSnapshot()
+	return &notifyCopyStat{
+		TotalNum:     m.totalNum,
+		TotalSize:    m.totalSize,
+		ScanEnd:      m.seekAheadEnd && m.seekAheadError == nil,
+		FileNum:      snap.fileNum,
+		DirNum:       snap.dirNum,
+		SkipNum:      snap.skipNum,
+		ErrNum:       snap.errNum,
+		TransferSize: snap.transferSize,
+		SkipSize:     snap.skipSize,
+	}
+}
+
+func (m *RMMonitor) getNotifyStat() *notifyRemoveStat {
+	snap := m.getSnapshot()
+	return &notifyRemoveStat{
+		TotalNum:       m.totalObjectNum + m.totalUploadIdNum,
+		ScanEnd:        m.seekAheadEnd && m.seekAheadError == nil,
+		ObjectNum:      snap.objectNum,
+		UploadIDNum:    snap.uploadIdNum,
+		ErrObjectNum:   snap.errObjectNum,
+		ErrUploadIDNum: snap.errUploadIdNum,
+		RemovedBucket:  snap.removedBucket,
+	}
+}
@@ -0,0 +1,253 @@
+package lib
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+// notifyServer records the notifications, it responds the statuses in order, then 200
+type notifyServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	headers  []http.Header
+	bodies   [][]byte
+}
+
+func newNotifyServer(statuses ...int) *notifyServer {
+	ns := &notifyServer{statuses: statuses}
+	ns.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		ns.mu.Lock()
+		ns.headers = append(ns.headers, r.Header)
+		ns.bodies = append(ns.bodies, body)
+		status := http.StatusOK
+		if len(ns.statuses) > 0 {
+			status, ns.statuses = ns.statuses[0], ns.statuses[1:]
+		}
+		ns.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	return ns
+}
+
+// messages returns the notifications of event received
+func (ns *notifyServer) messages(event string, c *C) ([]notifyMessage, []http.Header) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	msgs := []notifyMessage{}
+	headers := []http.Header{}
+	for i, body := range ns.bodies {
+		if ns.headers[i].Get(HTTPHeaderOssutilEvent) != event {
+			continue
+		}
+		var msg notifyMessage
+		c.Assert(json.Unmarshal(body, &msg), IsNil)
+		msgs = append(msgs, msg)
+		headers = append(headers, ns.headers[i])
+	}
+	return msgs, headers
+}
+
+func (s *OssutilCommandSuite) TestCopyNotify(c *C) {
+	ns := newNotifyServer()
+	defer ns.Close()
+
+	dir := randStr(10)
+	c.Assert(os.MkdirAll(dir, 0755), IsNil)
+	defer os.RemoveAll(dir)
+	s.createFile(dir+"/a", "aaa", c)
+	s.createFile(dir+"/b", "bbbbb", c)
+
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+	defer s.removeBucket(bucketName, true, c)
+
+	err := s.initCopyCommand(dir, CloudURLToString(bucketName, "notify/"), true, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	url := ns.URL + "/hook"
+	secret := "secret"
+	copyCommand.command.options[OptionNotifyURL] = &url
+	copyCommand.command.options[OptionNotifySecret] = &secret
+	c.Assert(copyCommand.RunCommand(), IsNil)
+
+	msgs, headers := ns.messages(notifyEventFinish, c)
+	c.Assert(len(msgs), Equals, 1)
+	msg := msgs[0]
+	c.Assert(msg.Command, Equals, "cp")
+	c.Assert(msg.CommandLine, Equals, commandLine)
+	c.Assert(msg.Status, Equals, notifyStatusSucceeded)
+	c.Assert(msg.ExitStatus, Equals, 0)
+	c.Assert(msg.Error, Equals, "")
+	c.Assert(msg.ReportFile, Equals, "")
+	c.Assert(msg.Remove, IsNil)
+	c.Assert(*msg.Copy, DeepEquals, notifyCopyStat{TotalNum: 2, TotalSize: 8, ScanEnd: true, FileNum: 2, TransferSize: 8})
+	start, err := time.Parse(time.RFC3339, msg.StartTime)
+	c.Assert(err, IsNil)
+	end, err := time.Parse(time.RFC3339, msg.EndTime)
+	c.Assert(err, IsNil)
+	c.Assert(end.Before(start), Equals, false)
+
+	// the signature is HMAC-SHA256 of body
+	ns.mu.Lock()
+	body := ns.bodies[len(ns.bodies)-1]
+	ns.mu.Unlock()
+	c.Assert(headers[0].Get(HTTPHeaderOssutilSignature), Equals, "sha256="+notifySignature(secret, body))
+	c.Assert(headers[0].Get("Content-Type"), Equals, "application/json")
+
+	// the failure is notified with exit status
+	err = s.initCopyCommand(dir+"/notexist", CloudURLToString(bucketName, "notify/"), true, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	copyCommand.command.options[OptionNotifyURL] = &url
+	err = copyCommand.RunCommand()
+	c.Assert(err, NotNil)
+	msgs, headers = ns.messages(notifyEventFinish, c)
+	c.Assert(len(msgs), Equals, 2)
+	c.Assert(msgs[1].Status, Equals, notifyStatusFailed)
+	c.Assert(msgs[1].ExitStatus, Equals, 1)
+	c.Assert(msgs[1].Error, Equals, err.Error())
+	c.Assert(headers[1].Get(HTTPHeaderOssutilSignature), Equals, "")
+}
+
+func (s *OssutilCommandSuite) TestCopyNotifyReportFile(c *C) {
+	ns := newNotifyServer()
+	defer ns.Close()
+
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+	defer s.removeBucket(bucketName, true, c)
+	s.putObject(bucketName, "report/object", uploadFileName, c)
+
+	// the object can not be downloaded to the path of a directory
+	dir := randStr(10)
+	c.Assert(os.MkdirAll(dir+"/report/object/sub", 0755), IsNil)
+	defer os.RemoveAll(dir)
+	outputDir := randStr(10)
+	defer os.RemoveAll(outputDir)
+
+	err := s.initCopyCommand(CloudURLToString(bucketName, "report/"), dir, true, true, false, DefaultBigFileThreshold, CheckpointDir, outputDir)
+	c.Assert(err, IsNil)
+	url := ns.URL
+	copyCommand.command.options[OptionNotifyURL] = &url
+	c.Assert(copyCommand.RunCommand(), IsNil)
+
+	msgs, _ := ns.messages(notifyEventFinish, c)
+	c.Assert(len(msgs), Equals, 1)
+	c.Assert(msgs[0].Copy.ErrNum, Equals, int64(1))
+	c.Assert(strings.HasPrefix(msgs[0].ReportFile, outputDir+string(os.PathSeparator)+ReportPrefix), Equals, true)
+	_, err = os.Stat(msgs[0].ReportFile)
+	c.Assert(err, IsNil)
+}
+
+func (s *OssutilCommandSuite) TestRemoveNotify(c *C) {
+	ns := newNotifyServer()
+	defer ns.Close()
+
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+	defer s.removeBucket(bucketName, true, c)
+	for _, object := range []string{"rm/1", "rm/2", "rm/3"} {
+		s.putObject(bucketName, object, uploadFileName, c)
+	}
+
+	c.Assert(s.initRemove(bucketName, "rm/", "rm -rf"), IsNil)
+	url := ns.URL
+	removeCommand.command.options[OptionNotifyURL] = &url
+	c.Assert(removeCommand.RunCommand(), IsNil)
+
+	msgs, _ := ns.messages(notifyEventFinish, c)
+	c.Assert(len(msgs), Equals, 1)
+	c.Assert(msgs[0].Command, Equals, "rm")
+	c.Assert(msgs[0].Status, Equals, notifyStatusSucceeded)
+	c.Assert(msgs[0].Copy, IsNil)
+	c.Assert(msgs[0].Remove.ObjectNum, Equals, int64(3))
+	c.Assert(msgs[0].Remove.ErrObjectNum, Equals, int64(0))
+}
+
+func (s *OssutilCommandSuite) TestNotifyRetry(c *C) {
+	ns := newNotifyServer(http.StatusInternalServerError, http.StatusTooManyRequests)
+	defer ns.Close()
+	cmd := Command{name: "cp", options: OptionMapType{}}
+	url := ns.URL
+	retryTimes := "2"
+	cmd.options[OptionNotifyURL] = &url
+	cmd.options[OptionNotifyRetryTimes] = &retryTimes
+	n, err := cmd.newNotifier(nil)
+	c.Assert(err, IsNil)
+
+	// 5xx and 429 are retried
+	n.finish(nil, "")
+	msgs, _ := ns.messages(notifyEventFinish, c)
+	c.Assert(len(msgs), Equals, 3)
+
+	// 4xx is not retried
+	ns.mu.Lock()
+	ns.statuses = []int{http.StatusBadRequest}
+	ns.mu.Unlock()
+	c.Assert(n.post(n.message(notifyEventFinish), 2), NotNil)
+	msgs, _ = ns.messages(notifyEventFinish, c)
+	c.Assert(len(msgs), Equals, 4)
+
+	// the retries are exhausted
+	ns.mu.Lock()
+	ns.statuses = []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}
+	ns.mu.Unlock()
+	c.Assert(n.post(n.message(notifyEventFinish), 1), NotNil)
+	msgs, _ = ns.messages(notifyEventFinish, c)
+	c.Assert(len(msgs), Equals, 6)
+
+	c.Assert(notifyRetryInterval(1), Equals, time.Second)
+	c.Assert(notifyRetryInterval(3), Equals, 4*time.Second)
+	c.Assert(notifyRetryInterval(100), Equals, time.Duration(NotifyMaxRetryInterval)*time.Second)
+
+	// no notifier without url
+	n, err = (&Command{options: OptionMapType{}}).newNotifier(nil)
+	c.Assert(err, IsNil)
+	c.Assert(n, IsNil)
+	n.progress(1)
+	n.finish(nil, "")
+}
+
+func (s *OssutilCommandSuite) TestNotifyHeartbeat(c *C) {
+	ns := newNotifyServer()
+	defer ns.Close()
+	cmd := Command{name: "cp", options: OptionMapType{}}
+	url := ns.URL
+	heartbeat := "2"
+	cmd.options[OptionNotifyURL] = &url
+	cmd.options[OptionNotifyHeartbeat] = &heartbeat
+	var monitor CPMonitor
+	monitor.init(operationTypePut)
+	n, err := cmd.newNotifier(func(msg *notifyMessage) {
+		msg.Copy = monitor.getNotifyStat()
+	})
+	c.Assert(err, IsNil)
+
+	// a heartbeat is sent for every 2 items
+	for i := int64(1); i <= 5; i++ {
+		monitor.updateFile(10, 1)
+		n.progress(i)
+		n.wg.Wait()
+	}
+	n.finish(nil, "")
+	n.progress(6)
+
+	beats, headers := ns.messages(notifyEventHeartbeat, c)
+	c.Assert(len(beats), Equals, 2)
+	c.Assert(beats[0].Status, Equals, notifyStatusRunning)
+	c.Assert(beats[0].EndTime, Equals, "")
+	c.Assert(beats[0].Copy.FileNum, Equals, int64(2))
+	c.Assert(beats[1].Copy.FileNum, Equals, int64(4))
+	c.Assert(headers[0].Get(HTTPHeaderOssutilSignature), Equals, "")
+	msgs, _ := ns.messages(notifyEventFinish, c)
+	c.Assert(len(msgs), Equals, 1)
+	c.Assert(msgs[0].Copy.FileNum, Equals, int64(5))
+}
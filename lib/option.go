@@ -70,12 +70,57 @@ var OptionMap = map[string]Option{
 		fmt.Sprintf("分片大小，默认情况下ossutil根据文件大小自行计算合适的分片大小值。如果有特殊需求或者需要性能调优，可以设置该值，取值范围：%dB-%dB", DefaultPartSize/1048576, MinPartSize, MaxPartSize),
 		fmt.Sprintf("Part size, in default situation, ossutil will calculate the suitable part size according to file size. The option is useful when user has special needs or user need to performance tuning, the value range is: %d-%d", DefaultPartSize/1048576, MinPartSize, MaxPartSize)},
 	OptionDisableCRC64: Option{"", "--disable-crc64", "", OptionTypeFlagTrue, "", "", "该选项关闭crc64，默认情况下，ossutil进行数据传输都打开crc64校验。", "Disable crc64, in default situation, ossutil open crc64 check when transmit data."},
+	OptionChecksum: Option{"", "--checksum", "", OptionTypeFlagTrue, "", "", "根据大小和crc64判断源和目标是否相同，而不是比较lastModifiedTime。cp命令中需要和--update选项一起使用。",
+		"Decide whether source and destination are the same by size and crc64 instead of lastModifiedTime. In cp command, it must be used with --update option."},
+	OptionSizeOnly: Option{"", "--size-only", "", OptionTypeFlagTrue, "", "", "和--update选项一起使用，只根据大小判断源和目标是否相同。",
+		"Used with --update option, decide whether source and destination are the same only by size."},
+	OptionPreserve: Option{"", "--preserve", "", OptionTypeFlagTrue, "", "", "上传时将本地文件的权限、属主、mtime和atime记录到object的meta中，下载时恢复这些属性（在有权限时）。",
+		"Record mode, owner, mtime and atime of local file in meta of object when upload, and restore them(where permitted) when download."},
+	OptionInclude: Option{"", "--include", "", OptionTypeString, "", "",
+		"只处理名称（object名中最后一个/之后的部分）匹配该通配符模式的object，例如：*.log。",
+		"only deal the objects whose name(the part after the last / of object name) matches the wildcard pattern, e.g: *.log."},
+	OptionExclude: Option{"", "--exclude", "", OptionTypeString, "", "",
+		"不处理名称（object名中最后一个/之后的部分）匹配该通配符模式的object，例如：*.tmp。",
+		"do not deal the objects whose name(the part after the last / of object name) matches the wildcard pattern, e.g: *.tmp."},
+	OptionDryRun: Option{"", "--dryrun", "", OptionTypeFlagTrue, "", "",
+		"只显示将要进行的操作，不实际执行。",
+		"only show the operations to be done, without doing them."},
+	OptionRestoreDays: Option{"", "--days", "", OptionTypeInt64, strconv.FormatInt(MinRestoreDays, 10), strconv.FormatInt(MaxRestoreDays, 10),
+		fmt.Sprintf("object解冻后保持可读状态的天数，取值范围：%d-%d，不指定时由oss决定（默认为1天）。", MinRestoreDays, MaxRestoreDays),
+		fmt.Sprintf("the days that the object keeps readable after restored, value range is: %d-%d, decided by oss if not specified(1 day in default).", MinRestoreDays, MaxRestoreDays)},
+	OptionRestoreTier: Option{"", "--restore-tier", "", OptionTypeAlternative, fmt.Sprintf("%s/%s/%s", RestoreTierExpedited, RestoreTierStandard, RestoreTierBulk), "",
+		fmt.Sprintf("解冻的优先级，取值范围：%s/%s/%s，不指定时由oss决定。", RestoreTierExpedited, RestoreTierStandard, RestoreTierBulk),
+		fmt.Sprintf("the tier of restore, value range is: %s/%s/%s, decided by oss if not specified.", RestoreTierExpedited, RestoreTierStandard, RestoreTierBulk)},
+	OptionWait: Option{"", "--wait", "", OptionTypeFlagTrue, "", "",
+		"发起解冻请求后，等待所有object解冻完成，进入可读状态。",
+		"wait until all the objects are restored and readable after restore requests are sent."},
+	OptionAutoRestore: Option{"", "--auto-restore", "", OptionTypeFlagTrue, "", "",
+		fmt.Sprintf("下载或拷贝时，自动解冻处于冷冻状态的%s类型object，等待解冻完成后再进行下载或拷贝。", StorageArchive),
+		fmt.Sprintf("when download or copy, restore frozen objects of %s storage class automatically, and download or copy them after they are restored.", StorageArchive)},
+	OptionManifest: Option{"", "--manifest", "", OptionTypeString, "", "",
+		"批量创建符号链接时，指定描述符号链接的文件，文件每行包含符号链接object名和目标object名，以空白字符分隔（名称中含有空格时，以tab分隔）。",
+		"specify the file describing symlinks when create symlinks in batch, each line of the file contains the symlink object name and target object name, separated by blank characters(separated by tab if the names contain spaces)."},
+	OptionSymlinks: Option{"", "--symlinks", "", OptionTypeAlternative, fmt.Sprintf("%s/%s/%s", SymlinkSkip, SymlinkFollow, SymlinkPreserve), "",
+		fmt.Sprintf("上传时本地符号链接的处理方式，取值范围：%s/%s/%s。%s表示忽略符号链接；%s表示上传链接指向的文件，并进入链接指向的目录（检测循环链接）；%s表示将符号链接上传为oss上的symlink object，下载时将symlink object还原为本地符号链接。", SymlinkSkip, SymlinkFollow, SymlinkPreserve, SymlinkSkip, SymlinkFollow, SymlinkPreserve),
+		fmt.Sprintf("The way to deal local symlinks when upload, value range is: %s/%s/%s. %s means ignore symlinks; %s means upload the files the links point to, and walk into the linked directories(with loop detection); %s means upload symlinks as symlink objects in oss, and turn symlink objects into local symlinks when download.", SymlinkSkip, SymlinkFollow, SymlinkPreserve, SymlinkSkip, SymlinkFollow, SymlinkPreserve)},
+	OptionOutputFormat: Option{"", "--output-format", OutputFormatText, OptionTypeAlternative, fmt.Sprintf("%s/%s", OutputFormatText, OutputFormatJSON), "",
+		fmt.Sprintf("输出的格式，默认值：%s，取值范围：%s/%s", OutputFormatText, OutputFormatText, OutputFormatJSON),
+		fmt.Sprintf("Format of output, default: %s, value range is: %s/%s", OutputFormatText, OutputFormatText, OutputFormatJSON)},
+	OptionExitCode: Option{"", "--exit-code", "", OptionTypeFlagTrue, "", "", "存在差异（diff命令）或者发现需要关注的acl（get-acl命令）时，以非0值退出，便于在脚本中使用。",
+		"Exit with non-zero value if there are differences(diff command) or flagged acls(get-acl command), which is useful in scripts."},
+	OptionSummary: Option{"", "--summary", "", OptionTypeFlagTrue, "", "", "只输出需要关注的acl，即公共读或公共读写的object，以及acl与bucket acl不同的object，并输出统计信息。",
+		"Only output flagged acls, which are public-read or public-read-write objects, and objects whose acl differs from bucket acl, with the statistic."},
+	OptionVerify: Option{"", "--verify", "", OptionTypeFlagTrue, "", "", "传输完成后，重新获取目标的大小和crc64值（oss上的object通过HEAD请求获取，本地文件计算crc64），并与源比较，不一致时该文件记为失败。",
+		"After transfer, get size and crc64 of destination again(by HEAD request for oss object, compute crc64 for local file) and compare with source, if they are different, the file is counted as failed."},
+	OptionVerifyRetry: Option{"", "--verify-retry", "0", OptionTypeInt64, "0", strconv.FormatInt(MaxRetryTimes, 10),
+		fmt.Sprintf("和--verify选项一起使用，校验不一致时自动重新传输的次数，默认值：0，取值范围：0-%d", MaxRetryTimes),
+		fmt.Sprintf("Used with --verify option, the times to transfer again automatically when verify failed, default: 0, value range is: 0-%d", MaxRetryTimes)},
 	OptionCheckpointDir: Option{"", "--checkpoint-dir", CheckpointDir, OptionTypeString, "", "",
-		fmt.Sprintf("checkpoint目录的路径(默认值为:%s)，断点续传时，操作失败ossutil会自动创建该目录，并在该目录下记录checkpoint信息，操作成功会删除该目录。如果指定了该选项，请确保所指定的目录可以被删除。", CheckpointDir),
-		fmt.Sprintf("Path of checkpoint directory(default:%s), the directory is used in resume upload or download, when operate failed, ossutil will create the directory automatically, and record the checkpoint information in the directory, when the operation is succeed, the directory will be removed, so when specify the option, please make sure the directory can be removed.", CheckpointDir)},
+		fmt.Sprintf("checkpoint目录的路径(默认值为:%s)，断点续传时，操作失败ossutil会自动创建该目录，并在该目录下记录checkpoint信息，操作成功会删除该目录。批量执行cp、rm、set-meta、restore时，ossutil也会在该目录下记录任务日志，中断后以相同的命令重新执行，已处理的文件或object会被跳过。如果指定了该选项，请确保所指定的目录可以被删除。", CheckpointDir),
+		fmt.Sprintf("Path of checkpoint directory(default:%s), the directory is used in resume upload or download, when operate failed, ossutil will create the directory automatically, and record the checkpoint information in the directory, when the operation is succeed, the directory will be removed. When batch cp, rm, set-meta or restore, ossutil also records the job journal in the directory, if the job is interrupted, run the same command again, the files or objects already dealed will be skipped. So when specify the option, please make sure the directory can be removed.", CheckpointDir)},
 	OptionSnapshotPath: Option{"", "--snapshot-path", "", OptionTypeString, "", "",
-		"该选项用于在某些场景下加速增量上传批量文件（目前，下载和拷贝不支持该选项）。在cp上传文件时使用该选项，ossutil在指定的目录下生成文件记录文件上传的快照信息，在下一次指定该选项上传时，ossutil会读取指定目录下的快照信息进行增量上传。用户指定的snapshot目录必须为本地文件系统上的可写目录，若该目录不存在，ossutil会创建该文件用于记录快照信息，如果该目录已存在，ossutil会读取里面的快照信息，根据快照信息进行增量上传（只上传上次未成功上传的文件和本地进行过修改的文件），并更新快照信息。注意：因为该选项通过在本地记录成功上传的文件的本地lastModifiedTime，从而在下次上传时通过比较lastModifiedTime来决定是否跳过相同文件的上传，所以在使用该选项时，请确保两次上传期间没有其他用户更改了oss上的对应object。当不满足该场景时，如果想要增量上传批量文件，请使用--update选项。另外，ossutil不会主动删除snapshot-path下的快照信息，为了避免快照信息过多，当用户确定快照信息无用时，请用户自行清理snapshot-path。",
-		"This option is used to accelerate the incremental upload of batch files in certain scenarios(currently, download and copy do not support this option). If you use the option when batch copy files, ossutil will generate files to record the snapshot information in the specified directory. When the next time you upload files with the option, ossutil will read the snapshot information under the specified directory for incremental upload. The snapshot-path you specified must be a local file system directory can be written in, if the directory does not exist, ossutil creates the files for recording snapshot information, else ossutil will read snapshot information from the path for incremental upload(ossutil will only upload the files which has not been successfully upload to oss and the files has been locally modified), and update the snapshot information to the directory. Note: The option record the lastModifiedTime of local files which has been successfully upload in local file system, and compare the lastModifiedTime of local files in the next cp to decided whether to skip the upload of the files, so if you use the option to achieve incremental upload, please make sure no other user modified the corresponding object in oss during the two uploads. If you can not guarantee the scenarios, please use --update option to achieve incremental upload. In addition, ossutil does not automatically delete snapshot-path snapshot information, in order to avoid too much snapshot information, when the snapshot information is useless, please clean up your own snapshot-path on your own."},
+		"该选项用于在某些场景下加速增量上传、下载或拷贝批量文件。下载时ossutil记录object的ETag和lastModifiedTime，拷贝时记录源object的ETag，再次执行时跳过未变化的object，不需要逐个查询object。在cp上传文件时使用该选项，ossutil在指定的目录下生成文件记录文件上传的快照信息，在下一次指定该选项上传时，ossutil会读取指定目录下的快照信息进行增量上传。用户指定的snapshot目录必须为本地文件系统上的可写目录，若该目录不存在，ossutil会创建该文件用于记录快照信息，如果该目录已存在，ossutil会读取里面的快照信息，根据快照信息进行增量上传（只上传上次未成功上传的文件和本地进行过修改的文件），并更新快照信息。注意：因为该选项通过在本地记录成功上传的文件的本地lastModifiedTime，从而在下次上传时通过比较lastModifiedTime来决定是否跳过相同文件的上传，所以在使用该选项时，请确保两次上传期间没有其他用户更改了oss上的对应object。当不满足该场景时，如果想要增量上传批量文件，请使用--update选项。另外，ossutil不会主动删除snapshot-path下的快照信息，为了避免快照信息过多，当用户确定快照信息无用时，请用户自行清理snapshot-path。",
+		"This option is used to accelerate the incremental upload, download or copy of batch files in certain scenarios. When download, ossutil records ETag and lastModifiedTime of objects, when copy, ossutil records ETag of source objects, and skips the unchanged objects next time without request for each object. If you use the option when batch copy files, ossutil will generate files to record the snapshot information in the specified directory. When the next time you upload files with the option, ossutil will read the snapshot information under the specified directory for incremental upload. The snapshot-path you specified must be a local file system directory can be written in, if the directory does not exist, ossutil creates the files for recording snapshot information, else ossutil will read snapshot information from the path for incremental upload(ossutil will only upload the files which has not been successfully upload to oss and the files has been locally modified), and update the snapshot information to the directory. Note: The option record the lastModifiedTime of local files which has been successfully upload in local file system, and compare the lastModifiedTime of local files in the next cp to decided whether to skip the upload of the files, so if you use the option to achieve incremental upload, please make sure no other user modified the corresponding object in oss during the two uploads. If you can not guarantee the scenarios, please use --update option to achieve incremental upload. In addition, ossutil does not automatically delete snapshot-path snapshot information, in order to avoid too much snapshot information, when the snapshot information is useless, please clean up your own snapshot-path on your own."},
 	OptionRetryTimes: Option{"", "--retry-times", strconv.Itoa(RetryTimes), OptionTypeInt64, strconv.FormatInt(MinRetryTimes, 10), strconv.FormatInt(MaxRetryTimes, 10),
 		fmt.Sprintf("当错误发生时的重试次数，默认值：%d，取值范围：%d-%d", RetryTimes, MinRetryTimes, MaxRetryTimes),
 		fmt.Sprintf("retry times when fail(default: %d), value range is: %d-%d", RetryTimes, MinRetryTimes, MaxRetryTimes)},
@@ -89,6 +134,98 @@ var OptionMap = map[string]Option{
 	OptionEncodingType: Option{"", "--encoding-type", "", OptionTypeAlternative, URLEncodingType, "",
 		fmt.Sprintf("输入的object名或文件名的编码方式，目前只支持url encode，即指定该选项时，取值范围为：%s，如果不指定该选项，则表示object名或文件名未经过编码。bucket名不支持url encode。注意，如果指定了该选项，则形如oss://bucket/object的cloud_url，输入形式为：oss://bucket/url_encode(object)，其中oss://bucket/字符串不需要编码。", URLEncodingType),
 		fmt.Sprintf("the encoding type of object name or file name that user inputs, currently ossutil only supports url encode, which means the value range of the option is: %s, if you do not specify the option, it means the object name or file name that user inputed was not encoded. bucket name does not support url encode. Note, if the option is specified, the cloud_url like: oss://bucket/object should be inputted as: oss://bucket/url_encode(object), the string: oss://bucket/ should not be url encoded.", URLEncodingType)},
+	OptionProxyHost: Option{"", "--proxy-host", "", OptionTypeString, "", "",
+		"访问oss使用的http代理服务器，形如：http://proxy.com:3128（该选项值会覆盖配置文件中的相应设置）。",
+		"the http proxy server to access oss through, e.g: http://proxy.com:3128(Notice that the value of the option will cover the value in config file)."},
+	OptionProxyUser: Option{"", "--proxy-user", "", OptionTypeString, "", "", "代理服务器的用户名（该选项值会覆盖配置文件中的相应设置）。", "the user name of proxy server(Notice that the value of the option will cover the value in config file)."},
+	OptionProxyPwd:  Option{"", "--proxy-pwd", "", OptionTypeString, "", "", "代理服务器的密码（该选项值会覆盖配置文件中的相应设置）。", "the password of proxy server(Notice that the value of the option will cover the value in config file)."},
+	OptionCAFile: Option{"", "--ca-file", "", OptionTypeString, "", "",
+		"PEM格式的CA证书文件，用于校验https服务端的证书（例如使用私有CA签发证书的endpoint），不指定时使用系统的CA证书。",
+		"the CA certificates file in PEM format, which is used to verify the certificate of https server(e.g. the endpoint whose certificate is issued by private CA), the CA certificates of system are used if not specified."},
+	OptionSkipVerify: Option{"", "--insecure-skip-verify", "", OptionTypeFlagTrue, "", "",
+		"不校验https服务端的证书。注意：这使得连接可能被中间人攻击，仅在测试环境中使用。",
+		"do not verify the certificate of https server. Notice that it makes the connection vulnerable to man-in-the-middle attacks, use it in test environments only."},
+	OptionForceHTTPS: Option{"", "--force-https", "", OptionTypeFlagTrue, "", "",
+		"使用https访问oss，即使endpoint未指定或者指定为http。",
+		"access oss by https, even if the scheme of endpoint is not specified or is http."},
+	OptionConnectTimeout: Option{"", "--connect-timeout", strconv.FormatInt(DefaultConnectTimeout, 10), OptionTypeInt64, strconv.FormatInt(MinTimeout, 10), strconv.FormatInt(MaxTimeout, 10),
+		fmt.Sprintf("建立连接的超时时间，单位为秒，默认值：%d，取值范围：%d-%d", DefaultConnectTimeout, MinTimeout, MaxTimeout),
+		fmt.Sprintf("the timeout of connecting in seconds(default: %d), value range is: %d-%d", DefaultConnectTimeout, MinTimeout, MaxTimeout)},
+	OptionReadTimeout: Option{"", "--read-timeout", strconv.FormatInt(DefaultReadTimeout, 10), OptionTypeInt64, strconv.FormatInt(MinTimeout, 10), strconv.FormatInt(MaxTimeout, 10),
+		fmt.Sprintf("读写数据的超时时间，单位为秒，连接上超过该时间没有数据传输时请求失败，默认值：%d，取值范围：%d-%d", DefaultReadTimeout, MinTimeout, MaxTimeout),
+		fmt.Sprintf("the timeout of reading or writing in seconds, the request fails if no data is transferred on the connection in the time(default: %d), value range is: %d-%d", DefaultReadTimeout, MinTimeout, MaxTimeout)},
+	OptionDiscover: Option{"", "--discover", "", OptionTypeFlagTrue, "", "",
+		"列举账号的所有bucket，将每个bucket所在地域的endpoint添加到配置文件的Bucket-Endpoint中。",
+		"list all the buckets of the account, and add the endpoint of the region where each bucket is to Bucket-Endpoint section of config file."},
+	OptionDestConfigFile: Option{"", "--dest-config-file", "", OptionTypeString, "", "",
+		"oss间拷贝时，目标bucket使用的配置文件，其中的endpoint和AccessKey用于访问目标bucket。",
+		"the config file for the destination bucket while copy between oss, the endpoint and AccessKey in it are used to access the destination bucket."},
+	OptionDestEndpoint: Option{"", "--dest-endpoint", "", OptionTypeString, "", "",
+		"oss间拷贝时，目标bucket的endpoint（该选项值会覆盖--dest-config-file中的相应设置）。",
+		"the endpoint of the destination bucket while copy between oss(Notice that the value of the option will cover the value in --dest-config-file)."},
+	OptionDestKeyID: Option{"", "--dest-access-key-id", "", OptionTypeString, "", "",
+		"oss间拷贝时，访问目标bucket的AccessKeyID（该选项值会覆盖--dest-config-file中的相应设置），未指定--dest-config-file时需要和--dest-access-key-secret一起使用。",
+		"the AccessKeyID to access the destination bucket while copy between oss(Notice that the value of the option will cover the value in --dest-config-file), it must be used with --dest-access-key-secret if --dest-config-file is not specified."},
+	OptionDestKeySecret: Option{"", "--dest-access-key-secret", "", OptionTypeString, "", "",
+		"oss间拷贝时，访问目标bucket的AccessKeySecret（该选项值会覆盖--dest-config-file中的相应设置），未指定--dest-config-file时需要和--dest-access-key-id一起使用。",
+		"the AccessKeySecret to access the destination bucket while copy between oss(Notice that the value of the option will cover the value in --dest-config-file), it must be used with --dest-access-key-id if --dest-config-file is not specified."},
+	OptionDestSTSToken: Option{"", "--dest-sts-token", "", OptionTypeString, "", "",
+		"oss间拷贝时，访问目标bucket的STSToken（该选项值会覆盖--dest-config-file中的相应设置）。",
+		"the STSToken to access the destination bucket while copy between oss(Notice that the value of the option will cover the value in --dest-config-file)."},
+	OptionS3Endpoint: Option{"", "--s3-endpoint", "", OptionTypeString, "", "",
+		"源为s3://时，s3兼容存储的endpoint，未指定协议时使用http。",
+		"the endpoint of s3 compatible storage when source is s3://, http is used if the scheme is not specified."},
+	OptionS3KeyID: Option{"", "--s3-access-key-id", "", OptionTypeString, "", "",
+		"访问s3兼容存储的AccessKeyID，未指定时使用环境变量AWS_ACCESS_KEY_ID。",
+		"the AccessKeyID to access s3 compatible storage, the environment variable AWS_ACCESS_KEY_ID is used if it's not specified."},
+	OptionS3KeySecret: Option{"", "--s3-access-key-secret", "", OptionTypeString, "", "",
+		"访问s3兼容存储的AccessKeySecret，未指定时使用环境变量AWS_SECRET_ACCESS_KEY。",
+		"the AccessKeySecret to access s3 compatible storage, the environment variable AWS_SECRET_ACCESS_KEY is used if it's not specified."},
+	OptionS3Token: Option{"", "--s3-session-token", "", OptionTypeString, "", "",
+		"访问s3兼容存储的临时凭证的SessionToken，未指定时使用环境变量AWS_SESSION_TOKEN。",
+		"the SessionToken of temporary credentials to access s3 compatible storage, the environment variable AWS_SESSION_TOKEN is used if it's not specified."},
+	OptionS3Region: Option{"", "--s3-region", "", OptionTypeString, "", "",
+		fmt.Sprintf("s3兼容存储的region，用于请求签名，未指定时使用环境变量AWS_REGION，默认值：%s", DefaultS3Region),
+		fmt.Sprintf("the region of s3 compatible storage used to sign requests, the environment variable AWS_REGION is used if it's not specified(default: %s)", DefaultS3Region)},
+	OptionWatch: Option{"", "--watch", "", OptionTypeFlagTrue, "", "",
+		"上传目录后持续监听目录的变化，上传新建或修改的文件，直到命令被中断。",
+		"after uploading the directory, keep watching it and upload the files created or modified, until the command is interrupted."},
+	OptionQuietPeriod: Option{"", "--quiet-period", strconv.FormatInt(DefaultQuietPeriod, 10), OptionTypeInt64, strconv.FormatInt(MinQuietPeriod, 10), strconv.FormatInt(MaxQuietPeriod, 10),
+		fmt.Sprintf("和--watch一起使用，文件在该时间内没有变化后才上传，单位为秒，默认值：%d，取值范围：%d-%d", DefaultQuietPeriod, MinQuietPeriod, MaxQuietPeriod),
+		fmt.Sprintf("used with --watch, the file is uploaded after it's not changed for the period in seconds(default: %d), value range is: %d-%d", DefaultQuietPeriod, MinQuietPeriod, MaxQuietPeriod)},
+	OptionPollInterval: Option{"", "--poll-interval", "", OptionTypeInt64, strconv.FormatInt(MinPollInterval, 10), strconv.FormatInt(MaxPollInterval, 10),
+		fmt.Sprintf("和--watch一起使用，不使用文件系统通知，而是按该间隔扫描目录，单位为秒，取值范围：%d-%d。文件系统不支持通知时，默认每%d秒扫描一次。", MinPollInterval, MaxPollInterval, DefaultPollInterval),
+		fmt.Sprintf("used with --watch, scan the directory by the interval in seconds instead of file system notification, value range is: %d-%d. If file system notification is not supported, the directory is scanned every %d seconds by default.", MinPollInterval, MaxPollInterval, DefaultPollInterval)},
+	OptionOnSuccess: Option{"", "--on-success", "", OptionTypeString, "", "",
+		"批量操作中每个文件（或object）成功后执行的命令，文件的信息通过环境变量和标准输入的json传递，详见cp命令的帮助。",
+		"the command run after each file(or object) succeeds in batch operation, the detail of the file is passed by environment variables and json on stdin, see help of cp command."},
+	OptionOnError: Option{"", "--on-error", "", OptionTypeString, "", "",
+		"批量操作中每个文件（或object）失败后执行的命令，文件的信息和错误通过环境变量和标准输入的json传递，详见cp命令的帮助。",
+		"the command run after each file(or object) fails in batch operation, the detail and error of the file are passed by environment variables and json on stdin, see help of cp command."},
+	OptionOnFinish: Option{"", "--on-finish", "", OptionTypeString, "", "",
+		"批量操作结束后执行的命令，成功和失败的数量通过环境变量和标准输入的json传递，详见cp命令的帮助。",
+		"the command run after batch operation finishes, the numbers of succeeded and failed files are passed by environment variables and json on stdin, see help of cp command."},
+	OptionHookRoutines: Option{"", "--hook-routines", strconv.FormatInt(DefaultHookRoutines, 10), OptionTypeInt64, strconv.FormatInt(MinHookRoutines, 10), strconv.FormatInt(MaxHookRoutines, 10),
+		fmt.Sprintf("同时执行的--on-success和--on-error命令的最大数量，默认值：%d，取值范围：%d-%d", DefaultHookRoutines, MinHookRoutines, MaxHookRoutines),
+		fmt.Sprintf("the max number of --on-success and --on-error commands run concurrently(default: %d), value range is: %d-%d", DefaultHookRoutines, MinHookRoutines, MaxHookRoutines)},
+	OptionHookTimeout: Option{"", "--hook-timeout", strconv.FormatInt(DefaultHookTimeout, 10), OptionTypeInt64, strconv.FormatInt(MinHookTimeout, 10), strconv.FormatInt(MaxHookTimeout, 10),
+		fmt.Sprintf("--on-success、--on-error、--on-finish命令的超时时间，超时后命令被终止，单位为秒，默认值：%d，取值范围：%d-%d", DefaultHookTimeout, MinHookTimeout, MaxHookTimeout),
+		fmt.Sprintf("the timeout of --on-success, --on-error and --on-finish commands in seconds, the command is killed after timeout(default: %d), value range is: %d-%d", DefaultHookTimeout, MinHookTimeout, MaxHookTimeout)},
+	OptionNotifyURL: Option{"", "--notify-url", "", OptionTypeString, "", "",
+		"命令结束时，将执行结果的json摘要以POST请求发送到该url，详见cp命令的帮助。",
+		"the url which the json summary of result is POSTed to when the command ends, see help of cp command."},
+	OptionNotifySecret: Option{"", "--notify-secret", "", OptionTypeString, "", "",
+		"用于签名--notify-url通知的密钥，签名为请求body的HMAC-SHA256，放在X-Ossutil-Signature头中。",
+		"the secret to sign the notifications of --notify-url, the signature is HMAC-SHA256 of request body, which is put in X-Ossutil-Signature header."},
+	OptionNotifyRetryTimes: Option{"", "--notify-retry-times", strconv.FormatInt(DefaultNotifyRetryTimes, 10), OptionTypeInt64, strconv.FormatInt(MinNotifyRetryTimes, 10), strconv.FormatInt(MaxNotifyRetryTimes, 10),
+		fmt.Sprintf("发送结束通知失败时的重试次数，默认值：%d，取值范围：%d-%d", DefaultNotifyRetryTimes, MinNotifyRetryTimes, MaxNotifyRetryTimes),
+		fmt.Sprintf("the retry times when the notification of end fails(default: %d), value range is: %d-%d", DefaultNotifyRetryTimes, MinNotifyRetryTimes, MaxNotifyRetryTimes)},
+	OptionNotifyHeartbeat: Option{"", "--notify-heartbeat", "0", OptionTypeInt64, "0", "",
+		"每处理指定数量的文件或object发送一次进度通知，默认值：0，表示不发送进度通知。",
+		"send a notification of progress each time the specified number of files or objects are dealt with(default: 0), 0 means no notification of progress."},
+	OptionMetricsAddr: Option{"", "--metrics-addr", "", OptionTypeString, "", "",
+		"命令运行期间，在该地址（如:9100）的/metrics路径提供prometheus格式的指标，详见cp命令的帮助。",
+		"serve the metrics in prometheus format on /metrics of the address(such as :9100) while the command runs, see help of cp command."},
 	OptionLanguage: Option{"-L", "--language", DefaultLanguage, OptionTypeAlternative, fmt.Sprintf("%s/%s", ChineseLanguage, EnglishLanguage), "",
 		fmt.Sprintf("设置ossutil工具的语言，默认值：%s，取值范围：%s/%s，若设置成\"%s\"，请确保您的系统编码为UTF-8。", DefaultLanguage, ChineseLanguage, EnglishLanguage, ChineseLanguage),
 		fmt.Sprintf("set the language of ossutil(default: %s), value range is: %s/%s, if you set it to \"%s\", please make sure your system language is UTF-8.", DefaultLanguage, ChineseLanguage, EnglishLanguage, ChineseLanguage)},
@@ -191,26 +328,15 @@ func checkOption(options OptionMapType) error {
 					if err != nil {
 						return fmt.Errorf("invalid option value of %s, the value: %s is not int64, please check", name, *val)
 					}
-
-					if optionInfo.minVal != "" {
-						minv, _ := strconv.ParseInt(optionInfo.minVal, 10, 64)
-						if num < minv {
-							return fmt.Errorf("invalid option value of %s, the value: %d is smaller than the min value range: %d", name, num, minv)
-						}
-					}
-					if optionInfo.maxVal != "" {
-						maxv, _ := strconv.ParseInt(optionInfo.maxVal, 10, 64)
-						if num > maxv {
-							return fmt.Errorf("invalid option value of %s, the value: %d is bigger than the max value range: %d", name, num, maxv)
-						}
+					if err := checkIntOptionValue(name, num); err != nil {
+						return err
 					}
 				}
 			}
 			if optionInfo.optionType == OptionTypeAlternative {
 				if val, ook := option.(*string); ook && *val != "" {
-					vals := strings.Split(optionInfo.minVal, "/")
-					if FindPosCaseInsen(*val, vals) == -1 {
-						return fmt.Errorf("invalid option value of %s, the value: %s is not anyone of %s", name, *val, optionInfo.minVal)
+					if err := checkAlternativeOptionValue(name, *val); err != nil {
+						return err
 					}
 				}
 			}
@@ -219,6 +345,34 @@ func checkOption(options OptionMapType) error {
 	return nil
 }
 
+// checkIntOptionValue checks the int value of option name is in the range of OptionMap
+func checkIntOptionValue(name string, num int64) error {
+	optionInfo := OptionMap[name]
+	if optionInfo.minVal != "" {
+		minv, _ := strconv.ParseInt(optionInfo.minVal, 10, 64)
+		if num < minv {
+			return fmt.Errorf("invalid option value of %s, the value: %d is smaller than the min value range: %d", name, num, minv)
+		}
+	}
+	if optionInfo.maxVal != "" {
+		maxv, _ := strconv.ParseInt(optionInfo.maxVal, 10, 64)
+		if num > maxv {
+			return fmt.Errorf("invalid option value of %s, the value: %d is bigger than the max value range: %d", name, num, maxv)
+		}
+	}
+	return nil
+}
+
+// checkAlternativeOptionValue checks the value of option name is one of the alternatives of OptionMap
+func checkAlternativeOptionValue(name, val string) error {
+	optionInfo := OptionMap[name]
+	vals := strings.Split(optionInfo.minVal, "/")
+	if FindPosCaseInsen(val, vals) == -1 {
+		return fmt.Errorf("invalid option value of %s, the value: %s is not anyone of %s", name, val, optionInfo.minVal)
+	}
+	return nil
+}
+
 // GetBool is used to get bool option from option map parsed by ParseArgOptions
 func GetBool(name string, options OptionMapType) (bool, error) {
 	if option, ok := options[name]; ok {
@@ -0,0 +1,70 @@
+package lib
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// the meta names to record attributes of local file when --preserve is specified
+const (
+	preserveMetaMode  string = "mode"
+	preserveMetaUID          = "uid"
+	preserveMetaGID          = "gid"
+	preserveMetaMtime        = "mtime"
+	preserveMetaAtime        = "atime"
+)
+
+// getPreserveOptions returns the meta options recording mode, owner, mtime and atime of local file
+func getPreserveOptions(f os.FileInfo) []oss.Option {
+	options := []oss.Option{
+		oss.Meta(preserveMetaMode, strconv.FormatUint(uint64(f.Mode().Perm()), 8)),
+		oss.Meta(preserveMetaMtime, strconv.FormatInt(f.ModTime().Unix(), 10)),
+	}
+	if uid, gid, ok := fileOwner(f); ok {
+		options = append(options, oss.Meta(preserveMetaUID, strconv.Itoa(uid)), oss.Meta(preserveMetaGID, strconv.Itoa(gid)))
+	}
+	if atime, ok := fileAccessTime(f); ok {
+		options = append(options, oss.Meta(preserveMetaAtime, strconv.FormatInt(atime.Unix(), 10)))
+	}
+	return options
+}
+
+// restoreFileAttributes sets mtime of downloaded file to lastModified of object. If props is not nil,
+// the attributes recorded in meta are restored, mode and owner are only set where permitted.
+func restoreFileAttributes(fileName string, props http.Header, lastModified time.Time) error {
+	mtime := lastModified
+	atime := lastModified
+	if props != nil {
+		if t, ok := getPreserveTime(props, preserveMetaMtime); ok {
+			mtime = t
+			atime = t
+		}
+		if t, ok := getPreserveTime(props, preserveMetaAtime); ok {
+			atime = t
+		}
+		if mode, err := strconv.ParseUint(props.Get(oss.HTTPHeaderOssMetaPrefix+preserveMetaMode), 8, 32); err == nil {
+			if err := os.Chmod(fileName, os.FileMode(mode).Perm()); err != nil {
+				return err
+			}
+		}
+		uid, uerr := strconv.Atoi(props.Get(oss.HTTPHeaderOssMetaPrefix + preserveMetaUID))
+		gid, gerr := strconv.Atoi(props.Get(oss.HTTPHeaderOssMetaPrefix + preserveMetaGID))
+		if uerr == nil && gerr == nil {
+			// only privileged user can change owner, ignore the error
+			os.Lchown(fileName, uid, gid)
+		}
+	}
+	return os.Chtimes(fileName, atime, mtime)
+}
+
+func getPreserveTime(props http.Header, name string) (time.Time, bool) {
+	sec, err := strconv.ParseInt(props.Get(oss.HTTPHeaderOssMetaPrefix+name), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(sec, 0), true
+}
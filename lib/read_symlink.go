@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
@@ -18,6 +19,7 @@ var specChineseReadSymlink = SpecText{
 
 	syntaxText: ` 
     ossutil read-symlink oss://bucket/object [--encoding-type url] [-c file] 
+    ossutil read-symlink oss://bucket[/prefix] -r [-j num] [--output-dir dir] [--encoding-type url] [-c file] 
 `,
 
 	detailHelpText: ` 
@@ -28,18 +30,33 @@ var specChineseReadSymlink = SpecText{
 
     如果object并非符号链接文件，该操作返回错误：NotSymlink。
 
+--recursive选项
+
+    如果指定了该选项，ossutil列举cloud_url指定的前缀下所有的符号链接object，并发地读取它们的
+    目标object，每行输出一个符号链接：
+
+        oss://bucket/symlink-object -> target-object
+
+    目标object不存在的符号链接（悬空链接）在行末标记为(dangling)，执行完成后输出符号链接总数和
+    悬空链接数。读取出错的符号链接会记录到report文件中（report文件的说明见cp命令的帮助）。
+
     更多信息见官网API文档：https://help.aliyun.com/document_detail/45146.html?spm=5176.doc31968.6.871.24y1VX
 
 用法：
 
     ossutil read-symlink oss://bucket/symlink-object
+    ossutil read-symlink oss://bucket[/prefix] -r
 `,
 
 	sampleText: ` 
-    ossutil read-symlink oss://bucket1/object1 
+    1) ossutil read-symlink oss://bucket1/object1 
         Etag                    : 455E20DBFFF1D588B67D092C46B16DB6
         Last-Modified           : 2017-04-17 14:49:42 +0800 CST
         X-Oss-Symlink-Target    : a
+
+    2) ossutil read-symlink oss://bucket1/dir/ -r
+        oss://bucket1/dir/link1 -> dir/object1
+        oss://bucket1/dir/link2 -> dir/object2 (dangling)
 `,
 }
 
@@ -51,6 +68,7 @@ var specEnglishReadSymlink = SpecText{
 
 	syntaxText: ` 
     ossutil read-symlink oss://bucket/object [--encoding-type url] [-c file]
+    ossutil read-symlink oss://bucket[/prefix] -r [-j num] [--output-dir dir] [--encoding-type url] [-c file]
 `,
 
 	detailHelpText: ` 
@@ -63,24 +81,44 @@ var specEnglishReadSymlink = SpecText{
 
     If the object is not symlink object, ossutil return error: NotSymlink.
 
+--recursive option
+
+    If the option is specified, ossutil lists all the symlink objects under the prefix of 
+    cloud_url, reads their target objects concurrently, and prints a symlink per line: 
+
+        oss://bucket/symlink-object -> target-object
+
+    The symlinks whose target object does not exist(dangling links) are marked with (dangling) 
+    at the end of line, the number of symlinks and dangling links is printed when finished. The 
+    symlinks failed to read are recorded in report file(for more information about report file, 
+    see help of cp command).
+
     More information about symlink see: https://help.aliyun.com/document_detail/45146.html?spm=5176.doc31968.6.871.24y1VX 
 
 Usage:
 
     ossutil read-symlink oss://bucket/symlink-object
+    ossutil read-symlink oss://bucket[/prefix] -r
 `,
 
 	sampleText: ` 
-    ossutil read-symlink oss://bucket1/object1 
+    1) ossutil read-symlink oss://bucket1/object1 
         Etag                    : 455E20DBFFF1D588B67D092C46B16DB6
         Last-Modified           : 2017-04-17 14:49:42 +0800 CST
         X-Oss-Symlink-Target    : a
+
+    2) ossutil read-symlink oss://bucket1/dir/ -r
+        oss://bucket1/dir/link1 -> dir/object1
+        oss://bucket1/dir/link2 -> dir/object2 (dangling)
 `,
 }
 
 // ReadSymlinkCommand is the command list buckets or objects
 type ReadSymlinkCommand struct {
-	command Command
+	command     Command
+	monitor     Monitor
+	rsOption    batchOptionType
+	danglingNum int64
 }
 
 var readSymlinkCommand = ReadSymlinkCommand{
@@ -99,7 +137,23 @@ var readSymlinkCommand = ReadSymlinkCommand{
 			OptionAccessKeyID,
 			OptionAccessKeySecret,
 			OptionSTSToken,
+			OptionProxyHost,
+			OptionProxyUser,
+			OptionProxyPwd,
+			OptionCAFile,
+			OptionSkipVerify,
+			OptionForceHTTPS,
+			OptionConnectTimeout,
+			OptionReadTimeout,
 			OptionRetryTimes,
+			OptionRecursion,
+			OptionRoutines,
+			OptionOutputDir,
+			OptionOnSuccess,
+			OptionOnError,
+			OptionOnFinish,
+			OptionHookRoutines,
+			OptionHookTimeout,
 		},
 	},
 }
@@ -121,6 +175,23 @@ func (rc *ReadSymlinkCommand) Init(args []string, options OptionMapType) error {
 // RunCommand simulate inheritance, and polymorphism
 func (rc *ReadSymlinkCommand) RunCommand() error {
 	encodingType, _ := GetString(OptionEncodingType, rc.command.options)
+	recursive, _ := GetBool(OptionRecursion, rc.command.options)
+	if recursive {
+		cloudURL, err := CloudURLFromString(rc.command.args[0], encodingType)
+		if err != nil {
+			return err
+		}
+		if cloudURL.bucket == "" {
+			return fmt.Errorf("invalid cloud url: %s, miss bucket", rc.command.args[0])
+		}
+
+		bucket, err := rc.command.ossBucket(cloudURL.bucket)
+		if err != nil {
+			return err
+		}
+		return rc.batchReadSymlinks(bucket, cloudURL)
+	}
+
 	cloudURL, err := ObjectURLFromString(rc.command.args[0], encodingType)
 	if err != nil {
 		return err
@@ -136,7 +207,7 @@ func (rc *ReadSymlinkCommand) RunCommand() error {
 
 func (rc *ReadSymlinkCommand) linkStat(bucket *oss.Bucket, cloudURL CloudURL) error {
 	// normal info
-	props, err := rc.ossGetSymlinkRetry(bucket, cloudURL.object)
+	props, err := rc.command.ossGetSymlinkRetry(bucket, cloudURL.object)
 	if err != nil {
 		return err
 	}
@@ -172,15 +243,60 @@ func (rc *ReadSymlinkCommand) linkStat(bucket *oss.Bucket, cloudURL CloudURL) er
 	return nil
 }
 
-func (rc *ReadSymlinkCommand) ossGetSymlinkRetry(bucket *oss.Bucket, symlinkObject string) (http.Header, error) {
-	retryTimes, _ := GetInt(OptionRetryTimes, rc.command.options)
-	for i := 1; ; i++ {
-		props, err := bucket.GetSymlink(symlinkObject)
-		if err == nil {
-			return props, err
+func (rc *ReadSymlinkCommand) batchReadSymlinks(bucket *oss.Bucket, cloudURL CloudURL) error {
+	rc.monitor.init("Read")
+	rc.danglingNum = 0
+	rc.rsOption.ctnu = true
+	outputDir, _ := GetString(OptionOutputDir, rc.command.options)
+
+	var err error
+	if rc.rsOption.reporter, err = GetReporter(rc.rsOption.ctnu, outputDir, commandLine); err != nil {
+		return err
+	}
+	defer rc.rsOption.reporter.Clear()
+	rc.rsOption.hooks = rc.command.newItemHooks()
+
+	// list the symlink objects under the prefix, and count them in monitor
+	source := rc.command.objectSource(bucket, cloudURL, nil, func(object oss.ObjectProperties) (batchItem, bool) {
+		if object.Type != SymlinkObjectType {
+			return batchItem{}, false
 		}
-		if int64(i) >= retryTimes {
-			return props, ObjectError{err, bucket.BucketName, symlinkObject}
+		rc.monitor.updateScanNum(1)
+		return batchItem{object.Key, object.Key}, true
+	})
+	routines, _ := GetInt(OptionRoutines, rc.command.options)
+	engine := rc.command.newBatchEngine(&rc.rsOption, routines, scanSource(&rc.monitor, source), func(item batchItem) (string, error) {
+		return fmt.Sprintf("read symlink %s", CloudURLToString(bucket.BucketName, item.key)), rc.readSymlink(bucket, item.key)
+	})
+	engine.monitor = &rc.monitor
+	engine.hookItem = objectHookItem(bucket)
+	err = engine.run()
+	rc.rsOption.hooks.finish(&rc.rsOption, err)
+	fmt.Printf("%d symlinks, %d dangling.\n", rc.monitor.getSnapshot().okNum, rc.danglingNum)
+	return err
+}
+
+// readSymlink prints the target of symlink, and checks whether the target exists
+func (rc *ReadSymlinkCommand) readSymlink(bucket *oss.Bucket, object string) error {
+	props, err := rc.command.ossGetSymlinkRetry(bucket, object)
+	if err != nil {
+		return err
+	}
+	target := props.Get(oss.HTTPHeaderOssSymlinkTarget)
+
+	dangling := ""
+	_, err = bucket.GetObjectMeta(target)
+	if err != nil && !isNotExist(err) {
+		// no need to retry when target not exist
+		_, err = rc.command.ossGetObjectMetaRetry(bucket, target)
+	}
+	if err != nil {
+		if !isNotExist(err) {
+			return err
 		}
+		atomic.AddInt64(&rc.danglingNum, 1)
+		dangling = " (dangling)"
 	}
+	fmt.Printf("%s%s -> %s%s\n", getClearStr(""), CloudURLToString(bucket.BucketName, object), target, dangling)
+	return nil
 }
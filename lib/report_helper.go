@@ -52,6 +52,14 @@ func (re *Reporter) Clear() {
 	}
 }
 
+// writtenPath returns the path of report file, it returns empty if no error is reported
+func (re *Reporter) writtenPath() string {
+	if re == nil || !re.written {
+		return ""
+	}
+	return re.path
+}
+
 func (re *Reporter) HasPrompt() bool {
 	if re == nil {
 		return false
@@ -78,7 +86,7 @@ func (re *Reporter) ReportError(msg string) {
 func (re *Reporter) Prompt(err error) {
 	if re != nil && re.written && re.HasPrompt() {
 		re.prompted = true
-		fmt.Printf("\r%s\rError occurs, message: %s. See more information in file: %s\n", clearStr, err.Error(), re.path)
+		fmt.Printf("\r%s\rError occurs, message: %s. See more information in file: %s\n", getClearSpace(), err.Error(), re.path)
 	}
 }
 
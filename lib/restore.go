@@ -1,17 +1,17 @@
 package lib
 
 import (
+	"bytes"
+	"encoding/xml"
 	"fmt"
+	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
 )
 
-type batchOptionType struct {
-	ctnu     bool
-	reporter *Reporter
-}
-
 var specChineseRestore = SpecText{
 
 	synopsisText: "恢复冷冻状态的Objects为可读状态",
@@ -19,7 +19,7 @@ var specChineseRestore = SpecText{
 	paramText: "cloud_url [options]",
 
 	syntaxText: ` 
-    ossutil restore cloud_url [--encoding-type url] [-r] [-f] [--output-dir=odir] [-c file] 
+    ossutil restore cloud_url [--encoding-type url] [-r] [-f] [--days num] [--restore-tier tier] [--wait] [--output-dir=odir] [-c file] 
 `,
 
 	detailHelpText: ` 
@@ -36,6 +36,16 @@ var specChineseRestore = SpecText{
     解冻状态默认持续1天，对于解冻状态的object调用restore命令，会将object的解冻状态延长
     一天，最多可以延长到7天，之后object又回到初始时的冷冻状态。
 
+    --days选项指定解冻后object保持可读状态的天数，--restore-tier选项指定解冻的优先级（` + RestoreTierExpedited + `、
+    ` + RestoreTierStandard + `或` + RestoreTierBulk + `），未指定时由oss决定。
+
+    object的解冻状态可以通过stat命令的RestoreStatus项，或ls命令长格式输出行末方括号中的内容查看：
+    ` + RestoreStatusFrozen + `表示处于冷冻状态，` + RestoreStatusOngoing + `表示正在解冻，` + RestoreStatusRestored + ` until time表示已解冻，
+    在time之前可读，` + RestoreStatusUnknown + `表示ls查询解冻状态失败。
+
+    如果指定了--wait选项，发起解冻请求后，ossutil定时查询object的解冻状态，直到所有解冻请求成功
+    的object都进入可读状态，查询的间隔从5秒开始逐次加倍，最长为120秒。
+
     更多信息见官网文档：https://help.aliyun.com/document_detail/52930.html?spm=5176.doc31947.6.874.8GjVvu 
 
 
@@ -62,6 +72,7 @@ var specChineseRestore = SpecText{
     2) ossutil restore oss://bucket-restore/object-prefix -r
     3) ossutil restore oss://bucket-restore/object-prefix -r -f
     4) ossutil restore oss://bucket-restore/%e4%b8%ad%e6%96%87 --encoding-type url
+    5) ossutil restore oss://bucket-restore/object-store --days 3 --restore-tier ` + RestoreTierExpedited + ` --wait
 `,
 }
 
@@ -72,7 +83,7 @@ var specEnglishRestore = SpecText{
 	paramText: "cloud_url [options]",
 
 	syntaxText: ` 
-    ossutil restore cloud_url [--encoding-type url] [-r] [-f] [--output-dir=odir] [-c file] 
+    ossutil restore cloud_url [--encoding-type url] [-r] [-f] [--days num] [--restore-tier tier] [--wait] [--output-dir=odir] [-c file] 
 `,
 
 	detailHelpText: ` 
@@ -91,6 +102,19 @@ var specEnglishRestore = SpecText{
     object again during the time, the time that the object can be downloaded will be extended for 
     one day, the time can be at most extended to seven days. 
 
+    --days option specify the days that the object keeps readable after restored, --restore-tier 
+    option specify the tier of restore(` + RestoreTierExpedited + `, ` + RestoreTierStandard + ` or ` + RestoreTierBulk + `), they are decided by oss if 
+    not specified.
+
+    The restore status of object can be checked by the RestoreStatus item of stat command, or the 
+    content in brackets at the end of line of long format output of ls command: ` + RestoreStatusFrozen + ` means the object is frozen, ` + RestoreStatusOngoing + ` 
+    means the object is being restored, ` + RestoreStatusRestored + ` until time means the object is restored and 
+    readable before the time, ` + RestoreStatusUnknown + ` means ls fails to query the restore status. 
+
+    If --wait option is specified, after restore requests are sent, ossutil queries the restore 
+    status of objects periodically, until all the objects restored successfully are readable, the 
+    interval of query begins with 5 seconds and doubles each time, up to 120 seconds.
+
     More information about restore see: https://help.aliyun.com/document_detail/52930.html?spm=5176.doc31947.6.874.8GjVvu  
 
 
@@ -118,6 +142,7 @@ Usage:
     2) ossutil restore oss://bucket-restore/object-prefix -r
     3) ossutil restore oss://bucket-restore/object-prefix -r -f
     4) ossutil restore oss://bucket-restore/%e4%b8%ad%e6%96%87 --encoding-type url
+    5) ossutil restore oss://bucket-restore/object-store --days 3 --restore-tier ` + RestoreTierExpedited + ` --wait
 `,
 }
 
@@ -126,6 +151,22 @@ type RestoreCommand struct {
 	command  Command
 	monitor  Monitor
 	reOption batchOptionType
+	days     int64
+	tier     string
+	wait     bool
+	restored []string
+	mu       sync.Mutex
+}
+
+// restoreConfiguration is the body of restore request
+type restoreConfiguration struct {
+	XMLName       xml.Name              `xml:"RestoreRequest"`
+	Days          int64                 `xml:"Days,omitempty"`
+	JobParameters *restoreJobParameters `xml:"JobParameters,omitempty"`
+}
+
+type restoreJobParameters struct {
+	Tier string `xml:"Tier"`
 }
 
 var restoreCommand = RestoreCommand{
@@ -146,9 +187,26 @@ var restoreCommand = RestoreCommand{
 			OptionAccessKeyID,
 			OptionAccessKeySecret,
 			OptionSTSToken,
+			OptionProxyHost,
+			OptionProxyUser,
+			OptionProxyPwd,
+			OptionCAFile,
+			OptionSkipVerify,
+			OptionForceHTTPS,
+			OptionConnectTimeout,
+			OptionReadTimeout,
 			OptionRetryTimes,
 			OptionRoutines,
 			OptionOutputDir,
+			OptionCheckpointDir,
+			OptionRestoreDays,
+			OptionRestoreTier,
+			OptionWait,
+			OptionOnSuccess,
+			OptionOnError,
+			OptionOnFinish,
+			OptionHookRoutines,
+			OptionHookTimeout,
 		},
 	},
 }
@@ -173,6 +231,10 @@ func (rc *RestoreCommand) RunCommand() error {
 
 	encodingType, _ := GetString(OptionEncodingType, rc.command.options)
 	recursive, _ := GetBool(OptionRecursion, rc.command.options)
+	rc.days, _ = GetInt(OptionRestoreDays, rc.command.options)
+	rc.tier, _ = GetString(OptionRestoreTier, rc.command.options)
+	rc.wait, _ = GetBool(OptionWait, rc.command.options)
+	rc.restored = []string{}
 
 	cloudURL, err := CloudURLFromString(rc.command.args[0], encodingType)
 	if err != nil {
@@ -189,7 +251,10 @@ func (rc *RestoreCommand) RunCommand() error {
 	}
 
 	if !recursive {
-		return rc.ossRestoreObject(bucket, cloudURL.object)
+		if err := rc.ossRestoreObject(bucket, cloudURL.object); err != nil || !rc.wait {
+			return err
+		}
+		return rc.command.waitObjectsRestored(bucket, []string{cloudURL.object})
 	}
 	return rc.batchRestoreObjects(bucket, cloudURL)
 }
@@ -205,9 +270,19 @@ func (rc *RestoreCommand) checkArgs(cloudURL CloudURL, recursive bool) error {
 }
 
 func (rc *RestoreCommand) ossRestoreObject(bucket *oss.Bucket, object string) error {
-	retryTimes, _ := GetInt(OptionRetryTimes, rc.command.options)
+	return rc.command.ossRestoreObjectRetry(bucket, object, rc.days, rc.tier)
+}
+
+// ossRestoreObjectRetry sends restore request, days and tier are set in request body if specified
+func (cmd *Command) ossRestoreObjectRetry(bucket *oss.Bucket, object string, days int64, tier string) error {
+	retryTimes, _ := GetInt(OptionRetryTimes, cmd.options)
 	for i := 1; ; i++ {
-		err := bucket.RestoreObject(object)
+		var err error
+		if days == 0 && tier == "" {
+			err = bucket.RestoreObject(object)
+		} else {
+			err = cmd.restoreObjectWithConfig(bucket, object, days, tier)
+		}
 		if err == nil {
 			return err
 		}
@@ -222,6 +297,7 @@ func (rc *RestoreCommand) ossRestoreObject(bucket *oss.Bucket, object string) er
 		if int64(i) >= retryTimes {
 			return ObjectError{err, bucket.BucketName, object}
 		}
+		cmd.metrics.retry()
 	}
 }
 
@@ -230,7 +306,7 @@ func (rc *RestoreCommand) batchRestoreObjects(bucket *oss.Bucket, cloudURL Cloud
 	if !force {
 		var val string
 		fmt.Printf("Do you really mean to recursivlly restore objects of %s(y or N)? ", rc.command.args[0])
-		if _, err := fmt.Scanln(&val); err != nil || (strings.ToLower(val) != "yes" && strings.ToLower(val) != "y") {
+		if _, err := scanln(&val); err != nil || (strings.ToLower(val) != "yes" && strings.ToLower(val) != "y") {
 			fmt.Println("operation is canceled.")
 			return nil
 		}
@@ -245,77 +321,181 @@ func (rc *RestoreCommand) batchRestoreObjects(bucket *oss.Bucket, cloudURL Cloud
 		return err
 	}
 	defer rc.reOption.reporter.Clear()
+	rc.reOption.hooks = rc.command.newItemHooks()
 
-	return rc.restoreObjects(bucket, cloudURL)
+	// load job journal
+	if rc.reOption.journal, err = rc.command.openJobJournal(); err != nil {
+		return err
+	}
+
+	err = rc.restoreObjects(bucket, cloudURL)
+	if err == nil {
+		err = rc.command.cancelError()
+	}
+	rc.reOption.hooks.finish(&rc.reOption, err)
+	rc.reOption.journal.close(err == nil)
+	if err != nil || !rc.wait {
+		return err
+	}
+	return rc.command.waitObjectsRestored(bucket, rc.restored)
 }
 
 func (rc *RestoreCommand) restoreObjects(bucket *oss.Bucket, cloudURL CloudURL) error {
 	routines, _ := GetInt(OptionRoutines, rc.command.options)
 
-	chObjects := make(chan string, ChannelBuf)
-	chError := make(chan error, routines+1)
-	chListError := make(chan error, 1)
-	go rc.command.objectStatistic(bucket, cloudURL, &rc.monitor)
-	go rc.command.objectProducer(bucket, cloudURL, chObjects, chListError)
-	for i := 0; int64(i) < routines; i++ {
-		go rc.restoreConsumer(bucket, cloudURL, chObjects, chError)
+	go rc.command.objectStatistic(bucket, cloudURL, &rc.monitor, rc.reOption.journal)
+	source := rc.command.objectSource(bucket, cloudURL, rc.reOption.journal, nil)
+	engine := rc.command.newBatchEngine(&rc.reOption, routines, source, func(item batchItem) (string, error) {
+		return rc.restoreItem(bucket, item.key)
+	})
+	engine.monitor = &rc.monitor
+	engine.hookItem = objectHookItem(bucket)
+	return engine.run()
+}
+
+// restoreItem is the batch action of restore
+func (rc *RestoreCommand) restoreItem(bucket *oss.Bucket, object string) (string, error) {
+	msg := fmt.Sprintf("restore %s", CloudURLToString(bucket.BucketName, object))
+	var err error
+	if !rc.reOption.journal.isDone(object) {
+		err = rc.ossRestoreObject(bucket, object)
+	}
+	if err == nil && rc.wait {
+		rc.mu.Lock()
+		rc.restored = append(rc.restored, object)
+		rc.mu.Unlock()
+	}
+	return msg, err
+}
+
+// restoreObjectWithConfig sends restore request with days and tier, which is not supported by RestoreObject of sdk
+func (cmd *Command) restoreObjectWithConfig(bucket *oss.Bucket, object string, days int64, tier string) error {
+	config := restoreConfiguration{Days: days}
+	if tier != "" {
+		config.JobParameters = &restoreJobParameters{Tier: tier}
+	}
+	data, err := xml.Marshal(config)
+	if err != nil {
+		return err
 	}
 
-	return rc.waitRoutinueComplete(chError, chListError, routines)
+	params := map[string]interface{}{"restore": nil}
+	headers := map[string]string{oss.HTTPHeaderContentType: "application/xml"}
+	resp, err := bucket.Client.Conn.Do("POST", bucket.BucketName, object, params, headers, bytes.NewReader(data), 0, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
 }
 
-func (rc *RestoreCommand) restoreConsumer(bucket *oss.Bucket, cloudURL CloudURL, chObjects <-chan string, chError chan<- error) {
-	for object := range chObjects {
-		err := rc.restoreObjectWithReport(bucket, object)
-		if err != nil {
-			chError <- err
-			if !rc.reOption.ctnu {
-				return
-			}
-			continue
+// getRestoreStatus returns restore status of object from its meta, expiry is the time that
+// a restored object will be frozen again. Status is empty for object which needs no restore.
+func getRestoreStatus(props http.Header) (string, time.Time) {
+	restore := props.Get(OssRestoreHeader)
+	if restore == "" {
+		if props.Get(OssStorageClassHeader) == StorageArchive {
+			return RestoreStatusFrozen, time.Time{}
 		}
+		return "", time.Time{}
+	}
+
+	if strings.Contains(restore, `ongoing-request="true"`) {
+		return RestoreStatusOngoing, time.Time{}
 	}
 
-	chError <- nil
+	var expiry time.Time
+	if pos := strings.Index(restore, `expiry-date="`); pos != -1 {
+		date := restore[pos+len(`expiry-date="`):]
+		date = strings.TrimSuffix(strings.TrimSpace(date), `"`)
+		expiry, _ = time.Parse(http.TimeFormat, date)
+	}
+	return RestoreStatusRestored, expiry
 }
 
-func (rc *RestoreCommand) restoreObjectWithReport(bucket *oss.Bucket, object string) error {
-	err := rc.ossRestoreObject(bucket, object)
-	rc.command.updateMonitor(err, &rc.monitor)
-	msg := fmt.Sprintf("restore %s", CloudURLToString(bucket.BucketName, object))
-	rc.command.report(msg, err, &rc.reOption)
-	return err
+// formatRestoreStatus shows restore status like: restored until 2017-04-16 16:12:33 +0800 CST
+func formatRestoreStatus(status string, expiry time.Time) string {
+	if status == RestoreStatusRestored && !expiry.IsZero() {
+		return fmt.Sprintf("%s until %s", status, utcToLocalTime(expiry.UTC()))
+	}
+	return status
 }
 
-func (rc *RestoreCommand) waitRoutinueComplete(chError, chListError <-chan error, routines int64) error {
-	completed := 0
-	var ferr error
-	for int64(completed) <= routines {
+// waitObjectsRestored polls restore status of objects with backoff, until all of them are readable
+func (cmd *Command) waitObjectsRestored(bucket *oss.Bucket, objects []string) error {
+	interval := RestoreWaitInterval
+	var done <-chan struct{}
+	if cmd.ctx != nil {
+		done = cmd.ctx.Done()
+	}
+	for {
+		pending, err := cmd.getRestoringObjects(bucket, objects)
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			cmd.printf("%sall %d objects are restored.\n", getClearStr(""), len(objects))
+			return nil
+		}
+		if err := cmd.cancelError(); err != nil {
+			return err
+		}
+
+		cmd.printf(getClearStr(fmt.Sprintf("waiting for %d objects to be restored, check again after %d seconds.", len(pending), interval)))
+		timer := time.NewTimer(time.Duration(interval) * time.Second)
 		select {
-		case err := <-chListError:
-			if err != nil {
-				return err
-			}
-			completed++
-		case err := <-chError:
-			if err == nil {
-				completed++
-			} else {
-				ferr = err
-				if !rc.reOption.ctnu {
-					fmt.Printf(rc.monitor.progressBar(true, errExit))
-					return err
-				}
-			}
+		case <-timer.C:
+		case <-done:
+			timer.Stop()
+			return cmd.ctx.Err()
+		}
+		objects = pending
+		if interval = interval * 2; interval > MaxRestoreWaitInterval {
+			interval = MaxRestoreWaitInterval
 		}
 	}
-	return rc.formatResultPrompt(ferr)
 }
 
-func (rc *RestoreCommand) formatResultPrompt(err error) error {
-	fmt.Printf(rc.monitor.progressBar(true, normalExit))
-	if err != nil && rc.reOption.ctnu {
-		return nil
+// getRestoringObjects returns the objects which are being restored
+func (cmd *Command) getRestoringObjects(bucket *oss.Bucket, objects []string) ([]string, error) {
+	routines, _ := GetInt(OptionRoutines, cmd.options)
+	if routines <= 0 {
+		routines = int64(Routines)
+	}
+
+	chObjects := make(chan string, len(objects))
+	for _, object := range objects {
+		chObjects <- object
+	}
+	close(chObjects)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var ferr error
+	pending := []string{}
+	for i := 0; int64(i) < routines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for object := range chObjects {
+				props, err := cmd.ossGetObjectStatRetry(bucket, object)
+				if err == nil {
+					if status, _ := getRestoreStatus(props); status == RestoreStatusFrozen {
+						err = ObjectError{fmt.Errorf("object is frozen, restore request may be lost, please restore it again"), bucket.BucketName, object}
+					} else if status == RestoreStatusOngoing {
+						mu.Lock()
+						pending = append(pending, object)
+						mu.Unlock()
+					}
+				}
+				if err != nil {
+					mu.Lock()
+					ferr = err
+					mu.Unlock()
+				}
+			}
+		}()
 	}
-	return err
+	wg.Wait()
+	return pending, ferr
 }
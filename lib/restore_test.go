@@ -1,10 +1,16 @@
 package lib
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"net/url"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
 	. "gopkg.in/check.v1"
 )
 
@@ -215,7 +221,7 @@ func (s *OssutilCommandSuite) TestBatchRestoreErrorContinue(c *C) {
 	c.Assert(bucket, NotNil)
 
 	// restore prepare
-	cloudURL, err := CloudURLFromString(CloudURLToString(bucketName, ""), "")
+	_, err = CloudURLFromString(CloudURLToString(bucketName, ""), "")
 	c.Assert(err, IsNil)
 
 	restoreCommand.monitor.init("Restored")
@@ -229,21 +235,12 @@ func (s *OssutilCommandSuite) TestBatchRestoreErrorContinue(c *C) {
 
 	var routines int64
 	routines = 3
-	chObjects := make(chan string, ChannelBuf)
-	chError := make(chan error, routines+1)
-	chListError := make(chan error, 1)
-
-	chObjects <- objectNames[0]
-	chObjects <- "notexistobject" + randStr(3)
-	chObjects <- objectNames[1]
-	chListError <- nil
-	close(chObjects)
-
-	for i := 0; int64(i) < routines; i++ {
-		restoreCommand.restoreConsumer(bucket, cloudURL, chObjects, chError)
-	}
-
-	err = restoreCommand.waitRoutinueComplete(chError, chListError, routines)
+	source := sliceSource(objectItems(objectNames[0], "notexistobject"+randStr(3), objectNames[1])...)
+	engine := restoreCommand.command.newBatchEngine(&restoreCommand.reOption, routines, source, func(item batchItem) (string, error) {
+		return restoreCommand.restoreItem(bucket, item.key)
+	})
+	engine.monitor = &restoreCommand.monitor
+	err = engine.run()
 	c.Assert(err, IsNil)
 
 	str := restoreCommand.monitor.progressBar(false, normalExit)
@@ -312,7 +309,7 @@ func (s *OssutilCommandSuite) TestBatchRestoreErrorBreak(c *C) {
 	c.Assert(bucket, NotNil)
 
 	// restore prepare
-	cloudURL, err := CloudURLFromString(CloudURLToString(bucketName, ""), "")
+	_, err = CloudURLFromString(CloudURLToString(bucketName, ""), "")
 	c.Assert(err, IsNil)
 
 	restoreCommand.monitor.init("Restored")
@@ -326,20 +323,12 @@ func (s *OssutilCommandSuite) TestBatchRestoreErrorBreak(c *C) {
 
 	var routines int64
 	routines = 3
-	chObjects := make(chan string, ChannelBuf)
-	chError := make(chan error, routines+1)
-	chListError := make(chan error, 1)
-
-	chObjects <- objectNames[0]
-	chObjects <- objectNames[1]
-	chListError <- nil
-	close(chObjects)
-
-	for i := 0; int64(i) < routines; i++ {
-		restoreCommand.restoreConsumer(bucket, cloudURL, chObjects, chError)
-	}
-
-	err = restoreCommand.waitRoutinueComplete(chError, chListError, routines)
+	source := sliceSource(objectItems(objectNames[0], objectNames[1])...)
+	engine := restoreCommand.command.newBatchEngine(&restoreCommand.reOption, routines, source, func(item batchItem) (string, error) {
+		return restoreCommand.restoreItem(bucket, item.key)
+	})
+	engine.monitor = &restoreCommand.monitor
+	err = engine.run()
 	c.Assert(err, NotNil)
 
 	str := restoreCommand.monitor.progressBar(false, normalExit)
@@ -387,3 +376,234 @@ func (s *OssutilCommandSuite) TestBatchRestoreErrorBreak(c *C) {
 
 	s.removeBucket(bucketName, true, c)
 }
+
+func (s *OssutilCommandSuite) TestRestoreStatus(c *C) {
+	props := http.Header{}
+	props.Set(OssStorageClassHeader, StorageStandard)
+	status, _ := getRestoreStatus(props)
+	c.Assert(status, Equals, "")
+
+	props.Set(OssStorageClassHeader, StorageArchive)
+	status, _ = getRestoreStatus(props)
+	c.Assert(status, Equals, RestoreStatusFrozen)
+
+	props.Set(OssRestoreHeader, `ongoing-request="true"`)
+	status, _ = getRestoreStatus(props)
+	c.Assert(status, Equals, RestoreStatusOngoing)
+	c.Assert(formatRestoreStatus(status, time.Time{}), Equals, RestoreStatusOngoing)
+
+	props.Set(OssRestoreHeader, `ongoing-request="false", expiry-date="Sun, 16 Apr 2017 08:12:33 GMT"`)
+	status, expiry := getRestoreStatus(props)
+	c.Assert(status, Equals, RestoreStatusRestored)
+	c.Assert(expiry.Unix(), Equals, time.Date(2017, 4, 16, 8, 12, 33, 0, time.UTC).Unix())
+	c.Assert(formatRestoreStatus(status, expiry), Equals, fmt.Sprintf("%s until %s", RestoreStatusRestored, utcToLocalTime(expiry.UTC())))
+}
+
+func (s *OssutilCommandSuite) TestRestoreObjectWait(c *C) {
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucketWithStorageClass(bucketName, StorageArchive, c)
+
+	object := "恢复文件" + randStr(5)
+	s.createFile(uploadFileName, randStr(20), c)
+	s.putObject(bucketName, object, uploadFileName, c)
+
+	objectStat := s.getStat(bucketName, object, c)
+	c.Assert(objectStat[StatRestoreStatus], Equals, RestoreStatusFrozen)
+
+	// invalid days
+	days := strconv.FormatInt(MaxRestoreDays+1, 10)
+	err := checkOption(OptionMapType{OptionRestoreDays: &days})
+	c.Assert(err, NotNil)
+
+	// restore with days and wait
+	err = s.initRestoreObject([]string{CloudURLToString(bucketName, object)}, "", DefaultOutputDir)
+	c.Assert(err, IsNil)
+	days = "2"
+	wait := true
+	restoreCommand.command.options[OptionRestoreDays] = &days
+	restoreCommand.command.options[OptionWait] = &wait
+	err = restoreCommand.RunCommand()
+	c.Assert(err, IsNil)
+
+	objectStat = s.getStat(bucketName, object, c)
+	c.Assert(strings.HasPrefix(objectStat[StatRestoreStatus], RestoreStatusRestored+" until "), Equals, true)
+
+	// ls shows restore status
+	testResultFile, _ = os.OpenFile(resultPath, os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0664)
+	out := os.Stdout
+	os.Stdout = testResultFile
+	_, err = s.rawList([]string{CloudURLToString(bucketName, "")}, "ls -")
+	os.Stdout = out
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(s.readFile(resultPath, c), "  ["+RestoreStatusRestored+" until "), Equals, true)
+	os.Remove(resultPath)
+
+	s.removeBucket(bucketName, true, c)
+}
+
+func (s *OssutilCommandSuite) TestCPObjectAutoRestore(c *C) {
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucketWithStorageClass(bucketName, StorageArchive, c)
+
+	object := "restoreObject" + randStr(5)
+	data := randStr(20)
+	s.createFile(uploadFileName, data, c)
+	s.putObject(bucketName, object, uploadFileName, c)
+	autoRestore := true
+
+	// upload not support auto restore
+	err := s.initCopyCommand(uploadFileName, CloudURLToString(bucketName, object), false, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	copyCommand.command.options[OptionAutoRestore] = &autoRestore
+	err = copyCommand.RunCommand()
+	c.Assert(err, NotNil)
+
+	// download frozen object fail
+	os.Remove(downloadFileName)
+	err = s.initCopyCommand(CloudURLToString(bucketName, object), downloadFileName, false, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	err = copyCommand.RunCommand()
+	c.Assert(err, NotNil)
+
+	// download with auto restore
+	err = s.initCopyCommand(CloudURLToString(bucketName, object), downloadFileName, false, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	copyCommand.command.options[OptionAutoRestore] = &autoRestore
+	err = copyCommand.RunCommand()
+	c.Assert(err, IsNil)
+	c.Assert(s.readFile(downloadFileName, c), Equals, data)
+
+	s.removeBucket(bucketName, true, c)
+}
+
+func (s *OssutilCommandSuite) TestCPObjectAutoRestoreRerun(c *C) {
+	server := newFakeOSSServer("restoreID", "restoreSecret")
+	server.restoreDuration = 2 * time.Second
+	defer server.close()
+	client, err := oss.New(server.endpoint(), "restoreID", "restoreSecret")
+	c.Assert(err, IsNil)
+	bucketName := bucketNamePrefix + randLowStr(10)
+	c.Assert(client.CreateBucket(bucketName), IsNil)
+	bucket, err := client.Bucket(bucketName)
+	c.Assert(err, IsNil)
+	data := randStr(20)
+	for _, object := range []string{"restore/1", "restore/2"} {
+		c.Assert(bucket.PutObject(object, strings.NewReader(data), oss.ObjectStorageClass(oss.StorageArchive)), IsNil)
+	}
+
+	// restore of restore/1 is in progress when cp starts
+	c.Assert(bucket.RestoreObject("restore/1"), IsNil)
+
+	downDir := randStr(10)
+	defer os.RemoveAll(downDir)
+	autoRestore := true
+	for i := 0; i < 2; i++ {
+		err = s.initCopyCommand(CloudURLToString(bucketName, "restore/"), downDir, true, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+		c.Assert(err, IsNil)
+		str := ""
+		ep, id, secret := server.endpoint(), "restoreID", "restoreSecret"
+		for name, val := range map[string]*string{
+			OptionEndpoint:        &ep,
+			OptionAccessKeyID:     &id,
+			OptionAccessKeySecret: &secret,
+			OptionSTSToken:        &str,
+		} {
+			copyCommand.command.options[name] = val
+		}
+		copyCommand.command.options[OptionAutoRestore] = &autoRestore
+		c.Assert(copyCommand.RunCommand(), IsNil)
+		c.Assert(copyCommand.monitor.fileNum, Equals, int64(2))
+		c.Assert(s.readFile(downDir+"/restore/2", c), Equals, data)
+
+		// only the frozen restore/2 is restored, the restored objects are not restored again
+		c.Assert(server.requestCount("RestoreObject"), Equals, 2)
+	}
+}
+
+func (s *OssutilCommandSuite) TestRestoreWaitCanceled(c *C) {
+	server := newFakeOSSServer("restoreID", "restoreSecret")
+	defer server.close()
+	client, err := oss.New(server.endpoint(), "restoreID", "restoreSecret")
+	c.Assert(err, IsNil)
+	bucketName := bucketNamePrefix + randLowStr(10)
+	c.Assert(client.CreateBucket(bucketName), IsNil)
+	bucket, err := client.Bucket(bucketName)
+	c.Assert(err, IsNil)
+	c.Assert(bucket.PutObject("object", strings.NewReader("data"), oss.ObjectStorageClass(oss.StorageArchive)), IsNil)
+	c.Assert(bucket.RestoreObject("object"), IsNil)
+
+	// the wait ends as soon as the command is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := Command{options: OptionMapType{}, ctx: ctx}
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	err = cmd.waitObjectsRestored(bucket, []string{"object"})
+	c.Assert(err, Equals, context.Canceled)
+	c.Assert(time.Since(start) < time.Duration(RestoreWaitInterval)*time.Second, Equals, true)
+}
+
+func (s *OssutilCommandSuite) TestListRestoreStatus(c *C) {
+	server := newFakeOSSServer("restoreID", "restoreSecret")
+	defer server.close()
+	client, err := oss.New(server.endpoint(), "restoreID", "restoreSecret")
+	c.Assert(err, IsNil)
+	bucketName := bucketNamePrefix + randLowStr(10)
+	c.Assert(client.CreateBucket(bucketName), IsNil)
+	bucket, err := client.Bucket(bucketName)
+	c.Assert(err, IsNil)
+	c.Assert(bucket.PutObject("standard", strings.NewReader("data")), IsNil)
+	for i := 0; i < 10; i++ {
+		c.Assert(bucket.PutObject("archive/"+strconv.Itoa(i), strings.NewReader("data"), oss.ObjectStorageClass(oss.StorageArchive)), IsNil)
+	}
+	c.Assert(bucket.RestoreObject("archive/1"), IsNil)
+
+	// the status is unknown if HEAD fails, the HEAD requests are sent concurrently
+	server.inject(fakeOSSFault{op: "HeadObject", object: "archive/2", status: http.StatusForbidden, code: "AccessDenied"})
+	server.inject(fakeOSSFault{op: "HeadObject", delay: 300 * time.Millisecond})
+
+	str := ""
+	ep, id, secret := server.endpoint(), "restoreID", "restoreSecret"
+	retryTimes := "1"
+	limitedNum := "-1"
+	options := OptionMapType{
+		OptionEndpoint:        &ep,
+		OptionAccessKeyID:     &id,
+		OptionAccessKeySecret: &secret,
+		OptionSTSToken:        &str,
+		OptionConfigFile:      &configFile,
+		OptionRetryTimes:      &retryTimes,
+		OptionLimitedNum:      &limitedNum,
+	}
+	testResultFile, _ = os.OpenFile(resultPath, os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0664)
+	out := os.Stdout
+	os.Stdout = testResultFile
+	start := time.Now()
+	_, err = cm.RunCommand("ls", []string{CloudURLToString(bucketName, "")}, options)
+	elapsed := time.Since(start)
+	os.Stdout = out
+	c.Assert(err, IsNil)
+	c.Assert(elapsed < 2*time.Second, Equals, true)
+	c.Assert(server.requestCount("HeadObject"), Equals, 10)
+
+	lines := map[string]string{}
+	for _, line := range strings.Split(s.readFile(resultPath, c), "\n") {
+		if pos := strings.Index(line, "oss://"); pos != -1 {
+			lines[strings.Fields(line[pos:])[0]] = line
+		}
+	}
+	c.Assert(strings.HasSuffix(lines[CloudURLToString(bucketName, "archive/0")], "["+RestoreStatusFrozen+"]"), Equals, true)
+	c.Assert(strings.HasSuffix(lines[CloudURLToString(bucketName, "archive/1")], "["+RestoreStatusOngoing+"]"), Equals, true)
+	c.Assert(strings.HasSuffix(lines[CloudURLToString(bucketName, "archive/2")], "["+RestoreStatusUnknown+"]"), Equals, true)
+	c.Assert(strings.HasSuffix(lines[CloudURLToString(bucketName, "standard")], "standard"), Equals, true)
+
+	// go api queries the restore status if it's specified
+	apiClient := NewClient(Config{ConfigFile: configFile, Endpoint: ep, AccessKeyID: id, AccessKeySecret: secret, RetryTimes: 1})
+	statuses := map[string]string{}
+	_, err = apiClient.List(context.Background(), CloudURLToString(bucketName, ""), ListOptions{RestoreStatus: true, OnEntry: func(entry ListEntry) {
+		statuses[entry.Key] = entry.RestoreStatus
+	}})
+	c.Assert(err, IsNil)
+	c.Assert(statuses["archive/0"], Equals, RestoreStatusFrozen)
+	c.Assert(statuses["archive/2"], Equals, RestoreStatusUnknown)
+	c.Assert(statuses["standard"], Equals, "")
+}
@@ -1,6 +1,7 @@
 package lib
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -13,9 +14,15 @@ type uploadIdInfoType struct {
 }
 
 type removeOptionType struct {
+	batchOptionType
 	recursive bool
 	force     bool
 	typeSet   int64
+
+	// onProgress receives the progress when an object or multipart upload is removed, onError
+	// receives the errors of them
+	onProgress func(Progress)
+	onError    func(ItemError)
 }
 
 var specChineseRemove = SpecText{
@@ -258,7 +265,26 @@ var removeCommand = RemoveCommand{
 			OptionAccessKeyID,
 			OptionAccessKeySecret,
 			OptionSTSToken,
+			OptionProxyHost,
+			OptionProxyUser,
+			OptionProxyPwd,
+			OptionCAFile,
+			OptionSkipVerify,
+			OptionForceHTTPS,
+			OptionConnectTimeout,
+			OptionReadTimeout,
 			OptionRetryTimes,
+			OptionCheckpointDir,
+			OptionNotifyURL,
+			OptionNotifySecret,
+			OptionNotifyRetryTimes,
+			OptionNotifyHeartbeat,
+			OptionMetricsAddr,
+			OptionOnSuccess,
+			OptionOnError,
+			OptionOnFinish,
+			OptionHookRoutines,
+			OptionHookTimeout,
 		},
 	},
 }
@@ -278,24 +304,36 @@ func (rc *RemoveCommand) Init(args []string, options OptionMapType) error {
 }
 
 // RunCommand simulate inheritance, and polymorphism
-func (rc *RemoveCommand) RunCommand() error {
+func (rc *RemoveCommand) RunCommand() (err error) {
 	rc.monitor.init()
 
-	encodingType, _ := GetString(OptionEncodingType, rc.command.options)
-	cloudURL, err := CloudURLFromString(rc.command.args[0], encodingType)
+	// notify the result when the command ends
+	rc.rmOption.notifier, err = rc.command.newNotifier(func(msg *notifyMessage) {
+		msg.Remove = rc.monitor.getNotifyStat()
+	})
 	if err != nil {
 		return err
 	}
+	defer func() {
+		rc.rmOption.notifier.finish(err, "")
+	}()
 
-	if cloudURL.bucket == "" {
-		return fmt.Errorf("invalid cloud url: %s, miss bucket", rc.command.args[0])
+	stopMetrics, err := rc.command.startMetrics(rc.monitor.getMetricsStat)
+	if err != nil {
+		return err
 	}
+	defer stopMetrics()
 
-	bucket, err := rc.command.ossBucket(cloudURL.bucket)
+	encodingType, _ := GetString(OptionEncodingType, rc.command.options)
+	cloudURL, err := CloudURLFromString(rc.command.args[0], encodingType)
 	if err != nil {
 		return err
 	}
 
+	if cloudURL.bucket == "" {
+		return fmt.Errorf("invalid cloud url: %s, miss bucket", rc.command.args[0])
+	}
+
 	// assembleOption
 	if err := rc.assembleOption(cloudURL); err != nil {
 		return err
@@ -306,13 +344,27 @@ func (rc *RemoveCommand) RunCommand() error {
 		return nil
 	}
 
-	// start progressbar
-	go rc.entryStatistic(bucket, cloudURL)
+	// load job journal and hooks, the hooks are run for the objects removed recursively
+	rc.rmOption.journal = nil
+	rc.rmOption.hooks = nil
+	if rc.rmOption.recursive && rc.rmOption.typeSet&objectType != 0 {
+		if rc.rmOption.journal, err = rc.command.openJobJournal(); err != nil {
+			return err
+		}
+		rc.rmOption.hooks = rc.command.newItemHooks()
+	}
+
+	// show progressbar
+	rc.rmOption.onProgress = func(Progress) {
+		fmt.Printf(rc.monitor.progressBar(false, normalExit))
+	}
 
 	exitStat := normalExit
-	if err = rc.removeEntry(bucket, cloudURL); err != nil {
+	if err = rc.remove(rc.command.ctx, cloudURL); err != nil && !rc.command.canceled() {
 		exitStat = errExit
 	}
+	rc.rmOption.hooks.finish(&rc.rmOption.batchOptionType, err)
+	rc.rmOption.journal.close(err == nil)
 	fmt.Printf(rc.monitor.progressBar(true, exitStat))
 	return err
 }
@@ -323,7 +375,11 @@ func (rc *RemoveCommand) assembleOption(cloudURL CloudURL) error {
 	isMultipart, _ := GetBool(OptionMultipart, rc.command.options)
 	isAllType, _ := GetBool(OptionAllType, rc.command.options)
 	toBucket, _ := GetBool(OptionBucket, rc.command.options)
+	return rc.assembleTypeSet(cloudURL, isMultipart, isAllType, toBucket)
+}
 
+// assembleTypeSet checks the types to remove and sets typeSet of rmOption
+func (rc *RemoveCommand) assembleTypeSet(cloudURL CloudURL, isMultipart, isAllType, toBucket bool) error {
 	if err := rc.checkOption(cloudURL, isMultipart, isAllType, toBucket); err != nil {
 		return err
 	}
@@ -386,7 +442,7 @@ func (rc *RemoveCommand) confirmRemoveObject(cloudURL CloudURL) bool {
 		}
 		var val string
 		fmt.Printf("Do you really mean to remove recursively %s of %s(y or N)? ", strings.Join(stringList, " and "), rc.command.args[0])
-		if _, err := fmt.Scanln(&val); err != nil || (strings.ToLower(val) != "yes" && strings.ToLower(val) != "y") {
+		if _, err := scanln(&val); err != nil || (strings.ToLower(val) != "yes" && strings.ToLower(val) != "y") {
 			fmt.Println("operation is canceled.")
 			return false
 		}
@@ -395,6 +451,24 @@ func (rc *RemoveCommand) confirmRemoveObject(cloudURL CloudURL) bool {
 	return true
 }
 
+// remove removes the objects, multipart uploads or bucket of cloudURL with rmOption, the progress
+// and the errors of items are passed to the callbacks of rmOption, ctx stops removing
+func (rc *RemoveCommand) remove(ctx context.Context, cloudURL CloudURL) error {
+	rc.command.ctx = ctx
+	bucket, err := rc.command.ossBucket(cloudURL.bucket)
+	if err != nil {
+		return err
+	}
+
+	// start statistic for progressbar
+	go rc.entryStatistic(bucket, cloudURL)
+
+	if err := rc.removeEntry(bucket, cloudURL); err != nil {
+		return err
+	}
+	return rc.command.cancelError()
+}
+
 func (rc *RemoveCommand) entryStatistic(bucket *oss.Bucket, cloudURL CloudURL) {
 	if rc.rmOption.typeSet&objectType != 0 {
 		rc.objectStatistic(bucket, cloudURL)
@@ -433,12 +507,13 @@ func (rc *RemoveCommand) ossIsObjectExistRetry(bucket *oss.Bucket, object string
 		if int64(i) >= retryTimes {
 			return false, ObjectError{err, bucket.BucketName, object}
 		}
+		rc.command.metrics.retry()
 	}
 }
 
 func (rc *RemoveCommand) batchObjectStatistic(bucket *oss.Bucket, cloudURL CloudURL) error {
 	pre := oss.Prefix(cloudURL.object)
-	marker := oss.Marker("")
+	marker := oss.Marker(rc.rmOption.journal.marker())
 	for {
 		lor, err := rc.command.ossListObjectsRetry(bucket, marker, pre)
 		if err != nil {
@@ -501,12 +576,12 @@ func (rc *RemoveCommand) removeEntry(bucket *oss.Bucket, cloudURL CloudURL) erro
 			return err
 		}
 	}
-	if rc.rmOption.typeSet&multipartType != 0 {
+	if rc.rmOption.typeSet&multipartType != 0 && !rc.command.canceled() {
 		if err := rc.removeMultipartUploadsEntry(bucket, cloudURL); err != nil {
 			return err
 		}
 	}
-	if rc.rmOption.typeSet&bucketType != 0 {
+	if rc.rmOption.typeSet&bucketType != 0 && !rc.command.canceled() {
 		return rc.removeBucket(bucket, cloudURL)
 	}
 	return nil
@@ -540,6 +615,7 @@ func (rc *RemoveCommand) deleteObjectWithMonitor(bucket *oss.Bucket, object stri
 		rc.updateObjectMonitor(1, 0)
 	} else {
 		rc.updateObjectMonitor(0, 1)
+		rc.itemError(fmt.Sprintf("remove %s", CloudURLToString(bucket.BucketName, object)), err)
 	}
 	return err
 }
@@ -554,62 +630,109 @@ func (rc *RemoveCommand) ossDeleteObjectRetry(bucket *oss.Bucket, object string)
 		if int64(i) >= retryTimes {
 			return ObjectError{err, bucket.BucketName, object}
 		}
+		rc.command.metrics.retry()
 	}
 }
 
 func (rc *RemoveCommand) updateObjectMonitor(okNum, errNum int64) {
 	rc.monitor.updateObjectNum(okNum)
 	rc.monitor.updateErrObjectNum(errNum)
-	fmt.Printf(rc.monitor.progressBar(false, normalExit))
+	rc.progress()
+}
+
+func (rc *RemoveCommand) progress() {
+	if rc.rmOption.onProgress != nil {
+		rc.rmOption.onProgress(rc.monitor.getProgress())
+	}
+	rc.rmOption.notifier.progress(rc.monitor.getSnapshot().dealNum)
+}
+
+func (rc *RemoveCommand) itemError(msg string, err error) {
+	if rc.rmOption.onError != nil {
+		rc.rmOption.onError(ItemError{msg, err})
+	}
 }
 
 func (rc *RemoveCommand) batchDeleteObjects(bucket *oss.Bucket, cloudURL CloudURL) error {
-	// list objects
-	pre := oss.Prefix(cloudURL.object)
-	marker := oss.Marker("")
-	for {
-		lor, err := rc.command.ossListObjectsRetry(bucket, marker, pre)
-		if err != nil {
-			return err
+	// the items are pages of objects, which are deleted one by one in order, so that the marker
+	// of journal moves forward
+	source := func(send func(batchItem) bool) error {
+		pre := oss.Prefix(cloudURL.object)
+		marker := oss.Marker(rc.rmOption.journal.marker())
+		for {
+			lor, err := rc.command.ossListObjectsRetry(bucket, marker, pre)
+			if err != nil {
+				return err
+			}
+			if !send(batchItem{value: lor}) || !lor.IsTruncated {
+				return nil
+			}
+			pre = oss.Prefix(lor.Prefix)
+			marker = oss.Marker(lor.NextMarker)
 		}
+	}
 
-		// batch delete
-		delNum, err := rc.ossBatchDeleteObjectsRetry(bucket, rc.getObjectsFromListResult(lor))
-		rc.updateObjectMonitor(int64(delNum), int64(len(lor.Objects)-delNum))
-		if err != nil {
-			return err
+	// the hooks are run for the objects of page instead of the page
+	engine := rc.command.newBatchEngine(&rc.rmOption.batchOptionType, 1, source, func(item batchItem) (string, error) {
+		lor := item.value.(oss.ListObjectsResult)
+		objects := rc.getObjectsFromListResult(lor)
+		failed, err := rc.ossBatchDeleteObjectsRetry(bucket, objects)
+		rc.updateObjectMonitor(int64(len(objects)-len(failed)), int64(len(failed)))
+		rc.runObjectHooks(bucket, objects, failed, err)
+		if err == nil && lor.IsTruncated {
+			rc.rmOption.journal.setMarker(lor.NextMarker)
 		}
-		pre = oss.Prefix(lor.Prefix)
-		marker = oss.Marker(lor.NextMarker)
-		if !lor.IsTruncated {
-			break
+		msg := fmt.Sprintf("remove objects of %s", CloudURLToString(bucket.BucketName, cloudURL.object))
+		if err != nil {
+			rc.itemError(msg, err)
 		}
-	}
-	return nil
+		return msg, err
+	})
+	engine.queue = 1
+	return engine.run()
 }
 
-func (rc *RemoveCommand) ossBatchDeleteObjectsRetry(bucket *oss.Bucket, objects []string) (int, error) {
+// ossBatchDeleteObjectsRetry deletes objects, and returns the objects failed to delete
+func (rc *RemoveCommand) ossBatchDeleteObjectsRetry(bucket *oss.Bucket, objects []string) ([]string, error) {
 	retryTimes, _ := GetInt(OptionRetryTimes, rc.command.options)
-	num := len(objects)
-	if num <= 0 {
-		return 0, nil
+	if len(objects) <= 0 {
+		return nil, nil
 	}
 
 	for i := 1; ; i++ {
 		delRes, err := bucket.DeleteObjects(objects, oss.DeleteObjectsQuiet(true))
 		if err == nil && len(delRes.DeletedObjects) == 0 {
-			return num, nil
+			return nil, nil
 		}
 		if int64(i) >= retryTimes {
 			if err != nil {
-				return num - len(objects), err
+				return objects, err
 			}
-			return num - len(delRes.DeletedObjects), fmt.Errorf("delete objects: %s failed", delRes.DeletedObjects)
+			return delRes.DeletedObjects, fmt.Errorf("delete objects: %s failed", delRes.DeletedObjects)
 		}
+		rc.command.metrics.retry()
 		objects = delRes.DeletedObjects
 	}
 }
 
+// runObjectHooks runs the hooks for the objects deleted in batch, err is the error of failed objects
+func (rc *RemoveCommand) runObjectHooks(bucket *oss.Bucket, objects, failed []string, err error) {
+	if rc.rmOption.hooks == nil {
+		return
+	}
+	failedSet := map[string]bool{}
+	for _, object := range failed {
+		failedSet[object] = true
+	}
+	for _, object := range objects {
+		var oerr error
+		if failedSet[object] {
+			oerr = err
+		}
+		rc.rmOption.hooks.done(&rc.rmOption.batchOptionType, hookItem{Source: CloudURLToString(bucket.BucketName, object)}, oerr)
+	}
+}
+
 func (rc *RemoveCommand) getObjectsFromListResult(lor oss.ListObjectsResult) []string {
 	objects := []string{}
 	for _, object := range lor.Objects {
@@ -619,73 +742,47 @@ func (rc *RemoveCommand) getObjectsFromListResult(lor oss.ListObjectsResult) []s
 }
 
 func (rc *RemoveCommand) removeMultipartUploadsEntry(bucket *oss.Bucket, cloudURL CloudURL) error {
-	routines := 1
-	chUploadIds := make(chan uploadIdInfoType, ChannelBuf)
-	chError := make(chan error, routines+1)
-	chListError := make(chan error, 1)
-	go rc.multipartUploadsProducer(bucket, cloudURL, chUploadIds, chListError)
-	for i := 0; i < routines; i++ {
-		go rc.abortMultipartUploadConsumer(bucket, chUploadIds, chError)
-	}
-
-	completed := 0
-	for completed <= routines {
-		select {
-		case err := <-chListError:
-			if err != nil {
-				return err
-			}
-			completed++
-		case err := <-chError:
+	engine := rc.command.newBatchEngine(&rc.rmOption.batchOptionType, 1, rc.multipartUploadsSource(bucket, cloudURL), func(item batchItem) (string, error) {
+		uploadIdInfo := item.value.(uploadIdInfoType)
+		err := rc.ossAbortMultipartUploadRetry(bucket, uploadIdInfo.key, uploadIdInfo.uploadId)
+		msg := fmt.Sprintf("abort uploadId %s of %s", uploadIdInfo.uploadId, CloudURLToString(bucket.BucketName, uploadIdInfo.key))
+		if err != nil {
+			rc.itemError(msg, err)
+		}
+		rc.updateUploadIdMonitor(err)
+		return msg, err
+	})
+	return engine.run()
+}
+
+func (rc *RemoveCommand) multipartUploadsSource(bucket *oss.Bucket, cloudURL CloudURL) batchSource {
+	return func(send func(batchItem) bool) error {
+		pre := oss.Prefix(cloudURL.object)
+		keyMarker := oss.KeyMarker("")
+		uploadIdMarker := oss.UploadIDMarker("")
+		for {
+			lmr, err := rc.command.ossListMultipartUploadsRetry(bucket, keyMarker, uploadIdMarker, pre)
 			if err != nil {
 				return err
 			}
-			completed++
-		}
-	}
-	return nil
-}
-
-func (rc *RemoveCommand) multipartUploadsProducer(bucket *oss.Bucket, cloudURL CloudURL, chUploadIds chan<- uploadIdInfoType, chListError chan<- error) {
-	pre := oss.Prefix(cloudURL.object)
-	keyMarker := oss.KeyMarker("")
-	uploadIdMarker := oss.UploadIDMarker("")
-	for {
-		lmr, err := rc.command.ossListMultipartUploadsRetry(bucket, keyMarker, uploadIdMarker, pre)
-		if err != nil {
-			chListError <- err
-			break
-		}
 
-		for _, uploadId := range lmr.Uploads {
-			if !rc.rmOption.recursive && uploadId.Key != cloudURL.object {
-				break
+			for _, uploadId := range lmr.Uploads {
+				if !rc.rmOption.recursive && uploadId.Key != cloudURL.object {
+					break
+				}
+				if !send(batchItem{value: uploadIdInfoType{uploadId.Key, uploadId.UploadID}}) {
+					return nil
+				}
 			}
-			chUploadIds <- uploadIdInfoType{uploadId.Key, uploadId.UploadID}
-		}
-
-		pre = oss.Prefix(lmr.Prefix)
-		keyMarker = oss.KeyMarker(lmr.NextKeyMarker)
-		uploadIdMarker = oss.UploadIDMarker(lmr.NextUploadIDMarker)
-		if !lmr.IsTruncated {
-			break
-		}
-	}
-	defer close(chUploadIds)
-	chListError <- nil
-}
 
-func (rc *RemoveCommand) abortMultipartUploadConsumer(bucket *oss.Bucket, chUploadIds <-chan uploadIdInfoType, chError chan<- error) {
-	for uploadIdInfo := range chUploadIds {
-		err := rc.ossAbortMultipartUploadRetry(bucket, uploadIdInfo.key, uploadIdInfo.uploadId)
-		rc.updateUploadIdMonitor(err)
-		if err != nil {
-			chError <- err
-			return
+			pre = oss.Prefix(lmr.Prefix)
+			keyMarker = oss.KeyMarker(lmr.NextKeyMarker)
+			uploadIdMarker = oss.UploadIDMarker(lmr.NextUploadIDMarker)
+			if !lmr.IsTruncated {
+				return nil
+			}
 		}
 	}
-
-	chError <- nil
 }
 
 func (rc *RemoveCommand) updateUploadIdMonitor(err error) {
@@ -694,7 +791,7 @@ func (rc *RemoveCommand) updateUploadIdMonitor(err error) {
 	} else {
 		rc.monitor.updateErrUploadIdNum(1)
 	}
-	fmt.Printf(rc.monitor.progressBar(false, normalExit))
+	rc.progress()
 }
 
 func (rc *RemoveCommand) ossAbortMultipartUploadRetry(bucket *oss.Bucket, key, uploadId string) error {
@@ -717,6 +814,7 @@ func (rc *RemoveCommand) ossAbortMultipartUploadRetry(bucket *oss.Bucket, key, u
 		if int64(i) >= retryTimes {
 			return ObjectError{err, bucket.BucketName, key}
 		}
+		rc.command.metrics.retry()
 	}
 }
 
@@ -737,7 +835,7 @@ func (rc *RemoveCommand) confirmRemoveBucket(cloudURL CloudURL) bool {
 	if !rc.rmOption.force {
 		var val string
 		fmt.Printf(getClearStr(fmt.Sprintf("Do you really mean to remove the Bucket: %s(y or N)? ", cloudURL.bucket)))
-		if _, err := fmt.Scanln(&val); err != nil || (strings.ToLower(val) != "yes" && strings.ToLower(val) != "y") {
+		if _, err := scanln(&val); err != nil || (strings.ToLower(val) != "yes" && strings.ToLower(val) != "y") {
 			fmt.Println("operation is canceled.")
 			return false
 		}
@@ -756,5 +854,6 @@ func (rc *RemoveCommand) ossDeleteBucketRetry(client *oss.Client, bucket string)
 		if int64(i) >= retryTimes {
 			return BucketError{err, bucket}
 		}
+		rc.command.metrics.retry()
 	}
 }
@@ -208,9 +208,9 @@ func (s *OssutilCommandSuite) TestErrRemove(c *C) {
 	objects := []string{}
 	ossBucket, err := removeCommand.command.ossBucket(bucketName)
 	c.Assert(err, IsNil)
-	num, err := removeCommand.ossBatchDeleteObjectsRetry(ossBucket, objects)
+	failed, err := removeCommand.ossBatchDeleteObjectsRetry(ossBucket, objects)
 	c.Assert(err, IsNil)
-	c.Assert(num, Equals, 0)
+	c.Assert(len(failed), Equals, 0)
 }
 
 func (s *OssutilCommandSuite) TestErrDeleteObject(c *C) {
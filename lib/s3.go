@@ -0,0 +1,531 @@
+package lib
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+const (
+	s3SignAlgorithm  = "AWS4-HMAC-SHA256"
+	s3SignService    = "s3"
+	s3DateFormat     = "20060102T150405Z"
+	s3EmptyBodyHash  = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	s3HeaderDate     = "X-Amz-Date"
+	s3HeaderSHA256   = "X-Amz-Content-Sha256"
+	s3HeaderToken    = "X-Amz-Security-Token"
+	s3HeaderRequstID = "X-Amz-Request-Id"
+)
+
+// s3Error is the error returned by s3 compatible storage
+type s3Error struct {
+	XMLName    xml.Name `xml:"Error"`
+	StatusCode int      `xml:"-"`
+	Code       string   `xml:"Code"`
+	Message    string   `xml:"Message"`
+	RequestID  string   `xml:"RequestId"`
+}
+
+func (e s3Error) Error() string {
+	return fmt.Sprintf("s3: service returned error: StatusCode=%d, ErrorCode=%s, ErrorMessage=\"%s\", RequestId=%s", e.StatusCode, e.Code, e.Message, e.RequestID)
+}
+
+// s3Client reads buckets and objects from s3 compatible storage with signature version 4, it
+// only supports the requests needed to migrate data into oss
+type s3Client struct {
+	endpoint        string
+	accessKeyID     string
+	accessKeySecret string
+	sessionToken    string
+	region          string
+	client          *http.Client
+	retryTimes      int64
+}
+
+// s3Option returns the value of option, or the environment variable if the option is not specified
+func (cmd *Command) s3Option(name, env string) string {
+	if val, _ := GetString(name, cmd.options); val != "" {
+		return val
+	}
+	if env != "" {
+		return os.Getenv(env)
+	}
+	return ""
+}
+
+// s3Client returns the client of s3 compatible storage with the s3 options
+func (cmd *Command) s3Client() (*s3Client, error) {
+	endpoint := strings.TrimSpace(cmd.s3Option(OptionS3Endpoint, ""))
+	accessKeyID := cmd.s3Option(OptionS3KeyID, "AWS_ACCESS_KEY_ID")
+	accessKeySecret := cmd.s3Option(OptionS3KeySecret, "AWS_SECRET_ACCESS_KEY")
+	if endpoint == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint, s3 endpoint is empty, please specify it by option --s3-endpoint")
+	}
+	if strings.TrimSpace(accessKeyID) == "" {
+		return nil, fmt.Errorf("invalid s3 accessKeyID, s3 accessKeyID is empty, please specify it by option --s3-access-key-id or environment variable AWS_ACCESS_KEY_ID")
+	}
+	if strings.TrimSpace(accessKeySecret) == "" {
+		return nil, fmt.Errorf("invalid s3 accessKeySecret, s3 accessKeySecret is empty, please specify it by option --s3-access-key-secret or environment variable AWS_SECRET_ACCESS_KEY")
+	}
+
+	endpoint = cmd.httpsEndpoint(endpoint)
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	region := cmd.s3Option(OptionS3Region, "AWS_REGION")
+	if region == "" {
+		region = DefaultS3Region
+	}
+
+	client, err := cmd.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = &http.Client{Transport: cmd.newTransport(nil, nil)}
+	}
+	retryTimes, err := GetInt(OptionRetryTimes, cmd.options)
+	if err != nil {
+		retryTimes = int64(RetryTimes)
+	}
+	return &s3Client{
+		endpoint:        strings.TrimRight(endpoint, "/"),
+		accessKeyID:     accessKeyID,
+		accessKeySecret: accessKeySecret,
+		sessionToken:    cmd.s3Option(OptionS3Token, "AWS_SESSION_TOKEN"),
+		region:          region,
+		client:          client,
+		retryTimes:      retryTimes,
+	}, nil
+}
+
+// s3Escape encodes the string as uri encoding of signature version 4, the slash is kept in path
+func s3Escape(s string, encodeSlash bool) string {
+	var buf []byte
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ('A' <= ch && ch <= 'Z') || ('a' <= ch && ch <= 'z') || ('0' <= ch && ch <= '9') ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' || (ch == '/' && !encodeSlash) {
+			buf = append(buf, ch)
+		} else {
+			buf = append(buf, fmt.Sprintf("%%%02X", ch)...)
+		}
+	}
+	return string(buf)
+}
+
+// s3CanonicalQuery returns the query sorted by name and encoded
+func s3CanonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := []string{}
+	for _, name := range names {
+		vals := append([]string{}, query[name]...)
+		sort.Strings(vals)
+		for _, val := range vals {
+			pairs = append(pairs, s3Escape(name, true)+"="+s3Escape(val, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func s3HMAC(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// sign signs the request without body by signature version 4, the host and all headers of request are signed
+func (c *s3Client) sign(req *http.Request, now time.Time) {
+	amzDate := now.UTC().Format(s3DateFormat)
+	req.Header.Set(s3HeaderDate, amzDate)
+	req.Header.Set(s3HeaderSHA256, s3EmptyBodyHash)
+	if c.sessionToken != "" {
+		req.Header.Set(s3HeaderToken, c.sessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, vals := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(vals, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		s3EmptyBodyHash,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	scope := strings.Join([]string{amzDate[:8], c.region, s3SignService, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{s3SignAlgorithm, amzDate, scope, hex.EncodeToString(hash[:])}, "\n")
+
+	key := s3HMAC([]byte("AWS4"+c.accessKeySecret), amzDate[:8])
+	key = s3HMAC(key, c.region)
+	key = s3HMAC(key, s3SignService)
+	key = s3HMAC(key, "aws4_request")
+	signature := hex.EncodeToString(s3HMAC(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s", s3SignAlgorithm, c.accessKeyID, scope, signedHeaders, signature))
+}
+
+// do sends the request in path style, and returns the response if the status is 2xx
+func (c *s3Client) do(method, bucket, object string, params url.Values, header http.Header) (*http.Response, error) {
+	path := "/"
+	if bucket != "" {
+		path += bucket
+		if object != "" {
+			path += "/" + object
+		}
+	}
+	urlStr := c.endpoint + s3Escape(path, false)
+	if len(params) > 0 {
+		urlStr += "?" + s3CanonicalQuery(params)
+	}
+	req, err := http.NewRequest(method, urlStr, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, vals := range header {
+		req.Header[name] = vals
+	}
+	c.sign(req, time.Now())
+	req.Header.Set("User-Agent", getUserAgent())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	serr := s3Error{StatusCode: resp.StatusCode}
+	if body, err := ioutil.ReadAll(resp.Body); err == nil && len(body) > 0 {
+		xml.Unmarshal(body, &serr)
+	}
+	if serr.Code == "" {
+		serr.Code = http.StatusText(resp.StatusCode)
+	}
+	if serr.RequestID == "" {
+		serr.RequestID = resp.Header.Get(s3HeaderRequstID)
+	}
+	return nil, serr
+}
+
+// doRetry retries the request if it fails by network or server error
+func (c *s3Client) doRetry(method, bucket, object string, params url.Values, header http.Header) (*http.Response, error) {
+	for i := int64(1); ; i++ {
+		resp, err := c.do(method, bucket, object, params, header)
+		if err == nil {
+			return resp, nil
+		}
+		if serr, ok := err.(s3Error); ok && serr.StatusCode < 500 {
+			return nil, err
+		}
+		if i >= c.retryTimes {
+			return nil, err
+		}
+	}
+}
+
+func (c *s3Client) getXMLRetry(bucket string, params url.Values, v interface{}) error {
+	resp, err := c.doRetry("GET", bucket, "", params, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return xml.NewDecoder(resp.Body).Decode(v)
+}
+
+// listBuckets lists all buckets of the account
+func (c *s3Client) listBuckets() (oss.ListBucketsResult, error) {
+	var result oss.ListBucketsResult
+	err := c.getXMLRetry("", nil, &result)
+	return result, err
+}
+
+// listObjects lists the objects of bucket in one page, the next marker is set if the result is
+// truncated, since s3 returns it only when delimiter is specified
+func (c *s3Client) listObjects(bucket, prefix, marker, delimiter string, maxKeys int) (oss.ListObjectsResult, error) {
+	params := url.Values{}
+	params.Set("prefix", prefix)
+	if marker != "" {
+		params.Set("marker", marker)
+	}
+	if delimiter != "" {
+		params.Set("delimiter", delimiter)
+	}
+	if maxKeys > 0 {
+		params.Set("max-keys", strconv.Itoa(maxKeys))
+	}
+
+	var result oss.ListObjectsResult
+	if err := c.getXMLRetry(bucket, params, &result); err != nil {
+		return result, err
+	}
+	if result.IsTruncated && result.NextMarker == "" {
+		if len(result.Objects) > 0 {
+			result.NextMarker = result.Objects[len(result.Objects)-1].Key
+		}
+		if n := len(result.CommonPrefixes); n > 0 && result.CommonPrefixes[n-1] > result.NextMarker {
+			result.NextMarker = result.CommonPrefixes[n-1]
+		}
+	}
+	return result, nil
+}
+
+// headObject returns the headers of object
+func (c *s3Client) headObject(bucket, object string) (http.Header, error) {
+	resp, err := c.doRetry("HEAD", bucket, object, nil, nil)
+	if err != nil {
+		return nil, ObjectError{err, bucket, object}
+	}
+	resp.Body.Close()
+	return resp.Header, nil
+}
+
+// getObject returns the data of object, or the range of it if end is not negative
+func (c *s3Client) getObject(bucket, object string, start, end int64, etag string) (*http.Response, error) {
+	header := http.Header{}
+	if end >= 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	}
+	if etag != "" {
+		header.Set(oss.HTTPHeaderIfMatch, etag)
+	}
+	return c.doRetry("GET", bucket, object, nil, header)
+}
+
+// s3StreamSource reads objects from the bucket of s3 compatible storage
+type s3StreamSource struct {
+	client *s3Client
+	bucket string
+}
+
+func (s s3StreamSource) bucketName() string {
+	return s.bucket
+}
+
+func (s s3StreamSource) stat(object string) (http.Header, error) {
+	return s.client.headObject(s.bucket, object)
+}
+
+func (s s3StreamSource) open(object string, start, end int64, etag string) (io.ReadCloser, http.Header, error) {
+	resp, err := s.client.getObject(s.bucket, object, start, end, etag)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Body, resp.Header, nil
+}
+
+// migrateFiles copies the objects of s3 compatible storage to oss by streaming
+func (cc *CopyCommand) migrateFiles(srcURL S3URL, destURL CloudURL) error {
+	client, err := cc.command.s3Client()
+	if err != nil {
+		return err
+	}
+	if err := destURL.checkObjectPrefix(); err != nil {
+		return err
+	}
+	src := s3StreamSource{client, srcURL.bucket}
+
+	go cc.s3ObjectStatistic(src, srcURL)
+	if !cc.cpOption.recursive {
+		if srcURL.object == "" {
+			return fmt.Errorf("copy object invalid url: %s, object empty. If you mean batch copy objects, please use --recursive option", srcURL.ToString())
+		}
+		item := batchItem{srcURL.object, objectInfoType{srcURL.object, -1, time.Now(), "", ""}}
+		return cc.migrateObjects(src, sliceSource(item), srcURL, destURL)
+	}
+	return cc.migrateObjects(src, cc.s3ObjectSource(src, srcURL), srcURL, destURL)
+}
+
+// s3ObjectStatistic counts the objects to migrate in monitor
+func (cc *CopyCommand) s3ObjectStatistic(src s3StreamSource, srcURL S3URL) {
+	if cc.cpOption.recursive {
+		marker := cc.cpOption.journal.marker()
+		for {
+			lor, err := src.client.listObjects(src.bucket, srcURL.object, marker, "", S3MaxKeys)
+			if err != nil {
+				cc.monitor.setScanError(err)
+				return
+			}
+			for _, object := range lor.Objects {
+				cc.monitor.updateScanSizeNum(object.Size, 1)
+			}
+			marker = lor.NextMarker
+			if !lor.IsTruncated {
+				break
+			}
+		}
+	} else {
+		props, err := src.stat(srcURL.object)
+		if err != nil {
+			cc.monitor.setScanError(err)
+			return
+		}
+		size, err := strconv.ParseInt(props.Get(oss.HTTPHeaderContentLength), 10, 64)
+		if err != nil {
+			cc.monitor.setScanError(err)
+			return
+		}
+		cc.monitor.updateScanSizeNum(size, 1)
+	}
+
+	cc.monitor.setScanEnd()
+	cc.freshProgress()
+}
+
+// s3ObjectSource lists the objects to migrate from the marker of journal
+func (cc *CopyCommand) s3ObjectSource(src s3StreamSource, srcURL S3URL) batchSource {
+	return func(send func(batchItem) bool) error {
+		marker := cc.cpOption.journal.marker()
+		for {
+			lor, err := src.client.listObjects(src.bucket, srcURL.object, marker, "", S3MaxKeys)
+			if err != nil {
+				return err
+			}
+
+			items := []batchItem{}
+			keys := []string{}
+			for _, object := range lor.Objects {
+				items = append(items, batchItem{object.Key, objectInfoType{object.Key, object.Size, object.LastModified, object.ETag, ""}})
+				keys = append(keys, object.Key)
+			}
+			cc.cpOption.journal.addPage(lor.NextMarker, keys)
+			for _, item := range items {
+				if !send(item) {
+					return nil
+				}
+			}
+
+			marker = lor.NextMarker
+			if !lor.IsTruncated {
+				return nil
+			}
+		}
+	}
+}
+
+func (cc *CopyCommand) migrateObjects(src s3StreamSource, source batchSource, srcURL S3URL, destURL CloudURL) error {
+	engine := cc.command.newBatchEngine(&cc.cpOption.batchOptionType, cc.cpOption.routines, source, func(item batchItem) (string, error) {
+		objectInfo := item.value.(objectInfoType)
+		skip, err, size, msg := cc.migrateSingleFile(src, objectInfo, srcURL, destURL)
+		cc.updateMonitor(skip, err, false, size)
+		if cc.cpOption.hooks != nil && !skip {
+			destObject := cc.makeCopyObjectName(objectInfo.key, srcURL.object, destURL)
+			hook := hookItem{Source: S3URLToString(srcURL.bucket, objectInfo.key), Destination: CloudURLToString(destURL.bucket, destObject)}
+			destBucket, _ := cc.command.ossBucket(destURL.bucket)
+			cc.transferHook(skip, err, hook, destBucket, destObject)
+		}
+		return msg, err
+	})
+	engine.finish = cc.finishProgress
+	return engine.run()
+}
+
+func (cc *CopyCommand) migrateSingleFile(src s3StreamSource, objectInfo objectInfoType, srcURL S3URL, destURL CloudURL) (bool, error, int64, string) {
+	srcObject := objectInfo.key
+	destObject := cc.makeCopyObjectName(objectInfo.key, srcURL.object, destURL)
+	size := objectInfo.size
+	srct := objectInfo.lastModified
+	etag := objectInfo.etag
+
+	msg := fmt.Sprintf("%s %s to %s", opCopy, S3URLToString(srcURL.bucket, srcObject), CloudURLToString(destURL.bucket, destObject))
+
+	if destObject == "" {
+		return false, CopyError{fmt.Errorf("dest object name is empty, try add a prefix to dest_url ==> change dest_url to: oss://dest_bucket/prefix, see naming rules in \"help cp\"")}, size, msg
+	}
+
+	if size < 0 {
+		props, err := src.stat(srcObject)
+		if err != nil {
+			return false, err, size, msg
+		}
+		size, err = strconv.ParseInt(props.Get(oss.HTTPHeaderContentLength), 10, 64)
+		if err != nil {
+			return false, err, size, msg
+		}
+		if srct, err = time.Parse(http.TimeFormat, props.Get(oss.HTTPHeaderLastModified)); err != nil {
+			return false, err, size, msg
+		}
+		etag = props.Get(oss.HTTPHeaderEtag)
+	}
+
+	if cc.cpOption.journal.isDone(srcObject) {
+		return true, nil, size, msg
+	}
+
+	spath := cc.formatSnapshotKey(S3URLToString(srcURL.bucket, srcObject), CloudURLToString(destURL.bucket, destObject))
+	if cc.skipSnapshot(spath, etag) {
+		return true, nil, size, msg
+	}
+
+	// the source bucket is used only by --checksum, which is not supported by migration
+	if skip, err := cc.skipCopy(nil, srcObject, destURL, destObject, size, srct); err != nil || skip {
+		return skip, err, size, msg
+	}
+
+	verify := func() error {
+		destBucket, err := cc.command.ossBucket(destURL.bucket)
+		if err != nil {
+			return err
+		}
+		dsize, _, err := cc.getObjectVerifyInfo(destBucket, destObject)
+		if err != nil {
+			return err
+		}
+		return cc.checkVerifyInfo(S3URLToString(srcURL.bucket, srcObject), CloudURLToString(destURL.bucket, destObject), size, dsize, "", "")
+	}
+	if size < cc.cpOption.threshold {
+		err := cc.transferWithVerify(func() error {
+			return cc.ossStreamObjectRetry(src, srcObject, destURL.bucket, destObject)
+		}, verify)
+		if serr := cc.updateSnapshotValue(err, spath, etag); serr != nil {
+			err = serr
+		}
+		return false, err, size, msg
+	}
+
+	partSize, rt := cc.preparePartOption(size)
+	cpDir := cc.formatCPFileName(cc.cpOption.cpDir, S3URLToString(srcURL.bucket, srcObject), CloudURLToString(destURL.bucket, destObject))
+	err := cc.transferWithVerify(func() error {
+		return cc.ossStreamCopyRetry(src, srcObject, destURL.bucket, destObject, partSize, rt, cpDir)
+	}, verify)
+	if serr := cc.updateSnapshotValue(err, spath, etag); serr != nil {
+		err = serr
+	}
+	return false, err, 0, msg
+}
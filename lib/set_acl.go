@@ -223,9 +223,22 @@ var setACLCommand = SetACLCommand{
 			OptionAccessKeyID,
 			OptionAccessKeySecret,
 			OptionSTSToken,
+			OptionProxyHost,
+			OptionProxyUser,
+			OptionProxyPwd,
+			OptionCAFile,
+			OptionSkipVerify,
+			OptionForceHTTPS,
+			OptionConnectTimeout,
+			OptionReadTimeout,
 			OptionRetryTimes,
 			OptionRoutines,
 			OptionOutputDir,
+			OptionOnSuccess,
+			OptionOnError,
+			OptionOnFinish,
+			OptionHookRoutines,
+			OptionHookTimeout,
 		},
 	},
 }
@@ -307,7 +320,7 @@ func (sc *SetACLCommand) getACL(aclType setACLType, recursive bool) (oss.ACLType
 			}
 		}
 		fmt.Printf("Please enter the acl you want to set on the %s(%s):", str, formatACLString(aclType, ", "))
-		if _, err := fmt.Scanln(&acl); err != nil {
+		if _, err := scanln(&acl); err != nil {
 			return "", fmt.Errorf("invalid acl: %s, please check", acl)
 		}
 	}
@@ -379,7 +392,7 @@ func (sc *SetACLCommand) batchSetObjectACL(bucket *oss.Bucket, cloudURL CloudURL
 	if !force {
 		var val string
 		fmt.Printf("Do you really mean to recursivlly set acl on objects of %s(y or N)? ", sc.command.args[0])
-		if _, err := fmt.Scanln(&val); err != nil || (strings.ToLower(val) != "yes" && strings.ToLower(val) != "y") {
+		if _, err := scanln(&val); err != nil || (strings.ToLower(val) != "yes" && strings.ToLower(val) != "y") {
 			fmt.Println("operation is canceled.")
 			return nil
 		}
@@ -398,77 +411,25 @@ func (sc *SetACLCommand) batchSetObjectACL(bucket *oss.Bucket, cloudURL CloudURL
 		return err
 	}
 	defer sc.saOption.reporter.Clear()
+	sc.saOption.hooks = sc.command.newItemHooks()
 
-	return sc.setObjectACLs(bucket, cloudURL, acl, force, routines)
+	err = sc.setObjectACLs(bucket, cloudURL, acl, force, routines)
+	sc.saOption.hooks.finish(&sc.saOption, err)
+	return err
 }
 
 func (sc *SetACLCommand) setObjectACLs(bucket *oss.Bucket, cloudURL CloudURL, acl oss.ACLType, force bool, routines int64) error {
-	// producer list objects
-	// consumer set acl
-	chObjects := make(chan string, ChannelBuf)
-	chError := make(chan error, routines+1)
-	chListError := make(chan error, 1)
-	go sc.command.objectStatistic(bucket, cloudURL, &sc.monitor)
-	go sc.command.objectProducer(bucket, cloudURL, chObjects, chListError)
-	for i := 0; int64(i) < routines; i++ {
-		go sc.setObjectACLConsumer(bucket, acl, chObjects, chError)
-	}
-
-	return sc.waitRoutinueComplete(chError, chListError, routines)
-}
-
-func (sc *SetACLCommand) setObjectACLConsumer(bucket *oss.Bucket, acl oss.ACLType, chObjects <-chan string, chError chan<- error) {
-	for object := range chObjects {
-		err := sc.setObjectACLWithReport(bucket, object, acl)
-		if err != nil {
-			chError <- err
-			if !sc.saOption.ctnu {
-				return
-			}
-			continue
-		}
-	}
-
-	chError <- nil
+	go sc.command.objectStatistic(bucket, cloudURL, &sc.monitor, nil)
+	engine := sc.command.newBatchEngine(&sc.saOption, routines, sc.command.objectSource(bucket, cloudURL, nil, nil), func(item batchItem) (string, error) {
+		return sc.setObjectACLItem(bucket, item.key, acl)
+	})
+	engine.monitor = &sc.monitor
+	engine.hookItem = objectHookItem(bucket)
+	return engine.run()
 }
 
-func (sc *SetACLCommand) setObjectACLWithReport(bucket *oss.Bucket, object string, acl oss.ACLType) error {
-	err := sc.ossSetObjectACLRetry(bucket, object, acl)
-	sc.command.updateMonitor(err, &sc.monitor)
+// setObjectACLItem is the batch action of set-acl
+func (sc *SetACLCommand) setObjectACLItem(bucket *oss.Bucket, object string, acl oss.ACLType) (string, error) {
 	msg := fmt.Sprintf("set acl on %s", CloudURLToString(bucket.BucketName, object))
-	sc.command.report(msg, err, &sc.saOption)
-	return err
-}
-
-func (sc *SetACLCommand) waitRoutinueComplete(chError, chListError <-chan error, routines int64) error {
-	completed := 0
-	var ferr error
-	for int64(completed) <= routines {
-		select {
-		case err := <-chListError:
-			if err != nil {
-				return err
-			}
-			completed++
-		case err := <-chError:
-			if err == nil {
-				completed++
-			} else {
-				ferr = err
-				if !sc.saOption.ctnu {
-					fmt.Printf(sc.monitor.progressBar(true, errExit))
-					return err
-				}
-			}
-		}
-	}
-	return sc.formatResultPrompt(ferr)
-}
-
-func (sc *SetACLCommand) formatResultPrompt(err error) error {
-	fmt.Printf(sc.monitor.progressBar(true, normalExit))
-	if err != nil && sc.saOption.ctnu {
-		return nil
-	}
-	return err
+	return msg, sc.ossSetObjectACLRetry(bucket, object, acl)
 }
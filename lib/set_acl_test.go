@@ -361,21 +361,12 @@ func (s *OssutilCommandSuite) TestBatchSetACLErrorContinue(c *C) {
 
 	var routines int64
 	routines = 3
-	chObjects := make(chan string, ChannelBuf)
-	chError := make(chan error, routines+1)
-	chListError := make(chan error, 1)
-
-	chObjects <- objectNames[0]
-	chObjects <- "notexistobject" + randStr(3)
-	chObjects <- objectNames[1]
-	chListError <- nil
-	close(chObjects)
-
-	for i := 0; int64(i) < routines; i++ {
-		setACLCommand.setObjectACLConsumer(bucket, acl, chObjects, chError)
-	}
-
-	err = setACLCommand.waitRoutinueComplete(chError, chListError, routines)
+	source := sliceSource(objectItems(objectNames[0], "notexistobject"+randStr(3), objectNames[1])...)
+	engine := setACLCommand.command.newBatchEngine(&setACLCommand.saOption, routines, source, func(item batchItem) (string, error) {
+		return setACLCommand.setObjectACLItem(bucket, item.key, acl)
+	})
+	engine.monitor = &setACLCommand.monitor
+	err = engine.run()
 	c.Assert(err, IsNil)
 
 	str := setACLCommand.monitor.progressBar(false, normalExit)
@@ -456,20 +447,12 @@ func (s *OssutilCommandSuite) TestBatchSetACLErrorBreak(c *C) {
 
 	var routines int64
 	routines = 3
-	chObjects := make(chan string, ChannelBuf)
-	chError := make(chan error, routines+1)
-	chListError := make(chan error, 1)
-
-	chObjects <- objectNames[0]
-	chObjects <- objectNames[1]
-	chListError <- nil
-	close(chObjects)
-
-	for i := 0; int64(i) < routines; i++ {
-		setACLCommand.setObjectACLConsumer(bucket, acl, chObjects, chError)
-	}
-
-	err = setACLCommand.waitRoutinueComplete(chError, chListError, routines)
+	source := sliceSource(objectItems(objectNames[0], objectNames[1])...)
+	engine := setACLCommand.command.newBatchEngine(&setACLCommand.saOption, routines, source, func(item batchItem) (string, error) {
+		return setACLCommand.setObjectACLItem(bucket, item.key, acl)
+	})
+	engine.monitor = &setACLCommand.monitor
+	err = engine.run()
 	c.Assert(err, NotNil)
 
 	str := setACLCommand.monitor.progressBar(false, normalExit)
@@ -255,10 +255,24 @@ var setMetaCommand = SetMetaCommand{
 			OptionAccessKeyID,
 			OptionAccessKeySecret,
 			OptionSTSToken,
+			OptionProxyHost,
+			OptionProxyUser,
+			OptionProxyPwd,
+			OptionCAFile,
+			OptionSkipVerify,
+			OptionForceHTTPS,
+			OptionConnectTimeout,
+			OptionReadTimeout,
 			OptionRetryTimes,
 			OptionRoutines,
 			OptionLanguage,
 			OptionOutputDir,
+			OptionCheckpointDir,
+			OptionOnSuccess,
+			OptionOnError,
+			OptionOnFinish,
+			OptionHookRoutines,
+			OptionHookTimeout,
 		},
 	},
 }
@@ -349,7 +363,7 @@ func (sc *SetMetaCommand) checkOption(isUpdate, isDelete, force bool, language s
 			fmt.Printf("警告：--update选项更新指定的header，--delete选项删除指定的header，两者同时缺失会更改object的全量meta信息，请确认是否要更改全量meta信息(y or N)? ")
 		}
 		var str string
-		if _, err := fmt.Scanln(&str); err != nil || (strings.ToLower(str) != "yes" && strings.ToLower(str) != "y") {
+		if _, err := scanln(&str); err != nil || (strings.ToLower(str) != "yes" && strings.ToLower(str) != "y") {
 			return fmt.Errorf("operation is canceled")
 		}
 		fmt.Println("")
@@ -361,7 +375,7 @@ func (sc *SetMetaCommand) confirmOP(recursive, force bool) bool {
 	if recursive && !force {
 		var val string
 		fmt.Printf("Do you really mean to recursivlly set meta on objects of %s(y or N)? ", sc.command.args[0])
-		if _, err := fmt.Scanln(&val); err != nil || (strings.ToLower(val) != "yes" && strings.ToLower(val) != "y") {
+		if _, err := scanln(&val); err != nil || (strings.ToLower(val) != "yes" && strings.ToLower(val) != "y") {
 			fmt.Println("operation is canceled.")
 			return false
 		}
@@ -384,7 +398,7 @@ func (sc *SetMetaCommand) getMetaData(force bool, language string) (string, erro
 		fmt.Printf("你是否确定你想设置的meta信息为空（或者忘记了输入header:value对）? \n输入yes(y)使用空meta继续设置，输入no(n)来展示支持的headers，其他输入将取消操作：")
 	}
 	var str string
-	if _, err := fmt.Scanln(&str); err != nil || (strings.ToLower(str) != "yes" && strings.ToLower(str) != "y" && strings.ToLower(str) != "no" && strings.ToLower(str) != "n") {
+	if _, err := scanln(&str); err != nil || (strings.ToLower(str) != "yes" && strings.ToLower(str) != "y" && strings.ToLower(str) != "no" && strings.ToLower(str) != "n") {
 		return "", fmt.Errorf("unknown input, operation is canceled")
 	}
 	if strings.ToLower(str) == "yes" || strings.ToLower(str) == "y" {
@@ -396,7 +410,7 @@ func (sc *SetMetaCommand) getMetaData(force bool, language string) (string, erro
 	} else {
 		fmt.Printf("\n支持的headers:\n    %s\n    以及以\"%s\"开头的headers\n\n请输入你想设置的header:value#header:value...：", formatHeaderString("\n    "), oss.HTTPHeaderOssMetaPrefix)
 	}
-	if _, err := fmt.Scanln(&str); err != nil {
+	if _, err := scanln(&str); err != nil {
 		return "", fmt.Errorf("meta empty, please check, operation is canceled")
 	}
 	return strings.TrimSpace(str), nil
@@ -507,77 +521,38 @@ func (sc *SetMetaCommand) batchSetObjectMeta(bucket *oss.Bucket, cloudURL CloudU
 		return err
 	}
 	defer sc.smOption.reporter.Clear()
+	sc.smOption.hooks = sc.command.newItemHooks()
 
-	return sc.setObjectMetas(bucket, cloudURL, headers, isUpdate, isDelete, force, routines)
-}
-
-func (sc *SetMetaCommand) setObjectMetas(bucket *oss.Bucket, cloudURL CloudURL, headers map[string]string, isUpdate, isDelete, force bool, routines int64) error {
-	// producer list objects
-	// consumer set meta
-	chObjects := make(chan string, ChannelBuf)
-	chError := make(chan error, routines+1)
-	chListError := make(chan error, 1)
-	go sc.command.objectStatistic(bucket, cloudURL, &sc.monitor)
-	go sc.command.objectProducer(bucket, cloudURL, chObjects, chListError)
-	for i := 0; int64(i) < routines; i++ {
-		go sc.setObjectMetaConsumer(bucket, headers, isUpdate, isDelete, chObjects, chError)
-	}
-
-	return sc.waitRoutinueComplete(chError, chListError, routines)
-}
-
-func (sc *SetMetaCommand) setObjectMetaConsumer(bucket *oss.Bucket, headers map[string]string, isUpdate, isDelete bool, chObjects <-chan string, chError chan<- error) {
-	for object := range chObjects {
-		err := sc.setObjectMetaWithReport(bucket, object, headers, isUpdate, isDelete)
-		if err != nil {
-			chError <- err
-			if !sc.smOption.ctnu {
-				return
-			}
-			continue
-		}
+	// load job journal
+	if sc.smOption.journal, err = sc.command.openJobJournal(); err != nil {
+		return err
 	}
 
-	chError <- nil
-}
-
-func (sc *SetMetaCommand) setObjectMetaWithReport(bucket *oss.Bucket, object string, headers map[string]string, isUpdate, isDelete bool) error {
-	err := sc.setObjectMeta(bucket, object, headers, isUpdate, isDelete)
-	sc.command.updateMonitor(err, &sc.monitor)
-	msg := fmt.Sprintf("set meta on %s", CloudURLToString(bucket.BucketName, object))
-	sc.command.report(msg, err, &sc.smOption)
+	err = sc.setObjectMetas(bucket, cloudURL, headers, isUpdate, isDelete, force, routines)
+	if err == nil {
+		err = sc.command.cancelError()
+	}
+	sc.smOption.hooks.finish(&sc.smOption, err)
+	sc.smOption.journal.close(err == nil)
 	return err
 }
 
-func (sc *SetMetaCommand) waitRoutinueComplete(chError, chListError <-chan error, routines int64) error {
-	completed := 0
-	var ferr error
-	for int64(completed) <= routines {
-		select {
-		case err := <-chListError:
-			if err != nil {
-				return err
-			}
-			completed++
-		case err := <-chError:
-			if err == nil {
-				completed++
-			} else {
-				ferr = err
-				if !sc.smOption.ctnu {
-					fmt.Printf(sc.monitor.progressBar(true, errExit))
-					return err
-				}
-			}
-		}
-	}
-	return sc.formatResultPrompt(ferr)
+func (sc *SetMetaCommand) setObjectMetas(bucket *oss.Bucket, cloudURL CloudURL, headers map[string]string, isUpdate, isDelete, force bool, routines int64) error {
+	go sc.command.objectStatistic(bucket, cloudURL, &sc.monitor, sc.smOption.journal)
+	source := sc.command.objectSource(bucket, cloudURL, sc.smOption.journal, nil)
+	engine := sc.command.newBatchEngine(&sc.smOption, routines, source, func(item batchItem) (string, error) {
+		return sc.setObjectMetaItem(bucket, item.key, headers, isUpdate, isDelete)
+	})
+	engine.monitor = &sc.monitor
+	engine.hookItem = objectHookItem(bucket)
+	return engine.run()
 }
 
-func (sc *SetMetaCommand) formatResultPrompt(err error) error {
-	fmt.Printf(sc.monitor.progressBar(true, normalExit))
-	if err != nil && sc.smOption.ctnu {
-		return nil
+// setObjectMetaItem is the batch action of set-meta
+func (sc *SetMetaCommand) setObjectMetaItem(bucket *oss.Bucket, object string, headers map[string]string, isUpdate, isDelete bool) (string, error) {
+	msg := fmt.Sprintf("set meta on %s", CloudURLToString(bucket.BucketName, object))
+	if sc.smOption.journal.isDone(object) {
+		return msg, nil
 	}
-	return err
+	return msg, sc.setObjectMeta(bucket, object, headers, isUpdate, isDelete)
 }
@@ -414,24 +414,15 @@ func (s *OssutilCommandSuite) TestBatchSetMetaErrorContinue(c *C) {
 
 	var routines int64
 	routines = 3
-	chObjects := make(chan string, ChannelBuf)
-	chError := make(chan error, routines+1)
-	chListError := make(chan error, 1)
-
-	chObjects <- objectNames[0]
-	chObjects <- "notexistobject" + randStr(3)
-	chObjects <- objectNames[1]
-	chListError <- nil
-	close(chObjects)
-
+	source := sliceSource(objectItems(objectNames[0], "notexistobject"+randStr(3), objectNames[1])...)
 	headers := map[string]string{}
 	headers[oss.HTTPHeaderOssObjectACL] = "public-read-write"
 
-	for i := 0; int64(i) < routines; i++ {
-		setMetaCommand.setObjectMetaConsumer(bucket, headers, false, false, chObjects, chError)
-	}
-
-	err = setMetaCommand.waitRoutinueComplete(chError, chListError, routines)
+	engine := setMetaCommand.command.newBatchEngine(&setMetaCommand.smOption, routines, source, func(item batchItem) (string, error) {
+		return setMetaCommand.setObjectMetaItem(bucket, item.key, headers, false, false)
+	})
+	engine.monitor = &setMetaCommand.monitor
+	err = engine.run()
 	c.Assert(err, IsNil)
 
 	str := setMetaCommand.monitor.progressBar(false, normalExit)
@@ -511,23 +502,15 @@ func (s *OssutilCommandSuite) TestBatchSetMetaErrorBreak(c *C) {
 
 	var routines int64
 	routines = 3
-	chObjects := make(chan string, ChannelBuf)
-	chError := make(chan error, routines+1)
-	chListError := make(chan error, 1)
-
-	chObjects <- objectNames[0]
-	chObjects <- objectNames[1]
-	chListError <- nil
-	close(chObjects)
-
+	source := sliceSource(objectItems(objectNames[0], objectNames[1])...)
 	headers := map[string]string{}
 	headers[oss.HTTPHeaderOssObjectACL] = "public-read-write"
 
-	for i := 0; int64(i) < routines; i++ {
-		setMetaCommand.setObjectMetaConsumer(bucket, headers, false, false, chObjects, chError)
-	}
-
-	err = setMetaCommand.waitRoutinueComplete(chError, chListError, routines)
+	engine := setMetaCommand.command.newBatchEngine(&setMetaCommand.smOption, routines, source, func(item batchItem) (string, error) {
+		return setMetaCommand.setObjectMetaItem(bucket, item.key, headers, false, false)
+	})
+	engine.monitor = &setMetaCommand.monitor
+	err = engine.run()
 	c.Assert(err, NotNil)
 
 	str := setMetaCommand.monitor.progressBar(false, normalExit)
@@ -0,0 +1,430 @@
+package lib
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+var specChineseSetStorageClass = SpecText{
+
+	synopsisText: "修改object的存储方式",
+
+	paramText: "cloud_url storage_class [options]",
+
+	syntaxText: `
+    ossutil set-storage-class oss://bucket[/prefix] storage_class [-r] [-f] [--include pattern] [--exclude pattern] [--dryrun] [--bigfile-threshold size] [--checkpoint-dir dir] [--output-dir dir] [-c file]
+`,
+
+	detailHelpText: `
+    该命令修改指定object的存储方式（StorageClass），storage_class的取值范围为：` + StorageStandard + `/` + StorageIA + `/` + StorageArchive + `
+    （不区分大小写）。关于StorageClass的更多信息请参考mb命令的帮助。
+
+    ossutil通过将object拷贝到自身并指定新的存储方式来修改存储方式，object的meta信息（包括
+    Content-Type等http头和用户自定义meta）和acl会被保留，但object的lastModifiedTime会改变。
+    大小超过--bigfile-threshold选项指定值的object，使用分片拷贝，--part-size和--parallel选项
+    的含义同cp命令，分片拷贝的断点信息保存在--checkpoint-dir选项指定的目录中。
+
+    已经是目标存储方式的object会被跳过。注意，` + StorageArchive + `类型的object需要先解冻（见restore命令
+    的帮助）才能修改存储方式，从` + StorageIA + `或` + StorageArchive + `类型转换可能产生最短存储时间相关的费用。
+
+--include、--exclude选项
+
+    批量修改时，只处理名称（object名中最后一个/之后的部分）匹配--include选项指定的通配符模式
+    的object，不处理匹配--exclude选项指定的通配符模式的object。
+
+--dryrun选项
+
+    如果指定了该选项，ossutil只输出将要修改存储方式的object及其当前和目标存储方式，不实际
+    进行修改。
+
+用法：
+
+    该命令有两种用法：
+
+    1) ossutil set-storage-class oss://bucket/object storage_class
+        该用法修改单个object的存储方式，当指定object不存在时，ossutil会提示错误。
+
+    2) ossutil set-storage-class oss://bucket[/prefix] storage_class -r [-f] [--include pattern] [--exclude pattern]
+        该用法可批量修改objects的存储方式，此时必须输入--recursive选项，ossutil会查找所有前缀匹配
+    cloud_url的objects，修改它们的存储方式。当一个object操作出现错误时，会将出错object的错误信息
+    记录到report文件，并继续操作其他object（更多信息见cp命令的帮助）。如果--force选项被指定，
+    则不会进行询问提示。
+`,
+
+	sampleText: `
+    1) ossutil set-storage-class oss://bucket1/obj1 IA
+
+    2) ossutil set-storage-class oss://bucket1/logs/ Archive -r --include "*.log"
+
+    3) ossutil set-storage-class oss://bucket1/logs/ IA -r --exclude "*.tmp" --dryrun
+`,
+}
+
+var specEnglishSetStorageClass = SpecText{
+
+	synopsisText: "Change storage class of objects",
+
+	paramText: "cloud_url storage_class [options]",
+
+	syntaxText: `
+    ossutil set-storage-class oss://bucket[/prefix] storage_class [-r] [-f] [--include pattern] [--exclude pattern] [--dryrun] [--bigfile-threshold size] [--checkpoint-dir dir] [--output-dir dir] [-c file]
+`,
+
+	detailHelpText: `
+    The command changes the storage class of the specified objects, value range of storage_class
+    is: ` + StorageStandard + `/` + StorageIA + `/` + StorageArchive + `(case-insensitive). For more information about StorageClass,
+    see help of mb command.
+
+    Ossutil changes storage class by copying the object onto itself with the new storage class,
+    the meta of object(include http headers like Content-Type and user meta) and acl are kept, but
+    the lastModifiedTime of object will change. Objects larger than the value of --bigfile-threshold
+    option are copied by multipart, --part-size and --parallel option have the same meaning as in
+    cp command, the checkpoint of multipart copy is saved in the directory specified by
+    --checkpoint-dir option.
+
+    Objects already in the target storage class are skipped. Note that objects of ` + StorageArchive + `
+    storage class must be restored first(see help of restore command) before changing storage
+    class, and changing from ` + StorageIA + ` or ` + StorageArchive + ` may cause fee of minimum storage duration.
+
+--include, --exclude option
+
+    When change in batch, only the objects whose name(the part after the last / of object name)
+    matches the wildcard pattern specified by --include option are dealed, and the objects match
+    the pattern specified by --exclude option are not dealed.
+
+--dryrun option
+
+    If the option is specified, ossutil only prints the objects whose storage class will be
+    changed, with their current and target storage class, without changing them.
+
+Usage:
+
+    There are two usages:
+
+    1) ossutil set-storage-class oss://bucket/object storage_class
+        The usage changes storage class of the single object, if the object does not exist, error
+    occurs.
+
+    2) ossutil set-storage-class oss://bucket[/prefix] storage_class -r [-f] [--include pattern] [--exclude pattern]
+        The usage changes storage class of objects in batch, --recursive option is required,
+    ossutil searches for prefix-matching objects and changes their storage class. When error occurs
+    on an object, ossutil records the error message to report file, and continues to deal the
+    remaining objects(more information see help of cp command). If --force option is specified,
+    ossutil will not show prompt question.
+`,
+
+	sampleText: `
+    1) ossutil set-storage-class oss://bucket1/obj1 IA
+
+    2) ossutil set-storage-class oss://bucket1/logs/ Archive -r --include "*.log"
+
+    3) ossutil set-storage-class oss://bucket1/logs/ IA -r --exclude "*.tmp" --dryrun
+`,
+}
+
+// storageClassObjectType is an object to change storage class
+type storageClassObjectType struct {
+	key          string
+	size         int64
+	storageClass string
+}
+
+// SetStorageClassCommand is the command change storage class of objects
+type SetStorageClassCommand struct {
+	command      Command
+	monitor      Monitor
+	scOption     batchOptionType
+	storageClass oss.StorageClassType
+	include      string
+	exclude      string
+	dryRun       bool
+}
+
+var setStorageClassCommand = SetStorageClassCommand{
+	command: Command{
+		name:        "set-storage-class",
+		nameAlias:   []string{},
+		minArgc:     2,
+		maxArgc:     2,
+		specChinese: specChineseSetStorageClass,
+		specEnglish: specEnglishSetStorageClass,
+		group:       GroupTypeNormalCommand,
+		validOptionNames: []string{
+			OptionRecursion,
+			OptionForce,
+			OptionInclude,
+			OptionExclude,
+			OptionDryRun,
+			OptionBigFileThreshold,
+			OptionPartSize,
+			OptionParallel,
+			OptionEncodingType,
+			OptionConfigFile,
+			OptionEndpoint,
+			OptionAccessKeyID,
+			OptionAccessKeySecret,
+			OptionSTSToken,
+			OptionProxyHost,
+			OptionProxyUser,
+			OptionProxyPwd,
+			OptionCAFile,
+			OptionSkipVerify,
+			OptionForceHTTPS,
+			OptionConnectTimeout,
+			OptionReadTimeout,
+			OptionRetryTimes,
+			OptionRoutines,
+			OptionOutputDir,
+			OptionCheckpointDir,
+			OptionOnSuccess,
+			OptionOnError,
+			OptionOnFinish,
+			OptionHookRoutines,
+			OptionHookTimeout,
+		},
+	},
+}
+
+// function for FormatHelper interface
+func (sc *SetStorageClassCommand) formatHelpForWhole() string {
+	return sc.command.formatHelpForWhole()
+}
+
+func (sc *SetStorageClassCommand) formatIndependHelp() string {
+	return sc.command.formatIndependHelp()
+}
+
+// Init simulate inheritance, and polymorphism
+func (sc *SetStorageClassCommand) Init(args []string, options OptionMapType) error {
+	return sc.command.Init(args, options, sc)
+}
+
+// RunCommand simulate inheritance, and polymorphism
+func (sc *SetStorageClassCommand) RunCommand() error {
+	sc.monitor.init("Setted storage class on")
+
+	recursive, _ := GetBool(OptionRecursion, sc.command.options)
+	force, _ := GetBool(OptionForce, sc.command.options)
+	encodingType, _ := GetString(OptionEncodingType, sc.command.options)
+	sc.include, _ = GetString(OptionInclude, sc.command.options)
+	sc.exclude, _ = GetString(OptionExclude, sc.command.options)
+	sc.dryRun, _ = GetBool(OptionDryRun, sc.command.options)
+
+	cloudURL, err := CloudURLFromString(sc.command.args[0], encodingType)
+	if err != nil {
+		return err
+	}
+
+	if err := sc.checkArgs(cloudURL, recursive); err != nil {
+		return err
+	}
+
+	bucket, err := sc.command.ossBucket(cloudURL.bucket)
+	if err != nil {
+		return err
+	}
+
+	if !recursive {
+		props, err := sc.command.ossGetObjectStatRetry(bucket, cloudURL.object)
+		if err != nil {
+			return err
+		}
+		size, _ := strconv.ParseInt(props.Get(oss.HTTPHeaderContentLength), 10, 64)
+		return sc.setStorageClass(bucket, cloudURL.object, size, sc.getObjectStorageClass(props))
+	}
+
+	if !sc.dryRun && !force {
+		var val string
+		fmt.Printf("Do you really mean to recursivlly set storage class of objects of %s to %s(y or N)? ", sc.command.args[0], sc.storageClass)
+		if _, err := scanln(&val); err != nil || (strings.ToLower(val) != "yes" && strings.ToLower(val) != "y") {
+			fmt.Println("operation is canceled.")
+			return nil
+		}
+	}
+	return sc.batchSetStorageClass(bucket, cloudURL)
+}
+
+func (sc *SetStorageClassCommand) checkArgs(cloudURL CloudURL, recursive bool) error {
+	if cloudURL.bucket == "" {
+		return fmt.Errorf("invalid cloud url: %s, miss bucket", sc.command.args[0])
+	}
+	if !recursive && cloudURL.object == "" {
+		return fmt.Errorf("set storage class invalid cloud url: %s, object empty. Set storage class of bucket is not supported, if you mean batch set storage class of objects, please use --recursive", sc.command.args[0])
+	}
+
+	storageClass := sc.command.args[1]
+	switch {
+	case strings.EqualFold(storageClass, StorageStandard):
+		sc.storageClass = oss.StorageStandard
+	case strings.EqualFold(storageClass, StorageIA):
+		sc.storageClass = oss.StorageIA
+	case strings.EqualFold(storageClass, StorageArchive):
+		sc.storageClass = oss.StorageArchive
+	default:
+		return fmt.Errorf("invalid storage class: %s, valid storage classes are: %s/%s/%s", storageClass, StorageStandard, StorageIA, StorageArchive)
+	}
+
+	for _, pattern := range []string{sc.include, sc.exclude} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern: %s, %s", pattern, err.Error())
+		}
+	}
+	return nil
+}
+
+func (sc *SetStorageClassCommand) getObjectStorageClass(props http.Header) string {
+	if class := props.Get(OssStorageClassHeader); class != "" {
+		return class
+	}
+	return StorageStandard
+}
+
+// filterObject shows if the object need to be dealed by the name patterns
+func (sc *SetStorageClassCommand) filterObject(object string) bool {
+	name := object[strings.LastIndex(object, "/")+1:]
+	if sc.include != "" {
+		if ok, _ := path.Match(sc.include, name); !ok {
+			return false
+		}
+	}
+	if sc.exclude != "" {
+		if ok, _ := path.Match(sc.exclude, name); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// setStorageClass copies the object onto itself with new storage class, skips if the object is in the storage class
+func (sc *SetStorageClassCommand) setStorageClass(bucket *oss.Bucket, object string, size int64, storageClass string) error {
+	if strings.EqualFold(storageClass, string(sc.storageClass)) {
+		return nil
+	}
+	if sc.dryRun {
+		fmt.Printf("%s%s: %s -> %s\n", getClearStr(""), CloudURLToString(bucket.BucketName, object), storageClass, sc.storageClass)
+		return nil
+	}
+
+	options := []oss.Option{oss.ObjectStorageClass(sc.storageClass)}
+	goar, err := sc.command.ossGetObjectACLRetry(bucket, object)
+	if err != nil {
+		return err
+	}
+	if goar.ACL != "" && goar.ACL != string(oss.ACLDefault) {
+		options = append(options, oss.ObjectACL(oss.ACLType(goar.ACL)))
+	}
+
+	threshold, err := GetInt(OptionBigFileThreshold, sc.command.options)
+	if err != nil {
+		threshold = DefaultBigFileThreshold
+	}
+	if size < threshold {
+		options = append(options, oss.MetadataDirective(oss.MetaCopy))
+		return sc.command.ossCopyObjectRetry(bucket, object, bucket.BucketName, object, options...)
+	}
+
+	// meta is not copied by multipart copy, set it when initiate multipart upload
+	props, err := sc.command.ossGetObjectStatRetry(bucket, object)
+	if err != nil {
+		return err
+	}
+	metaOptions, err := sc.getMetaOptions(props)
+	if err != nil {
+		return ObjectError{err, bucket.BucketName, object}
+	}
+	options = append(options, metaOptions...)
+
+	// part size is decided the same way as cp command
+	cc := CopyCommand{}
+	cc.cpOption.partSize, _ = GetInt(OptionPartSize, sc.command.options)
+	cc.cpOption.parallel, _ = GetInt(OptionParallel, sc.command.options)
+	partSize, rt := cc.preparePartOption(size)
+	cpDir, _ := GetString(OptionCheckpointDir, sc.command.options)
+	options = append(options, oss.Routines(rt), oss.CheckpointDir(true, cpDir))
+	return sc.command.ossResumeCopyRetry(bucket, bucket.BucketName, object, object, partSize, options...)
+}
+
+// getMetaOptions returns the options to keep http headers and user meta of object
+func (sc *SetStorageClassCommand) getMetaOptions(props http.Header) ([]oss.Option, error) {
+	options := []oss.Option{}
+	for name := range props {
+		if strings.HasPrefix(strings.ToLower(name), strings.ToLower(oss.HTTPHeaderOssMetaPrefix)) {
+			options = append(options, oss.Meta(name[len(oss.HTTPHeaderOssMetaPrefix):], props.Get(name)))
+			continue
+		}
+		if strings.EqualFold(name, oss.HTTPHeaderOssStorageClass) || strings.EqualFold(name, oss.HTTPHeaderOssObjectACL) {
+			continue
+		}
+		if _, err := fetchHeaderOptionMap(name); err == nil {
+			option, err := getOSSOption(name, props.Get(name))
+			if err != nil {
+				return nil, err
+			}
+			options = append(options, option)
+		}
+	}
+	return options, nil
+}
+
+func (sc *SetStorageClassCommand) batchSetStorageClass(bucket *oss.Bucket, cloudURL CloudURL) error {
+	sc.scOption.ctnu = true
+	outputDir, _ := GetString(OptionOutputDir, sc.command.options)
+
+	// init reporter
+	var err error
+	if sc.scOption.reporter, err = GetReporter(sc.scOption.ctnu, outputDir, commandLine); err != nil {
+		return err
+	}
+	defer sc.scOption.reporter.Clear()
+
+	// load job journal and hooks, dry run is not recorded
+	sc.scOption.journal = nil
+	sc.scOption.hooks = nil
+	if !sc.dryRun {
+		sc.scOption.hooks = sc.command.newItemHooks()
+		if sc.scOption.journal, err = sc.command.openJobJournal(); err != nil {
+			return err
+		}
+	}
+
+	err = sc.setStorageClasses(bucket, cloudURL)
+	if err == nil {
+		err = sc.command.cancelError()
+	}
+	sc.scOption.hooks.finish(&sc.scOption, err)
+	sc.scOption.journal.close(err == nil)
+	return err
+}
+
+func (sc *SetStorageClassCommand) setStorageClasses(bucket *oss.Bucket, cloudURL CloudURL) error {
+	routines, _ := GetInt(OptionRoutines, sc.command.options)
+
+	// list the objects matching the filters and not in the target storage class
+	source := sc.command.objectSource(bucket, cloudURL, sc.scOption.journal, func(object oss.ObjectProperties) (batchItem, bool) {
+		if !sc.filterObject(object.Key) || strings.EqualFold(object.StorageClass, string(sc.storageClass)) {
+			return batchItem{}, false
+		}
+		sc.monitor.updateScanNum(1)
+		return batchItem{object.Key, storageClassObjectType{object.Key, object.Size, object.StorageClass}}, true
+	})
+	engine := sc.command.newBatchEngine(&sc.scOption, routines, scanSource(&sc.monitor, source), func(item batchItem) (string, error) {
+		return sc.setStorageClassItem(bucket, item.value.(storageClassObjectType))
+	})
+	engine.monitor = &sc.monitor
+	engine.hookItem = objectHookItem(bucket)
+	return engine.run()
+}
+
+// setStorageClassItem is the batch action of set-storage-class
+func (sc *SetStorageClassCommand) setStorageClassItem(bucket *oss.Bucket, object storageClassObjectType) (string, error) {
+	msg := fmt.Sprintf("set storage class on %s", CloudURLToString(bucket.BucketName, object.key))
+	if sc.scOption.journal.isDone(object.key) {
+		return msg, nil
+	}
+	return msg, sc.setStorageClass(bucket, object.key, object.size, object.storageClass)
+}
@@ -0,0 +1,189 @@
+package lib
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	. "gopkg.in/check.v1"
+)
+
+func (s *OssutilCommandSuite) rawSetStorageClass(args []string, recursive, dryRun bool, include, exclude string, threshold int64) error {
+	command := "set-storage-class"
+	str := ""
+	routines := strconv.Itoa(Routines)
+	thre := strconv.FormatInt(threshold, 10)
+	force := true
+	options := OptionMapType{
+		"endpoint":         &str,
+		"accessKeyID":      &str,
+		"accessKeySecret":  &str,
+		"stsToken":         &str,
+		"configFile":       &configFile,
+		"recursive":        &recursive,
+		"force":            &force,
+		"dryRun":           &dryRun,
+		"include":          &include,
+		"exclude":          &exclude,
+		"bigfileThreshold": &thre,
+		"routines":         &routines,
+	}
+	_, err := cm.RunCommand(command, args, options)
+	return err
+}
+
+func (s *OssutilCommandSuite) TestSetStorageClass(c *C) {
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+
+	object := "存储类型" + randStr(5)
+	s.createFile(uploadFileName, randStr(20), c)
+	s.putObject(bucketName, object, uploadFileName, c)
+	s.setObjectMeta(bucketName, object, "X-Oss-Meta-A:A#Content-Type:text/plain", true, false, false, true, c)
+
+	// dryrun does not change the object
+	testResultFile, _ = os.OpenFile(resultPath, os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0664)
+	out := os.Stdout
+	os.Stdout = testResultFile
+	err := s.rawSetStorageClass([]string{CloudURLToString(bucketName, object), "ia"}, false, true, "", "", DefaultBigFileThreshold)
+	os.Stdout = out
+	c.Assert(err, IsNil)
+	str := s.readFile(resultPath, c)
+	c.Assert(strings.Contains(str, StorageStandard+" -> "+StorageIA), Equals, true)
+
+	objectStat := s.getStat(bucketName, object, c)
+	c.Assert(objectStat["X-Oss-Storage-Class"], Equals, StorageStandard)
+
+	// set storage class, meta is kept
+	err = s.rawSetStorageClass([]string{CloudURLToString(bucketName, object), "ia"}, false, false, "", "", DefaultBigFileThreshold)
+	c.Assert(err, IsNil)
+
+	objectStat = s.getStat(bucketName, object, c)
+	c.Assert(objectStat["X-Oss-Storage-Class"], Equals, StorageIA)
+	c.Assert(objectStat["X-Oss-Meta-A"], Equals, "A")
+	c.Assert(objectStat["Content-Type"], Equals, "text/plain")
+
+	// set again is skipped
+	err = s.rawSetStorageClass([]string{CloudURLToString(bucketName, object), StorageIA}, false, false, "", "", DefaultBigFileThreshold)
+	c.Assert(err, IsNil)
+
+	// multipart copy keeps meta
+	err = s.rawSetStorageClass([]string{CloudURLToString(bucketName, object), StorageStandard}, false, false, "", "", 1)
+	c.Assert(err, IsNil)
+
+	objectStat = s.getStat(bucketName, object, c)
+	c.Assert(objectStat["X-Oss-Storage-Class"], Equals, StorageStandard)
+	c.Assert(objectStat["X-Oss-Meta-A"], Equals, "A")
+	c.Assert(objectStat["Content-Type"], Equals, "text/plain")
+
+	// not exist object
+	err = s.rawSetStorageClass([]string{CloudURLToString(bucketName, "notexist"+randStr(5)), StorageIA}, false, false, "", "", DefaultBigFileThreshold)
+	c.Assert(err, NotNil)
+
+	s.removeBucket(bucketName, true, c)
+}
+
+func (s *OssutilCommandSuite) TestBatchSetStorageClass(c *C) {
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+
+	prefix := "logs/"
+	s.createFile(uploadFileName, randStr(20), c)
+	for _, name := range []string{"a.log", "b.log", "c.tmp", "sub/d.log"} {
+		s.putObject(bucketName, prefix+name, uploadFileName, c)
+	}
+
+	// dryrun
+	testResultFile, _ = os.OpenFile(resultPath, os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0664)
+	out := os.Stdout
+	os.Stdout = testResultFile
+	err := s.rawSetStorageClass([]string{CloudURLToString(bucketName, prefix), StorageIA}, true, true, "*.log", "b*", DefaultBigFileThreshold)
+	os.Stdout = out
+	c.Assert(err, IsNil)
+	str := s.readFile(resultPath, c)
+	c.Assert(strings.Contains(str, prefix+"a.log"), Equals, true)
+	c.Assert(strings.Contains(str, prefix+"sub/d.log"), Equals, true)
+	c.Assert(strings.Contains(str, prefix+"b.log"), Equals, false)
+	c.Assert(strings.Contains(str, prefix+"c.tmp"), Equals, false)
+	c.Assert(s.getStat(bucketName, prefix+"a.log", c)["X-Oss-Storage-Class"], Equals, StorageStandard)
+
+	// include and exclude
+	err = s.rawSetStorageClass([]string{CloudURLToString(bucketName, prefix), StorageIA}, true, false, "*.log", "b*", DefaultBigFileThreshold)
+	c.Assert(err, IsNil)
+
+	c.Assert(s.getStat(bucketName, prefix+"a.log", c)["X-Oss-Storage-Class"], Equals, StorageIA)
+	c.Assert(s.getStat(bucketName, prefix+"sub/d.log", c)["X-Oss-Storage-Class"], Equals, StorageIA)
+	c.Assert(s.getStat(bucketName, prefix+"b.log", c)["X-Oss-Storage-Class"], Equals, StorageStandard)
+	c.Assert(s.getStat(bucketName, prefix+"c.tmp", c)["X-Oss-Storage-Class"], Equals, StorageStandard)
+
+	s.removeBucket(bucketName, true, c)
+}
+
+func (s *OssutilCommandSuite) TestSetStorageClassErrArgs(c *C) {
+	bucketName := bucketNamePrefix + randLowStr(10)
+	object := randStr(10)
+
+	// invalid storage class
+	err := s.rawSetStorageClass([]string{CloudURLToString(bucketName, object), "Cold"}, false, false, "", "", DefaultBigFileThreshold)
+	c.Assert(err, NotNil)
+
+	// miss object
+	err = s.rawSetStorageClass([]string{CloudURLToString(bucketName, ""), StorageIA}, false, false, "", "", DefaultBigFileThreshold)
+	c.Assert(err, NotNil)
+
+	// invalid pattern
+	err = s.rawSetStorageClass([]string{CloudURLToString(bucketName, ""), StorageIA}, true, false, "[", "", DefaultBigFileThreshold)
+	c.Assert(err, NotNil)
+}
+
+func (s *OssutilCommandSuite) TestSetStorageClassFilterObject(c *C) {
+	sc := SetStorageClassCommand{include: "*.log", exclude: "b*"}
+	c.Assert(sc.filterObject("logs/a.log"), Equals, true)
+	c.Assert(sc.filterObject("logs/b.log"), Equals, false)
+	c.Assert(sc.filterObject("logs/c.tmp"), Equals, false)
+	c.Assert(sc.filterObject("b.log/a.log"), Equals, true)
+
+	sc = SetStorageClassCommand{}
+	c.Assert(sc.filterObject("logs/c.tmp"), Equals, true)
+}
+
+func (s *OssutilCommandSuite) TestSetStorageClassRetry(c *C) {
+	server := newFakeOSSServer("scID", "scSecret")
+	defer server.close()
+	client, err := oss.New(server.endpoint(), "scID", "scSecret")
+	c.Assert(err, IsNil)
+	bucketName := bucketNamePrefix + randLowStr(10)
+	c.Assert(client.CreateBucket(bucketName), IsNil)
+	bucket, err := client.Bucket(bucketName)
+	c.Assert(err, IsNil)
+	c.Assert(bucket.PutObject("small", strings.NewReader(randStr(20))), IsNil)
+	c.Assert(bucket.PutObject("big", strings.NewReader(randStr(200*1024))), IsNil)
+	server.inject(fakeOSSFault{op: "CopyObject", status: http.StatusInternalServerError, code: "InternalError", times: 1})
+	server.inject(fakeOSSFault{op: "UploadPartCopy", status: http.StatusInternalServerError, code: "InternalError", times: 1})
+
+	str := ""
+	ep, id, secret := server.endpoint(), "scID", "scSecret"
+	retryTimes := "2"
+	threshold := strconv.Itoa(100 * 1024)
+	force := true
+	for _, object := range []string{"small", "big"} {
+		options := OptionMapType{
+			OptionEndpoint:         &ep,
+			OptionAccessKeyID:      &id,
+			OptionAccessKeySecret:  &secret,
+			OptionSTSToken:         &str,
+			OptionConfigFile:       &configFile,
+			OptionRetryTimes:       &retryTimes,
+			OptionBigFileThreshold: &threshold,
+			OptionForce:            &force,
+		}
+		_, err = cm.RunCommand("set-storage-class", []string{CloudURLToString(bucketName, object), StorageIA}, options)
+		c.Assert(err, IsNil)
+		props, err := bucket.GetObjectDetailedMeta(object)
+		c.Assert(err, IsNil)
+		c.Assert(props.Get(oss.HTTPHeaderOssStorageClass), Equals, StorageIA)
+	}
+	c.Assert(server.requestCount("CopyObject"), Equals, 2)
+}
@@ -0,0 +1,758 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+var specChineseShell = SpecText{
+
+	synopsisText: "进入交互式命令行",
+
+	paramText: "[options]",
+
+	syntaxText: `
+    ossutil shell [-c file] [-e endpoint] [-i id] [-k key] [-t token] [-L language]
+`,
+
+	detailHelpText: `
+    该命令进入交互式命令行，逐行读取并执行命令，命令的写法与命令行中相同，但省略ossutil，
+    例如：ls -s。所有ossutil命令都可以在其中执行（shell命令除外）。交互式命令行在多条命令
+    之间保持会话：配置文件只读取一次，访问同一bucket的命令复用同一个oss client。
+
+    执行shell命令时指定的-c、-e、-i、-k、-t、-L选项，以及代理、https和超时相关的选项（例如
+    --proxy-host、--ca-file、--read-timeout）会传给其中执行的命令（命令支持该选项，且命令
+    中未指定时）。
+
+工作目录
+
+    会话有一个oss上的工作目录（bucket和前缀），初始为oss://，提示符中显示当前工作目录。
+
+        cd [path]   改变工作目录，path可以是cloud_url或者相对路径，..表示上一级，以/开头的
+                    路径相对于oss://，不指定path时回到oss://
+        pwd         显示工作目录
+
+    ls、rm、stat、get-acl、restore、read-symlink、mb命令的参数，以及set-acl、set-meta、
+    set-storage-class、create-symlink命令的第一个参数都是cloud_url，它们可以写成相对于工作
+    目录的路径。ls不指定参数时列举工作目录。其他命令（例如cp、diff）中，相对的cloud_url写成
+    oss:path的形式（oss:之后没有//），以与本地路径区分。
+
+历史命令
+
+        history     显示历史命令
+        !n          执行第n条历史命令
+        !!          执行上一条命令
+
+    历史命令保存在` + ShellHistoryFile + `文件中，至多保留` + shellHistorySizeText + `条。
+
+    exit、quit或者输入结束（Ctrl-D）退出交互式命令行。命令执行中按下Ctrl-C会中断该命令，
+    回到交互式命令行。
+
+用法：
+
+    ossutil shell [options]
+`,
+
+	sampleText: `
+    ossutil shell
+    oss://> cd bucket1/dir
+    oss://bucket1/dir/> ls -s
+    oss://bucket1/dir/> stat a.txt
+    oss://bucket1/dir/> cp oss:a.txt /tmp/a.txt
+    oss://bucket1/dir/> cd ..
+    oss://bucket1/> pwd
+    oss://bucket1/
+    oss://bucket1/> exit
+`,
+}
+
+var specEnglishShell = SpecText{
+
+	synopsisText: "Run commands interactively",
+
+	paramText: "[options]",
+
+	syntaxText: `
+    ossutil shell [-c file] [-e endpoint] [-i id] [-k key] [-t token] [-L language]
+`,
+
+	detailHelpText: `
+    The command starts an interactive shell, which reads commands line by line and runs
+    them, the commands are written as in command line without ossutil, e.g: ls -s. All
+    the commands of ossutil can be run in it(except shell command). The shell keeps a
+    session between commands: config file is read only once, and the commands accessing
+    the same bucket reuse the same oss client.
+
+    The -c, -e, -i, -k, -t, -L options, and the options of proxy, https and timeouts(e.g.
+    --proxy-host, --ca-file, --read-timeout) specified when run shell command are passed to
+    the commands run in it(if the command supports the option and it's not specified).
+
+Working directory
+
+    The session has a working directory in oss(bucket and prefix), which is oss:// at
+    start and shown in prompt.
+
+        cd [path]   change working directory, path can be cloud_url or relative path, ..
+                    means the parent, the path starts with / is relative to oss://, go
+                    back to oss:// if path is not specified
+        pwd         show working directory
+
+    The args of ls, rm, stat, get-acl, restore, read-symlink, mb command, and the first arg
+    of set-acl, set-meta, set-storage-class, create-symlink command are cloud_url, they can
+    be written as path relative to the working directory. ls lists the working directory
+    if no arg is specified. In other commands(e.g. cp, diff), relative cloud_url should be
+    written as oss:path(without // after oss:), to be different from local path.
+
+History
+
+        history     show history commands
+        !n          run the nth history command
+        !!          run the last command
+
+    The history is saved in ` + ShellHistoryFile + ` file, at most ` + shellHistorySizeText + ` commands are kept.
+
+    exit, quit or end of input(Ctrl-D) leaves the shell. Ctrl-C interrupts the running
+    command and goes back to the shell.
+
+Usage:
+
+    ossutil shell [options]
+`,
+
+	sampleText: `
+    ossutil shell
+    oss://> cd bucket1/dir
+    oss://bucket1/dir/> ls -s
+    oss://bucket1/dir/> stat a.txt
+    oss://bucket1/dir/> cp oss:a.txt /tmp/a.txt
+    oss://bucket1/dir/> cd ..
+    oss://bucket1/> pwd
+    oss://bucket1/
+    oss://bucket1/> exit
+`,
+}
+
+var shellHistorySizeText = strconv.Itoa(ShellHistorySize)
+
+// shellInheritOptions are the options of shell command passed to the commands run in it
+var shellInheritOptions = []string{
+	OptionConfigFile,
+	OptionEndpoint,
+	OptionAccessKeyID,
+	OptionAccessKeySecret,
+	OptionSTSToken,
+	OptionProxyHost,
+	OptionProxyUser,
+	OptionProxyPwd,
+	OptionCAFile,
+	OptionSkipVerify,
+	OptionForceHTTPS,
+	OptionConnectTimeout,
+	OptionReadTimeout,
+	OptionLanguage,
+}
+
+// shellCloudArgs is the number of leading args which are cloud urls of commands, -1 means all
+var shellCloudArgs = map[string]int{
+	listCommand.command.name:            -1,
+	removeCommand.command.name:          -1,
+	statCommand.command.name:            -1,
+	getACLCommand.command.name:          -1,
+	restoreCommand.command.name:         -1,
+	readSymlinkCommand.command.name:     -1,
+	makeBucketCommand.command.name:      -1,
+	setACLCommand.command.name:          1,
+	setMetaCommand.command.name:         1,
+	setStorageClassCommand.command.name: 1,
+	createSymlinkCommand.command.name:   1,
+}
+
+// shellSession is the session of shell, the commands run in it share the configs and clients
+type shellSession struct {
+	mu      sync.Mutex
+	configs map[string]OptionMapType
+	clients map[string]*oss.Client
+
+	// the working directory
+	bucket string
+	prefix string
+
+	history []string
+}
+
+// currentSession is the session of the running shell, it's nil out of shell
+var currentSession *shellSession
+
+func newShellSession() *shellSession {
+	return &shellSession{configs: map[string]OptionMapType{}, clients: map[string]*oss.Client{}}
+}
+
+// loadConfig loads config file once in session, the commands get a copy since they change it
+func (s *shellSession) loadConfig(configFile string) (OptionMapType, error) {
+	if s == nil {
+		return LoadConfig(configFile)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	config, ok := s.configs[configFile]
+	if !ok {
+		var err error
+		if config, err = LoadConfig(configFile); err != nil {
+			return nil, err
+		}
+		s.configs[configFile] = config
+	}
+	options := OptionMapType{}
+	for name, val := range config {
+		options[name] = val
+	}
+	return options, nil
+}
+
+// client returns the client created with the same key in session
+func (s *shellSession) client(key string) *oss.Client {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clients[key]
+}
+
+func (s *shellSession) addClient(key string, client *oss.Client) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients[key] = client
+}
+
+// reset drops the configs and clients, it's called after config is changed
+func (s *shellSession) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.configs = map[string]OptionMapType{}
+	s.clients = map[string]*oss.Client{}
+}
+
+func (s *shellSession) pwd() string {
+	if s.bucket == "" {
+		return SchemePrefix
+	}
+	return SchemePrefix + s.bucket + "/" + s.prefix
+}
+
+// resolve returns the cloud url of path relative to the working directory, the path starts
+// with / is relative to oss://, the cloud url is returned as it is
+func (s *shellSession) resolve(path string) string {
+	if strings.HasPrefix(strings.ToLower(path), SchemePrefix) {
+		return path
+	}
+
+	segs := []string{}
+	if !strings.HasPrefix(path, "/") && s.bucket != "" {
+		segs = append(segs, s.bucket)
+		if s.prefix != "" {
+			segs = append(segs, strings.Split(strings.TrimSuffix(s.prefix, "/"), "/")...)
+		}
+	}
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for _, seg := range parts {
+		switch seg {
+		case "..":
+			if len(segs) > 0 {
+				segs = segs[:len(segs)-1]
+			}
+		case ".":
+		default:
+			segs = append(segs, seg)
+		}
+	}
+
+	// .. and . mean directories
+	last := parts[len(parts)-1]
+	if (last == ".." || last == ".") && len(segs) > 0 {
+		segs = append(segs, "")
+	}
+	return SchemePrefix + strings.Join(segs, "/")
+}
+
+// cd changes the working directory to path
+func (s *shellSession) cd(path string) error {
+	if path == "" {
+		s.bucket, s.prefix = "", ""
+		return nil
+	}
+	cloudURL, err := CloudURLFromString(s.resolve(path), "")
+	if err != nil {
+		return err
+	}
+	s.bucket, s.prefix = cloudURL.bucket, cloudURL.object
+	if s.prefix != "" && !strings.HasSuffix(s.prefix, "/") {
+		s.prefix += "/"
+	}
+	return nil
+}
+
+// resolveArgs resolves the relative cloud urls in args of command
+func (s *shellSession) resolveArgs(command string, args []string) []string {
+	n, ok := shellCloudArgs[command]
+	if !ok {
+		n = 0
+	}
+	resolved := make([]string, 0, len(args))
+	for i, arg := range args {
+		lower := strings.ToLower(arg)
+		switch {
+		case strings.HasPrefix(lower, SchemePrefix):
+		case strings.HasPrefix(lower, Scheme+":"):
+			arg = s.resolve(arg[len(Scheme)+1:])
+		case n == -1 || i < n:
+			arg = s.resolve(arg)
+		}
+		resolved = append(resolved, arg)
+	}
+	if command == listCommand.command.name && len(resolved) == 0 && s.bucket != "" {
+		resolved = append(resolved, s.pwd())
+	}
+	return resolved
+}
+
+// ShellCommand is the command to run commands interactively
+type ShellCommand struct {
+	command Command
+	inherit OptionMapType
+}
+
+var shellCommand = ShellCommand{
+	command: Command{
+		name:        "shell",
+		nameAlias:   []string{},
+		minArgc:     0,
+		maxArgc:     0,
+		specChinese: specChineseShell,
+		specEnglish: specEnglishShell,
+		group:       GroupTypeAdditionalCommand,
+		validOptionNames: []string{
+			OptionConfigFile,
+			OptionEndpoint,
+			OptionAccessKeyID,
+			OptionAccessKeySecret,
+			OptionSTSToken,
+			OptionProxyHost,
+			OptionProxyUser,
+			OptionProxyPwd,
+			OptionCAFile,
+			OptionSkipVerify,
+			OptionForceHTTPS,
+			OptionConnectTimeout,
+			OptionReadTimeout,
+			OptionLanguage,
+		},
+	},
+}
+
+// function for RewriteLoadConfiger interface
+func (sc *ShellCommand) rewriteLoadConfig(configFile string) error {
+	// the config is loaded by the commands run in shell
+	sc.command.configOptions = OptionMapType{}
+	return nil
+}
+
+// function for FormatHelper interface
+func (sc *ShellCommand) formatHelpForWhole() string {
+	return sc.command.formatHelpForWhole()
+}
+
+func (sc *ShellCommand) formatIndependHelp() string {
+	return sc.command.formatIndependHelp()
+}
+
+// Init simulate inheritance, and polymorphism
+func (sc *ShellCommand) Init(args []string, options OptionMapType) error {
+	// keep the options user specified, before they are assembled with config
+	sc.inherit = OptionMapType{}
+	for _, name := range shellInheritOptions {
+		if val, _ := GetString(name, options); val != "" {
+			sc.inherit[name] = val
+		}
+		if val, _ := GetBool(name, options); val {
+			sc.inherit[name] = val
+		}
+	}
+	return sc.command.Init(args, options, sc)
+}
+
+// RunCommand simulate inheritance, and polymorphism
+func (sc *ShellCommand) RunCommand() error {
+	if currentSession != nil {
+		return fmt.Errorf("shell can not be run in shell")
+	}
+	currentSession = newShellSession()
+	defer func() {
+		currentSession = nil
+	}()
+	currentSession.history = loadShellHistory()
+
+	// Ctrl-C at prompt does not leave the shell
+	chSignal := make(chan os.Signal, 1)
+	signal.Notify(chSignal, os.Interrupt)
+	defer signal.Stop(chSignal)
+
+	cm := CommandManager{}
+	cm.Init()
+	prompt := isTerminal(os.Stdin)
+	for {
+		if prompt {
+			fmt.Printf("%s> ", currentSession.pwd())
+		}
+		line, err := readShellLine(os.Stdin)
+		if err != nil && line == "" {
+			if prompt {
+				fmt.Println()
+			}
+			return nil
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if line, err = sc.expandHistory(line); err != nil {
+			fmt.Printf("Error: %s!\n", err)
+			continue
+		}
+		sc.addHistory(line)
+
+		if exit := sc.runLine(&cm, line); exit {
+			return nil
+		}
+	}
+}
+
+// runLine runs a line in shell, it returns true if the shell should exit
+func (sc *ShellCommand) runLine(cm *CommandManager, line string) bool {
+	words, err := splitShellLine(line)
+	if err != nil {
+		fmt.Printf("Error: %s!\n", err)
+		return false
+	}
+
+	switch words[0] {
+	case "exit", "quit":
+		return true
+	case "pwd":
+		fmt.Println(currentSession.pwd())
+		return false
+	case "cd":
+		path := ""
+		if len(words) > 1 {
+			path = words[1]
+		}
+		if err := currentSession.cd(path); err != nil {
+			fmt.Printf("Error: %s!\n", err)
+		}
+		return false
+	case "history":
+		for i, cmd := range currentSession.history {
+			fmt.Printf("%5d  %s\n", i+1, cmd)
+		}
+		return false
+	case sc.command.name:
+		fmt.Printf("Error: shell can not be run in shell!\n")
+		return false
+	}
+
+	ts := time.Now().UnixNano()
+	showElapse, err := sc.runCommand(cm, words)
+	if err != nil {
+		fmt.Printf("Error: %s!\n", err)
+		return false
+	}
+	if showElapse {
+		te := time.Now().UnixNano()
+		fmt.Printf("%.6f(s) elapsed\n", float64(te-ts)/1e9)
+	}
+	return false
+}
+
+func (sc *ShellCommand) runCommand(cm *CommandManager, words []string) (bool, error) {
+	args, options, err := parseShellOptions(words[1:])
+	if err != nil {
+		return false, err
+	}
+	command := words[0]
+	if cmd, ok := cm.commandMap[command]; ok {
+		validOptionNames := commandValidOptionNames(cmd)
+		for name, val := range sc.inherit {
+			if FindPos(name, validOptionNames) == -1 {
+				continue
+			}
+			switch opval := val.(type) {
+			case string:
+				if cur, _ := GetString(name, options); cur == "" {
+					options[name] = &opval
+				}
+			case bool:
+				options[name] = &opval
+			}
+		}
+	}
+	args = currentSession.resolveArgs(command, args)
+
+	var stop func()
+	commandContext, stop = watchInterrupt()
+	defer func() {
+		stop()
+		commandContext = context.Background()
+	}()
+
+	showElapse, err := cm.RunCommand(command, args, options)
+	if command == configCommand.command.name {
+		currentSession.reset()
+	}
+	if commandContext.Err() != nil {
+		return false, fmt.Errorf("the command is interrupted, run it again to continue the unfinished job")
+	}
+	return showElapse, err
+}
+
+// expandHistory expands !n and !! to the history command
+func (sc *ShellCommand) expandHistory(line string) (string, error) {
+	if !strings.HasPrefix(line, "!") {
+		return line, nil
+	}
+	history := currentSession.history
+	index := len(history)
+	if line != "!!" {
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", fmt.Errorf("invalid history: %s", line)
+		}
+		index = n
+	}
+	if index < 1 || index > len(history) {
+		return "", fmt.Errorf("no such history: %s", line)
+	}
+	fmt.Println(history[index-1])
+	return history[index-1], nil
+}
+
+// addHistory records the line in session and history file, the errors of history file are ignored
+func (sc *ShellCommand) addHistory(line string) {
+	currentSession.history = append(currentSession.history, line)
+	if len(currentSession.history) > ShellHistorySize {
+		currentSession.history = currentSession.history[len(currentSession.history)-ShellHistorySize:]
+	}
+	f, err := os.OpenFile(shellHistoryPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, line)
+}
+
+func shellHistoryPath() string {
+	path := ShellHistoryFile
+	if usr, err := user.Current(); err == nil {
+		path = strings.Replace(path, "~", usr.HomeDir, 1)
+	}
+	return path
+}
+
+// loadShellHistory reads the history file, and truncates it if it's too long
+func loadShellHistory() []string {
+	data, err := ioutil.ReadFile(shellHistoryPath())
+	if err != nil {
+		return []string{}
+	}
+	history := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			history = append(history, line)
+		}
+	}
+	if len(history) > ShellHistorySize {
+		history = history[len(history)-ShellHistorySize:]
+		ioutil.WriteFile(shellHistoryPath(), []byte(strings.Join(history, "\n")+"\n"), 0600)
+	}
+	return history
+}
+
+// readShellLine reads a line byte by byte, so that the input after the line is left to the
+// commands which read stdin, e.g. the confirmation of rm
+func readShellLine(r io.Reader) (string, error) {
+	var line bytes.Buffer
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n == 1 {
+			if b[0] == '\n' {
+				return line.String(), nil
+			}
+			line.WriteByte(b[0])
+		}
+		if err != nil {
+			return line.String(), err
+		}
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// splitShellLine splits line into words like shell, the blanks in quotes or escaped by backslash
+// are kept in word. Backslash escapes blank, quote and backslash only, so that windows paths
+// need not be escaped.
+func splitShellLine(line string) ([]string, error) {
+	words := []string{}
+	var word bytes.Buffer
+	inWord := false
+	var quote rune
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && quote != '\'' && i+1 < len(runes) && strings.ContainsRune(" \t'\"\\", runes[i+1]):
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in: %s", line)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// parseShellOptions parses the options in words like command line, the other words are args.
+// The short flags can be combined like -rf, the value of option can be given as --name=value,
+// --name value, -nvalue or -n value.
+func parseShellOptions(words []string) ([]string, OptionMapType, error) {
+	options := make(OptionMapType, len(OptionMap))
+	names := map[string]string{}
+	for key, option := range OptionMap {
+		if option.optionType == OptionTypeFlagTrue {
+			val := false
+			options[key] = &val
+		} else {
+			val := ""
+			options[key] = &val
+		}
+		if option.name != "" {
+			names[option.name] = key
+		}
+		if option.nameAlias != "" {
+			names[option.nameAlias] = key
+		}
+	}
+
+	set := func(key, value string) {
+		options[key] = &value
+	}
+	args := []string{}
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if word == "--" {
+			args = append(args, words[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(word, "-") || word == "-" {
+			args = append(args, word)
+			continue
+		}
+
+		if strings.HasPrefix(word, "--") {
+			name, value, hasValue := word, "", false
+			if pos := strings.Index(word, "="); pos != -1 {
+				name, value, hasValue = word[:pos], word[pos+1:], true
+			}
+			key, ok := names[name]
+			if !ok {
+				return nil, nil, fmt.Errorf("unknown option: %s", name)
+			}
+			if OptionMap[key].optionType == OptionTypeFlagTrue {
+				if hasValue {
+					return nil, nil, fmt.Errorf("option %s does not need value", name)
+				}
+				val := true
+				options[key] = &val
+				continue
+			}
+			if !hasValue {
+				if i+1 >= len(words) {
+					return nil, nil, fmt.Errorf("option %s needs value", name)
+				}
+				i++
+				value = words[i]
+			}
+			set(key, value)
+			continue
+		}
+
+		for j := 1; j < len(word); j++ {
+			name := "-" + word[j:j+1]
+			key, ok := names[name]
+			if !ok {
+				return nil, nil, fmt.Errorf("unknown option: %s", name)
+			}
+			if OptionMap[key].optionType == OptionTypeFlagTrue {
+				val := true
+				options[key] = &val
+				continue
+			}
+			value := word[j+1:]
+			if value == "" {
+				if i+1 >= len(words) {
+					return nil, nil, fmt.Errorf("option %s needs value", name)
+				}
+				i++
+				value = words[i]
+			}
+			set(key, value)
+			break
+		}
+	}
+
+	if err := checkOption(options); err != nil {
+		return nil, nil, err
+	}
+	return args, options, nil
+}
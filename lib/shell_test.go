@@ -0,0 +1,163 @@
+package lib
+
+import (
+	"os"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *OssutilCommandSuite) runShell(script string, c *C) string {
+	str := ""
+	options := OptionMapType{
+		"endpoint":        &endpoint,
+		"accessKeyID":     &accessKeyID,
+		"accessKeySecret": &accessKeySecret,
+		"stsToken":        &str,
+		"configFile":      &configFile,
+	}
+	scriptPath := "ossutil_test_shell_script" + randLowStr(5)
+	s.createFile(scriptPath, script, c)
+	defer os.Remove(scriptPath)
+	in, err := os.Open(scriptPath)
+	c.Assert(err, IsNil)
+	defer in.Close()
+
+	stdin, out := os.Stdin, os.Stdout
+	os.Stdin = in
+	testResultFile, _ = os.OpenFile(resultPath, os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0664)
+	os.Stdout = testResultFile
+	_, err = cm.RunCommand("shell", []string{}, options)
+	os.Stdin, os.Stdout = stdin, out
+	c.Assert(err, IsNil)
+	return s.readFile(resultPath, c)
+}
+
+func (s *OssutilCommandSuite) TestShellSplitLine(c *C) {
+	words, err := splitShellLine(`  cp "a b" 'c\ d' e\ f C:\dir\file  `)
+	c.Assert(err, IsNil)
+	c.Assert(words, DeepEquals, []string{"cp", "a b", `c\ d`, "e f", `C:\dir\file`})
+
+	words, err = splitShellLine(`set-meta oss://b/o "X-Oss-Meta-A:\"x y\""`)
+	c.Assert(err, IsNil)
+	c.Assert(words, DeepEquals, []string{"set-meta", "oss://b/o", `X-Oss-Meta-A:"x y"`})
+
+	_, err = splitShellLine(`ls "oss://b`)
+	c.Assert(err, NotNil)
+}
+
+func (s *OssutilCommandSuite) TestShellParseOptions(c *C) {
+	args, options, err := parseShellOptions([]string{"-rf", "a", "--jobs=3", "-e", "endpoint", "--acl", "private", "-Lch", "--", "-b"})
+	c.Assert(err, IsNil)
+	c.Assert(args, DeepEquals, []string{"a", "-b"})
+	recursive, _ := GetBool(OptionRecursion, options)
+	force, _ := GetBool(OptionForce, options)
+	c.Assert(recursive && force, Equals, true)
+	jobs, _ := GetString(OptionRoutines, options)
+	c.Assert(jobs, Equals, "3")
+	ep, _ := GetString(OptionEndpoint, options)
+	c.Assert(ep, Equals, "endpoint")
+	acl, _ := GetString(OptionACL, options)
+	c.Assert(acl, Equals, "private")
+	language, _ := GetString(OptionLanguage, options)
+	c.Assert(language, Equals, "ch")
+
+	for _, words := range [][]string{{"--notexist"}, {"-Z"}, {"-e"}, {"--force=true"}, {"--jobs=abc"}} {
+		_, _, err = parseShellOptions(words)
+		c.Assert(err, NotNil)
+	}
+}
+
+func (s *OssutilCommandSuite) TestShellResolve(c *C) {
+	session := newShellSession()
+	c.Assert(session.pwd(), Equals, "oss://")
+	c.Assert(session.resolve("bucket"), Equals, "oss://bucket")
+
+	c.Assert(session.cd("bucket/dir"), IsNil)
+	c.Assert(session.pwd(), Equals, "oss://bucket/dir/")
+	c.Assert(session.resolve("a.txt"), Equals, "oss://bucket/dir/a.txt")
+	c.Assert(session.resolve("../b/"), Equals, "oss://bucket/b/")
+	c.Assert(session.resolve(".."), Equals, "oss://bucket/")
+	c.Assert(session.resolve("./c"), Equals, "oss://bucket/dir/c")
+	c.Assert(session.resolve("/other/d"), Equals, "oss://other/d")
+	c.Assert(session.resolve("oss://other/e"), Equals, "oss://other/e")
+
+	c.Assert(session.resolveArgs("ls", []string{}), DeepEquals, []string{"oss://bucket/dir/"})
+	c.Assert(session.resolveArgs("set-acl", []string{"a", "private"}), DeepEquals, []string{"oss://bucket/dir/a", "private"})
+	c.Assert(session.resolveArgs("cp", []string{"a", "oss:b", "oss://c/d"}), DeepEquals, []string{"a", "oss://bucket/dir/b", "oss://c/d"})
+
+	c.Assert(session.cd("../.."), IsNil)
+	c.Assert(session.pwd(), Equals, "oss://")
+	c.Assert(session.resolveArgs("ls", []string{}), DeepEquals, []string{})
+	c.Assert(session.cd("bucket"), IsNil)
+	c.Assert(session.cd("oss://"), IsNil)
+	c.Assert(session.pwd(), Equals, "oss://")
+	c.Assert(session.cd("bucket"), IsNil)
+	c.Assert(session.cd(""), IsNil)
+	c.Assert(session.pwd(), Equals, "oss://")
+}
+
+func (s *OssutilCommandSuite) TestShellSession(c *C) {
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+	s.createFile(uploadFileName, "abc", c)
+	s.putObject(bucketName, "dir/a.txt", uploadFileName, c)
+	s.putObject(bucketName, "dir/b.txt", uploadFileName, c)
+
+	script := strings.Join([]string{
+		"cd " + bucketName + "/dir",
+		"pwd",
+		"ls -s",
+		"stat a.txt",
+		"!!",
+		"cp oss:b.txt " + downloadFileName + " -f",
+		"ls oss://" + bucketName + "notexist",
+		`ls "unterminated`,
+		"shell",
+		"exit",
+		"ls oss://" + bucketName + "notexist",
+	}, "\n")
+	out := s.runShell(script, c)
+
+	c.Assert(strings.Contains(out, "oss://"+bucketName+"/dir/\n"), Equals, true)
+	c.Assert(strings.Contains(out, "oss://"+bucketName+"/dir/a.txt\noss://"+bucketName+"/dir/b.txt\n"), Equals, true)
+	c.Assert(strings.Count(out, "Etag"), Equals, 2)
+	// the command after exit is not run
+	c.Assert(strings.Count(out, "Error: "), Equals, 3)
+	c.Assert(s.readFile(downloadFileName, c), Equals, "abc")
+
+	// the session is closed
+	c.Assert(currentSession, IsNil)
+
+	s.removeBucket(bucketName, true, c)
+}
+
+func (s *OssutilCommandSuite) TestShellClientReuse(c *C) {
+	currentSession = newShellSession()
+	defer func() {
+		currentSession = nil
+	}()
+
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+	s.getStat(bucketName, "", c)
+	s.getStat(bucketName, "", c)
+	c.Assert(len(currentSession.clients), Equals, 1)
+	s.removeBucket(bucketName, true, c)
+	c.Assert(len(currentSession.clients), Equals, 1)
+
+	// the client observed by metrics is not shared
+	endpoint, id, secret := "oss-cn-hangzhou.aliyuncs.com", "metricsID", "metricsSecret"
+	cmd := Command{name: "cp", options: OptionMapType{OptionEndpoint: &endpoint, OptionAccessKeyID: &id, OptionAccessKeySecret: &secret}}
+	cmd.metrics = &metrics{command: "cp", requests: map[string]*latencyHistogram{}}
+	_, err := cmd.ossClient(bucketName)
+	c.Assert(err, IsNil)
+	c.Assert(len(currentSession.clients), Equals, 1)
+	cmd.metrics = nil
+	_, err = cmd.ossClient(bucketName)
+	c.Assert(err, IsNil)
+	c.Assert(len(currentSession.clients), Equals, 2)
+
+	currentSession.reset()
+	c.Assert(len(currentSession.clients), Equals, 0)
+}
@@ -0,0 +1,313 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	leveldb "github.com/syndtr/goleveldb/leveldb"
+)
+
+const (
+	snapshotInspect string = "inspect"
+	snapshotPrune          = "prune"
+	snapshotExport         = "export"
+)
+
+var specChineseSnapshot = SpecText{
+
+	synopsisText: "查看、清理或导出cp命令的snapshot信息",
+
+	paramText: "mode snapshot_path [url_prefix]",
+
+	syntaxText: `
+    ossutil snapshot inspect snapshot_path [url_prefix]
+    ossutil snapshot prune snapshot_path [url_prefix]
+    ossutil snapshot export snapshot_path [url_prefix]
+`,
+
+	detailHelpText: `
+    该命令用于管理cp命令通过--snapshot-path选项生成的快照信息，snapshot_path为cp命令中
+    --snapshot-path选项指定的目录。每条快照信息记录一次上传、下载或拷贝的源、目的以及判断源
+    是否变化的值（上传为本地文件的lastModifiedTime，下载为object的ETag和lastModifiedTime，
+    拷贝为源object的ETag）。
+
+    如果指定了url_prefix，则只处理源或目的以url_prefix为前缀的快照信息，url_prefix可以为
+    oss://bucket[/prefix]或本地文件的绝对路径前缀。
+
+    该命令有三种模式：
+
+    1) inspect
+        输出快照信息，以及各类型快照信息的数目。
+
+    2) prune
+        删除快照信息。未指定url_prefix时，删除本地文件已经不存在的上传和下载快照信息；指定了
+        url_prefix时，删除所有匹配的快照信息。删除后，下次执行cp命令时，对应的文件会被重新上传、
+        下载或拷贝。
+
+    3) export
+        以JSON格式输出快照信息，每行一条，可以重定向到文件中保存或供其他工具处理。
+
+用法:
+
+    ossutil snapshot inspect|prune|export snapshot_path [url_prefix]
+`,
+
+	sampleText: `
+    1) 查看所有快照信息
+        ossutil snapshot inspect your_local_path
+
+    2) 查看bucket1中object的快照信息
+        ossutil snapshot inspect your_local_path oss://bucket1
+
+    3) 删除本地文件已不存在的快照信息
+        ossutil snapshot prune your_local_path
+
+    4) 删除bucket1/dir前缀的快照信息
+        ossutil snapshot prune your_local_path oss://bucket1/dir
+
+    5) 导出快照信息到文件
+        ossutil snapshot export your_local_path > snapshot.json
+`,
+}
+
+var specEnglishSnapshot = SpecText{
+
+	synopsisText: "Inspect, prune or export the snapshot of cp command",
+
+	paramText: "mode snapshot_path [url_prefix]",
+
+	syntaxText: `
+    ossutil snapshot inspect snapshot_path [url_prefix]
+    ossutil snapshot prune snapshot_path [url_prefix]
+    ossutil snapshot export snapshot_path [url_prefix]
+`,
+
+	detailHelpText: `
+    The command manages the snapshot information generated by cp command with --snapshot-path
+    option, snapshot_path is the directory specified by --snapshot-path in cp command. Each record
+    of snapshot contains the source and destination of an upload, download or copy, and the value
+    used to decide whether the source is changed(lastModifiedTime of local file for upload, ETag
+    and lastModifiedTime of object for download, ETag of source object for copy).
+
+    If url_prefix is specified, only the records whose source or destination starts with
+    url_prefix will be dealed, url_prefix can be oss://bucket[/prefix] or the prefix of absolute
+    path of local files.
+
+    The command has three modes:
+
+    1) inspect
+        Print the records, and the number of records of each type.
+
+    2) prune
+        Remove records. If url_prefix is not specified, remove the records of upload and download
+        whose local file no longer exists, else remove all the matched records. After that, the
+        corresponding files will be uploaded, downloaded or copied again in the next cp.
+
+    3) export
+        Print the records in JSON format, one record per line, the output can be redirected to
+        a file for saving, or be used by other tools.
+
+Usage:
+
+    ossutil snapshot inspect|prune|export snapshot_path [url_prefix]
+`,
+
+	sampleText: `
+    1) Inspect all records
+        ossutil snapshot inspect your_local_path
+
+    2) Inspect records of objects in bucket1
+        ossutil snapshot inspect your_local_path oss://bucket1
+
+    3) Remove the records whose local file no longer exists
+        ossutil snapshot prune your_local_path
+
+    4) Remove the records of bucket1/dir prefix
+        ossutil snapshot prune your_local_path oss://bucket1/dir
+
+    5) Export the records to file
+        ossutil snapshot export your_local_path > snapshot.json
+`,
+}
+
+// snapshotEntry is a record in snapshot of cp command
+type snapshotEntry struct {
+	Type        string `json:"type"`
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Value       string `json:"value"`
+}
+
+// SnapshotCommand is the command to manage the snapshot of cp command
+type SnapshotCommand struct {
+	command Command
+}
+
+var snapshotCommand = SnapshotCommand{
+	command: Command{
+		name:        "snapshot",
+		nameAlias:   []string{""},
+		minArgc:     2,
+		maxArgc:     3,
+		specChinese: specChineseSnapshot,
+		specEnglish: specEnglishSnapshot,
+		group:       GroupTypeAdditionalCommand,
+		validOptionNames: []string{
+			OptionConfigFile,
+		},
+	},
+}
+
+// function for RewriteLoadConfiger interface
+func (sc *SnapshotCommand) rewriteLoadConfig(configFile string) error {
+	// read config file, if error exist, do not print error
+	var err error
+	if sc.command.configOptions, err = LoadConfig(configFile); err != nil {
+		sc.command.configOptions = OptionMapType{}
+	}
+	return nil
+}
+
+// function for FormatHelper interface
+func (sc *SnapshotCommand) formatHelpForWhole() string {
+	return sc.command.formatHelpForWhole()
+}
+
+func (sc *SnapshotCommand) formatIndependHelp() string {
+	return sc.command.formatIndependHelp()
+}
+
+// Init simulate inheritance, and polymorphism
+func (sc *SnapshotCommand) Init(args []string, options OptionMapType) error {
+	return sc.command.Init(args, options, sc)
+}
+
+// RunCommand simulate inheritance, and polymorphism
+func (sc *SnapshotCommand) RunCommand() error {
+	mode := strings.ToLower(sc.command.args[0])
+	if mode != snapshotInspect && mode != snapshotPrune && mode != snapshotExport {
+		return fmt.Errorf("invalid mode: %s, please check, valid modes are: %s/%s/%s", sc.command.args[0], snapshotInspect, snapshotPrune, snapshotExport)
+	}
+
+	spath := sc.command.args[1]
+	if _, err := os.Stat(spath); err != nil {
+		return fmt.Errorf("invalid snapshot path: %s, reason: %s", spath, err.Error())
+	}
+
+	prefix := ""
+	if len(sc.command.args) > 2 {
+		prefix = sc.command.args[2]
+	}
+
+	db, err := leveldb.OpenFile(spath, nil)
+	if err != nil {
+		return fmt.Errorf("load snapshot error, reason: %s", err.Error())
+	}
+	defer db.Close()
+
+	switch mode {
+	case snapshotInspect:
+		return sc.inspect(db, prefix)
+	case snapshotPrune:
+		return sc.prune(db, prefix)
+	default:
+		return sc.export(db, prefix)
+	}
+}
+
+func (sc *SnapshotCommand) inspect(db *leveldb.DB, prefix string) error {
+	count := map[string]int64{}
+	err := sc.walk(db, prefix, func(entry snapshotEntry) {
+		fmt.Printf("%-10s%s %s %s %s\n", entry.Type, entry.Source, SnapshotConnector, entry.Destination, entry.Value)
+		count[entry.Type]++
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n%s: %d, %s: %d, %s: %d\n", opUpload, count[opUpload], opDownload, count[opDownload], opCopy, count[opCopy])
+	return nil
+}
+
+func (sc *SnapshotCommand) prune(db *leveldb.DB, prefix string) error {
+	batch := new(leveldb.Batch)
+	err := sc.walk(db, prefix, func(entry snapshotEntry) {
+		if prefix != "" || sc.isStale(entry) {
+			batch.Delete([]byte(sc.formatKey(entry)))
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if err := db.Write(batch, nil); err != nil {
+		return fmt.Errorf("dump snapshot error: %s", err.Error())
+	}
+	fmt.Printf("pruned %d records\n", batch.Len())
+	return nil
+}
+
+func (sc *SnapshotCommand) export(db *leveldb.DB, prefix string) error {
+	return sc.walk(db, prefix, func(entry snapshotEntry) {
+		data, _ := json.Marshal(entry)
+		fmt.Println(string(data))
+	})
+}
+
+// walk calls fn for every record whose source or destination matches prefix
+func (sc *SnapshotCommand) walk(db *leveldb.DB, prefix string, fn func(entry snapshotEntry)) error {
+	iter := db.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		entry, ok := sc.parseEntry(string(iter.Key()), string(iter.Value()))
+		if !ok {
+			continue
+		}
+		if prefix == "" || strings.HasPrefix(entry.Source, prefix) || strings.HasPrefix(entry.Destination, prefix) {
+			fn(entry)
+		}
+	}
+	return iter.Error()
+}
+
+func (sc *SnapshotCommand) parseEntry(key, value string) (snapshotEntry, bool) {
+	pos := strings.Index(key, SnapshotConnector+SchemePrefix)
+	if pos < 0 {
+		pos = strings.Index(key, SnapshotConnector)
+	}
+	if pos < 0 {
+		return snapshotEntry{}, false
+	}
+
+	entry := snapshotEntry{Source: key[:pos], Destination: key[pos+len(SnapshotConnector):], Value: value}
+	srcCloud := strings.HasPrefix(entry.Source, SchemePrefix)
+	destCloud := strings.HasPrefix(entry.Destination, SchemePrefix)
+	switch {
+	case srcCloud && destCloud:
+		entry.Type = opCopy
+	case srcCloud:
+		entry.Type = opDownload
+	default:
+		entry.Type = opUpload
+	}
+	return entry, true
+}
+
+func (sc *SnapshotCommand) formatKey(entry snapshotEntry) string {
+	return entry.Source + SnapshotConnector + entry.Destination
+}
+
+// isStale shows if the local file of the record no longer exists
+func (sc *SnapshotCommand) isStale(entry snapshotEntry) bool {
+	var localPath string
+	switch entry.Type {
+	case opUpload:
+		localPath = entry.Source
+	case opDownload:
+		localPath = entry.Destination
+	default:
+		return false
+	}
+	_, err := os.Stat(localPath)
+	return os.IsNotExist(err)
+}
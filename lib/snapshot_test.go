@@ -0,0 +1,70 @@
+package lib
+
+import (
+	"os"
+	"strings"
+
+	leveldb "github.com/syndtr/goleveldb/leveldb"
+	. "gopkg.in/check.v1"
+)
+
+func (s *OssutilCommandSuite) runSnapshot(mode, spath, prefix string, c *C) string {
+	args := []string{mode, spath}
+	if prefix != "" {
+		args = append(args, prefix)
+	}
+	out := os.Stdout
+	testResultFile, _ = os.OpenFile(resultPath, os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0664)
+	os.Stdout = testResultFile
+	_, err := cm.RunCommand("snapshot", args, OptionMapType{})
+	os.Stdout = out
+	c.Assert(err, IsNil)
+	return s.readFile(resultPath, c)
+}
+
+func (s *OssutilCommandSuite) TestSnapshotCommand(c *C) {
+	spath := "ossutil.snapshot-dir" + randStr(6)
+	os.RemoveAll(spath)
+	existFile := "ossutil_test_snapshot_" + randStr(5)
+	s.createFile(existFile, "abc", c)
+	defer os.Remove(existFile)
+	missFile := "ossutil_test_snapshot_" + randStr(5)
+
+	db, err := leveldb.OpenFile(spath, nil)
+	c.Assert(err, IsNil)
+	db.Put([]byte(existFile+SnapshotConnector+CloudURLToString("b1", "o1")), []byte("1543"), nil)
+	db.Put([]byte(missFile+SnapshotConnector+CloudURLToString("b1", "o2")), []byte("1543"), nil)
+	db.Put([]byte(CloudURLToString("b1", "o1")+SnapshotConnector+missFile), []byte("\"etag\"#1543"), nil)
+	db.Put([]byte(CloudURLToString("b1", "o1")+SnapshotConnector+CloudURLToString("b2", "o1")), []byte("\"etag\""), nil)
+	db.Close()
+
+	str := s.runSnapshot(snapshotInspect, spath, "", c)
+	c.Assert(strings.Contains(str, "upload: 2, download: 1, copy: 1"), Equals, true)
+
+	str = s.runSnapshot(snapshotInspect, spath, CloudURLToString("b2", ""), c)
+	c.Assert(strings.Contains(str, "upload: 0, download: 0, copy: 1"), Equals, true)
+
+	str = s.runSnapshot(snapshotExport, spath, "", c)
+	c.Assert(strings.Count(str, "\n"), Equals, 4)
+	c.Assert(strings.Contains(str, "\"type\":\"download\""), Equals, true)
+
+	// prune records of missing local files
+	str = s.runSnapshot(snapshotPrune, spath, "", c)
+	c.Assert(strings.Contains(str, "pruned 2 records"), Equals, true)
+	str = s.runSnapshot(snapshotInspect, spath, "", c)
+	c.Assert(strings.Contains(str, "upload: 1, download: 0, copy: 1"), Equals, true)
+
+	// prune by prefix
+	str = s.runSnapshot(snapshotPrune, spath, CloudURLToString("b2", ""), c)
+	c.Assert(strings.Contains(str, "pruned 1 records"), Equals, true)
+	str = s.runSnapshot(snapshotInspect, spath, "", c)
+	c.Assert(strings.Contains(str, "upload: 1, download: 0, copy: 0"), Equals, true)
+
+	// invalid mode and path
+	_, err = cm.RunCommand("snapshot", []string{"show", spath}, OptionMapType{})
+	c.Assert(err, NotNil)
+	_, err = cm.RunCommand("snapshot", []string{snapshotInspect, spath + "notexist"}, OptionMapType{})
+	c.Assert(err, NotNil)
+
+	os.RemoveAll(spath)
+}
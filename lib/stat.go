@@ -1,6 +1,7 @@
 package lib
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"sort"
@@ -99,6 +100,14 @@ var statCommand = StatCommand{
 			OptionAccessKeyID,
 			OptionAccessKeySecret,
 			OptionSTSToken,
+			OptionProxyHost,
+			OptionProxyUser,
+			OptionProxyPwd,
+			OptionCAFile,
+			OptionSkipVerify,
+			OptionForceHTTPS,
+			OptionConnectTimeout,
+			OptionReadTimeout,
 			OptionRetryTimes,
 		},
 	},
@@ -126,13 +135,37 @@ func (sc *StatCommand) RunCommand() error {
 		return err
 	}
 
+	items, err := sc.stat(sc.command.ctx, cloudURL)
+	if err != nil {
+		return err
+	}
+
+	format := "%-28s: %s\n"
+	if cloudURL.object == "" {
+		format = "%-18s: %s\n"
+	}
+	for _, item := range items {
+		fmt.Printf(format, item.name, item.value)
+	}
+	return nil
+}
+
+// statItem is a name and value of the stat information
+type statItem struct {
+	name  string
+	value string
+}
+
+// stat returns the stat information of the bucket or object of cloudURL in the order to show
+func (sc *StatCommand) stat(ctx context.Context, cloudURL CloudURL) ([]statItem, error) {
+	sc.command.ctx = ctx
 	if cloudURL.bucket == "" {
-		return fmt.Errorf("invalid cloud url: %s, miss bucket", sc.command.args[0])
+		return nil, fmt.Errorf("invalid cloud url: %s, miss bucket", cloudURL.urlStr)
 	}
 
 	bucket, err := sc.command.ossBucket(cloudURL.bucket)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if cloudURL.object == "" {
@@ -141,22 +174,23 @@ func (sc *StatCommand) RunCommand() error {
 	return sc.objectStat(bucket, cloudURL)
 }
 
-func (sc *StatCommand) bucketStat(bucket *oss.Bucket, cloudURL CloudURL) error {
+func (sc *StatCommand) bucketStat(bucket *oss.Bucket, cloudURL CloudURL) ([]statItem, error) {
 	// TODO: go sdk should implement GetBucketInfo
 	gbar, err := sc.ossGetBucketStatRetry(bucket)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	fmt.Printf("%-18s: %s\n", StatName, gbar.BucketInfo.Name)
-	fmt.Printf("%-18s: %s\n", StatLocation, gbar.BucketInfo.Location)
-	fmt.Printf("%-18s: %s\n", StatCreationDate, utcToLocalTime(gbar.BucketInfo.CreationDate))
-	fmt.Printf("%-18s: %s\n", StatExtranetEndpoint, gbar.BucketInfo.ExtranetEndpoint)
-	fmt.Printf("%-18s: %s\n", StatIntranetEndpoint, gbar.BucketInfo.IntranetEndpoint)
-	fmt.Printf("%-18s: %s\n", StatACL, gbar.BucketInfo.ACL)
-	fmt.Printf("%-18s: %s\n", StatOwner, gbar.BucketInfo.Owner.ID)
-	fmt.Printf("%-18s: %s\n", StatStorageClass, gbar.BucketInfo.StorageClass)
-	return nil
+	return []statItem{
+		{StatName, gbar.BucketInfo.Name},
+		{StatLocation, gbar.BucketInfo.Location},
+		{StatCreationDate, fmt.Sprintf("%s", utcToLocalTime(gbar.BucketInfo.CreationDate))},
+		{StatExtranetEndpoint, gbar.BucketInfo.ExtranetEndpoint},
+		{StatIntranetEndpoint, gbar.BucketInfo.IntranetEndpoint},
+		{StatACL, gbar.BucketInfo.ACL},
+		{StatOwner, gbar.BucketInfo.Owner.ID},
+		{StatStorageClass, gbar.BucketInfo.StorageClass},
+	}, nil
 }
 
 func (sc *StatCommand) ossGetBucketStatRetry(bucket *oss.Bucket) (oss.GetBucketInfoResult, error) {
@@ -172,17 +206,17 @@ func (sc *StatCommand) ossGetBucketStatRetry(bucket *oss.Bucket) (oss.GetBucketI
 	}
 }
 
-func (sc *StatCommand) objectStat(bucket *oss.Bucket, cloudURL CloudURL) error {
+func (sc *StatCommand) objectStat(bucket *oss.Bucket, cloudURL CloudURL) ([]statItem, error) {
 	// acl info
 	goar, err := sc.ossGetObjectACLRetry(bucket, cloudURL.object)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// normal info
 	props, err := sc.command.ossGetObjectStatRetry(bucket, cloudURL.object)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	sortNames := []string{}
@@ -203,20 +237,25 @@ func (sc *StatCommand) objectStat(bucket *oss.Bucket, cloudURL CloudURL) error {
 	sortNames = append(sortNames, "ACL")
 	attrMap[StatOwner] = goar.Owner.ID
 	attrMap[StatACL] = goar.ACL
+	if status, expiry := getRestoreStatus(props); status != "" {
+		sortNames = append(sortNames, StatRestoreStatus)
+		attrMap[StatRestoreStatus] = formatRestoreStatus(status, expiry)
+	}
 	if lm, err := time.Parse(http.TimeFormat, attrMap[StatLastModified]); err == nil {
 		attrMap[StatLastModified] = fmt.Sprintf("%s", utcToLocalTime(lm.UTC()))
 	}
 
 	sort.Strings(sortNames)
 
+	items := make([]statItem, 0, len(sortNames))
 	for _, name := range sortNames {
 		if strings.ToLower(name) != "etag" {
-			fmt.Printf("%-28s: %s\n", name, attrMap[name])
+			items = append(items, statItem{name, attrMap[name]})
 		} else {
-			fmt.Printf("%-28s: %s\n", name, strings.Trim(attrMap[name], "\""))
+			items = append(items, statItem{name, strings.Trim(attrMap[name], "\"")})
 		}
 	}
-	return nil
+	return items, nil
 }
 
 func (sc *StatCommand) ossGetObjectACLRetry(bucket *oss.Bucket, object string) (oss.GetObjectACLResult, error) {
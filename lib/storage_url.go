@@ -11,6 +11,9 @@ import (
 // SchemePrefix is the prefix of oss url
 const SchemePrefix string = "oss://"
 
+// S3SchemePrefix is the prefix of the url of s3 compatible storage
+const S3SchemePrefix string = "s3://"
+
 type CloudURLType string
 
 const (
@@ -161,8 +164,65 @@ func (fu FileURL) ToString() string {
 	return fu.urlStr
 }
 
+// S3URL describes the url of s3 compatible storage, it's only used as source
+type S3URL struct {
+	urlStr string
+	bucket string
+	object string
+}
+
+// Init is used to create a s3 url from a user input url
+func (su *S3URL) Init(urlStr, encodingType string) error {
+	su.urlStr = urlStr
+	sli := strings.SplitN(urlStr[len(S3SchemePrefix):], "/", 2)
+	su.bucket = sli[0]
+	if len(sli) > 1 {
+		su.object = sli[1]
+		if encodingType == URLEncodingType {
+			var err error
+			if su.object, err = url.QueryUnescape(su.object); err != nil {
+				return fmt.Errorf("invalid s3 url: %s, object name is not url encoded, %s", su.urlStr, err.Error())
+			}
+		}
+	}
+	if su.bucket == "" && su.object != "" {
+		return fmt.Errorf("invalid s3 url: %s, miss bucket", su.urlStr)
+	}
+	return nil
+}
+
+// IsCloudURL shows if the url is a cloud url, the url of s3 is not an oss url
+func (su S3URL) IsCloudURL() bool {
+	return false
+}
+
+// IsFileURL shows if the url is a file url
+func (su S3URL) IsFileURL() bool {
+	return false
+}
+
+// ToString reconstruct url
+func (su S3URL) ToString() string {
+	return S3URLToString(su.bucket, su.object)
+}
+
+// S3URLToString format s3 url string from input
+func S3URLToString(bucket string, object string) string {
+	if object == "" {
+		return fmt.Sprintf("%s%s", S3SchemePrefix, bucket)
+	}
+	return fmt.Sprintf("%s%s/%s", S3SchemePrefix, bucket, object)
+}
+
 // StorageURLFromString analysis input url type and build a storage url from the url
 func StorageURLFromString(urlStr, encodingType string) (StorageURLer, error) {
+	if strings.HasPrefix(strings.ToLower(urlStr), S3SchemePrefix) {
+		var s3URL S3URL
+		if err := s3URL.Init(urlStr, encodingType); err != nil {
+			return nil, err
+		}
+		return s3URL, nil
+	}
 	if strings.HasPrefix(strings.ToLower(urlStr), SchemePrefix) {
 		var cloudURL CloudURL
 		if err := cloudURL.Init(urlStr, encodingType); err != nil {
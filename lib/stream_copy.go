@@ -0,0 +1,427 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// streamCheckpointFile is the name of checkpoint file of streaming copy in the checkpoint directory of object
+const streamCheckpointFile = "stream.cp"
+
+// streamMetaHeaders are the headers of source object kept by streaming copy, besides the user meta
+var streamMetaHeaders = []string{
+	oss.HTTPHeaderContentType,
+	oss.HTTPHeaderCacheControl,
+	oss.HTTPHeaderContentDisposition,
+	oss.HTTPHeaderContentEncoding,
+	oss.HTTPHeaderExpires,
+}
+
+// streamSource is the source which streaming copy reads objects from
+type streamSource interface {
+	bucketName() string
+
+	// stat returns the headers of object, including the size, etag and meta
+	stat(object string) (http.Header, error)
+
+	// open returns the data of object, or the range of it if end is not negative, the range is read
+	// only if the etag of object matches. The data of whole object is checked when it's closed.
+	open(object string, start, end int64, etag string) (io.ReadCloser, http.Header, error)
+}
+
+// ossStreamSource reads objects from oss bucket
+type ossStreamSource struct {
+	command *Command
+	bucket  *oss.Bucket
+}
+
+func (s ossStreamSource) bucketName() string {
+	return s.bucket.BucketName
+}
+
+func (s ossStreamSource) stat(object string) (http.Header, error) {
+	return s.command.ossGetObjectStatRetry(s.bucket, object)
+}
+
+func (s ossStreamSource) open(object string, start, end int64, etag string) (io.ReadCloser, http.Header, error) {
+	if end >= 0 {
+		result, err := s.bucket.DoGetObject(&oss.GetObjectRequest{ObjectKey: object}, []oss.Option{oss.Range(start, end), oss.IfMatch(etag)})
+		if err != nil {
+			return nil, nil, err
+		}
+		return result.Response, result.Response.Headers, nil
+	}
+	result, err := s.bucket.DoGetObject(&oss.GetObjectRequest{ObjectKey: object}, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &crcCheckReader{result}, result.Response.Headers, nil
+}
+
+// crcCheckReader checks crc64 of the data downloaded when it's closed
+type crcCheckReader struct {
+	result *oss.GetObjectResult
+}
+
+func (r *crcCheckReader) Read(p []byte) (int, error) {
+	return r.result.Response.Read(p)
+}
+
+func (r *crcCheckReader) Close() error {
+	r.result.Response.Close()
+	if r.result.ClientCRC != nil && r.result.ServerCRC != 0 && r.result.ClientCRC.Sum64() != r.result.ServerCRC {
+		return fmt.Errorf("crc64 of the data downloaded is %d, but crc64 of source is %d", r.result.ClientCRC.Sum64(), r.result.ServerCRC)
+	}
+	return nil
+}
+
+// streamPart is a part uploaded by streaming copy
+type streamPart struct {
+	Number int
+	ETag   string
+}
+
+type streamParts []streamPart
+
+func (p streamParts) Len() int           { return len(p) }
+func (p streamParts) Less(i, j int) bool { return p[i].Number < p[j].Number }
+func (p streamParts) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// streamCheckpoint records the parts uploaded by streaming copy, so that it can continue when run again.
+// It's valid only if the source object and part size are not changed.
+type streamCheckpoint struct {
+	Dest     string
+	ETag     string
+	Size     int64
+	PartSize int64
+	UploadID string
+	Parts    streamParts
+}
+
+// newDestCommand returns the command to access the destination bucket of copy between oss, it's the
+// copy command itself unless the destination is specified by --dest-* options. The endpoint or
+// AccessKey not specified is the same as the source.
+func (cc *CopyCommand) newDestCommand() (*Command, error) {
+	configFile, _ := GetString(OptionDestConfigFile, cc.command.options)
+	endpoint, _ := GetString(OptionDestEndpoint, cc.command.options)
+	accessKeyID, _ := GetString(OptionDestKeyID, cc.command.options)
+	accessKeySecret, _ := GetString(OptionDestKeySecret, cc.command.options)
+	stsToken, _ := GetString(OptionDestSTSToken, cc.command.options)
+	if configFile == "" && endpoint == "" && accessKeyID == "" && accessKeySecret == "" && stsToken == "" {
+		return &cc.command, nil
+	}
+
+	dest := cc.command
+	dest.options = OptionMapType{}
+	for name, val := range cc.command.options {
+		dest.options[name] = val
+	}
+	dest.explicitEndpoint = endpoint != ""
+	if configFile != "" {
+		configOptions, err := LoadConfig(configFile)
+		if err != nil {
+			return nil, err
+		}
+		dest.configOptions = configOptions
+		for name, val := range map[string]*string{OptionEndpoint: &endpoint, OptionAccessKeyID: &accessKeyID, OptionAccessKeySecret: &accessKeySecret, OptionSTSToken: &stsToken} {
+			if *val == "" {
+				*val, _ = configOptions[name].(string)
+			}
+		}
+	} else if dest.explicitEndpoint {
+		// the bucket endpoints in config file are for source
+		dest.configOptions = OptionMapType{}
+	}
+
+	setOption := func(name, val string) {
+		dest.options[name] = &val
+	}
+	if endpoint != "" {
+		setOption(OptionEndpoint, endpoint)
+	}
+	if accessKeyID != "" {
+		// the STSToken of source is invalid for other AccessKey
+		setOption(OptionAccessKeyID, accessKeyID)
+		setOption(OptionSTSToken, stsToken)
+	}
+	if accessKeySecret != "" {
+		setOption(OptionAccessKeySecret, accessKeySecret)
+	}
+	if stsToken != "" {
+		setOption(OptionSTSToken, stsToken)
+	}
+	return &dest, nil
+}
+
+// needStreamCopy shows if the objects must be streamed from source to destination, since copy in oss
+// is only possible when the buckets are in the same region and accessed by the same AccessKey
+func (cc *CopyCommand) needStreamCopy(srcBucket, destBucket string) (bool, error) {
+	if cc.cpOption.destCommand == &cc.command {
+		return false, nil
+	}
+	src, err := cc.command.ossClient(srcBucket)
+	if err != nil {
+		return false, err
+	}
+	dest, err := cc.cpOption.destCommand.ossClient(destBucket)
+	if err != nil {
+		return false, err
+	}
+	if src.Config.AccessKeyID != dest.Config.AccessKeyID {
+		return true, nil
+	}
+	if srcRegion, _, ok := endpointRegion(src.Config.Endpoint); ok {
+		destRegion, _, ok := endpointRegion(dest.Config.Endpoint)
+		return !ok || srcRegion != destRegion, nil
+	}
+	_, srcHost := splitEndpoint(src.Config.Endpoint)
+	_, destHost := splitEndpoint(dest.Config.Endpoint)
+	return !strings.EqualFold(srcHost, destHost), nil
+}
+
+// streamMetaOptions returns the options to keep the meta of source object
+func streamMetaOptions(header http.Header) []oss.Option {
+	options := []oss.Option{}
+	for _, name := range streamMetaHeaders {
+		val := header.Get(name)
+		if val == "" {
+			continue
+		}
+		switch name {
+		case oss.HTTPHeaderContentType:
+			options = append(options, oss.ContentType(val))
+		case oss.HTTPHeaderCacheControl:
+			options = append(options, oss.CacheControl(val))
+		case oss.HTTPHeaderContentDisposition:
+			options = append(options, oss.ContentDisposition(val))
+		case oss.HTTPHeaderContentEncoding:
+			options = append(options, oss.ContentEncoding(val))
+		case oss.HTTPHeaderExpires:
+			if t, err := time.Parse(http.TimeFormat, val); err == nil {
+				options = append(options, oss.Expires(t))
+			}
+		}
+	}
+	for name := range header {
+		for _, prefix := range []string{oss.HTTPHeaderOssMetaPrefix, S3MetaPrefix} {
+			if strings.HasPrefix(strings.ToLower(name), strings.ToLower(prefix)) {
+				options = append(options, oss.Meta(name[len(prefix):], header.Get(name)))
+			}
+		}
+	}
+	return options
+}
+
+// ossStreamObjectRetry downloads the object from source and uploads it to destination in one request
+func (cc *CopyCommand) ossStreamObjectRetry(src streamSource, objectName, destBucketName, destObjectName string) error {
+	destBucket, err := cc.cpOption.destCommand.ossBucket(destBucketName)
+	if err != nil {
+		return err
+	}
+	retryTimes, _ := GetInt(OptionRetryTimes, cc.command.options)
+	for i := 1; ; i++ {
+		err := cc.streamObject(src, objectName, destBucket, destObjectName)
+		if err == nil {
+			return err
+		}
+		if int64(i) >= retryTimes {
+			return ObjectError{err, src.bucketName(), objectName}
+		}
+		cc.command.metrics.retry()
+	}
+}
+
+func (cc *CopyCommand) streamObject(src streamSource, objectName string, destBucket *oss.Bucket, destObjectName string) error {
+	body, header, err := src.open(objectName, 0, -1, "")
+	if err != nil {
+		return err
+	}
+	size, err := strconv.ParseInt(header.Get(oss.HTTPHeaderContentLength), 10, 64)
+	if err != nil {
+		body.Close()
+		return err
+	}
+
+	// the data uploaded is checked by crc64 of destination, and the data downloaded is checked by source
+	err = destBucket.PutObject(destObjectName, &io.LimitedReader{R: body, N: size}, streamMetaOptions(header)...)
+	if cerr := body.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// ossStreamCopyRetry downloads the big object from source by range and uploads it to destination by
+// multipart, the parts uploaded are recorded in checkpoint file under cpDir
+func (cc *CopyCommand) ossStreamCopyRetry(src streamSource, objectName, destBucketName, destObjectName string, partSize int64, routines int, cpDir string) error {
+	destBucket, err := cc.cpOption.destCommand.ossBucket(destBucketName)
+	if err != nil {
+		return err
+	}
+	retryTimes, _ := GetInt(OptionRetryTimes, cc.command.options)
+	for i := 1; ; i++ {
+		err := cc.streamCopy(src, objectName, destBucket, destObjectName, partSize, routines, filepath.Join(cpDir, streamCheckpointFile))
+		if err == nil {
+			return err
+		}
+		if int64(i) >= retryTimes || cc.command.canceled() {
+			return ObjectError{err, src.bucketName(), objectName}
+		}
+		cc.command.metrics.retry()
+	}
+}
+
+func (cc *CopyCommand) streamCopy(src streamSource, objectName string, destBucket *oss.Bucket, destObjectName string, partSize int64, routines int, cpFile string) error {
+	props, err := src.stat(objectName)
+	if err != nil {
+		return err
+	}
+	size, err := strconv.ParseInt(props.Get(oss.HTTPHeaderContentLength), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	cp := streamCheckpoint{
+		Dest:     CloudURLToString(destBucket.BucketName, destObjectName),
+		ETag:     props.Get(oss.HTTPHeaderEtag),
+		Size:     size,
+		PartSize: partSize,
+	}
+	if old, err := readStreamCheckpoint(cpFile); err == nil && old.UploadID != "" {
+		if old.Dest == cp.Dest && old.ETag == cp.ETag && old.Size == cp.Size && old.PartSize == cp.PartSize {
+			cp = old
+		} else {
+			destBucket.AbortMultipartUpload(oss.InitiateMultipartUploadResult{Bucket: destBucket.BucketName, Key: destObjectName, UploadID: old.UploadID})
+		}
+	}
+
+	imur := oss.InitiateMultipartUploadResult{Bucket: destBucket.BucketName, Key: destObjectName, UploadID: cp.UploadID}
+	if cp.UploadID == "" {
+		if imur, err = destBucket.InitiateMultipartUpload(destObjectName, streamMetaOptions(props)...); err != nil {
+			return err
+		}
+		cp.UploadID = imur.UploadID
+		cp.Parts = nil
+		if err := writeStreamCheckpoint(cpFile, cp); err != nil {
+			return err
+		}
+	}
+
+	uploaded := map[int]bool{}
+	for _, part := range cp.Parts {
+		uploaded[part.Number] = true
+	}
+	chParts := make(chan int, routines)
+	go func() {
+		defer close(chParts)
+		for number := 1; int64(number-1)*partSize < size; number++ {
+			if !uploaded[number] {
+				chParts <- number
+			}
+		}
+	}()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < routines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for number := range chParts {
+				mu.Lock()
+				failed := err != nil
+				mu.Unlock()
+				if failed || cc.command.canceled() {
+					continue
+				}
+				etag, perr := cc.streamPart(src, objectName, cp.ETag, destBucket, imur, number, partSize, size)
+				mu.Lock()
+				if perr != nil {
+					err = perr
+				} else {
+					// the checkpoint is only used to continue, so errors are ignored
+					cp.Parts = append(cp.Parts, streamPart{number, etag})
+					writeStreamCheckpoint(cpFile, cp)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if err != nil {
+		if serr, ok := err.(oss.ServiceError); ok && serr.Code == "NoSuchUpload" {
+			// the upload is aborted, upload from the beginning next time
+			os.Remove(cpFile)
+		}
+		return err
+	}
+	if err := cc.command.cancelError(); err != nil {
+		return err
+	}
+
+	sort.Sort(cp.Parts)
+	parts := make([]oss.UploadPart, 0, len(cp.Parts))
+	for _, part := range cp.Parts {
+		parts = append(parts, oss.UploadPart{PartNumber: part.Number, ETag: part.ETag})
+	}
+	if _, err := destBucket.CompleteMultipartUpload(imur, parts); err != nil {
+		return err
+	}
+	os.Remove(cpFile)
+	return nil
+}
+
+// streamPart downloads the part of source object by range and uploads it, the source must not be
+// changed during copy
+func (cc *CopyCommand) streamPart(src streamSource, objectName, etag string, destBucket *oss.Bucket, imur oss.InitiateMultipartUploadResult, number int, partSize, size int64) (string, error) {
+	start := int64(number-1) * partSize
+	end := start + partSize - 1
+	if end >= size {
+		end = size - 1
+	}
+	body, _, err := src.open(objectName, start, end, etag)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	// each request reports the bytes transferred from 0
+	listener := &OssProgressListener{cc, 0, 0}
+	part, err := destBucket.UploadPart(imur, body, end-start+1, number, oss.Progress(listener))
+	if err != nil {
+		return "", err
+	}
+	return part.ETag, nil
+}
+
+func readStreamCheckpoint(cpFile string) (streamCheckpoint, error) {
+	var cp streamCheckpoint
+	data, err := ioutil.ReadFile(cpFile)
+	if err != nil {
+		return cp, err
+	}
+	err = json.Unmarshal(data, &cp)
+	return cp, err
+}
+
+func writeStreamCheckpoint(cpFile string, cp streamCheckpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(cpFile), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(cpFile, data, 0600)
+}
@@ -0,0 +1,214 @@
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	. "gopkg.in/check.v1"
+)
+
+// initStreamCopyCommand inits copy command from the bucket of test account to the bucket of emulator
+func (s *OssutilCommandSuite) initStreamCopyCommand(srcURL, destURL string, server *fakeOSSServer, id, secret string, c *C) {
+	err := s.initCopyCommand(srcURL, destURL, true, true, false, 200*1024, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	destEndpoint := server.endpoint()
+	partSize := strconv.FormatInt(100*1024, 10)
+	copyCommand.command.options[OptionDestEndpoint] = &destEndpoint
+	copyCommand.command.options[OptionDestKeyID] = &id
+	copyCommand.command.options[OptionDestKeySecret] = &secret
+	copyCommand.command.options[OptionPartSize] = &partSize
+}
+
+func (s *OssutilCommandSuite) getStreamSourceBucket(bucketName string, c *C) *oss.Bucket {
+	client, err := oss.New(endpoint, accessKeyID, accessKeySecret)
+	c.Assert(err, IsNil)
+	bucket, err := client.Bucket(bucketName)
+	c.Assert(err, IsNil)
+	return bucket
+}
+
+func (s *OssutilCommandSuite) TestCopyStreamAcrossAccounts(c *C) {
+	server := newFakeOSSServer("destID", "destSecret")
+	defer server.close()
+	destClient, err := oss.New(server.endpoint(), "destID", "destSecret")
+	c.Assert(err, IsNil)
+	destBucketName := bucketNamePrefix + randLowStr(10)
+	c.Assert(destClient.CreateBucket(destBucketName), IsNil)
+	destBucket, err := destClient.Bucket(destBucketName)
+	c.Assert(err, IsNil)
+
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+	defer s.removeBucket(bucketName, true, c)
+	bucket := s.getStreamSourceBucket(bucketName, c)
+	small := randStr(100)
+	big := randStr(350 * 1024)
+	c.Assert(bucket.PutObject("dir/small", strings.NewReader(small), oss.ContentType("text/plain"), oss.Meta("Owner", "test")), IsNil)
+	c.Assert(bucket.PutObject("dir/big", strings.NewReader(big), oss.Meta("Owner", "test")), IsNil)
+
+	verify := true
+	s.initStreamCopyCommand(CloudURLToString(bucketName, "dir/"), CloudURLToString(destBucketName, "copy/"), server, "destID", "destSecret", c)
+	copyCommand.command.options[OptionVerify] = &verify
+	c.Assert(copyCommand.RunCommand(), IsNil)
+	c.Assert(copyCommand.cpOption.streamCopy, Equals, true)
+	c.Assert(copyCommand.monitor.fileNum, Equals, int64(2))
+	c.Assert(copyCommand.monitor.errNum, Equals, int64(0))
+
+	// the objects are uploaded to destination, big one by multipart
+	c.Assert(server.requestCount("PutObject"), Equals, 1)
+	c.Assert(server.requestCount("UploadPart"), Equals, 4)
+	c.Assert(server.requestCount("CompleteMultipartUpload"), Equals, 1)
+	for object, data := range map[string]string{"copy/small": small, "copy/big": big} {
+		body, err := destBucket.GetObject(object)
+		c.Assert(err, IsNil)
+		str, err := ioutil.ReadAll(body)
+		body.Close()
+		c.Assert(err, IsNil)
+		c.Assert(string(str), Equals, data)
+
+		props, err := destBucket.GetObjectDetailedMeta(object)
+		c.Assert(err, IsNil)
+		c.Assert(props.Get("X-Oss-Meta-Owner"), Equals, "test")
+	}
+	props, err := destBucket.GetObjectDetailedMeta("copy/small")
+	c.Assert(err, IsNil)
+	c.Assert(props.Get(oss.HTTPHeaderContentType), Equals, "text/plain")
+}
+
+func (s *OssutilCommandSuite) TestCopyStreamResume(c *C) {
+	server := newFakeOSSServer("resumeID", "resumeSecret")
+	defer server.close()
+	destClient, err := oss.New(server.endpoint(), "resumeID", "resumeSecret")
+	c.Assert(err, IsNil)
+	destBucketName := bucketNamePrefix + randLowStr(10)
+	c.Assert(destClient.CreateBucket(destBucketName), IsNil)
+
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+	defer s.removeBucket(bucketName, true, c)
+	bucket := s.getStreamSourceBucket(bucketName, c)
+	big := randStr(350 * 1024)
+	c.Assert(bucket.PutObject("big", strings.NewReader(big)), IsNil)
+
+	// the parts uploaded are recorded in checkpoint when complete fails
+	server.inject(fakeOSSFault{op: "CompleteMultipartUpload", status: http.StatusInternalServerError, code: "InternalError", times: 1})
+	retryTimes := "1"
+	s.initStreamCopyCommand(CloudURLToString(bucketName, "big"), CloudURLToString(destBucketName, "big"), server, "resumeID", "resumeSecret", c)
+	copyCommand.command.options[OptionRetryTimes] = &retryTimes
+	recursive := false
+	copyCommand.command.options[OptionRecursion] = &recursive
+	c.Assert(copyCommand.RunCommand(), NotNil)
+	c.Assert(server.requestCount("InitiateMultipartUpload"), Equals, 1)
+	cpFile := filepath.Join(copyCommand.formatCPFileName(CheckpointDir, CloudURLToString(bucketName, "big"), CloudURLToString(destBucketName, "big")), streamCheckpointFile)
+	cp, err := readStreamCheckpoint(cpFile)
+	c.Assert(err, IsNil)
+	c.Assert(len(cp.Parts), Equals, 4)
+
+	// continue with the same upload
+	s.initStreamCopyCommand(CloudURLToString(bucketName, "big"), CloudURLToString(destBucketName, "big"), server, "resumeID", "resumeSecret", c)
+	copyCommand.command.options[OptionRecursion] = &recursive
+	c.Assert(copyCommand.RunCommand(), IsNil)
+	c.Assert(server.requestCount("InitiateMultipartUpload"), Equals, 1)
+	c.Assert(server.requestCount("UploadPart"), Equals, 4)
+	c.Assert(server.requestCount("CompleteMultipartUpload"), Equals, 2)
+	_, err = os.Stat(cpFile)
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	destBucket, err := destClient.Bucket(destBucketName)
+	c.Assert(err, IsNil)
+	props, err := destBucket.GetObjectDetailedMeta("big")
+	c.Assert(err, IsNil)
+	c.Assert(props.Get(oss.HTTPHeaderContentLength), Equals, fmt.Sprint(len(big)))
+}
+
+func (s *OssutilCommandSuite) TestCopyDestCommand(c *C) {
+	cc := CopyCommand{command: Command{options: OptionMapType{}, configOptions: OptionMapType{}, explicitEndpoint: true}}
+	for name, val := range map[string]string{OptionEndpoint: "oss-cn-hangzhou.aliyuncs.com", OptionAccessKeyID: "srcID", OptionAccessKeySecret: "srcSecret", OptionSTSToken: "srcToken"} {
+		opval := val
+		cc.command.options[name] = &opval
+	}
+	dest, err := cc.newDestCommand()
+	c.Assert(err, IsNil)
+	c.Assert(dest == &cc.command, Equals, true)
+
+	// only the endpoint is different
+	destEndpoint := "oss-cn-beijing.aliyuncs.com"
+	cc.command.options[OptionDestEndpoint] = &destEndpoint
+	dest, err = cc.newDestCommand()
+	c.Assert(err, IsNil)
+	c.Assert(dest.explicitEndpoint, Equals, true)
+	c.Assert(*dest.options[OptionEndpoint].(*string), Equals, destEndpoint)
+	c.Assert(*dest.options[OptionAccessKeyID].(*string), Equals, "srcID")
+	c.Assert(*dest.options[OptionSTSToken].(*string), Equals, "srcToken")
+	c.Assert(*cc.command.options[OptionEndpoint].(*string), Equals, "oss-cn-hangzhou.aliyuncs.com")
+	cc.cpOption.destCommand = dest
+	stream, err := cc.needStreamCopy("bucket1", "bucket2")
+	c.Assert(err, IsNil)
+	c.Assert(stream, Equals, true)
+
+	// the AccessKey in dest config file
+	destConfigFile := "ossutil_test_dest_config" + randStr(5)
+	s.createFile(destConfigFile, "[Credentials]\naccessKeyID = destID\naccessKeySecret = destSecret\n", c)
+	defer os.Remove(destConfigFile)
+	cc.command.options[OptionDestConfigFile] = &destConfigFile
+	destEndpoint = "oss-cn-hangzhou-internal.aliyuncs.com"
+	dest, err = cc.newDestCommand()
+	c.Assert(err, IsNil)
+	c.Assert(*dest.options[OptionAccessKeyID].(*string), Equals, "destID")
+	c.Assert(*dest.options[OptionAccessKeySecret].(*string), Equals, "destSecret")
+	c.Assert(*dest.options[OptionSTSToken].(*string), Equals, "")
+	cc.cpOption.destCommand = dest
+	stream, err = cc.needStreamCopy("bucket1", "bucket2")
+	c.Assert(err, IsNil)
+	c.Assert(stream, Equals, true)
+
+	// the same account in the same region
+	sameID := "srcID"
+	cc.command.options[OptionDestKeyID] = &sameID
+	dest, err = cc.newDestCommand()
+	c.Assert(err, IsNil)
+	cc.cpOption.destCommand = dest
+	stream, err = cc.needStreamCopy("bucket1", "bucket2")
+	c.Assert(err, IsNil)
+	c.Assert(stream, Equals, false)
+
+	notExist := "notexist" + randStr(5)
+	cc.command.options[OptionDestConfigFile] = &notExist
+	_, err = cc.newDestCommand()
+	c.Assert(err, NotNil)
+}
+
+func (s *OssutilCommandSuite) TestCopyDestOptionsOnlyForCopy(c *C) {
+	bucketName := bucketNamePrefix + randLowStr(10)
+	fileName := "destOptionFile" + randStr(5)
+	s.createFile(fileName, randStr(10), c)
+	defer os.Remove(fileName)
+
+	destEndpoint := "oss-cn-beijing.aliyuncs.com"
+	err := s.initCopyCommand(fileName, CloudURLToString(bucketName, "object"), false, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	copyCommand.command.options[OptionDestEndpoint] = &destEndpoint
+	err = copyCommand.RunCommand()
+	c.Assert(err, NotNil)
+	c.Assert(strings.Contains(err.Error(), "only copy between oss support option"), Equals, true)
+}
+
+func (s *OssutilCommandSuite) TestCopyDestKeyPair(c *C) {
+	srcURL := CloudURLToString(bucketNamePrefix+randLowStr(10), "object")
+	destURL := CloudURLToString(bucketNamePrefix+randLowStr(10), "object")
+	for _, name := range []string{OptionDestKeyID, OptionDestKeySecret} {
+		err := s.initCopyCommand(srcURL, destURL, false, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+		c.Assert(err, IsNil)
+		str := "destKey"
+		copyCommand.command.options[name] = &str
+		err = copyCommand.RunCommand()
+		c.Assert(err, NotNil)
+		c.Assert(strings.Contains(err.Error(), "must be used together"), Equals, true)
+	}
+}
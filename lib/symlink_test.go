@@ -3,6 +3,9 @@ package lib
 import (
 	"fmt"
 	"net/url"
+	"os"
+	"strconv"
+	"strings"
 
 	. "gopkg.in/check.v1"
 )
@@ -198,3 +201,58 @@ func (s *OssutilCommandSuite) TestReadSymlink(c *C) {
 
 	s.removeBucket(bucketName, true, c)
 }
+
+func (s *OssutilCommandSuite) TestBatchSymlink(c *C) {
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+
+	s.createFile(uploadFileName, randStr(10), c)
+	s.putObject(bucketName, "dir/object1", uploadFileName, c)
+	s.putObject(bucketName, "dir/sub object2", uploadFileName, c)
+
+	// create symlinks with manifest
+	manifest := "manifest" + randStr(5)
+	s.createFile(manifest, "# links\nlink1 object1\n\nsub/link2\tsub object2\nlink3 notexist\n", c)
+	err := s.rawBatchSymlink("create-symlink", CloudURLToString(bucketName, "dir/"), OptionMapType{"manifest": &manifest})
+	c.Assert(err, IsNil)
+
+	linkStat := s.readSymlink(CloudURLToString(bucketName, "dir/sub/link2"), c)
+	c.Assert(linkStat["X-Oss-Symlink-Target"], Equals, "dir/sub object2")
+
+	// invalid manifest
+	s.createFile(manifest, "link1 object1 object2\n", c)
+	err = s.rawBatchSymlink("create-symlink", CloudURLToString(bucketName, "dir/"), OptionMapType{"manifest": &manifest})
+	c.Assert(err, NotNil)
+
+	// read symlinks recursively
+	testResultFile, _ = os.OpenFile(resultPath, os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0664)
+	out := os.Stdout
+	os.Stdout = testResultFile
+	recursive := true
+	err = s.rawBatchSymlink("read-symlink", CloudURLToString(bucketName, "dir/"), OptionMapType{"recursive": &recursive})
+	os.Stdout = out
+	c.Assert(err, IsNil)
+
+	result := s.readFile(resultPath, c)
+	c.Assert(strings.Contains(result, CloudURLToString(bucketName, "dir/link1")+" -> dir/object1\n"), Equals, true)
+	c.Assert(strings.Contains(result, CloudURLToString(bucketName, "dir/link3")+" -> dir/notexist (dangling)"), Equals, true)
+	c.Assert(strings.Contains(result, "3 symlinks, 1 dangling."), Equals, true)
+	c.Assert(strings.Contains(result, "dir/object1 ->"), Equals, false)
+
+	os.Remove(manifest)
+	os.Remove(resultPath)
+	s.removeBucket(bucketName, true, c)
+}
+
+func (s *OssutilCommandSuite) rawBatchSymlink(command, cloudURL string, options OptionMapType) error {
+	str := ""
+	routines := strconv.Itoa(Routines)
+	options["endpoint"] = &str
+	options["accessKeyID"] = &str
+	options["accessKeySecret"] = &str
+	options["stsToken"] = &str
+	options["configFile"] = &configFile
+	options["routines"] = &routines
+	_, err := cm.RunCommand(command, []string{cloudURL}, options)
+	return err
+}
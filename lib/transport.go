@@ -0,0 +1,171 @@
+package lib
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// transportOptions are the options deciding how to connect to oss
+var transportOptions = []string{
+	OptionProxyHost,
+	OptionProxyUser,
+	OptionProxyPwd,
+	OptionCAFile,
+	OptionSkipVerify,
+	OptionForceHTTPS,
+	OptionConnectTimeout,
+	OptionReadTimeout,
+}
+
+// deadlineConn fails the read or write if no data is transferred in timeout
+type deadlineConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *deadlineConn) Read(b []byte) (int, error) {
+	c.SetReadDeadline(time.Now().Add(c.timeout))
+	return c.Conn.Read(b)
+}
+
+func (c *deadlineConn) Write(b []byte) (int, error) {
+	c.SetWriteDeadline(time.Now().Add(c.timeout))
+	return c.Conn.Write(b)
+}
+
+// timeouts returns the connect and read timeout in seconds
+func (cmd *Command) timeouts() (int64, int64) {
+	connectTimeout, err := GetInt(OptionConnectTimeout, cmd.options)
+	if err != nil {
+		connectTimeout = DefaultConnectTimeout
+	}
+	readTimeout, err := GetInt(OptionReadTimeout, cmd.options)
+	if err != nil {
+		readTimeout = DefaultReadTimeout
+	}
+	return connectTimeout, readTimeout
+}
+
+// httpsEndpoint returns the endpoint with https scheme if --force-https is specified
+func (cmd *Command) httpsEndpoint(endpoint string) string {
+	if forceHTTPS, _ := GetBool(OptionForceHTTPS, cmd.options); !forceHTTPS {
+		return endpoint
+	}
+	lower := strings.ToLower(endpoint)
+	if strings.HasPrefix(lower, "https://") {
+		return endpoint
+	}
+	if strings.HasPrefix(lower, "http://") {
+		endpoint = endpoint[len("http://"):]
+	}
+	return "https://" + endpoint
+}
+
+// proxyURL returns the url of proxy server with the user, it returns nil if proxy is not specified
+func (cmd *Command) proxyURL() (*url.URL, error) {
+	proxyHost, _ := GetString(OptionProxyHost, cmd.options)
+	if proxyHost == "" {
+		return nil, nil
+	}
+	if !strings.Contains(proxyHost, "://") {
+		proxyHost = "http://" + proxyHost
+	}
+	proxy, err := url.Parse(proxyHost)
+	if err != nil || proxy.Host == "" {
+		return nil, fmt.Errorf("invalid proxy host: %s", proxyHost)
+	}
+
+	proxyUser, _ := GetString(OptionProxyUser, cmd.options)
+	proxyPwd, _ := GetString(OptionProxyPwd, cmd.options)
+	if proxyUser != "" {
+		proxy.User = url.UserPassword(proxyUser, proxyPwd)
+	}
+	return proxy, nil
+}
+
+// tlsConfig returns the tls config with the CA file, it returns nil if no tls option is specified
+func (cmd *Command) tlsConfig() (*tls.Config, error) {
+	caFile, _ := GetString(OptionCAFile, cmd.options)
+	skipVerify, _ := GetBool(OptionSkipVerify, cmd.options)
+	if caFile == "" && !skipVerify {
+		return nil, nil
+	}
+
+	config := &tls.Config{InsecureSkipVerify: skipVerify}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file error: %s", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate is found in CA file: %s", caFile)
+		}
+	}
+	return config, nil
+}
+
+// httpClient returns the http client which connects through the proxy and with the tls config.
+// It returns nil if neither is specified, then the transport of oss sdk is used.
+func (cmd *Command) httpClient() (*http.Client, error) {
+	proxy, err := cmd.proxyURL()
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := cmd.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if proxy == nil && tlsConfig == nil {
+		return nil, nil
+	}
+	return &http.Client{Transport: cmd.newTransport(proxy, tlsConfig)}, nil
+}
+
+// newTransport returns the transport with the connect and read timeout
+func (cmd *Command) newTransport(proxy *url.URL, tlsConfig *tls.Config) *http.Transport {
+	connectTimeout, readTimeout := cmd.timeouts()
+	transport := &http.Transport{
+		Dial: func(network, addr string) (net.Conn, error) {
+			conn, err := net.DialTimeout(network, addr, time.Duration(connectTimeout)*time.Second)
+			if err != nil {
+				return nil, err
+			}
+			return &deadlineConn{conn, time.Duration(readTimeout) * time.Second}, nil
+		},
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   time.Duration(connectTimeout) * time.Second,
+		ResponseHeaderTimeout: time.Duration(readTimeout) * time.Second,
+		MaxIdleConnsPerHost:   100,
+	}
+	if proxy != nil {
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	return transport
+}
+
+// transportKey returns the values of transport options, the clients are shared only if they're the same
+func (cmd *Command) transportKey() string {
+	vals := make([]string, 0, len(transportOptions))
+	for _, name := range transportOptions {
+		switch val := cmd.options[name].(type) {
+		case *string:
+			vals = append(vals, *val)
+		case *bool:
+			vals = append(vals, strconv.FormatBool(*val))
+		case *int64:
+			vals = append(vals, strconv.FormatInt(*val, 10))
+		default:
+			vals = append(vals, "")
+		}
+	}
+	return strings.Join(vals, "\n")
+}
@@ -24,13 +24,15 @@ var specChineseUpdate = SpecText{
 	paramText: "[options]",
 
 	syntaxText: ` 
-    ossutil update [-f] 
+    ossutil update [-f] [--proxy-host host] [--proxy-user user] [--proxy-pwd pwd]
 `,
 
 	detailHelpText: ` 
     该命令检查当前ossutil的版本与最新版本，输出两者的版本号，如果有更新版本，询问是否
     进行升级。如果指定了--force选项，则不询问，当有可用更新时，直接升级。
 
+    如果指定了--proxy-host选项（或者在配置文件中配置了proxyHost），通过该代理服务器下载。
+
 `,
 
 	sampleText: ` 
@@ -46,13 +48,16 @@ var specEnglishUpdate = SpecText{
 	paramText: "[options]",
 
 	syntaxText: ` 
-    ossutil update [-f]
+    ossutil update [-f] [--proxy-host host] [--proxy-user user] [--proxy-pwd pwd]
 `,
 
 	detailHelpText: ` 
     The command check version of current ossutil and get the latest version, output the 
     versions, if any updated version exists, the command ask you for upgrading. If --force 
     option is specified, the command upgrade without asking. 
+
+    If --proxy-host option is specified(or proxyHost is configured in config file), the
+    command downloads through the proxy server.
 `,
 
 	sampleText: ` 
@@ -78,6 +83,9 @@ var updateCommand = UpdateCommand{
 		validOptionNames: []string{
 			OptionForce,
 			OptionRetryTimes,
+			OptionProxyHost,
+			OptionProxyUser,
+			OptionProxyPwd,
 			OptionLanguage,
 		},
 	},
@@ -142,7 +150,7 @@ func (uc *UpdateCommand) RunCommand() error {
 		}
 
 		var val string
-		if _, err := fmt.Scanln(&val); err == nil && (strings.EqualFold(val, "yes") || strings.EqualFold(val, "y")) {
+		if _, err := scanln(&val); err == nil && (strings.EqualFold(val, "yes") || strings.EqualFold(val, "y")) {
 			return uc.updateVersion(version, language)
 		}
 
@@ -197,7 +205,14 @@ func (uc *UpdateCommand) anonymousGetToFileRetry(bucketName, objectName, filePat
 }
 
 func (uc *UpdateCommand) ossAnonymousGetToFile(host, filePath string) error {
-	response, err := http.Get(host)
+	client, err := uc.command.httpClient()
+	if err != nil {
+		return err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Get(host)
 	if err != nil {
 		return err
 	}
@@ -18,6 +18,8 @@ func (s *OssutilCommandSuite) rawUpdate(force bool, language string) (bool, erro
 }
 
 func (s *OssutilCommandSuite) TestUpdate(c *C) {
+	skipUpdateOnFakeOSS(c)
+
 	showElapse, err := s.rawUpdate(false, "ch")
 	c.Assert(err, IsNil)
 	c.Assert(showElapse, Equals, false)
@@ -40,6 +42,8 @@ func (s *OssutilCommandSuite) TestUpdate(c *C) {
 }
 
 func (s *OssutilCommandSuite) TestUpdateDiffVersion(c *C) {
+	skipUpdateOnFakeOSS(c)
+
 	// error get lastest version
 	ue := vUpdateBucket
 	vUpdateBucket = "abc"
@@ -80,6 +84,8 @@ func (s *OssutilCommandSuite) TestRevertRename(c *C) {
 }
 
 func (s *OssutilCommandSuite) TestDownloadLastestBinary(c *C) {
+	skipUpdateOnFakeOSS(c)
+
 	tempBinaryFile := ".ossutil_test_update.temp"
 	err := updateCommand.getBinary(tempBinaryFile, "1.0.0.Beta")
 	c.Assert(err, IsNil)
@@ -107,3 +107,14 @@ func getSizeString(size int64) string {
 	}
 	return fmt.Sprintf("%s%s", prefix, strings.Join(strList, ","))
 }
+
+// isNotExist shows if the error means the object does not exist
+func isNotExist(err error) bool {
+	if oerr, ok := err.(ObjectError); ok {
+		err = oerr.err
+	}
+	if serr, ok := err.(oss.ServiceError); ok {
+		return serr.StatusCode == 404
+	}
+	return false
+}
@@ -0,0 +1,385 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// watchTick is the interval to check the changes which are quiet enough to upload
+const watchTick = 200 * time.Millisecond
+
+// watchEvent is a change of the watched directory, path is the file or directory changed
+type watchEvent struct {
+	path  string
+	isDir bool
+}
+
+// fsWatcher reports the changes of a directory tree
+type fsWatcher interface {
+	// events returns the channel of changes, it's closed if the watcher fails
+	events() <-chan watchEvent
+
+	// err returns the reason of failure after the events channel is closed
+	err() error
+
+	close()
+}
+
+// watchItem is a changed path waiting to be uploaded or deleted at readyAt
+type watchItem struct {
+	path    string
+	isDir   bool
+	readyAt time.Time
+	retries int
+}
+
+// watchTask is the batch item of a watch round
+type watchTask struct {
+	item   *watchItem
+	file   fileInfoType
+	remove bool
+}
+
+// newWatcher watches root by file system notification if it's supported, otherwise polls root
+// every --poll-interval, which forces polling if it's specified
+func (cc *CopyCommand) newWatcher(root string) (fsWatcher, error) {
+	if cc.cpOption.pollInterval <= 0 {
+		if watcher, err := newNotifyWatcher(root, cc.walkFiles); err == nil {
+			return watcher, nil
+		}
+	}
+	interval := cc.cpOption.pollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	return newPollWatcher(root, time.Duration(interval)*time.Second, cc.walkFiles)
+}
+
+// watchFiles uploads the directory, then keeps uploading the files changed in it until the command
+// is interrupted. A change is dealed after it's quiet for --quiet-period, so that a file being
+// written is uploaded once. The failed files are retried later with backoff.
+func (cc *CopyCommand) watchFiles(srcURL FileURL, destURL CloudURL) error {
+	root := filepath.Clean(srcURL.ToString())
+	f, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !f.IsDir() {
+		return fmt.Errorf("invalid url: %s, option \"%s\" can only be used to upload directory", srcURL.ToString(), OptionWatch)
+	}
+	if err := destURL.checkObjectPrefix(); err != nil {
+		return err
+	}
+
+	bucket, err := cc.command.ossBucket(destURL.bucket)
+	if err != nil {
+		return err
+	}
+	srcURLList := []StorageURLer{srcURL}
+	if destURL, err = cc.adjustDestURLForUpload(srcURLList, destURL); err != nil {
+		return err
+	}
+
+	// watch before the first upload, so that the changes during it are not missed
+	watcher, err := cc.newWatcher(root)
+	if err != nil {
+		return err
+	}
+	defer watcher.close()
+
+	go cc.fileStatistic(srcURLList)
+	source := cc.fileSource(srcURLList)
+	failed, err := cc.watchBatch(bucket, destURL, func(send func(batchItem) bool) error {
+		return source(func(item batchItem) bool {
+			file := item.value.(fileInfoType)
+			isDir := strings.HasSuffix(file.filePath, string(os.PathSeparator))
+			return send(batchItem{"", watchTask{item: &watchItem{path: filepath.Join(file.dir, file.filePath), isDir: isDir}, file: file}})
+		})
+	})
+	if err != nil {
+		cc.finishProgress(errExit)
+		return err
+	}
+
+	// the changed files overwrite the objects uploaded before
+	cc.cpOption.force = true
+
+	quiet := time.Duration(cc.cpOption.quietPeriod) * time.Second
+	pending := map[string]*watchItem{}
+	retry := func(failed []*watchItem) {
+		for _, item := range failed {
+			// a new change of the path is dealed as a fresh one
+			if _, ok := pending[item.path]; ok || item.retries >= WatchMaxRetry {
+				continue
+			}
+			item.retries++
+			item.readyAt = time.Now().Add(watchRetryInterval(item.retries))
+			pending[item.path] = item
+		}
+	}
+	retry(failed)
+
+	ticker := time.NewTicker(watchTick)
+	defer ticker.Stop()
+	var done <-chan struct{}
+	if cc.command.ctx != nil {
+		done = cc.command.ctx.Done()
+	}
+	for {
+		select {
+		case event, ok := <-watcher.events():
+			if !ok {
+				cc.finishProgress(errExit)
+				return fmt.Errorf("watch directory %s error: %v", root, watcher.err())
+			}
+			item, ok := pending[event.path]
+			if !ok {
+				item = &watchItem{path: event.path}
+				pending[event.path] = item
+			}
+			item.isDir = item.isDir || event.isDir
+			item.readyAt = time.Now().Add(quiet)
+			item.retries = 0
+		case now := <-ticker.C:
+			items := []*watchItem{}
+			for path, item := range pending {
+				if !item.readyAt.After(now) {
+					items = append(items, item)
+					delete(pending, path)
+				}
+			}
+			if len(items) == 0 {
+				continue
+			}
+			failed, err := cc.watchBatch(bucket, destURL, sliceSource(cc.watchTasks(root, items)...))
+			if err != nil {
+				cc.command.printf("\n%s\n", err.Error())
+			}
+			retry(failed)
+		case <-done:
+			cc.finishProgress(normalExit)
+			return nil
+		}
+	}
+}
+
+// watchRetryInterval doubles the interval for each retry, up to WatchMaxRetryInterval seconds
+func watchRetryInterval(retries int) time.Duration {
+	interval := time.Second << uint(retries-1)
+	if max := time.Duration(WatchMaxRetryInterval) * time.Second; retries > 16 || interval > max {
+		return max
+	}
+	return interval
+}
+
+// watchTasks makes the tasks of the changed paths: the paths which still exist are uploaded, and
+// the objects of the removed ones are deleted if --delete is specified
+func (cc *CopyCommand) watchTasks(root string, items []*watchItem) []batchItem {
+	tasks := []batchItem{}
+	for _, item := range items {
+		rel, err := filepath.Rel(root, item.path)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+
+		task := watchTask{item: item, file: fileInfoType{rel, root}}
+		f, err := os.Stat(item.path)
+		if err != nil && os.IsNotExist(err) {
+			if !cc.cpOption.watchDelete {
+				continue
+			}
+			task.remove = true
+		} else if err == nil {
+			item.isDir = f.IsDir()
+		}
+		if item.isDir {
+			task.file.filePath += string(os.PathSeparator)
+		}
+		if !cc.filterFile(task.file, cc.cpOption.cpDir) {
+			continue
+		}
+
+		if !task.remove && item.isDir {
+			cc.monitor.updateScanNum(1)
+		} else if !task.remove {
+			size := int64(0)
+			if f != nil {
+				size = f.Size()
+			}
+			cc.monitor.updateScanSizeNum(size, 1)
+		}
+		tasks = append(tasks, batchItem{"", task})
+	}
+	return tasks
+}
+
+// watchBatch deals the tasks from source, it returns the failed items, the errors of items are
+// reported and the job always continues
+func (cc *CopyCommand) watchBatch(bucket *oss.Bucket, destURL CloudURL, source batchSource) ([]*watchItem, error) {
+	var mu sync.Mutex
+	failed := []*watchItem{}
+	cc.cpOption.ctnu = true
+	engine := cc.command.newBatchEngine(&cc.cpOption.batchOptionType, cc.cpOption.routines, source, func(item batchItem) (string, error) {
+		task := item.value.(watchTask)
+		var msg string
+		var err error
+		if task.remove {
+			msg, err = cc.removeWatchedObject(bucket, destURL, task.file, task.item.isDir)
+		} else {
+			msg, err = cc.uploadItem(bucket, destURL, task.file)
+		}
+		if err != nil {
+			mu.Lock()
+			failed = append(failed, task.item)
+			mu.Unlock()
+		}
+		return msg, err
+	})
+	err := engine.run()
+	return failed, err
+}
+
+// removeWatchedObject deletes the object of the removed file, the objects under the prefix of
+// removed directory are deleted too, since the files moved out with it are not reported
+func (cc *CopyCommand) removeWatchedObject(bucket *oss.Bucket, destURL CloudURL, file fileInfoType, isDir bool) (string, error) {
+	objectName := cc.makeObjectName(destURL, file)
+	msg := fmt.Sprintf("delete %s", CloudURLToString(bucket.BucketName, objectName))
+	if !isDir {
+		return msg, cc.ossDeleteObjectRetry(bucket, objectName)
+	}
+
+	var derr error
+	prefixURL := CloudURL{bucket: bucket.BucketName, object: objectName}
+	err := cc.command.objectSource(bucket, prefixURL, nil, nil)(func(item batchItem) bool {
+		derr = cc.ossDeleteObjectRetry(bucket, item.key)
+		return derr == nil
+	})
+	if err != nil {
+		return msg, err
+	}
+	if derr != nil {
+		return msg, derr
+	}
+	return msg, cc.ossDeleteObjectRetry(bucket, objectName)
+}
+
+func (cc *CopyCommand) ossDeleteObjectRetry(bucket *oss.Bucket, object string) error {
+	retryTimes, _ := GetInt(OptionRetryTimes, cc.command.options)
+	for i := 1; ; i++ {
+		err := bucket.DeleteObject(object)
+		if err == nil {
+			return err
+		}
+		if int64(i) >= retryTimes {
+			return ObjectError{err, bucket.BucketName, object}
+		}
+		cc.command.metrics.retry()
+	}
+}
+
+// fileState is the state of a file compared by pollWatcher
+type fileState struct {
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+// pollWatcher finds the changes by walking the directory tree every interval
+type pollWatcher struct {
+	root     string
+	interval time.Duration
+	walk     func(root string, walkFn filepath.WalkFunc) error
+	files    map[string]fileState
+	ch       chan watchEvent
+	done     chan struct{}
+	once     sync.Once
+}
+
+func newPollWatcher(root string, interval time.Duration, walk func(root string, walkFn filepath.WalkFunc) error) (fsWatcher, error) {
+	w := &pollWatcher{root: root, interval: interval, walk: walk, ch: make(chan watchEvent, ChannelBuf), done: make(chan struct{})}
+	files, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+	w.files = files
+	go w.run()
+	return w, nil
+}
+
+func (w *pollWatcher) events() <-chan watchEvent {
+	return w.ch
+}
+
+func (w *pollWatcher) err() error {
+	return nil
+}
+
+func (w *pollWatcher) close() {
+	w.once.Do(func() {
+		close(w.done)
+	})
+}
+
+// scan walks the tree, the files removed during walking are ignored
+func (w *pollWatcher) scan() (map[string]fileState, error) {
+	files := map[string]fileState{}
+	err := w.walk(w.root, func(fpath string, f os.FileInfo, err error) error {
+		if f == nil {
+			if fpath == w.root {
+				return err
+			}
+			return nil
+		}
+		fpath = filepath.Clean(fpath)
+		if fpath != w.root {
+			files[fpath] = fileState{f.Size(), f.ModTime(), f.IsDir()}
+		}
+		return nil
+	})
+	return files, err
+}
+
+func (w *pollWatcher) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.done:
+			return
+		}
+
+		files, err := w.scan()
+		if err != nil {
+			continue
+		}
+		events := []watchEvent{}
+		for fpath, state := range files {
+			// the modification time of directory changes with its entries, which are reported
+			old, ok := w.files[fpath]
+			if !ok || (!state.isDir && (old.size != state.size || !old.modTime.Equal(state.modTime))) {
+				events = append(events, watchEvent{fpath, state.isDir})
+			}
+		}
+		for fpath, old := range w.files {
+			if _, ok := files[fpath]; !ok {
+				events = append(events, watchEvent{fpath, old.isDir})
+			}
+		}
+		w.files = files
+
+		for _, event := range events {
+			select {
+			case w.ch <- event:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// +build linux
+
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	notifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE
+
+	// notifyReadInterval is the interval to read the events when there are none
+	notifyReadInterval = 100 * time.Millisecond
+)
+
+// notifyWatcher watches the directory tree by inotify, each directory in it is watched, and the
+// directories created later are added when they are reported
+type notifyWatcher struct {
+	fd   int
+	root string
+	walk func(root string, walkFn filepath.WalkFunc) error
+	wds  map[int32]string
+	ch   chan watchEvent
+	done chan struct{}
+	once sync.Once
+	rerr error
+}
+
+func newNotifyWatcher(root string, walk func(root string, walkFn filepath.WalkFunc) error) (fsWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	w := &notifyWatcher{fd: fd, root: root, walk: walk, wds: map[int32]string{}, ch: make(chan watchEvent, ChannelBuf), done: make(chan struct{})}
+	if err := w.addTree(root, false); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	go w.run()
+	return w, nil
+}
+
+func (w *notifyWatcher) events() <-chan watchEvent {
+	return w.ch
+}
+
+func (w *notifyWatcher) err() error {
+	return w.rerr
+}
+
+func (w *notifyWatcher) close() {
+	w.once.Do(func() {
+		close(w.done)
+	})
+}
+
+// addTree watches the directories under dir, the entries found are reported if report is true,
+// since they may be created before the directory is watched
+func (w *notifyWatcher) addTree(dir string, report bool) error {
+	return w.walk(dir, func(fpath string, f os.FileInfo, err error) error {
+		if f == nil {
+			if report {
+				return nil
+			}
+			return err
+		}
+		fpath = filepath.Clean(fpath)
+		if f.IsDir() {
+			wd, err := syscall.InotifyAddWatch(w.fd, fpath, notifyMask)
+			if err != nil && !report {
+				return os.NewSyscallError("inotify_add_watch", err)
+			}
+			if err == nil {
+				w.wds[int32(wd)] = fpath
+			}
+		}
+		if report && fpath != filepath.Clean(dir) && !w.send(watchEvent{fpath, f.IsDir()}) {
+			return errBatchStopped
+		}
+		return nil
+	})
+}
+
+func (w *notifyWatcher) send(event watchEvent) bool {
+	select {
+	case w.ch <- event:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+func (w *notifyWatcher) run() {
+	defer syscall.Close(w.fd)
+	defer close(w.ch)
+
+	buf := make([]byte, 64*1024)
+	for {
+		select {
+		case <-w.done:
+			return
+		default:
+		}
+
+		n, err := syscall.Read(w.fd, buf)
+		if err == syscall.EAGAIN || err == syscall.EINTR {
+			select {
+			case <-w.done:
+				return
+			case <-time.After(notifyReadInterval):
+			}
+			continue
+		}
+		if err != nil {
+			w.rerr = os.NewSyscallError("read", err)
+			return
+		}
+		if !w.parse(buf[:n]) {
+			return
+		}
+	}
+}
+
+// parse reports the events in buf, it returns false if the watcher is closed
+func (w *notifyWatcher) parse(buf []byte) bool {
+	for offset := 0; offset+syscall.SizeofInotifyEvent <= len(buf); {
+		event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+		start := offset + syscall.SizeofInotifyEvent
+		offset = start + int(event.Len)
+		if offset > len(buf) {
+			break
+		}
+		name := strings.TrimRight(string(buf[start:offset]), "\x00")
+
+		if event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+			// the events are lost, report all the entries again
+			if w.addTree(w.root, true) == errBatchStopped {
+				return false
+			}
+			continue
+		}
+		if event.Mask&syscall.IN_IGNORED != 0 {
+			delete(w.wds, event.Wd)
+			continue
+		}
+		dir, ok := w.wds[event.Wd]
+		if !ok || name == "" {
+			continue
+		}
+
+		fpath := filepath.Join(dir, name)
+		isDir := event.Mask&syscall.IN_ISDIR != 0
+		if !w.send(watchEvent{fpath, isDir}) {
+			return false
+		}
+		if isDir && event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+			if w.addTree(fpath, true) == errBatchStopped {
+				return false
+			}
+		}
+	}
+	return true
+}
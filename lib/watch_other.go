@@ -0,0 +1,14 @@
+// +build !linux
+
+package lib
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+)
+
+// newNotifyWatcher is not supported on the platform, the directory is watched by polling
+func newNotifyWatcher(root string, walk func(root string, walkFn filepath.WalkFunc) error) (fsWatcher, error) {
+	return nil, fmt.Errorf("file system notification is not supported on %s", runtime.GOOS)
+}
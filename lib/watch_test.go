@@ -0,0 +1,207 @@
+package lib
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	. "gopkg.in/check.v1"
+)
+
+// startWatch runs cp --watch in background without quiet period, the function returned interrupts
+// the command and returns its error
+func (s *OssutilCommandSuite) startWatch(dir, destURL string, options OptionMapType, c *C) func() error {
+	err := s.initCopyCommand(dir, destURL, true, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	watch := true
+	quietPeriod := "0"
+	copyCommand.command.options[OptionWatch] = &watch
+	copyCommand.command.options[OptionQuietPeriod] = &quietPeriod
+	for name, val := range options {
+		copyCommand.command.options[name] = val
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	copyCommand.command.ctx = ctx
+	chErr := make(chan error, 1)
+	go func() {
+		chErr <- copyCommand.RunCommand()
+	}()
+	return func() error {
+		cancel()
+		return <-chErr
+	}
+}
+
+// waitObjectData waits until the object is uploaded with data
+func waitObjectData(bucket *oss.Bucket, object, data string) bool {
+	for i := 0; i < 100; i++ {
+		if body, err := bucket.GetObject(object); err == nil {
+			str, _ := ioutil.ReadAll(body)
+			body.Close()
+			if string(str) == data {
+				return true
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return false
+}
+
+// waitObjectDeleted waits until the object is deleted
+func waitObjectDeleted(bucket *oss.Bucket, object string) bool {
+	for i := 0; i < 100; i++ {
+		if exist, err := bucket.IsObjectExist(object); err == nil && !exist {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return false
+}
+
+func (s *OssutilCommandSuite) TestCopyWatch(c *C) {
+	dir := randStr(10)
+	c.Assert(os.MkdirAll(dir, 0755), IsNil)
+	defer os.RemoveAll(dir)
+	s.createFile(dir+"/old", "old", c)
+
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+	defer s.removeBucket(bucketName, true, c)
+	bucket := s.getStreamSourceBucket(bucketName, c)
+
+	stop := s.startWatch(dir, CloudURLToString(bucketName, "watch/"), nil, c)
+	c.Assert(waitObjectData(bucket, "watch/old", "old"), Equals, true)
+
+	// created, modified and the files in new directory are uploaded
+	s.createFile(dir+"/new", "new", c)
+	s.createFile(dir+"/old", "modified", c)
+	c.Assert(os.MkdirAll(dir+"/sub/deep", 0755), IsNil)
+	s.createFile(dir+"/sub/deep/file", "deep", c)
+	c.Assert(waitObjectData(bucket, "watch/new", "new"), Equals, true)
+	c.Assert(waitObjectData(bucket, "watch/old", "modified"), Equals, true)
+	c.Assert(waitObjectData(bucket, "watch/sub/deep/file", "deep"), Equals, true)
+	c.Assert(waitObjectData(bucket, "watch/sub/", ""), Equals, true)
+
+	// the object is kept without --delete
+	c.Assert(os.Remove(dir+"/new"), IsNil)
+	s.createFile(dir+"/last", "last", c)
+	c.Assert(waitObjectData(bucket, "watch/last", "last"), Equals, true)
+	c.Assert(waitObjectData(bucket, "watch/new", "new"), Equals, true)
+
+	c.Assert(stop(), Equals, context.Canceled)
+	c.Assert(copyCommand.monitor.errNum, Equals, int64(0))
+}
+
+func (s *OssutilCommandSuite) TestCopyWatchPollDelete(c *C) {
+	dir := randStr(10)
+	c.Assert(os.MkdirAll(dir+"/sub", 0755), IsNil)
+	defer os.RemoveAll(dir)
+	s.createFile(dir+"/file", "file", c)
+	s.createFile(dir+"/sub/file", "sub", c)
+
+	bucketName := bucketNamePrefix + randLowStr(10)
+	s.putBucket(bucketName, c)
+	defer s.removeBucket(bucketName, true, c)
+	bucket := s.getStreamSourceBucket(bucketName, c)
+
+	pollInterval := "1"
+	remove := true
+	stop := s.startWatch(dir, CloudURLToString(bucketName, "poll"), OptionMapType{OptionPollInterval: &pollInterval, OptionDelete: &remove}, c)
+	c.Assert(waitObjectData(bucket, "poll/file", "file"), Equals, true)
+	c.Assert(waitObjectData(bucket, "poll/sub/file", "sub"), Equals, true)
+
+	c.Assert(os.Remove(dir+"/file"), IsNil)
+	c.Assert(os.RemoveAll(dir+"/sub"), IsNil)
+	s.createFile(dir+"/new", "new", c)
+	c.Assert(waitObjectData(bucket, "poll/new", "new"), Equals, true)
+	c.Assert(waitObjectDeleted(bucket, "poll/file"), Equals, true)
+	c.Assert(waitObjectDeleted(bucket, "poll/sub/file"), Equals, true)
+	c.Assert(waitObjectDeleted(bucket, "poll/sub/"), Equals, true)
+
+	c.Assert(stop(), Equals, context.Canceled)
+}
+
+func (s *OssutilCommandSuite) TestCopyWatchRetry(c *C) {
+	server := newFakeOSSServer("watchID", "watchSecret")
+	defer server.close()
+	client, err := oss.New(server.endpoint(), "watchID", "watchSecret")
+	c.Assert(err, IsNil)
+	bucketName := bucketNamePrefix + randLowStr(10)
+	c.Assert(client.CreateBucket(bucketName), IsNil)
+	bucket, err := client.Bucket(bucketName)
+	c.Assert(err, IsNil)
+
+	dir := randStr(10)
+	c.Assert(os.MkdirAll(dir, 0755), IsNil)
+	defer os.RemoveAll(dir)
+	s.createFile(dir+"/first", "first", c)
+
+	// the failed upload of the first pass is retried too
+	server.inject(fakeOSSFault{op: "PutObject", object: "first", status: http.StatusInternalServerError, code: "InternalError", times: 1})
+	str := ""
+	ep, id, secret := server.endpoint(), "watchID", "watchSecret"
+	retryTimes := "1"
+	quietPeriod := "1"
+	stop := s.startWatch(dir, CloudURLToString(bucketName, ""), OptionMapType{
+		OptionEndpoint:        &ep,
+		OptionAccessKeyID:     &id,
+		OptionAccessKeySecret: &secret,
+		OptionSTSToken:        &str,
+		OptionRetryTimes:      &retryTimes,
+		OptionQuietPeriod:     &quietPeriod,
+	}, c)
+	c.Assert(waitObjectData(bucket, "first", "first"), Equals, true)
+	c.Assert(server.requestCount("PutObject"), Equals, 2)
+
+	// the failed change is retried with backoff
+	server.inject(fakeOSSFault{op: "PutObject", object: "retry", status: http.StatusInternalServerError, code: "InternalError", times: 2})
+	s.createFile(dir+"/retry", "retry", c)
+	c.Assert(waitObjectData(bucket, "retry", "retry"), Equals, true)
+	c.Assert(server.requestCount("PutObject"), Equals, 5)
+
+	c.Assert(stop(), Equals, context.Canceled)
+	c.Assert(copyCommand.monitor.errNum, Equals, int64(3))
+
+	c.Assert(watchRetryInterval(1), Equals, time.Second)
+	c.Assert(watchRetryInterval(3), Equals, 4*time.Second)
+	c.Assert(watchRetryInterval(WatchMaxRetry), Equals, time.Duration(WatchMaxRetryInterval)*time.Second)
+	c.Assert(watchRetryInterval(100), Equals, time.Duration(WatchMaxRetryInterval)*time.Second)
+}
+
+func (s *OssutilCommandSuite) TestCopyWatchArgs(c *C) {
+	dir := randStr(10)
+	c.Assert(os.MkdirAll(dir, 0755), IsNil)
+	defer os.RemoveAll(dir)
+	s.createFile(dir+"/file", "file", c)
+	bucketName := bucketNameExist
+	watch := true
+	remove := true
+
+	// without --recursive
+	err := s.initCopyCommand(dir, CloudURLToString(bucketName, ""), false, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	copyCommand.command.options[OptionWatch] = &watch
+	c.Assert(copyCommand.RunCommand(), NotNil)
+
+	// download
+	err = s.initCopyCommand(CloudURLToString(bucketName, ""), dir, true, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	copyCommand.command.options[OptionWatch] = &watch
+	c.Assert(copyCommand.RunCommand(), NotNil)
+
+	// --delete without --watch
+	err = s.initCopyCommand(dir, CloudURLToString(bucketName, ""), true, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	copyCommand.command.options[OptionDelete] = &remove
+	c.Assert(copyCommand.RunCommand(), NotNil)
+
+	// file
+	err = s.initCopyCommand(dir+"/file", CloudURLToString(bucketName, ""), true, true, false, DefaultBigFileThreshold, CheckpointDir, DefaultOutputDir)
+	c.Assert(err, IsNil)
+	copyCommand.command.options[OptionWatch] = &watch
+	c.Assert(copyCommand.RunCommand(), NotNil)
+}